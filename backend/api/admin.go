@@ -4,41 +4,91 @@ import (
 	"database/sql"
 	"net/http"
 
+	audit "github.com/nicograef/jotti/backend/api/audit/http"
+	auth "github.com/nicograef/jotti/backend/api/auth/http"
+	device "github.com/nicograef/jotti/backend/api/device/http"
+	"github.com/nicograef/jotti/backend/api/openapi"
+	period "github.com/nicograef/jotti/backend/api/period/http"
 	product "github.com/nicograef/jotti/backend/api/product/http"
+	report "github.com/nicograef/jotti/backend/api/report/http"
+	roles "github.com/nicograef/jotti/backend/api/role/http"
 	table "github.com/nicograef/jotti/backend/api/table/http"
 	user "github.com/nicograef/jotti/backend/api/user/http"
+	"github.com/nicograef/jotti/backend/config"
+	"github.com/nicograef/jotti/backend/domain/jwt"
+	"github.com/nicograef/jotti/backend/domain/role"
 )
 
-func NewAdminApi(db *sql.DB) http.Handler {
-	r := http.NewServeMux()
+func NewAdminApi(cfg config.Config, db *sql.DB, jwtKeys jwt.Keys, doc *openapi.Document) *http.ServeMux {
+	r := newRouter(doc, "/admin", true)
 
-	uc := user.NewCommandHandler(db)
-	r.HandleFunc("/create-user", uc.CreateUserHandler())
-	r.HandleFunc("/update-user", uc.UpdateUserHandler())
-	r.HandleFunc("/activate-user", uc.ActivateUserHandler())
-	r.HandleFunc("/deactivate-user", uc.DeactivateUserHandler())
-	r.HandleFunc("/reset-password", uc.ResetPasswordHandler())
+	uc := user.NewCommandHandler(db, passwordPolicy(cfg))
+	r.handle("/create-user", role.ManageUsers, uc.CreateUserHandler(), user.CreateUserOperation)
+	r.handle("/update-user", role.ManageUsers, uc.UpdateUserHandler(), user.UpdateUserOperation)
+	r.handle("/activate-user", role.ManageUsers, uc.ActivateUserHandler(), user.ActivateUserOperation)
+	r.handle("/deactivate-user", role.ManageUsers, uc.DeactivateUserHandler(), user.DeactivateUserOperation)
+	r.handle("/reset-password", role.ManageUsers, uc.ResetPasswordHandler(), user.ResetPasswordOperation)
+	r.handle("/require-password-change", role.ManageUsers, uc.RequirePasswordChangeHandler(), user.RequirePasswordChangeOperation)
 
 	uq := user.NewQueryHandler(db)
-	r.HandleFunc("/get-all-users", uq.GetAllUsersHandler())
+	r.handle("/get-all-users", role.ManageUsers, uq.GetAllUsersHandler(), user.GetAllUsersOperation)
+
+	rolec := roles.NewCommandHandler(db)
+	r.handle("/create-role", role.ManageRoles, rolec.CreateRoleHandler(), roles.CreateRoleOperation)
+	r.handle("/update-role", role.ManageRoles, rolec.UpdateRoleHandler(), roles.UpdateRoleOperation)
+	r.handle("/delete-role", role.ManageRoles, rolec.DeleteRoleHandler(), roles.DeleteRoleOperation)
+
+	// Listing roles is part of user management, roles are assigned to users there
+	roleq := roles.NewQueryHandler(db)
+	r.handle("/get-all-roles", role.ManageUsers, roleq.GetAllRolesHandler(), roles.GetAllRolesOperation)
+
+	ac := auth.NewCommandHandler(db, jwtKeys, passwordPolicy(cfg))
+	r.handle("/clear-login-attempts", role.ManageUsers, ac.ClearLoginAttemptsHandler(), auth.ClearLoginAttemptsOperation)
+
+	aq := auth.NewQueryHandler(db)
+	r.handle("/get-login-attempts", role.ManageUsers, aq.GetLoginAttemptsHandler(), auth.GetLoginAttemptsOperation)
+
+	dc := device.NewCommandHandler(db)
+	r.handle("/register-device", role.ManageDevices, dc.RegisterDeviceHandler(), device.RegisterDeviceOperation)
+	r.handle("/revoke-device", role.ManageDevices, dc.RevokeDeviceHandler(), device.RevokeDeviceOperation)
+
+	dq := device.NewQueryHandler(db)
+	r.handle("/get-all-devices", role.ManageDevices, dq.GetAllDevicesHandler(), device.GetAllDevicesOperation)
 
 	pc := product.NewCommandHandler(db)
-	r.HandleFunc("/create-product", pc.CreateProductHandler())
-	r.HandleFunc("/update-product", pc.UpdateProductHandler())
-	r.HandleFunc("/activate-product", pc.ActivateProductHandler())
-	r.HandleFunc("/deactivate-product", pc.DeactivateProductHandler())
+	r.handle("/create-product", role.ManageProducts, pc.CreateProductHandler(), product.CreateProductOperation)
+	r.handle("/update-product", role.ManageProducts, pc.UpdateProductHandler(), product.UpdateProductOperation)
+	r.handle("/activate-product", role.ManageProducts, pc.ActivateProductHandler(), product.ActivateProductOperation)
+	r.handle("/deactivate-product", role.ManageProducts, pc.DeactivateProductHandler(), product.DeactivateProductOperation)
 
 	pq := product.NewQueryHandler(db)
-	r.HandleFunc("/get-all-products", pq.GetAllProductsHandler())
+	r.handle("/get-all-products", role.ManageProducts, pq.GetAllProductsHandler(), product.GetAllProductsOperation)
+	r.handle("/get-product-at", role.ViewReports, pq.GetProductAtHandler(), product.GetProductAtOperation)
 
-	tc := table.NewCommandHandler(db)
-	r.HandleFunc("/update-table", tc.UpdateTableHandler())
-	r.HandleFunc("/create-table", tc.CreateTableHandler())
-	r.HandleFunc("/activate-table", tc.ActivateTableHandler())
-	r.HandleFunc("/deactivate-table", tc.DeactivateTableHandler())
+	tc := table.NewCommandHandler(db, cfg.SyncMaxItemAge)
+	r.handle("/update-table", role.ManageTables, tc.UpdateTableHandler(), table.UpdateTableOperation)
+	r.handle("/create-table", role.ManageTables, tc.CreateTableHandler(), table.CreateTableOperation)
+	r.handle("/activate-table", role.ManageTables, tc.ActivateTableHandler(), table.ActivateTableOperation)
+	r.handle("/deactivate-table", role.ManageTables, tc.DeactivateTableHandler(), table.DeactivateTableOperation)
+	r.handle("/force-close-table", role.ForceCloseTables, tc.ForceCloseTableHandler(), table.ForceCloseTableOperation)
+	r.handle("/write-off-table-items", role.WriteOffItems, tc.WriteOffTableItemsHandler(), table.WriteOffTableItemsOperation)
 
 	tq := table.NewQueryHandler(db)
-	r.HandleFunc("/get-all-tables", tq.GetAllTablesHandler())
+	r.handle("/get-all-tables", role.ManageTables, tq.GetAllTablesHandler(), table.GetAllTablesOperation)
+
+	rq := report.NewQueryHandler(db, cfg.ReportLocation)
+	r.handle("/get-daily-report", role.ViewReports, rq.GetDailyReportHandler(), report.GetDailyReportOperation)
+	r.handle("/get-period-report", role.ViewReports, rq.GetPeriodReportHandler(), report.GetPeriodReportOperation)
+
+	auq := audit.NewQueryHandler(db)
+	r.handle("/get-audit-events", role.ViewAudit, auq.GetAuditEventsHandler(), audit.GetAuditEventsOperation)
+
+	perc := period.NewCommandHandler(db)
+	r.handle("/open-period", role.ManagePeriods, perc.OpenPeriodHandler(), period.OpenPeriodOperation)
+	r.handle("/close-period", role.ManagePeriods, perc.ClosePeriodHandler(), period.ClosePeriodOperation)
+
+	perq := period.NewQueryHandler(db)
+	r.handle("/get-all-periods", role.ManagePeriods, perq.GetAllPeriodsHandler(), period.GetAllPeriodsOperation)
 
-	return r
+	return r.mux
 }
@@ -0,0 +1,11 @@
+package application
+
+import (
+	"errors"
+)
+
+// ErrDatabase is returned when there is a database error.
+var ErrDatabase = errors.New("database error")
+
+// ErrInvalidTimeRange is returned when the start of the requested time range is not before its end.
+var ErrInvalidTimeRange = errors.New("invalid time range")
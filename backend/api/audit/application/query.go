@@ -0,0 +1,97 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nicograef/jotti/backend/domain/audit"
+	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/tracing"
+	"github.com/rs/zerolog"
+)
+
+// MaxAuditEvents is the maximum number of audit events returned by one query.
+const MaxAuditEvents = 500
+
+type eventRepoQuery interface {
+	ReadEvents(ctx context.Context, f event.Filter) ([]event.Event, error)
+}
+
+type Query struct {
+	EventRepo eventRepoQuery
+}
+
+// Filter selects audit events. Zero values do not restrict the result.
+type Filter struct {
+	ActorID int
+	// Subject of the changed entity, e.g. "audit:user:3".
+	Subject string
+	// From and To limit the events to [From, To).
+	From time.Time
+	To   time.Time
+}
+
+// AuditEvent is a change an admin (actor) made to a user, product, table, role or device.
+type AuditEvent struct {
+	ID      int             `json:"id"`
+	ActorID int             `json:"actorId"`
+	Type    string          `json:"type"`
+	Subject string          `json:"subject"`
+	Time    time.Time       `json:"time"`
+	Before  json.RawMessage `json:"before"`
+	After   json.RawMessage `json:"after"`
+}
+
+// GetAuditEvents returns the latest audit events (at most MaxAuditEvents) selected by the filter, latest first.
+func (q Query) GetAuditEvents(ctx context.Context, f Filter) ([]AuditEvent, error) {
+	ctx, span := tracing.Start(ctx, "audit.GetAuditEvents")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		log.Warn().Time("from", f.From).Time("to", f.To).Msg("Invalid time range for audit events")
+		return nil, ErrInvalidTimeRange
+	}
+
+	types := make([]string, len(audit.EventTypes))
+	for i, t := range audit.EventTypes {
+		types[i] = string(t)
+	}
+
+	events, err := q.EventRepo.ReadEvents(ctx, event.Filter{
+		Types:   types,
+		UserID:  f.ActorID,
+		Subject: f.Subject,
+		From:    f.From,
+		To:      f.To,
+		Limit:   MaxAuditEvents,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to read audit events")
+		return nil, ErrDatabase
+	}
+
+	auditEvents := make([]AuditEvent, 0, len(events))
+	for _, e := range events {
+		change, err := audit.ParseChange(e)
+		if err != nil {
+			log.Error().Err(err).Int("event_id", e.ID).Msg("Failed to parse audit event")
+			return nil, err
+		}
+
+		auditEvents = append(auditEvents, AuditEvent{
+			ID:      e.ID,
+			ActorID: e.UserID,
+			Type:    e.Type,
+			Subject: e.Subject,
+			Time:    e.Time,
+			Before:  change.Before,
+			After:   change.After,
+		})
+	}
+
+	log.Info().Int("events", len(auditEvents)).Msg("Retrieved audit events")
+	return auditEvents, nil
+}
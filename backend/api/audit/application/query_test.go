@@ -0,0 +1,78 @@
+//go:build unit
+
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nicograef/jotti/backend/domain/audit"
+	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/product"
+	"github.com/nicograef/jotti/backend/domain/table"
+	"github.com/nicograef/jotti/backend/repository/event_repo"
+)
+
+func TestGetAuditEvents(t *testing.T) {
+	created, err := audit.NewEvent(1, audit.EventTypeProductCreatedV1, audit.ProductEntity, "5", nil, product.Product{ID: 5, Name: "Bier", NetPriceCents: 400})
+	if err != nil {
+		t.Fatalf("expected no error creating event, got %v", err)
+	}
+	created.ID = 1
+	created.Time = time.Date(2025, 6, 13, 20, 0, 0, 0, time.UTC)
+	updated, err := audit.NewEvent(2, audit.EventTypeProductUpdatedV1, audit.ProductEntity, "5", product.Product{ID: 5, Name: "Bier", NetPriceCents: 400}, product.Product{ID: 5, Name: "Bier", NetPriceCents: 450})
+	if err != nil {
+		t.Fatalf("expected no error creating event, got %v", err)
+	}
+	updated.ID = 2
+	updated.Time = time.Date(2025, 6, 14, 20, 0, 0, 0, time.UTC)
+	order, err := table.NewOrderPlacedEvent(2, 1, "", []table.OrderProduct{{ID: 5, Name: "Bier", NetPriceCents: 450, Quantity: 1}})
+	if err != nil {
+		t.Fatalf("expected no error creating event, got %v", err)
+	}
+	order.ID = 3
+	order.Time = time.Date(2025, 6, 14, 21, 0, 0, 0, time.UTC)
+
+	query := Query{EventRepo: event_repo.NewMock([]event.Event{created, updated, order}, nil)}
+
+	all, err := query.GetAuditEvents(context.Background(), Filter{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected only the 2 audit events, got %d", len(all))
+	}
+	if all[0].ID != 2 {
+		t.Errorf("expected latest event first, got %d", all[0].ID)
+	}
+
+	byActor, err := query.GetAuditEvents(context.Background(), Filter{ActorID: 1, Subject: audit.Subject(audit.ProductEntity, "5")})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(byActor) != 1 || byActor[0].Type != string(audit.EventTypeProductCreatedV1) {
+		t.Fatalf("expected the product created event of actor 1, got %v", byActor)
+	}
+	if string(byActor[0].Before) != "null" {
+		t.Errorf("expected no before value for created product, got %s", byActor[0].Before)
+	}
+
+	byTime, err := query.GetAuditEvents(context.Background(), Filter{From: time.Date(2025, 6, 14, 0, 0, 0, 0, time.UTC), To: time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(byTime) != 1 || byTime[0].ID != 2 {
+		t.Fatalf("expected the product updated event, got %v", byTime)
+	}
+}
+
+func TestGetAuditEvents_InvalidTimeRange(t *testing.T) {
+	query := Query{EventRepo: event_repo.NewMock(nil, nil)}
+
+	from := time.Date(2025, 6, 14, 0, 0, 0, 0, time.UTC)
+	_, err := query.GetAuditEvents(context.Background(), Filter{From: from, To: from})
+	if err != ErrInvalidTimeRange {
+		t.Fatalf("expected invalid time range error, got %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+package http
+
+import (
+	"database/sql"
+
+	"github.com/nicograef/jotti/backend/api/audit/application"
+	"github.com/nicograef/jotti/backend/repository/event_repo"
+)
+
+func NewQueryHandler(db *sql.DB) QueryHandler {
+	repo := event_repo.Repository{DB: db}
+	query := application.Query{EventRepo: repo}
+	return QueryHandler{Query: query}
+}
@@ -0,0 +1,12 @@
+package http
+
+import "github.com/nicograef/jotti/backend/api/openapi"
+
+// Operations of the handlers in the OpenAPI document.
+
+var GetAuditEventsOperation = openapi.Operation{
+	Summary:  "List the changes admins made to users, products, tables, roles and devices",
+	Request:  getAuditEvents{},
+	Response: getAuditEventsResponse{},
+	Errors:   []string{"invalid_time_range"},
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/nicograef/jotti/backend/api/audit/application"
+	"github.com/nicograef/jotti/backend/api/helper"
+)
+
+type query interface {
+	GetAuditEvents(ctx context.Context, f application.Filter) ([]application.AuditEvent, error)
+}
+
+type QueryHandler struct {
+	Query query
+}
+
+type getAuditEvents struct {
+	ActorID int       `json:"actorId"` // 0 or omitted for all actors
+	Subject string    `json:"subject"` // e.g. "audit:user:3", omitted for all subjects
+	From    time.Time `json:"from"`    // RFC 3339, inclusive
+	To      time.Time `json:"to"`      // RFC 3339, exclusive
+}
+
+type getAuditEventsResponse struct {
+	AuditEvents []application.AuditEvent `json:"auditEvents"`
+}
+
+// GetAuditEventsHandler returns the changes admins made to users, products, tables, roles and devices.
+func (h *QueryHandler) GetAuditEventsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := getAuditEvents{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		filter := application.Filter{ActorID: body.ActorID, Subject: body.Subject, From: body.From, To: body.To}
+		auditEvents, err := h.Query.GetAuditEvents(r.Context(), filter)
+		if err != nil {
+			if errors.Is(err, application.ErrInvalidTimeRange) {
+				helper.SendClientError(w, "invalid_time_range", nil)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendResponse(w, getAuditEventsResponse{AuditEvents: auditEvents})
+	}
+}
@@ -0,0 +1,58 @@
+//go:build unit
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nicograef/jotti/backend/api/audit/application"
+)
+
+type mockQuery struct {
+	err    error
+	filter application.Filter
+}
+
+func (m *mockQuery) GetAuditEvents(ctx context.Context, f application.Filter) ([]application.AuditEvent, error) {
+	m.filter = f
+	return []application.AuditEvent{{ID: 1, ActorID: 1, Type: "user.created:v1", Subject: "audit:user:3"}}, m.err
+}
+
+func TestGetAuditEventsHandler_Success(t *testing.T) {
+	query := &mockQuery{}
+	handler := &QueryHandler{Query: query}
+
+	body := `{"actorId":1,"subject":"audit:user:3","from":"2025-06-14T00:00:00Z"}`
+	req := httptest.NewRequest(http.MethodPost, "/get-audit-events", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.GetAuditEventsHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"auditEvents"`) {
+		t.Errorf("expected audit events in response, got %s", rec.Body.String())
+	}
+	if query.filter.ActorID != 1 || query.filter.Subject != "audit:user:3" || query.filter.From.IsZero() || !query.filter.To.IsZero() {
+		t.Errorf("unexpected filter: %+v", query.filter)
+	}
+}
+
+func TestGetAuditEventsHandler_InvalidTimeRange(t *testing.T) {
+	handler := &QueryHandler{Query: &mockQuery{err: application.ErrInvalidTimeRange}}
+
+	body := `{"from":"2025-06-15T00:00:00Z","to":"2025-06-14T00:00:00Z"}`
+	req := httptest.NewRequest(http.MethodPost, "/get-audit-events", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.GetAuditEventsHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
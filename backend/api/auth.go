@@ -5,15 +5,38 @@ import (
 	"net/http"
 
 	auth "github.com/nicograef/jotti/backend/api/auth/http"
+	"github.com/nicograef/jotti/backend/api/openapi"
 	"github.com/nicograef/jotti/backend/config"
+	"github.com/nicograef/jotti/backend/domain/jwt"
+	"github.com/nicograef/jotti/backend/domain/user"
 )
 
-func NewAuthApi(cfg config.Config, db *sql.DB) http.Handler {
-	r := http.NewServeMux()
+func NewAuthApi(cfg config.Config, db *sql.DB, jwtKeys jwt.Keys, doc *openapi.Document) *http.ServeMux {
+	r := newRouter(doc, "/auth", false)
 
-	ah := auth.NewCommandHandler(db, cfg.JWTSecret)
-	r.HandleFunc("/login", ah.LoginHandler())
-	r.HandleFunc("/set-password", ah.SetPasswordHandler())
+	ah := auth.NewCommandHandler(db, jwtKeys, passwordPolicy(cfg))
+	r.handle("/login", "", ah.LoginHandler(), auth.LoginOperation)
+	r.handle("/refresh", "", ah.RefreshHandler(), auth.RefreshOperation)
+	r.handle("/logout", "", ah.LogoutHandler(), auth.LogoutOperation)
+	r.handle("/set-password", "", ah.SetPasswordHandler(), auth.SetPasswordOperation)
+	r.handle("/change-password", "", ah.ChangePasswordHandler(), auth.ChangePasswordOperation)
+	r.handle("/pin-login", "", ah.PinLoginHandler(), auth.PinLoginOperation)
 
-	return r
+	aq := auth.NewQueryHandler(db)
+	r.handle("/get-device-users", "", aq.GetDeviceUsersHandler(), auth.GetDeviceUsersOperation)
+
+	return r.mux
+}
+
+// passwordPolicy returns the configured policy for new passwords.
+func passwordPolicy(cfg config.Config) user.PasswordPolicy {
+	return user.PasswordPolicy{
+		MinLength:       cfg.PasswordMinLength,
+		CommonPasswords: cfg.CommonPasswords,
+		Hashing: user.HashParams{
+			MemoryCost: uint32(cfg.Argon2.MemoryKiB),
+			TimeCost:   uint32(cfg.Argon2.Iterations),
+			Threads:    uint8(cfg.Argon2.Parallelism),
+		},
+	}
 }
@@ -3,58 +3,394 @@ package application
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/device"
+	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/jwt"
+	"github.com/nicograef/jotti/backend/domain/login"
+	"github.com/nicograef/jotti/backend/domain/role"
+	"github.com/nicograef/jotti/backend/domain/session"
 	"github.com/nicograef/jotti/backend/domain/user"
+	"github.com/nicograef/jotti/backend/domain/validation"
+	"github.com/nicograef/jotti/backend/tracing"
 	"github.com/rs/zerolog"
 )
 
 type commandUserRepo interface {
+	GetUser(ctx context.Context, userID int) (user.User, error)
 	GetUserByUsername(ctx context.Context, username string) (user.User, error)
 	UpdateUser(ctx context.Context, u user.User) error
+	CountOnetimePasswordAttempt(ctx context.Context, userID int) (int, error)
+}
+
+type roleRepo interface {
+	GetRole(ctx context.Context, name string) (role.Role, error)
+}
+
+type commandSessionRepo interface {
+	CreateSession(ctx context.Context, s session.Session) error
+	GetSessionByRefreshTokenHash(ctx context.Context, hash string) (session.Session, error)
+	UpdateSession(ctx context.Context, s session.Session, refreshTokenHash string) error
+	GetSessionByUsedRefreshTokenHash(ctx context.Context, hash string) (session.Session, error)
+	RevokeSession(ctx context.Context, id string) error
+	RevokeUserSessions(ctx context.Context, userID int) error
+}
+
+type commandAttemptsRepo interface {
+	GetAttempts(ctx context.Context, kind login.Kind, value string) (login.Attempts, error)
+	CountFailedAttempt(ctx context.Context, kind login.Kind, value string, now time.Time) (login.Attempts, error)
+	BlockAttempts(ctx context.Context, kind login.Kind, value string, until time.Time) error
+	DeleteAttempts(ctx context.Context, kind login.Kind, value string) error
+}
+
+type commandEventRepo interface {
+	WriteEvent(ctx context.Context, e event.Event) (int, error)
 }
 
 type Command struct {
-	JWTSecret string
-	UserRepo  commandUserRepo
+	JWTKeys        jwt.Keys
+	PasswordPolicy user.PasswordPolicy
+	UserRepo       commandUserRepo
+	SessionRepo    commandSessionRepo
+	AttemptsRepo   commandAttemptsRepo
+	EventRepo      commandEventRepo
+	DeviceRepo     deviceRepo
+	RoleRepo       roleRepo
 }
 
-func (c Command) GenerateJWTToken(ctx context.Context, username, password string) (string, error) {
+// Tokens are returned on login and refresh. The access token is a short-lived JWT, the refresh token
+// is an opaque value that can be exchanged once for new tokens.
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// Login verifies the credentials and starts a new session.
+// Failed attempts are counted per username and client IP; too many failures block further attempts for a while.
+// Every rejected login is recorded as audit event.
+func (c Command) Login(ctx context.Context, username, password, clientIP string) (Tokens, error) {
+	ctx, span := tracing.Start(ctx, "auth.Login")
+	defer span.End()
+
 	log := zerolog.Ctx(ctx)
+	now := time.Now().UTC()
+
+	usernameAttempts, ipAttempts, err := c.getLoginAttempts(ctx, username, clientIP)
+	if err != nil {
+		log.Error().Err(err).Str("username", username).Msg("Failed to retrieve failed login attempts")
+		return Tokens{}, ErrDatabase
+	}
+
+	if retryAfter := max(usernameAttempts.RetryAfter(now), ipAttempts.RetryAfter(now)); retryAfter > 0 {
+		log.Warn().Str("username", username).Str("client_ip", clientIP).Dur("retry_after", retryAfter).Msg("Login attempt while blocked")
+		c.writeLoginFailedEvent(ctx, 0, username, clientIP, login.BlockedReason)
+		return Tokens{}, BlockedError{RetryAfter: retryAfter}
+	}
 
 	u, err := c.UserRepo.GetUserByUsername(ctx, username)
 	if err != nil {
 		if errors.Is(err, db.ErrNotFound) {
 			log.Warn().Str("username", username).Msg("User not found during login")
-			return "", ErrUserNotFound
+			c.recordFailedLogin(ctx, now, usernameAttempts, ipAttempts)
+			c.writeLoginFailedEvent(ctx, 0, username, clientIP, login.UnknownUserReason)
+			return Tokens{}, ErrUserNotFound
 		} else {
 			log.Error().Str("username", username).Msg("Failed to retrieve user ID")
-			return "", ErrDatabase
+			return Tokens{}, ErrDatabase
 		}
 	}
 
-	token, err := u.GenerateJWTToken(password, c.JWTSecret)
+	r, err := c.RoleRepo.GetRole(ctx, string(u.Role))
+	if err != nil {
+		log.Error().Err(err).Str("username", username).Str("role", string(u.Role)).Msg("Failed to retrieve role of user")
+		return Tokens{}, ErrDatabase
+	}
+
+	s, refreshToken, err := session.NewSession(u.ID)
+	if err != nil {
+		log.Error().Err(err).Str("username", username).Msg("Failed to create session")
+		return Tokens{}, ErrTokenGeneration
+	}
+
+	passwordHash := u.PasswordHash
+	token, err := u.GenerateJWTToken(password, r, s.ID, c.JWTKeys, c.PasswordPolicy.HashParams())
 	if err != nil {
 		if errors.Is(err, user.ErrNotActive) {
 			log.Warn().Str("username", username).Msg("Inactive user attempted to log in")
-			return "", ErrNotActive
+			c.writeLoginFailedEvent(ctx, u.ID, username, clientIP, login.UserInactiveReason)
+			return Tokens{}, ErrNotActive
 		} else if errors.Is(err, user.ErrNoPassword) {
 			log.Warn().Str("username", username).Msg("No password set for user during login")
-			return "", ErrNoPassword
+			c.writeLoginFailedEvent(ctx, u.ID, username, clientIP, login.NoPasswordReason)
+			return Tokens{}, ErrNoPassword
 		} else if errors.Is(err, user.ErrInvalidPassword) {
 			log.Warn().Err(err).Str("username", username).Msg("Password validation failed")
-			return "", ErrInvalidPassword
+			c.recordFailedLogin(ctx, now, usernameAttempts, ipAttempts)
+			c.writeLoginFailedEvent(ctx, u.ID, username, clientIP, login.InvalidPasswordReason)
+			return Tokens{}, ErrInvalidPassword
+		} else if errors.Is(err, user.ErrPasswordChangeRequired) {
+			log.Warn().Str("username", username).Msg("Login of user who has to change their password")
+			c.writeLoginFailedEvent(ctx, u.ID, username, clientIP, login.PasswordChangeRequiredReason)
+			return Tokens{}, ErrPasswordChangeRequired
 		} else {
 			log.Error().Err(err).Str("username", username).Msg("Failed to generate JWT token")
-			return "", ErrTokenGeneration
+			return Tokens{}, ErrTokenGeneration
+		}
+	}
+
+	err = c.SessionRepo.CreateSession(ctx, s)
+	if err != nil {
+		log.Error().Err(err).Str("username", username).Msg("Failed to store session")
+		return Tokens{}, ErrDatabase
+	}
+
+	if u.PasswordHash != passwordHash {
+		// The password was rehashed with stronger parameters. If storing fails, the next login tries again.
+		if err := c.UserRepo.UpdateUser(ctx, u); err != nil {
+			log.Error().Err(err).Str("username", username).Msg("Failed to store rehashed password")
+		} else {
+			log.Info().Str("username", username).Msg("Password rehashed with current parameters")
+		}
+	}
+
+	if usernameAttempts.FailedAttempts > 0 {
+		err = c.AttemptsRepo.DeleteAttempts(ctx, usernameAttempts.Kind, usernameAttempts.Value)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			log.Error().Err(err).Str("username", username).Msg("Failed to reset failed login attempts")
+		}
+	}
+
+	log.Info().Str("username", username).Str("session_id", s.ID).Msg("User logged in successfully")
+	return Tokens{AccessToken: token, RefreshToken: refreshToken}, nil
+}
+
+// PinLogin logs a user in on a registered device with their PIN. The session is bound to the device
+// and ends after session.DeviceIdleTimeout without activity.
+// Failed attempts are throttled and recorded like failed password logins.
+func (c Command) PinLogin(ctx context.Context, deviceToken string, userID int, pin, clientIP string) (Tokens, error) {
+	ctx, span := tracing.Start(ctx, "auth.PinLogin")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+	now := time.Now().UTC()
+
+	d, err := getActiveDevice(ctx, c.DeviceRepo, deviceToken)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	u, err := c.UserRepo.GetUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			log.Warn().Int("user_id", userID).Int("device_id", d.ID).Msg("User not found during PIN login")
+			return Tokens{}, ErrUserNotFound
+		} else {
+			log.Error().Err(err).Int("user_id", userID).Msg("Failed to retrieve user")
+			return Tokens{}, ErrDatabase
+		}
+	}
+
+	usernameAttempts, ipAttempts, err := c.getLoginAttempts(ctx, u.Username, clientIP)
+	if err != nil {
+		log.Error().Err(err).Str("username", u.Username).Msg("Failed to retrieve failed login attempts")
+		return Tokens{}, ErrDatabase
+	}
+
+	if retryAfter := max(usernameAttempts.RetryAfter(now), ipAttempts.RetryAfter(now)); retryAfter > 0 {
+		log.Warn().Str("username", u.Username).Str("client_ip", clientIP).Dur("retry_after", retryAfter).Msg("PIN login attempt while blocked")
+		c.writeLoginFailedEvent(ctx, u.ID, u.Username, clientIP, login.BlockedReason)
+		return Tokens{}, BlockedError{RetryAfter: retryAfter}
+	}
+
+	r, err := c.RoleRepo.GetRole(ctx, string(u.Role))
+	if err != nil {
+		log.Error().Err(err).Str("username", u.Username).Str("role", string(u.Role)).Msg("Failed to retrieve role of user")
+		return Tokens{}, ErrDatabase
+	}
+
+	s, refreshToken, err := session.NewDeviceSession(u.ID, d.ID)
+	if err != nil {
+		log.Error().Err(err).Str("username", u.Username).Msg("Failed to create session")
+		return Tokens{}, ErrTokenGeneration
+	}
+
+	token, err := u.GenerateJWTTokenWithPin(pin, r, s.ID, c.JWTKeys)
+	if err != nil {
+		if errors.Is(err, user.ErrNotActive) {
+			log.Warn().Str("username", u.Username).Msg("Inactive user attempted to log in with PIN")
+			c.writeLoginFailedEvent(ctx, u.ID, u.Username, clientIP, login.UserInactiveReason)
+			return Tokens{}, ErrNotActive
+		} else if errors.Is(err, user.ErrNoPin) {
+			log.Warn().Str("username", u.Username).Msg("No PIN set for user during PIN login")
+			c.writeLoginFailedEvent(ctx, u.ID, u.Username, clientIP, login.NoPinReason)
+			return Tokens{}, ErrNoPin
+		} else if errors.Is(err, user.ErrInvalidPin) {
+			log.Warn().Str("username", u.Username).Msg("PIN validation failed")
+			c.recordFailedLogin(ctx, now, usernameAttempts, ipAttempts)
+			c.writeLoginFailedEvent(ctx, u.ID, u.Username, clientIP, login.InvalidPinReason)
+			return Tokens{}, ErrInvalidPin
+		} else if errors.Is(err, user.ErrPasswordChangeRequired) {
+			log.Warn().Str("username", u.Username).Msg("PIN login of user who has to change their password")
+			c.writeLoginFailedEvent(ctx, u.ID, u.Username, clientIP, login.PasswordChangeRequiredReason)
+			return Tokens{}, ErrPasswordChangeRequired
+		} else {
+			log.Error().Err(err).Str("username", u.Username).Msg("Failed to generate JWT token")
+			return Tokens{}, ErrTokenGeneration
+		}
+	}
+
+	err = c.SessionRepo.CreateSession(ctx, s)
+	if err != nil {
+		log.Error().Err(err).Str("username", u.Username).Msg("Failed to store session")
+		return Tokens{}, ErrDatabase
+	}
+
+	if usernameAttempts.FailedAttempts > 0 {
+		err = c.AttemptsRepo.DeleteAttempts(ctx, usernameAttempts.Kind, usernameAttempts.Value)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			log.Error().Err(err).Str("username", u.Username).Msg("Failed to reset failed login attempts")
 		}
 	}
 
-	log.Info().Str("username", username).Msg("User logged in successfully")
-	return token, nil
+	log.Info().Str("username", u.Username).Int("device_id", d.ID).Str("session_id", s.ID).Msg("User logged in with PIN successfully")
+	return Tokens{AccessToken: token, RefreshToken: refreshToken}, nil
 }
 
+// Refresh exchanges a refresh token for a new access token and a new refresh token.
+// The access token gets the current role and permissions of the user.
+func (c Command) Refresh(ctx context.Context, refreshToken string) (Tokens, error) {
+	ctx, span := tracing.Start(ctx, "auth.Refresh")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	refreshTokenHash := session.HashRefreshToken(refreshToken)
+	s, err := c.SessionRepo.GetSessionByRefreshTokenHash(ctx, refreshTokenHash)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return Tokens{}, c.revokeOnReuse(ctx, refreshTokenHash)
+		} else {
+			log.Error().Err(err).Msg("Failed to retrieve session")
+			return Tokens{}, ErrDatabase
+		}
+	}
+
+	if !s.IsActive(time.Now()) {
+		log.Warn().Str("session_id", s.ID).Msg("Refresh token of revoked or expired session")
+		return Tokens{}, ErrInvalidRefreshToken
+	}
+
+	u, err := c.UserRepo.GetUser(ctx, s.UserID)
+	if err != nil {
+		log.Error().Err(err).Int("user_id", s.UserID).Msg("Failed to retrieve user for session")
+		return Tokens{}, ErrDatabase
+	}
+
+	if u.Status != user.ActiveStatus {
+		log.Warn().Int("user_id", u.ID).Msg("Inactive user attempted to refresh session")
+		return Tokens{}, ErrNotActive
+	}
+
+	r, err := c.RoleRepo.GetRole(ctx, string(u.Role))
+	if err != nil {
+		log.Error().Err(err).Int("user_id", u.ID).Str("role", string(u.Role)).Msg("Failed to retrieve role of user")
+		return Tokens{}, ErrDatabase
+	}
+
+	newRefreshToken, err := s.Rotate()
+	if err != nil {
+		log.Error().Err(err).Str("session_id", s.ID).Msg("Failed to rotate refresh token")
+		return Tokens{}, ErrTokenGeneration
+	}
+
+	token, err := u.GenerateJWTTokenForSession(r, s.ID, c.JWTKeys)
+	if err != nil {
+		log.Error().Err(err).Str("session_id", s.ID).Msg("Failed to generate JWT token")
+		return Tokens{}, ErrTokenGeneration
+	}
+
+	err = c.SessionRepo.UpdateSession(ctx, s, refreshTokenHash)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			// a parallel refresh already used the token, so it was stolen or replayed, or the session was revoked meanwhile
+			log.Warn().Str("session_id", s.ID).Msg("Refresh token reused, revoking session")
+			if err := c.SessionRepo.RevokeSession(ctx, s.ID); err != nil {
+				log.Error().Err(err).Str("session_id", s.ID).Msg("Failed to revoke session")
+				return Tokens{}, ErrDatabase
+			}
+			return Tokens{}, ErrInvalidRefreshToken
+		} else {
+			log.Error().Err(err).Str("session_id", s.ID).Msg("Failed to update session")
+			return Tokens{}, ErrDatabase
+		}
+	}
+
+	log.Info().Int("user_id", u.ID).Str("session_id", s.ID).Msg("Session refreshed successfully")
+	return Tokens{AccessToken: token, RefreshToken: newRefreshToken}, nil
+}
+
+// revokeOnReuse handles a refresh token that is not the current token of any session. A token that was already
+// replaced by a refresh was stolen or replayed, so its session is revoked. It returns the error for the refresh.
+func (c Command) revokeOnReuse(ctx context.Context, refreshTokenHash string) error {
+	log := zerolog.Ctx(ctx)
+
+	s, err := c.SessionRepo.GetSessionByUsedRefreshTokenHash(ctx, refreshTokenHash)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			log.Warn().Msg("Unknown refresh token")
+			return ErrInvalidRefreshToken
+		} else {
+			log.Error().Err(err).Msg("Failed to retrieve session of used refresh token")
+			return ErrDatabase
+		}
+	}
+
+	log.Warn().Str("session_id", s.ID).Msg("Used refresh token presented again, revoking session")
+	if err := c.SessionRepo.RevokeSession(ctx, s.ID); err != nil {
+		log.Error().Err(err).Str("session_id", s.ID).Msg("Failed to revoke session")
+		return ErrDatabase
+	}
+	return ErrInvalidRefreshToken
+}
+
+// Logout revokes the session of the given refresh token. Unknown refresh tokens are ignored,
+// so logging out twice is not an error.
+func (c Command) Logout(ctx context.Context, refreshToken string) error {
+	ctx, span := tracing.Start(ctx, "auth.Logout")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	s, err := c.SessionRepo.GetSessionByRefreshTokenHash(ctx, session.HashRefreshToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			log.Warn().Msg("Logout with unknown refresh token")
+			return nil
+		} else {
+			log.Error().Err(err).Msg("Failed to retrieve session")
+			return ErrDatabase
+		}
+	}
+
+	err = c.SessionRepo.RevokeSession(ctx, s.ID)
+	if err != nil {
+		log.Error().Err(err).Str("session_id", s.ID).Msg("Failed to revoke session")
+		return ErrDatabase
+	}
+
+	log.Info().Int("user_id", s.UserID).Str("session_id", s.ID).Msg("User logged out successfully")
+	return nil
+}
+
+// SetNewPassword sets the first password of a user (or the first after a reset) with their one-time password.
+// The new password has to satisfy the password policy.
 func (c Command) SetNewPassword(ctx context.Context, username, newPassword, onetimePassword string) error {
+	ctx, span := tracing.Start(ctx, "auth.SetNewPassword")
+	defer span.End()
+
 	log := zerolog.Ctx(ctx)
 
 	u, err := c.UserRepo.GetUserByUsername(ctx, username)
@@ -68,11 +404,35 @@ func (c Command) SetNewPassword(ctx context.Context, username, newPassword, onet
 		}
 	}
 
-	err = u.SetPassword(onetimePassword, newPassword)
+	if u.HasPendingOnetimePassword(time.Now().UTC()) {
+		if err := c.PasswordPolicy.Check(u.Username, newPassword); err != nil {
+			log.Warn().Err(err).Str("username", username).Msg("New password rejected by password policy")
+			return passwordPolicyError(err)
+		}
+
+		// the attempt is counted before the one-time password is verified, so parallel guesses can't exceed the limit
+		attempts, err := c.UserRepo.CountOnetimePasswordAttempt(ctx, u.ID)
+		if err != nil {
+			log.Error().Err(err).Str("username", username).Msg("Failed to count one-time password attempt")
+			return ErrDatabase
+		}
+		if attempts > user.MaxOnetimePasswordAttempts {
+			log.Warn().Str("username", username).Int("attempts", attempts).Msg("Locked one-time password during password reset")
+			return ErrOnetimePasswordExpired
+		}
+	}
+
+	err = u.SetPassword(onetimePassword, newPassword, c.PasswordPolicy)
 	if err != nil {
 		if errors.Is(err, user.ErrNoPassword) {
 			log.Warn().Str("username", username).Msg("No one-time password set for user during password reset")
 			return ErrNoOnetimePassword
+		} else if errors.Is(err, user.ErrOnetimePasswordExpired) {
+			log.Warn().Str("username", username).Msg("Expired one-time password during password reset")
+			return ErrOnetimePasswordExpired
+		} else if policyErr := passwordPolicyError(err); policyErr != nil {
+			log.Warn().Err(err).Str("username", username).Msg("New password rejected by password policy")
+			return policyErr
 		} else {
 			log.Warn().Err(err).Str("username", username).Msg("One-time password validation failed")
 			return ErrInvalidPassword
@@ -85,6 +445,226 @@ func (c Command) SetNewPassword(ctx context.Context, username, newPassword, onet
 		return ErrDatabase
 	}
 
+	err = c.SessionRepo.RevokeUserSessions(ctx, u.ID)
+	if err != nil {
+		log.Error().Err(err).Str("username", username).Msg("Failed to revoke sessions after password change")
+		return ErrDatabase
+	}
+
 	log.Info().Str("username", username).Msg("New password set successfully")
 	return nil
 }
+
+// ChangePassword replaces the password of a user after verifying the current one. Users who are required
+// to change their password use this instead of logging in. Wrong passwords are throttled like failed logins.
+func (c Command) ChangePassword(ctx context.Context, username, password, newPassword, clientIP string) error {
+	ctx, span := tracing.Start(ctx, "auth.ChangePassword")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+	now := time.Now().UTC()
+
+	usernameAttempts, ipAttempts, err := c.getLoginAttempts(ctx, username, clientIP)
+	if err != nil {
+		log.Error().Err(err).Str("username", username).Msg("Failed to retrieve failed login attempts")
+		return ErrDatabase
+	}
+
+	if retryAfter := max(usernameAttempts.RetryAfter(now), ipAttempts.RetryAfter(now)); retryAfter > 0 {
+		log.Warn().Str("username", username).Str("client_ip", clientIP).Dur("retry_after", retryAfter).Msg("Password change attempt while blocked")
+		c.writeLoginFailedEvent(ctx, 0, username, clientIP, login.BlockedReason)
+		return BlockedError{RetryAfter: retryAfter}
+	}
+
+	u, err := c.UserRepo.GetUserByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			log.Warn().Str("username", username).Msg("User not found during password change")
+			c.recordFailedLogin(ctx, now, usernameAttempts, ipAttempts)
+			c.writeLoginFailedEvent(ctx, 0, username, clientIP, login.UnknownUserReason)
+			return ErrUserNotFound
+		} else {
+			log.Error().Err(err).Str("username", username).Msg("Failed to retrieve user")
+			return ErrDatabase
+		}
+	}
+
+	err = u.ChangePassword(password, newPassword, c.PasswordPolicy)
+	if err != nil {
+		if errors.Is(err, user.ErrNotActive) {
+			log.Warn().Str("username", username).Msg("Inactive user attempted to change password")
+			return ErrNotActive
+		} else if errors.Is(err, user.ErrNoPassword) {
+			log.Warn().Str("username", username).Msg("No password set for user during password change")
+			return ErrNoPassword
+		} else if errors.Is(err, user.ErrInvalidPassword) {
+			log.Warn().Str("username", username).Msg("Password validation failed during password change")
+			c.recordFailedLogin(ctx, now, usernameAttempts, ipAttempts)
+			c.writeLoginFailedEvent(ctx, u.ID, username, clientIP, login.InvalidPasswordReason)
+			return ErrInvalidPassword
+		} else if policyErr := passwordPolicyError(err); policyErr != nil {
+			log.Warn().Err(err).Str("username", username).Msg("New password rejected by password policy")
+			return policyErr
+		} else {
+			log.Error().Err(err).Str("username", username).Msg("Failed to change password")
+			return ErrTokenGeneration
+		}
+	}
+
+	err = c.UserRepo.UpdateUser(ctx, u)
+	if err != nil {
+		log.Error().Err(err).Str("username", username).Msg("Failed to set password hash in persistence")
+		return ErrDatabase
+	}
+
+	err = c.SessionRepo.RevokeUserSessions(ctx, u.ID)
+	if err != nil {
+		log.Error().Err(err).Str("username", username).Msg("Failed to revoke sessions after password change")
+		return ErrDatabase
+	}
+
+	if usernameAttempts.FailedAttempts > 0 {
+		err = c.AttemptsRepo.DeleteAttempts(ctx, usernameAttempts.Kind, usernameAttempts.Value)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			log.Error().Err(err).Str("username", username).Msg("Failed to reset failed login attempts")
+		}
+	}
+
+	log.Info().Str("username", username).Msg("Password changed successfully")
+	return nil
+}
+
+// ClearLoginAttempts lifts a lockout by removing the failed attempts of a username or client IP.
+func (c Command) ClearLoginAttempts(ctx context.Context, kind login.Kind, value string) error {
+	ctx, span := tracing.Start(ctx, "auth.ClearLoginAttempts")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	if err := validation.Field("kind", login.KindSchema.Validate(&kind)); err != nil {
+		log.Warn().Str("kind", string(kind)).Msg("Invalid login attempts kind")
+		return fmt.Errorf("%w: %w", ErrInvalidLoginAttemptsData, err)
+	}
+
+	attempts := login.NewAttempts(kind, value)
+	err := c.AttemptsRepo.DeleteAttempts(ctx, attempts.Kind, attempts.Value)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			log.Warn().Str("key", attempts.Key()).Msg("No failed login attempts to clear")
+			return ErrLoginAttemptsNotFound
+		} else {
+			log.Error().Err(err).Str("key", attempts.Key()).Msg("Failed to clear failed login attempts")
+			return ErrDatabase
+		}
+	}
+
+	log.Info().Str("key", attempts.Key()).Msg("Failed login attempts cleared successfully")
+	return nil
+}
+
+type deviceRepo interface {
+	GetDeviceByTokenHash(ctx context.Context, hash string) (device.Device, error)
+}
+
+// getActiveDevice returns the device of the given device token if it is still active.
+func getActiveDevice(ctx context.Context, repo deviceRepo, deviceToken string) (device.Device, error) {
+	log := zerolog.Ctx(ctx)
+
+	d, err := repo.GetDeviceByTokenHash(ctx, device.HashToken(deviceToken))
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			log.Warn().Msg("Unknown device token")
+			return device.Device{}, ErrInvalidDevice
+		} else {
+			log.Error().Err(err).Msg("Failed to retrieve device")
+			return device.Device{}, ErrDatabase
+		}
+	}
+
+	if d.Status != device.ActiveStatus {
+		log.Warn().Int("device_id", d.ID).Msg("Token of revoked device")
+		return device.Device{}, ErrInvalidDevice
+	}
+
+	return d, nil
+}
+
+// passwordPolicyError maps a violated password policy rule to its application error.
+// Returns nil if err is not a policy violation.
+func passwordPolicyError(err error) error {
+	if errors.Is(err, user.ErrPasswordTooShort) {
+		return ErrPasswordTooShort
+	} else if errors.Is(err, user.ErrPasswordTooLong) {
+		return ErrPasswordTooLong
+	} else if errors.Is(err, user.ErrPasswordContainsUsername) {
+		return ErrPasswordContainsUsername
+	} else if errors.Is(err, user.ErrPasswordTooCommon) {
+		return ErrPasswordTooCommon
+	} else if errors.Is(err, user.ErrPasswordUnchanged) {
+		return ErrPasswordUnchanged
+	} else {
+		return nil
+	}
+}
+
+// getLoginAttempts returns the failed attempts for the username and the client IP.
+// Counters that don't exist yet are returned empty.
+func (c Command) getLoginAttempts(ctx context.Context, username, clientIP string) (login.Attempts, login.Attempts, error) {
+	usernameAttempts := login.NewAttempts(login.UsernameKind, username)
+	ipAttempts := login.NewAttempts(login.IPKind, clientIP)
+
+	for _, a := range []*login.Attempts{&usernameAttempts, &ipAttempts} {
+		if a.Value == "" {
+			continue
+		}
+
+		stored, err := c.AttemptsRepo.GetAttempts(ctx, a.Kind, a.Value)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			return login.Attempts{}, login.Attempts{}, err
+		} else if err == nil {
+			*a = stored
+		}
+	}
+
+	return usernameAttempts, ipAttempts, nil
+}
+
+// recordFailedLogin counts a failed attempt for the username and the client IP.
+// Errors are only logged, so the client still gets the actual login error.
+func (c Command) recordFailedLogin(ctx context.Context, now time.Time, usernameAttempts, ipAttempts login.Attempts) {
+	log := zerolog.Ctx(ctx)
+
+	for _, a := range []login.Attempts{usernameAttempts, ipAttempts} {
+		if a.Value == "" {
+			continue
+		}
+
+		// the block follows the count returned by the database, which includes parallel failed attempts
+		counted, err := c.AttemptsRepo.CountFailedAttempt(ctx, a.Kind, a.Value, now)
+		if err != nil {
+			log.Error().Err(err).Str("key", a.Key()).Msg("Failed to record failed login attempt")
+			continue
+		}
+		counted.Block(now)
+		if err := c.AttemptsRepo.BlockAttempts(ctx, counted.Kind, counted.Value, counted.BlockedUntil); err != nil {
+			log.Error().Err(err).Str("key", a.Key()).Msg("Failed to block login attempts")
+		} else if counted.IsLockedOut(now) {
+			log.Warn().Str("key", a.Key()).Int("failed_attempts", counted.FailedAttempts).Msg("Login locked out")
+		}
+	}
+}
+
+// writeLoginFailedEvent records a rejected login in the event log. Errors are only logged.
+func (c Command) writeLoginFailedEvent(ctx context.Context, userID int, username, clientIP string, reason login.FailureReason) {
+	log := zerolog.Ctx(ctx)
+
+	e, err := login.NewLoginFailedEvent(userID, username, clientIP, reason)
+	if err != nil {
+		log.Error().Err(err).Str("username", username).Msg("Failed to create login failed event")
+		return
+	}
+
+	if _, err := c.EventRepo.WriteEvent(ctx, e); err != nil {
+		log.Error().Err(err).Str("username", username).Msg("Failed to write login failed event")
+	}
+}
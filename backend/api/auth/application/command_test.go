@@ -4,67 +4,495 @@ package application
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/device"
+	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/jwt"
+	"github.com/nicograef/jotti/backend/domain/login"
+	"github.com/nicograef/jotti/backend/domain/role"
+	"github.com/nicograef/jotti/backend/domain/session"
 	"github.com/nicograef/jotti/backend/domain/user"
+	"github.com/nicograef/jotti/backend/repository/device_repo"
+	"github.com/nicograef/jotti/backend/repository/event_repo"
+	"github.com/nicograef/jotti/backend/repository/login_repo"
+	"github.com/nicograef/jotti/backend/repository/role_repo"
+	"github.com/nicograef/jotti/backend/repository/session_repo"
 	"github.com/nicograef/jotti/backend/repository/user_repo"
 )
 
-func TestGenerateJWTToken_NotFound(t *testing.T) {
+var testKeys = jwt.NewSecretKeys("test-secret")
+
+func newRoleRepo() interface {
+	GetRole(ctx context.Context, name string) (role.Role, error)
+} {
+	return role_repo.NewMock([]role.Role{{Name: role.AdminName}, {Name: role.ServiceName, Permissions: []role.Permission{role.ViewTables}}}, nil, nil)
+}
+
+func TestLogin_NotFound(t *testing.T) {
 	repo := user_repo.NewMock([]user.User{}, db.ErrNotFound)
-	command := Command{UserRepo: repo, JWTSecret: "test-secret"}
+	command := Command{UserRepo: repo, SessionRepo: session_repo.NewMock([]session.Session{}, nil), AttemptsRepo: login_repo.NewMock([]login.Attempts{}, nil), EventRepo: event_repo.NewMock([]event.Event{}, nil), RoleRepo: newRoleRepo(), JWTKeys: testKeys}
 
-	_, err := command.GenerateJWTToken(context.Background(), "nonexistent", "password")
+	_, err := command.Login(context.Background(), "nonexistent", "password", "192.0.2.1")
 
 	if err != ErrUserNotFound {
 		t.Fatalf("expected user not found error, got %v", err)
 	}
 }
 
-func TestGenerateJWTToken_Success(t *testing.T) {
-	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Status: user.ActiveStatus, PasswordHash: "$argon2id$v=19$m=64,t=2,p=4$QzFPUlMxVUd2Wm51a09BNA$WC7jqeO84JjhcPYJKIN6Ep71DLRc0wog7vjIwYq+EEk"}}, nil)
-	command := Command{UserRepo: repo, JWTSecret: "test-secret"}
+func TestLogin_Success(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.ActiveStatus, PasswordHash: "$argon2id$v=19$m=64,t=2,p=4$QzFPUlMxVUd2Wm51a09BNA$WC7jqeO84JjhcPYJKIN6Ep71DLRc0wog7vjIwYq+EEk"}}, nil)
+	command := Command{UserRepo: repo, SessionRepo: session_repo.NewMock([]session.Session{}, nil), AttemptsRepo: login_repo.NewMock([]login.Attempts{}, nil), EventRepo: event_repo.NewMock([]event.Event{}, nil), RoleRepo: newRoleRepo(), JWTKeys: testKeys}
 
-	token, err := command.GenerateJWTToken(context.Background(), "testuser", "testpassword")
+	tokens, err := command.Login(context.Background(), "testuser", "testpassword", "192.0.2.1")
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
-	if token == "" {
-		t.Fatalf("expected a token, got empty string")
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("expected access and refresh token, got %+v", tokens)
+	}
+
+	claims, err := jwt.ParseAndValidateJWTToken(tokens.AccessToken, testKeys)
+	if err != nil || len(claims.Permissions) != 1 || claims.Permissions[0] != string(role.ViewTables) {
+		t.Fatalf("expected token with permissions of the role, got %+v (%v)", claims, err)
 	}
 }
 
-func TestGenerateJWTToken_InvalidPassword(t *testing.T) {
-	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Status: user.ActiveStatus, PasswordHash: "$argon2id$v=19$m=64,t=2,p=4$QzFPUlMxVUd2Wm51a09BNA$WC7jqeO84JjhcPYJKIN6Ep71DLRc0wog7vjIwYq+EEk"}}, nil)
-	command := Command{UserRepo: repo, JWTSecret: "test-secret"}
+func TestLogin_InvalidPassword(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.ActiveStatus, PasswordHash: "$argon2id$v=19$m=64,t=2,p=4$QzFPUlMxVUd2Wm51a09BNA$WC7jqeO84JjhcPYJKIN6Ep71DLRc0wog7vjIwYq+EEk"}}, nil)
+	command := Command{UserRepo: repo, SessionRepo: session_repo.NewMock([]session.Session{}, nil), AttemptsRepo: login_repo.NewMock([]login.Attempts{}, nil), EventRepo: event_repo.NewMock([]event.Event{}, nil), RoleRepo: newRoleRepo(), JWTKeys: testKeys}
 
-	_, err := command.GenerateJWTToken(context.Background(), "testuser", "wrongpassword")
+	_, err := command.Login(context.Background(), "testuser", "wrongpassword", "192.0.2.1")
 
 	if err != ErrInvalidPassword {
 		t.Fatalf("expected invalid password error, got %v", err)
 	}
 }
 
-func TestGenerateJWTToken_HashError(t *testing.T) {
-	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Status: user.ActiveStatus, PasswordHash: "invalidhashformat"}}, nil)
-	command := Command{UserRepo: repo, JWTSecret: "test-secret"}
+func TestLogin_HashError(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.ActiveStatus, PasswordHash: "invalidhashformat"}}, nil)
+	command := Command{UserRepo: repo, SessionRepo: session_repo.NewMock([]session.Session{}, nil), AttemptsRepo: login_repo.NewMock([]login.Attempts{}, nil), EventRepo: event_repo.NewMock([]event.Event{}, nil), RoleRepo: newRoleRepo(), JWTKeys: testKeys}
 
-	_, err := command.GenerateJWTToken(context.Background(), "testuser", "somepassword")
+	_, err := command.Login(context.Background(), "testuser", "somepassword", "192.0.2.1")
 
 	if err != ErrTokenGeneration {
 		t.Fatalf("expected token generation error, got %v", err)
 	}
 }
 
-func TestGenerateJWTToken_UserInactive(t *testing.T) {
-	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Status: user.InactiveStatus, PasswordHash: "$argon2id$v=19$m=64,t=2,p=4$QzFPUlMxVUd2Wm51a09BNA$WC7jqeO84JjhcPYJKIN6Ep71DLRc0wog7vjIwYq+EEk"}}, nil)
-	command := Command{UserRepo: repo, JWTSecret: "test-secret"}
+func TestLogin_UserInactive(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.InactiveStatus, PasswordHash: "$argon2id$v=19$m=64,t=2,p=4$QzFPUlMxVUd2Wm51a09BNA$WC7jqeO84JjhcPYJKIN6Ep71DLRc0wog7vjIwYq+EEk"}}, nil)
+	command := Command{UserRepo: repo, SessionRepo: session_repo.NewMock([]session.Session{}, nil), AttemptsRepo: login_repo.NewMock([]login.Attempts{}, nil), EventRepo: event_repo.NewMock([]event.Event{}, nil), RoleRepo: newRoleRepo(), JWTKeys: testKeys}
+
+	_, err := command.Login(context.Background(), "testuser", "testpassword", "192.0.2.1")
+
+	if err != ErrNotActive {
+		t.Fatalf("expected user not active error, got %v", err)
+	}
+}
+
+func TestRefresh_RotatesRefreshToken(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.ActiveStatus}}, nil)
+	s, refreshToken, _ := session.NewSession(1)
+	command := Command{UserRepo: repo, SessionRepo: session_repo.NewMock([]session.Session{s}, nil), RoleRepo: newRoleRepo(), JWTKeys: testKeys}
+
+	tokens, err := command.Refresh(context.Background(), refreshToken)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if tokens.RefreshToken == refreshToken {
+		t.Fatal("expected a new refresh token")
+	}
+
+	claims, err := jwt.ParseAndValidateJWTToken(tokens.AccessToken, testKeys)
+	if err != nil {
+		t.Fatalf("expected valid access token, got %v", err)
+	}
+	if claims.SessionID != s.ID || claims.Role != string(user.ServiceRole) {
+		t.Fatalf("expected token for session %s with role service, got %+v", s.ID, claims)
+	}
+
+	_, err = command.Refresh(context.Background(), refreshToken)
+	if err != ErrInvalidRefreshToken {
+		t.Fatalf("expected old refresh token to be rejected, got %v", err)
+	}
+
+	// reusing the old token revokes the session, so the new token is rejected as well
+	_, err = command.Refresh(context.Background(), tokens.RefreshToken)
+	if err != ErrInvalidRefreshToken {
+		t.Fatalf("expected session to be revoked after reuse, got %v", err)
+	}
+}
+
+func TestRefresh_UnknownRefreshToken(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.ActiveStatus}}, nil)
+	s, refreshToken, _ := session.NewSession(1)
+	command := Command{UserRepo: repo, SessionRepo: session_repo.NewMock([]session.Session{s}, nil), RoleRepo: newRoleRepo(), JWTKeys: testKeys}
+
+	if _, err := command.Refresh(context.Background(), "unknown"); err != ErrInvalidRefreshToken {
+		t.Fatalf("expected invalid refresh token error, got %v", err)
+	}
+	if _, err := command.Refresh(context.Background(), refreshToken); err != nil {
+		t.Fatalf("expected session to stay active after an unknown token, got %v", err)
+	}
+}
+
+// racingSessionRepo rotates the refresh token in between, like a parallel refresh with the same token.
+type racingSessionRepo struct {
+	commandSessionRepo
+}
+
+func (r racingSessionRepo) GetSessionByRefreshTokenHash(ctx context.Context, hash string) (session.Session, error) {
+	s, err := r.commandSessionRepo.GetSessionByRefreshTokenHash(ctx, hash)
+	if err != nil {
+		return s, err
+	}
+
+	parallel := s
+	_, _ = parallel.Rotate()
+	if err := r.UpdateSession(ctx, parallel, hash); err != nil {
+		return session.Session{}, err
+	}
+	return s, nil
+}
+
+func TestRefresh_ParallelReuseRevokesSession(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.ActiveStatus}}, nil)
+	s, refreshToken, _ := session.NewSession(1)
+	sessionRepo := session_repo.NewMock([]session.Session{s}, nil)
+	command := Command{UserRepo: repo, SessionRepo: racingSessionRepo{sessionRepo}, RoleRepo: newRoleRepo(), JWTKeys: testKeys}
+
+	if _, err := command.Refresh(context.Background(), refreshToken); err != ErrInvalidRefreshToken {
+		t.Fatalf("expected invalid refresh token error, got %v", err)
+	}
+
+	got, _ := sessionRepo.GetSession(context.Background(), s.ID)
+	if got.RevokedAt == nil {
+		t.Fatal("expected session to be revoked")
+	}
+}
+
+func TestRefresh_RevokedSession(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.ActiveStatus}}, nil)
+	s, refreshToken, _ := session.NewSession(1)
+	s.Revoke()
+	command := Command{UserRepo: repo, SessionRepo: session_repo.NewMock([]session.Session{s}, nil), RoleRepo: newRoleRepo(), JWTKeys: testKeys}
 
-	_, err := command.GenerateJWTToken(context.Background(), "testuser", "testpassword")
+	_, err := command.Refresh(context.Background(), refreshToken)
+
+	if err != ErrInvalidRefreshToken {
+		t.Fatalf("expected invalid refresh token error, got %v", err)
+	}
+}
+
+func TestRefresh_UserInactive(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.InactiveStatus}}, nil)
+	s, refreshToken, _ := session.NewSession(1)
+	command := Command{UserRepo: repo, SessionRepo: session_repo.NewMock([]session.Session{s}, nil), RoleRepo: newRoleRepo(), JWTKeys: testKeys}
+
+	_, err := command.Refresh(context.Background(), refreshToken)
 
 	if err != ErrNotActive {
 		t.Fatalf("expected user not active error, got %v", err)
 	}
 }
+
+func TestLogout_RevokesSession(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.ActiveStatus}}, nil)
+	s, refreshToken, _ := session.NewSession(1)
+	sessionRepo := session_repo.NewMock([]session.Session{s}, nil)
+	command := Command{UserRepo: repo, SessionRepo: sessionRepo, RoleRepo: newRoleRepo(), JWTKeys: testKeys}
+
+	if err := command.Logout(context.Background(), refreshToken); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got, _ := sessionRepo.GetSession(context.Background(), s.ID)
+	if got.RevokedAt == nil {
+		t.Fatal("expected session to be revoked")
+	}
+
+	if err := command.Logout(context.Background(), "unknown"); err != nil {
+		t.Fatalf("expected logout with unknown token to succeed, got %v", err)
+	}
+}
+
+func TestLogin_RecordsFailedAttemptAndEvent(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.ActiveStatus, PasswordHash: "$argon2id$v=19$m=64,t=2,p=4$QzFPUlMxVUd2Wm51a09BNA$WC7jqeO84JjhcPYJKIN6Ep71DLRc0wog7vjIwYq+EEk"}}, nil)
+	attemptsRepo := login_repo.NewMock([]login.Attempts{}, nil)
+	eventRepo := event_repo.NewMock([]event.Event{}, nil)
+	command := Command{UserRepo: repo, SessionRepo: session_repo.NewMock([]session.Session{}, nil), AttemptsRepo: attemptsRepo, EventRepo: eventRepo, RoleRepo: newRoleRepo(), JWTKeys: testKeys}
+
+	_, err := command.Login(context.Background(), "testuser", "wrongpassword", "192.0.2.1")
+	if err != ErrInvalidPassword {
+		t.Fatalf("expected invalid password error, got %v", err)
+	}
+
+	for _, a := range []login.Attempts{login.NewAttempts(login.UsernameKind, "testuser"), login.NewAttempts(login.IPKind, "192.0.2.1")} {
+		got, err := attemptsRepo.GetAttempts(context.Background(), a.Kind, a.Value)
+		if err != nil || got.FailedAttempts != 1 {
+			t.Errorf("expected 1 failed attempt for %s, got %+v (%v)", a.Key(), got, err)
+		}
+	}
+
+	events, _ := eventRepo.ReadEventsBySubject(context.Background(), "login:testuser")
+	if len(events) != 1 || events[0].Type != string(login.EventTypeLoginFailedV1) || events[0].UserID != 1 {
+		t.Fatalf("expected one login failed event for user 1, got %+v", events)
+	}
+}
+
+func TestLogin_Blocked(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.ActiveStatus, PasswordHash: "$argon2id$v=19$m=64,t=2,p=4$QzFPUlMxVUd2Wm51a09BNA$WC7jqeO84JjhcPYJKIN6Ep71DLRc0wog7vjIwYq+EEk"}}, nil)
+	locked := login.NewAttempts(login.IPKind, "192.0.2.1")
+	for range login.LockoutThreshold {
+		locked.RecordFailure(time.Now().UTC())
+	}
+	command := Command{UserRepo: repo, SessionRepo: session_repo.NewMock([]session.Session{}, nil), AttemptsRepo: login_repo.NewMock([]login.Attempts{locked}, nil), EventRepo: event_repo.NewMock([]event.Event{}, nil), RoleRepo: newRoleRepo(), JWTKeys: testKeys}
+
+	_, err := command.Login(context.Background(), "testuser", "testpassword", "192.0.2.1")
+
+	var blocked BlockedError
+	if !errors.As(err, &blocked) || !errors.Is(err, ErrLoginBlocked) {
+		t.Fatalf("expected blocked error, got %v", err)
+	}
+	if blocked.RetryAfter <= 0 || blocked.RetryAfter > login.LockoutDuration {
+		t.Errorf("expected retry after within lockout duration, got %s", blocked.RetryAfter)
+	}
+}
+
+// staleAttemptsRepo returns empty counters, like a read before parallel failed attempts were counted.
+type staleAttemptsRepo struct {
+	commandAttemptsRepo
+}
+
+func (r staleAttemptsRepo) GetAttempts(ctx context.Context, kind login.Kind, value string) (login.Attempts, error) {
+	return login.Attempts{}, db.ErrNotFound
+}
+
+func TestLogin_CountsParallelFailedAttempts(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.ActiveStatus, PasswordHash: "$argon2id$v=19$m=64,t=2,p=4$QzFPUlMxVUd2Wm51a09BNA$WC7jqeO84JjhcPYJKIN6Ep71DLRc0wog7vjIwYq+EEk"}}, nil)
+	attempts := login.NewAttempts(login.UsernameKind, "testuser")
+	for range login.LockoutThreshold - 1 {
+		attempts.RecordFailure(time.Now().UTC())
+	}
+	attemptsRepo := login_repo.NewMock([]login.Attempts{attempts}, nil)
+	command := Command{UserRepo: repo, SessionRepo: session_repo.NewMock([]session.Session{}, nil), AttemptsRepo: staleAttemptsRepo{attemptsRepo}, EventRepo: event_repo.NewMock([]event.Event{}, nil), RoleRepo: newRoleRepo(), JWTKeys: testKeys}
+
+	if _, err := command.Login(context.Background(), "testuser", "wrongpassword", "192.0.2.1"); err != ErrInvalidPassword {
+		t.Fatalf("expected invalid password error, got %v", err)
+	}
+
+	got, err := attemptsRepo.GetAttempts(context.Background(), login.UsernameKind, "testuser")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !got.IsLockedOut(time.Now().UTC()) {
+		t.Fatalf("expected lockout counted on top of the stored attempts, got %+v", got)
+	}
+}
+
+func TestLogin_SuccessResetsUsernameAttempts(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.ActiveStatus, PasswordHash: "$argon2id$v=19$m=64,t=2,p=4$QzFPUlMxVUd2Wm51a09BNA$WC7jqeO84JjhcPYJKIN6Ep71DLRc0wog7vjIwYq+EEk"}}, nil)
+	attempts := login.NewAttempts(login.UsernameKind, "testuser")
+	attempts.RecordFailure(time.Now().UTC())
+	attemptsRepo := login_repo.NewMock([]login.Attempts{attempts}, nil)
+	command := Command{UserRepo: repo, SessionRepo: session_repo.NewMock([]session.Session{}, nil), AttemptsRepo: attemptsRepo, EventRepo: event_repo.NewMock([]event.Event{}, nil), RoleRepo: newRoleRepo(), JWTKeys: testKeys}
+
+	if _, err := command.Login(context.Background(), "testuser", "testpassword", "192.0.2.1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err := attemptsRepo.GetAttempts(context.Background(), login.UsernameKind, "testuser"); err != db.ErrNotFound {
+		t.Fatalf("expected failed attempts to be reset, got %v", err)
+	}
+}
+
+func TestLogin_RehashesSeededAdminPassword(t *testing.T) {
+	// Same parameters as the hash of the seeded admin in 01_initial.up.sql, for the password "testpassword"
+	const seededHash = "$argon2id$v=19$m=64,t=2,p=2$+B3JhqYaV3ftpCUlU5MMRw$Lw522cuiFsZxNy8kODux9D4JemmFih7ue3EkYpykeSM"
+	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "admin", Role: user.AdminRole, Status: user.ActiveStatus, PasswordHash: seededHash}}, nil)
+	command := Command{UserRepo: repo, SessionRepo: session_repo.NewMock([]session.Session{}, nil), AttemptsRepo: login_repo.NewMock([]login.Attempts{}, nil), EventRepo: event_repo.NewMock([]event.Event{}, nil), RoleRepo: newRoleRepo(), JWTKeys: testKeys, PasswordPolicy: user.PasswordPolicy{Hashing: user.HashParams{MemoryCost: 128, TimeCost: 2, Threads: 2}}}
+
+	if _, err := command.Login(context.Background(), "admin", "testpassword", "192.0.2.1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	u, _ := repo.GetUser(context.Background(), 1)
+	if !strings.HasPrefix(u.PasswordHash, "$argon2id$v=19$m=128,t=2,p=2$") {
+		t.Fatalf("expected stored hash with new parameters, got %s", u.PasswordHash)
+	}
+	if _, err := command.Login(context.Background(), "admin", "testpassword", "192.0.2.1"); err != nil {
+		t.Fatalf("expected login with rehashed password, got %v", err)
+	}
+}
+
+func TestLogin_PasswordChangeRequired(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.ActiveStatus, PasswordChangeRequired: true, PasswordHash: "$argon2id$v=19$m=64,t=2,p=4$QzFPUlMxVUd2Wm51a09BNA$WC7jqeO84JjhcPYJKIN6Ep71DLRc0wog7vjIwYq+EEk"}}, nil)
+	attemptsRepo := login_repo.NewMock([]login.Attempts{}, nil)
+	command := Command{UserRepo: repo, SessionRepo: session_repo.NewMock([]session.Session{}, nil), AttemptsRepo: attemptsRepo, EventRepo: event_repo.NewMock([]event.Event{}, nil), RoleRepo: newRoleRepo(), JWTKeys: testKeys}
+
+	_, err := command.Login(context.Background(), "testuser", "testpassword", "192.0.2.1")
+	if err != ErrPasswordChangeRequired {
+		t.Fatalf("expected password change required error, got %v", err)
+	}
+
+	if _, err := attemptsRepo.GetAttempts(context.Background(), login.UsernameKind, "testuser"); err != db.ErrNotFound {
+		t.Fatalf("expected no failed attempt to be recorded, got %v", err)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.ActiveStatus, PasswordChangeRequired: true, PasswordHash: "$argon2id$v=19$m=64,t=2,p=4$QzFPUlMxVUd2Wm51a09BNA$WC7jqeO84JjhcPYJKIN6Ep71DLRc0wog7vjIwYq+EEk"}}, nil)
+	s, _, _ := session.NewSession(1)
+	sessionRepo := session_repo.NewMock([]session.Session{s}, nil)
+	command := Command{UserRepo: repo, SessionRepo: sessionRepo, AttemptsRepo: login_repo.NewMock([]login.Attempts{}, nil), EventRepo: event_repo.NewMock([]event.Event{}, nil), RoleRepo: newRoleRepo(), JWTKeys: testKeys, PasswordPolicy: user.DefaultPasswordPolicy}
+
+	if err := command.ChangePassword(context.Background(), "testuser", "testpassword", "passwort123", "192.0.2.1"); err != ErrPasswordTooCommon {
+		t.Fatalf("expected password too common error, got %v", err)
+	}
+	if err := command.ChangePassword(context.Background(), "testuser", "testpassword", "testuser2024", "192.0.2.1"); err != ErrPasswordContainsUsername {
+		t.Fatalf("expected password contains username error, got %v", err)
+	}
+	if err := command.ChangePassword(context.Background(), "testuser", "testpassword", "Kellner-Tisch-42", "192.0.2.1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got, _ := sessionRepo.GetSession(context.Background(), s.ID)
+	if got.RevokedAt == nil {
+		t.Fatal("expected session to be revoked")
+	}
+	if _, err := command.Login(context.Background(), "testuser", "Kellner-Tisch-42", "192.0.2.1"); err != nil {
+		t.Fatalf("expected login with new password, got %v", err)
+	}
+}
+
+func TestSetNewPassword_TooShort(t *testing.T) {
+	u := user.User{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.ActiveStatus}
+	onetimePassword, _ := u.ResetPassword()
+	command := Command{UserRepo: user_repo.NewMock([]user.User{u}, nil), SessionRepo: session_repo.NewMock([]session.Session{}, nil), PasswordPolicy: user.DefaultPasswordPolicy}
+
+	if err := command.SetNewPassword(context.Background(), "testuser", "", onetimePassword); err != ErrPasswordTooShort {
+		t.Fatalf("expected password too short error, got %v", err)
+	}
+}
+
+func TestSetNewPassword_RecordsFailedOnetimePasswordAttempts(t *testing.T) {
+	u := user.User{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.ActiveStatus}
+	onetimePassword, _ := u.ResetPassword()
+	repo := user_repo.NewMock([]user.User{u}, nil)
+	command := Command{UserRepo: repo, SessionRepo: session_repo.NewMock([]session.Session{}, nil), PasswordPolicy: user.DefaultPasswordPolicy}
+	wrong := "000000"
+	if onetimePassword == wrong {
+		wrong = "111111"
+	}
+
+	for range user.MaxOnetimePasswordAttempts {
+		if err := command.SetNewPassword(context.Background(), "testuser", "Kellner-Tisch-42", wrong); err != ErrInvalidPassword {
+			t.Fatalf("expected invalid password error, got %v", err)
+		}
+	}
+
+	if err := command.SetNewPassword(context.Background(), "testuser", "Kellner-Tisch-42", onetimePassword); err != ErrOnetimePasswordExpired {
+		t.Fatalf("expected one-time password expired error, got %v", err)
+	}
+}
+
+func TestSetNewPassword_LastOnetimePasswordAttempt(t *testing.T) {
+	u := user.User{ID: 1, Username: "testuser", Role: user.ServiceRole, Status: user.ActiveStatus}
+	onetimePassword, _ := u.ResetPassword()
+	u.OnetimePasswordFailedAttempts = user.MaxOnetimePasswordAttempts - 1
+	repo := user_repo.NewMock([]user.User{u}, nil)
+	command := Command{UserRepo: repo, SessionRepo: session_repo.NewMock([]session.Session{}, nil), PasswordPolicy: user.DefaultPasswordPolicy}
+
+	if err := command.SetNewPassword(context.Background(), "testuser", "", onetimePassword); err != ErrPasswordTooShort {
+		t.Fatalf("expected password too short error, got %v", err)
+	}
+	if err := command.SetNewPassword(context.Background(), "testuser", "Kellner-Tisch-42", onetimePassword); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got, _ := repo.GetUser(context.Background(), 1)
+	if got.OnetimePasswordFailedAttempts != 0 || got.OnetimePasswordHash != "" {
+		t.Fatalf("expected one-time password to be cleared, got %+v", got)
+	}
+}
+
+func TestClearLoginAttempts(t *testing.T) {
+	attempts := login.NewAttempts(login.IPKind, "192.0.2.1")
+	attempts.RecordFailure(time.Now().UTC())
+	command := Command{AttemptsRepo: login_repo.NewMock([]login.Attempts{attempts}, nil)}
+
+	if err := command.ClearLoginAttempts(context.Background(), login.IPKind, "192.0.2.1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := command.ClearLoginAttempts(context.Background(), login.IPKind, "192.0.2.1"); err != ErrLoginAttemptsNotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	if err := command.ClearLoginAttempts(context.Background(), "device", "tablet"); !errors.Is(err, ErrInvalidLoginAttemptsData) {
+		t.Fatalf("expected invalid data error, got %v", err)
+	}
+}
+
+func newPinUser(t *testing.T) user.User {
+	t.Helper()
+	u := user.User{ID: 1, Username: "testuser", Name: "Test User", Role: user.ServiceRole, Status: user.ActiveStatus, PasswordHash: "$argon2id$v=19$m=64,t=2,p=4$QzFPUlMxVUd2Wm51a09BNA$WC7jqeO84JjhcPYJKIN6Ep71DLRc0wog7vjIwYq+EEk"}
+	if err := u.SetPin("testpassword", "1234", user.DefaultHashParams); err != nil {
+		t.Fatalf("failed to set pin: %v", err)
+	}
+	return u
+}
+
+func TestPinLogin_Success(t *testing.T) {
+	d, deviceToken, _ := device.NewDevice("Tablet Theke")
+	d.ID = 1
+	sessionRepo := session_repo.NewMock([]session.Session{}, nil)
+	command := Command{UserRepo: user_repo.NewMock([]user.User{newPinUser(t)}, nil), SessionRepo: sessionRepo, AttemptsRepo: login_repo.NewMock([]login.Attempts{}, nil), EventRepo: event_repo.NewMock([]event.Event{}, nil), DeviceRepo: device_repo.NewMock([]device.Device{d}, nil), RoleRepo: newRoleRepo(), JWTKeys: testKeys}
+
+	tokens, err := command.PinLogin(context.Background(), deviceToken, 1, "1234", "192.0.2.1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	claims, err := jwt.ParseAndValidateJWTToken(tokens.AccessToken, testKeys)
+	if err != nil {
+		t.Fatalf("expected valid access token, got %v", err)
+	}
+	s, err := sessionRepo.GetSession(context.Background(), claims.SessionID)
+	if err != nil || s.DeviceID == nil || *s.DeviceID != 1 {
+		t.Fatalf("expected session bound to device 1, got %+v (%v)", s, err)
+	}
+}
+
+func TestPinLogin_InvalidPin(t *testing.T) {
+	d, deviceToken, _ := device.NewDevice("Tablet Theke")
+	d.ID = 1
+	attemptsRepo := login_repo.NewMock([]login.Attempts{}, nil)
+	command := Command{UserRepo: user_repo.NewMock([]user.User{newPinUser(t)}, nil), SessionRepo: session_repo.NewMock([]session.Session{}, nil), AttemptsRepo: attemptsRepo, EventRepo: event_repo.NewMock([]event.Event{}, nil), DeviceRepo: device_repo.NewMock([]device.Device{d}, nil), RoleRepo: newRoleRepo(), JWTKeys: testKeys}
+
+	_, err := command.PinLogin(context.Background(), deviceToken, 1, "9999", "192.0.2.1")
+	if err != ErrInvalidPin {
+		t.Fatalf("expected invalid pin error, got %v", err)
+	}
+
+	got, err := attemptsRepo.GetAttempts(context.Background(), login.UsernameKind, "testuser")
+	if err != nil || got.FailedAttempts != 1 {
+		t.Errorf("expected 1 failed attempt for username, got %+v (%v)", got, err)
+	}
+}
+
+func TestPinLogin_RevokedDevice(t *testing.T) {
+	d, deviceToken, _ := device.NewDevice("Tablet Theke")
+	d.ID = 1
+	_ = d.Revoke()
+	command := Command{UserRepo: user_repo.NewMock([]user.User{newPinUser(t)}, nil), DeviceRepo: device_repo.NewMock([]device.Device{d}, nil), RoleRepo: newRoleRepo(), JWTKeys: testKeys}
+
+	_, err := command.PinLogin(context.Background(), deviceToken, 1, "1234", "192.0.2.1")
+	if err != ErrInvalidDevice {
+		t.Fatalf("expected invalid device error, got %v", err)
+	}
+}
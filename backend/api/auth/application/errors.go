@@ -2,6 +2,8 @@ package application
 
 import (
 	"errors"
+	"fmt"
+	"time"
 )
 
 var ErrUserNotFound = errors.New("user not found")
@@ -12,8 +14,52 @@ var ErrNoPassword = errors.New("no password set")
 
 var ErrInvalidPassword = errors.New("invalid password")
 
+var ErrNoPin = errors.New("no pin set")
+
+var ErrInvalidPin = errors.New("invalid pin")
+
+var ErrInvalidDevice = errors.New("invalid device")
+
 var ErrNoOnetimePassword = errors.New("no onetime password set")
 
+// ErrOnetimePasswordExpired is returned if the one-time password is expired or was guessed wrong too often.
+var ErrOnetimePasswordExpired = errors.New("onetime password expired")
+
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
 var ErrTokenGeneration = errors.New("token generation failed")
 
 var ErrDatabase = errors.New("database error")
+
+var ErrLoginBlocked = errors.New("login blocked")
+
+var ErrLoginAttemptsNotFound = errors.New("login attempts not found")
+
+var ErrInvalidLoginAttemptsData = errors.New("invalid login attempts data")
+
+// ErrPasswordChangeRequired is returned if the credentials are valid but an admin requires a new password first.
+var ErrPasswordChangeRequired = errors.New("password change required")
+
+var ErrPasswordTooShort = errors.New("password too short")
+
+var ErrPasswordTooLong = errors.New("password too long")
+
+var ErrPasswordContainsUsername = errors.New("password contains username")
+
+var ErrPasswordTooCommon = errors.New("password too common")
+
+var ErrPasswordUnchanged = errors.New("password unchanged")
+
+// BlockedError is returned when logins for the username or client IP are blocked after too many failed attempts.
+// It matches ErrLoginBlocked.
+type BlockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e BlockedError) Error() string {
+	return fmt.Sprintf("login blocked, retry after %s", e.RetryAfter)
+}
+
+func (e BlockedError) Unwrap() error {
+	return ErrLoginBlocked
+}
@@ -0,0 +1,73 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"github.com/nicograef/jotti/backend/domain/login"
+	"github.com/nicograef/jotti/backend/domain/user"
+	"github.com/nicograef/jotti/backend/tracing"
+	"github.com/rs/zerolog"
+)
+
+type queryAttemptsRepo interface {
+	GetRecentAttempts(ctx context.Context, since time.Time) ([]login.Attempts, error)
+}
+
+type queryUserRepo interface {
+	GetUsersWithPin(ctx context.Context) ([]user.User, error)
+}
+
+type Query struct {
+	AttemptsRepo queryAttemptsRepo
+	UserRepo     queryUserRepo
+	DeviceRepo   deviceRepo
+}
+
+// DeviceUser is a user shown for selection on the PIN login screen of a device.
+type DeviceUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// GetLoginAttempts returns the failed login attempts per username and client IP that still count,
+// including running lockouts.
+func (q Query) GetLoginAttempts(ctx context.Context) ([]login.Attempts, error) {
+	ctx, span := tracing.Start(ctx, "auth.GetLoginAttempts")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	attempts, err := q.AttemptsRepo.GetRecentAttempts(ctx, time.Now().UTC().Add(-login.ResetWindow))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to retrieve failed login attempts")
+		return nil, ErrDatabase
+	}
+
+	return attempts, nil
+}
+
+// GetDeviceUsers returns the active users with a PIN that can log in on the device of the given device token.
+func (q Query) GetDeviceUsers(ctx context.Context, deviceToken string) ([]DeviceUser, error) {
+	ctx, span := tracing.Start(ctx, "auth.GetDeviceUsers")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	if _, err := getActiveDevice(ctx, q.DeviceRepo, deviceToken); err != nil {
+		return nil, err
+	}
+
+	users, err := q.UserRepo.GetUsersWithPin(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to retrieve users with PIN")
+		return nil, ErrDatabase
+	}
+
+	deviceUsers := make([]DeviceUser, 0, len(users))
+	for _, u := range users {
+		deviceUsers = append(deviceUsers, DeviceUser{ID: u.ID, Name: u.Name})
+	}
+
+	return deviceUsers, nil
+}
@@ -3,42 +3,61 @@ package http
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/nicograef/jotti/backend/api/auth/application"
 	"github.com/nicograef/jotti/backend/api/helper"
+	"github.com/nicograef/jotti/backend/api/middleware"
+	"github.com/nicograef/jotti/backend/domain/login"
 )
 
 type authCommand interface {
-	GenerateJWTToken(ctx context.Context, username, password string) (string, error)
+	Login(ctx context.Context, username, password, clientIP string) (application.Tokens, error)
+	PinLogin(ctx context.Context, deviceToken string, userID int, pin, clientIP string) (application.Tokens, error)
+	Refresh(ctx context.Context, refreshToken string) (application.Tokens, error)
+	Logout(ctx context.Context, refreshToken string) error
 	SetNewPassword(ctx context.Context, username, password, onetimePassword string) error
+	ChangePassword(ctx context.Context, username, password, newPassword, clientIP string) error
+	ClearLoginAttempts(ctx context.Context, kind login.Kind, value string) error
 }
 
 type CommandHandler struct {
 	Command authCommand
 }
 
-type login struct {
+type credentials struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 }
 
-type loginResponse struct {
-	Token string `json:"token"`
+type tokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
 }
 
 func (h *CommandHandler) LoginHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		body := login{}
+		body := credentials{}
 		if !helper.ReadBody(w, r, &body) {
 			return
 		}
 
-		token, err := h.Command.GenerateJWTToken(ctx, body.Username, body.Password)
+		clientIP, _ := ctx.Value(middleware.ClientIPKey).(string)
+
+		tokens, err := h.Command.Login(ctx, body.Username, body.Password, clientIP)
 		if err != nil {
-			if errors.Is(err, application.ErrNotActive) {
+			var blocked application.BlockedError
+			if errors.As(err, &blocked) {
+				retryAfter := int(math.Ceil(blocked.RetryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				helper.SendClientError(w, "login_blocked", fmt.Sprintf("Too many failed login attempts. Retry after %d seconds.", retryAfter))
+				return
+			} else if errors.Is(err, application.ErrNotActive) {
 				helper.SendClientError(w, "user_inactive", nil)
 				return
 			} else if errors.Is(err, application.ErrUserNotFound) || errors.Is(err, application.ErrInvalidPassword) {
@@ -47,13 +66,116 @@ func (h *CommandHandler) LoginHandler() http.HandlerFunc {
 			} else if errors.Is(err, application.ErrNoPassword) {
 				helper.SendClientError(w, "no_password_set", "No password set for user. Please set a password first.")
 				return
+			} else if errors.Is(err, application.ErrPasswordChangeRequired) {
+				helper.SendClientError(w, "password_change_required", "Please choose a new password.")
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendResponse(w, tokenResponse{Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken})
+	}
+}
+
+type pinLogin struct {
+	DeviceToken string `json:"deviceToken"`
+	UserID      int    `json:"userId"`
+	Pin         string `json:"pin"`
+}
+
+func (h *CommandHandler) PinLoginHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		body := pinLogin{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		clientIP, _ := ctx.Value(middleware.ClientIPKey).(string)
+
+		tokens, err := h.Command.PinLogin(ctx, body.DeviceToken, body.UserID, body.Pin, clientIP)
+		if err != nil {
+			var blocked application.BlockedError
+			if errors.As(err, &blocked) {
+				retryAfter := int(math.Ceil(blocked.RetryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				helper.SendClientError(w, "login_blocked", fmt.Sprintf("Too many failed login attempts. Retry after %d seconds.", retryAfter))
+				return
+			} else if errors.Is(err, application.ErrInvalidDevice) {
+				helper.SendClientError(w, "invalid_device", nil)
+				return
+			} else if errors.Is(err, application.ErrNotActive) {
+				helper.SendClientError(w, "user_inactive", nil)
+				return
+			} else if errors.Is(err, application.ErrUserNotFound) || errors.Is(err, application.ErrInvalidPin) {
+				helper.SendClientError(w, "invalid_credentials", nil)
+				return
+			} else if errors.Is(err, application.ErrNoPin) {
+				helper.SendClientError(w, "no_pin_set", "No PIN set for user. Please log in with your password and set a PIN first.")
+				return
+			} else if errors.Is(err, application.ErrPasswordChangeRequired) {
+				helper.SendClientError(w, "password_change_required", "Please choose a new password.")
+				return
 			} else {
 				helper.SendServerError(w)
 				return
 			}
 		}
 
-		helper.SendResponse(w, loginResponse{Token: token})
+		helper.SendResponse(w, tokenResponse{Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken})
+	}
+}
+
+type refresh struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (h *CommandHandler) RefreshHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		body := refresh{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		tokens, err := h.Command.Refresh(ctx, body.RefreshToken)
+		if err != nil {
+			if errors.Is(err, application.ErrInvalidRefreshToken) {
+				helper.SendClientError(w, "invalid_refresh_token", nil)
+				return
+			} else if errors.Is(err, application.ErrNotActive) {
+				helper.SendClientError(w, "user_inactive", nil)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendResponse(w, tokenResponse{Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken})
+	}
+}
+
+func (h *CommandHandler) LogoutHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		body := refresh{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		err := h.Command.Logout(ctx, body.RefreshToken)
+		if err != nil {
+			helper.SendServerError(w)
+			return
+		}
+
+		helper.SendEmptyResponse(w)
 	}
 }
 
@@ -83,6 +205,108 @@ func (h *CommandHandler) SetPasswordHandler() http.HandlerFunc {
 			} else if errors.Is(err, application.ErrNoOnetimePassword) {
 				helper.SendClientError(w, "already_has_password", "No one-time password set for user. User probably already has a password.")
 				return
+			} else if errors.Is(err, application.ErrOnetimePasswordExpired) {
+				helper.SendClientError(w, "onetime_password_expired", "The one-time password has expired. Please ask an admin for a new one.")
+				return
+			} else if sendPasswordPolicyError(w, err) {
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendEmptyResponse(w)
+	}
+}
+
+type changePassword struct {
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	NewPassword string `json:"newPassword"`
+}
+
+func (h *CommandHandler) ChangePasswordHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		body := changePassword{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		clientIP, _ := ctx.Value(middleware.ClientIPKey).(string)
+
+		err := h.Command.ChangePassword(ctx, body.Username, body.Password, body.NewPassword, clientIP)
+		if err != nil {
+			var blocked application.BlockedError
+			if errors.As(err, &blocked) {
+				retryAfter := int(math.Ceil(blocked.RetryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				helper.SendClientError(w, "login_blocked", fmt.Sprintf("Too many failed login attempts. Retry after %d seconds.", retryAfter))
+				return
+			} else if errors.Is(err, application.ErrNotActive) {
+				helper.SendClientError(w, "user_inactive", nil)
+				return
+			} else if errors.Is(err, application.ErrUserNotFound) || errors.Is(err, application.ErrInvalidPassword) {
+				helper.SendClientError(w, "invalid_credentials", nil)
+				return
+			} else if errors.Is(err, application.ErrNoPassword) {
+				helper.SendClientError(w, "no_password_set", "No password set for user. Please set a password first.")
+				return
+			} else if sendPasswordPolicyError(w, err) {
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendEmptyResponse(w)
+	}
+}
+
+// sendPasswordPolicyError sends the client error for a new password that violates the password policy.
+// Returns false if err is not a policy violation.
+func sendPasswordPolicyError(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, application.ErrPasswordTooShort) {
+		helper.SendClientError(w, "password_too_short", nil)
+	} else if errors.Is(err, application.ErrPasswordTooLong) {
+		helper.SendClientError(w, "password_too_long", nil)
+	} else if errors.Is(err, application.ErrPasswordContainsUsername) {
+		helper.SendClientError(w, "password_contains_username", nil)
+	} else if errors.Is(err, application.ErrPasswordTooCommon) {
+		helper.SendClientError(w, "password_too_common", nil)
+	} else if errors.Is(err, application.ErrPasswordUnchanged) {
+		helper.SendClientError(w, "password_unchanged", nil)
+	} else {
+		return false
+	}
+	return true
+}
+
+type clearLoginAttempts struct {
+	Kind  login.Kind `json:"kind"`
+	Value string     `json:"value"`
+}
+
+func (h *CommandHandler) ClearLoginAttemptsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		body := clearLoginAttempts{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		err := h.Command.ClearLoginAttempts(ctx, body.Kind, body.Value)
+		if err != nil {
+			if errors.Is(err, application.ErrInvalidLoginAttemptsData) {
+				helper.SendValidationError(w, "invalid_login_attempts_data", err)
+				return
+			} else if errors.Is(err, application.ErrLoginAttemptsNotFound) {
+				helper.SendClientError(w, "login_attempts_not_found", nil)
+				return
 			} else {
 				helper.SendServerError(w)
 				return
@@ -8,8 +8,10 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/nicograef/jotti/backend/api/auth/application"
+	"github.com/nicograef/jotti/backend/domain/login"
 )
 
 type mockAuthCommand struct {
@@ -17,14 +19,34 @@ type mockAuthCommand struct {
 	err   error
 }
 
-func (m mockAuthCommand) GenerateJWTToken(ctx context.Context, username, password string) (string, error) {
-	return m.token, m.err
+func (m mockAuthCommand) Login(ctx context.Context, username, password, clientIP string) (application.Tokens, error) {
+	return application.Tokens{AccessToken: m.token, RefreshToken: m.token}, m.err
+}
+
+func (m mockAuthCommand) PinLogin(ctx context.Context, deviceToken string, userID int, pin, clientIP string) (application.Tokens, error) {
+	return application.Tokens{AccessToken: m.token, RefreshToken: m.token}, m.err
+}
+
+func (m mockAuthCommand) Refresh(ctx context.Context, refreshToken string) (application.Tokens, error) {
+	return application.Tokens{AccessToken: m.token, RefreshToken: m.token}, m.err
+}
+
+func (m mockAuthCommand) Logout(ctx context.Context, refreshToken string) error {
+	return m.err
 }
 
 func (m mockAuthCommand) SetNewPassword(ctx context.Context, username, password, onetimePassword string) error {
 	return m.err
 }
 
+func (m mockAuthCommand) ChangePassword(ctx context.Context, username, password, newPassword, clientIP string) error {
+	return m.err
+}
+
+func (m mockAuthCommand) ClearLoginAttempts(ctx context.Context, kind login.Kind, value string) error {
+	return m.err
+}
+
 func TestLoginHandler_Success(t *testing.T) {
 	command := mockAuthCommand{token: "test-token", err: nil}
 	handler := CommandHandler{Command: command}
@@ -52,8 +74,8 @@ func TestLoginHandler_InvalidCredentials(t *testing.T) {
 
 	handler.LoginHandler().ServeHTTP(rec, req)
 
-	if rec.Code != http.StatusBadRequest {
-		t.Errorf("expected status 400, got %d", rec.Code)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", rec.Code)
 	}
 }
 
@@ -68,7 +90,163 @@ func TestLoginHandler_InactiveUser(t *testing.T) {
 
 	handler.LoginHandler().ServeHTTP(rec, req)
 
-	if rec.Code != http.StatusBadRequest {
-		t.Errorf("expected status 400, got %d", rec.Code)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestPinLoginHandler_InvalidDevice(t *testing.T) {
+	command := mockAuthCommand{token: "", err: application.ErrInvalidDevice}
+	handler := CommandHandler{Command: command}
+
+	body := `{"deviceToken":"unknown","userId":1,"pin":"1234"}`
+	req := httptest.NewRequest(http.MethodPost, "/pin-login", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	handler.PinLoginHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "invalid_device") {
+		t.Errorf("expected invalid_device error, got %s", rec.Body.String())
+	}
+}
+
+func TestRefreshHandler_Success(t *testing.T) {
+	command := mockAuthCommand{token: "test-token", err: nil}
+	handler := CommandHandler{Command: command}
+
+	body := `{"refreshToken":"refresh-token"}`
+	req := httptest.NewRequest(http.MethodPost, "/refresh", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	handler.RefreshHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestRefreshHandler_InvalidRefreshToken(t *testing.T) {
+	command := mockAuthCommand{token: "", err: application.ErrInvalidRefreshToken}
+	handler := CommandHandler{Command: command}
+
+	body := `{"refreshToken":"used-token"}`
+	req := httptest.NewRequest(http.MethodPost, "/refresh", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	handler.RefreshHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", rec.Code)
+	}
+}
+
+func TestLogoutHandler_Success(t *testing.T) {
+	command := mockAuthCommand{err: nil}
+	handler := CommandHandler{Command: command}
+
+	body := `{"refreshToken":"refresh-token"}`
+	req := httptest.NewRequest(http.MethodPost, "/logout", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	handler.LogoutHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestLoginHandler_Blocked(t *testing.T) {
+	command := mockAuthCommand{err: application.BlockedError{RetryAfter: 1500 * time.Millisecond}}
+	handler := CommandHandler{Command: command}
+
+	body := `{"username":"testuser","password":"Test123!"}`
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	handler.LoginHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "2" {
+		t.Errorf("expected Retry-After 2, got %q", rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestClearLoginAttemptsHandler_NotFound(t *testing.T) {
+	command := mockAuthCommand{err: application.ErrLoginAttemptsNotFound}
+	handler := CommandHandler{Command: command}
+
+	body := `{"kind":"ip","value":"192.0.2.1"}`
+	req := httptest.NewRequest(http.MethodPost, "/clear-login-attempts", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	handler.ClearLoginAttemptsHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestSetPasswordHandler_PasswordTooCommon(t *testing.T) {
+	command := mockAuthCommand{err: application.ErrPasswordTooCommon}
+	handler := CommandHandler{Command: command}
+
+	body := `{"username":"testuser","password":"password","onetimePassword":"123456"}`
+	req := httptest.NewRequest(http.MethodPost, "/set-password", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	handler.SetPasswordHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "password_too_common") {
+		t.Errorf("expected password_too_common error, got %s", rec.Body.String())
+	}
+}
+
+func TestLoginHandler_PasswordChangeRequired(t *testing.T) {
+	command := mockAuthCommand{err: application.ErrPasswordChangeRequired}
+	handler := CommandHandler{Command: command}
+
+	body := `{"username":"testuser","password":"Test123!"}`
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	handler.LoginHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "password_change_required") {
+		t.Errorf("expected password_change_required error, got %s", rec.Body.String())
+	}
+}
+
+func TestChangePasswordHandler_Success(t *testing.T) {
+	command := mockAuthCommand{err: nil}
+	handler := CommandHandler{Command: command}
+
+	body := `{"username":"testuser","password":"Test123!","newPassword":"Kellner-Tisch-42"}`
+	req := httptest.NewRequest(http.MethodPost, "/change-password", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	handler.ChangePasswordHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
 	}
 }
@@ -4,11 +4,31 @@ import (
 	"database/sql"
 
 	"github.com/nicograef/jotti/backend/api/auth/application"
+	"github.com/nicograef/jotti/backend/domain/jwt"
+	"github.com/nicograef/jotti/backend/domain/user"
+	"github.com/nicograef/jotti/backend/repository/device_repo"
+	"github.com/nicograef/jotti/backend/repository/event_repo"
+	"github.com/nicograef/jotti/backend/repository/login_repo"
+	"github.com/nicograef/jotti/backend/repository/role_repo"
+	"github.com/nicograef/jotti/backend/repository/session_repo"
 	"github.com/nicograef/jotti/backend/repository/user_repo"
 )
 
-func NewCommandHandler(db *sql.DB, jwtSecret string) CommandHandler {
-	repo := user_repo.Repository{DB: db}
-	command := application.Command{UserRepo: repo, JWTSecret: jwtSecret}
+func NewCommandHandler(db *sql.DB, jwtKeys jwt.Keys, passwordPolicy user.PasswordPolicy) CommandHandler {
+	userRepo := user_repo.Repository{DB: db}
+	sessionRepo := session_repo.Repository{DB: db}
+	attemptsRepo := login_repo.Repository{DB: db}
+	eventRepo := event_repo.Repository{DB: db}
+	deviceRepo := device_repo.Repository{DB: db}
+	roleRepo := role_repo.Repository{DB: db}
+	command := application.Command{UserRepo: userRepo, SessionRepo: sessionRepo, AttemptsRepo: attemptsRepo, EventRepo: eventRepo, DeviceRepo: deviceRepo, RoleRepo: roleRepo, JWTKeys: jwtKeys, PasswordPolicy: passwordPolicy}
 	return CommandHandler{Command: command}
 }
+
+func NewQueryHandler(db *sql.DB) QueryHandler {
+	attemptsRepo := login_repo.Repository{DB: db}
+	userRepo := user_repo.Repository{DB: db}
+	deviceRepo := device_repo.Repository{DB: db}
+	query := application.Query{AttemptsRepo: attemptsRepo, UserRepo: userRepo, DeviceRepo: deviceRepo}
+	return QueryHandler{Query: query}
+}
@@ -0,0 +1,72 @@
+package http
+
+import "github.com/nicograef/jotti/backend/api/openapi"
+
+// Operations of the handlers in the OpenAPI document.
+
+var LoginOperation = openapi.Operation{
+	Summary:  "Log in with username and password",
+	Request:  credentials{},
+	Response: tokenResponse{},
+	Errors: []string{
+		"invalid_credentials", "login_blocked", "no_password_set", "password_change_required", "user_inactive",
+	},
+}
+
+var PinLoginOperation = openapi.Operation{
+	Summary:  "Log in with the PIN of a user on a registered device",
+	Request:  pinLogin{},
+	Response: tokenResponse{},
+	Errors: []string{
+		"invalid_credentials", "invalid_device", "login_blocked", "no_pin_set", "password_change_required",
+		"user_inactive",
+	},
+}
+
+var RefreshOperation = openapi.Operation{
+	Summary:  "Get new tokens for a refresh token",
+	Request:  refresh{},
+	Response: tokenResponse{},
+	Errors:   []string{"invalid_refresh_token", "user_inactive"},
+}
+
+var LogoutOperation = openapi.Operation{
+	Summary: "Revoke the session of a refresh token",
+	Request: refresh{},
+}
+
+var SetPasswordOperation = openapi.Operation{
+	Summary: "Set the first password with the one-time password",
+	Request: setPassword{},
+	Errors: []string{
+		"already_has_password", "invalid_credentials", "onetime_password_expired", "password_contains_username",
+		"password_too_common", "password_too_long", "password_too_short", "password_unchanged",
+	},
+}
+
+var ChangePasswordOperation = openapi.Operation{
+	Summary: "Change the password with the current one",
+	Request: changePassword{},
+	Errors: []string{
+		"invalid_credentials", "login_blocked", "no_password_set", "password_contains_username",
+		"password_too_common", "password_too_long", "password_too_short", "password_unchanged", "user_inactive",
+	},
+}
+
+var ClearLoginAttemptsOperation = openapi.Operation{
+	Summary: "Unblock a username or client IP after failed logins",
+	Request: clearLoginAttempts{},
+	Errors:  []string{"invalid_login_attempts_data", "login_attempts_not_found"},
+}
+
+var GetLoginAttemptsOperation = openapi.Operation{
+	Summary:  "List the failed login attempts by username and client IP",
+	Response: getLoginAttemptsResponse{},
+}
+
+var GetDeviceUsersOperation = openapi.Operation{
+	Summary:  "List the users that can log in with a PIN on the device",
+	Request:  getDeviceUsers{},
+	Response: getDeviceUsersResponse{},
+	Errors:   []string{"invalid_device"},
+}
@@ -0,0 +1,66 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/nicograef/jotti/backend/api/auth/application"
+	"github.com/nicograef/jotti/backend/api/helper"
+	"github.com/nicograef/jotti/backend/domain/login"
+)
+
+type authQuery interface {
+	GetLoginAttempts(ctx context.Context) ([]login.Attempts, error)
+	GetDeviceUsers(ctx context.Context, deviceToken string) ([]application.DeviceUser, error)
+}
+
+type QueryHandler struct {
+	Query authQuery
+}
+
+type getLoginAttemptsResponse struct {
+	LoginAttempts []login.Attempts `json:"loginAttempts"`
+}
+
+func (h *QueryHandler) GetLoginAttemptsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		attempts, err := h.Query.GetLoginAttempts(r.Context())
+		if err != nil {
+			helper.SendServerError(w)
+			return
+		}
+
+		helper.SendResponse(w, getLoginAttemptsResponse{LoginAttempts: attempts})
+	}
+}
+
+type getDeviceUsers struct {
+	DeviceToken string `json:"deviceToken"`
+}
+
+type getDeviceUsersResponse struct {
+	Users []application.DeviceUser `json:"users"`
+}
+
+func (h *QueryHandler) GetDeviceUsersHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := getDeviceUsers{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		users, err := h.Query.GetDeviceUsers(r.Context(), body.DeviceToken)
+		if err != nil {
+			if errors.Is(err, application.ErrInvalidDevice) {
+				helper.SendClientError(w, "invalid_device", nil)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendResponse(w, getDeviceUsersResponse{Users: users})
+	}
+}
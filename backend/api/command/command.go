@@ -0,0 +1,62 @@
+// Package command holds what the commands of the API modules share: running a command in one transaction and
+// recording changes in the audit log.
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/audit"
+	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/rs/zerolog"
+)
+
+// ErrRetryLater is returned when a change conflicted with a concurrent change or the database was not reachable.
+var ErrRetryLater = errors.New("retry later")
+
+// ErrDatabase is returned when there is a database error.
+var ErrDatabase = errors.New("database error")
+
+type eventWriter interface {
+	WriteEvent(ctx context.Context, e event.Event) (int, error)
+}
+
+// InTransaction runs the reads and writes of a command in one transaction, e.g. an entity, the revocation of its
+// sessions and the audit event. Database errors are wrapped in ErrDatabase within fn, so a serialization failure is
+// retried; if it remains, ErrRetryLater is returned. The entity and its ID are only logged, the ID is empty when
+// the command creates the entity.
+func InTransaction(ctx context.Context, t db.TxRunner, entity audit.Entity, id string, fn func(ctx context.Context) error) error {
+	err := t.InTransaction(ctx, fn)
+	if db.IsTemporary(err) {
+		log := zerolog.Ctx(ctx).Warn().Err(err)
+		if id != "" {
+			log = log.Str(string(entity)+"_id", id)
+		}
+		log.Msg("Temporary database error")
+		return ErrRetryLater
+	} else if errors.Is(err, ErrDatabase) {
+		return ErrDatabase
+	}
+	return err
+}
+
+// WriteAuditEvent records a change of an entity by a user (actorID) in the audit log. Audit events have their own
+// subject, e.g. they are not part of the sessions of a table.
+func WriteAuditEvent(ctx context.Context, events eventWriter, actorID int, eventType audit.EventType, entity audit.Entity, id string, before, after any) error {
+	log := zerolog.Ctx(ctx)
+
+	e, err := audit.NewEvent(actorID, eventType, entity, id, before, after)
+	if err != nil {
+		log.Error().Err(err).Str(string(entity)+"_id", id).Str("event_type", string(eventType)).Msg("Failed to create audit event")
+		return fmt.Errorf("%w: %w", ErrDatabase, err)
+	}
+
+	if _, err := events.WriteEvent(ctx, e); err != nil {
+		log.Error().Err(err).Str(string(entity)+"_id", id).Str("event_type", string(eventType)).Msg("Failed to write audit event")
+		return fmt.Errorf("%w: %w", ErrDatabase, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+//go:build unit
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/audit"
+	"github.com/nicograef/jotti/backend/domain/event"
+)
+
+type failingEventWriter struct{}
+
+func (failingEventWriter) WriteEvent(ctx context.Context, e event.Event) (int, error) {
+	return 0, db.ErrConnection
+}
+
+func TestInTransaction(t *testing.T) {
+	errInvalid := errors.New("invalid")
+
+	for _, tc := range []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{"success", nil, nil},
+		{"serialization failure", fmt.Errorf("%w: %w", ErrDatabase, db.ErrSerializationFailure), ErrRetryLater},
+		{"connection error", fmt.Errorf("%w: %w", ErrDatabase, db.ErrConnection), ErrRetryLater},
+		{"database error", fmt.Errorf("%w: %w", ErrDatabase, db.ErrNotFound), ErrDatabase},
+		{"other error", errInvalid, errInvalid},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := InTransaction(context.Background(), db.NewMockTransactor(), audit.UserEntity, "1", func(ctx context.Context) error {
+				return tc.err
+			})
+			if err != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestWriteAuditEvent_Error(t *testing.T) {
+	err := WriteAuditEvent(context.Background(), failingEventWriter{}, 1, audit.EventTypeUserCreatedV1, audit.UserEntity, "2", nil, map[string]int{"id": 2})
+	if !errors.Is(err, ErrDatabase) || !errors.Is(err, db.ErrConnection) {
+		t.Fatalf("expected database error wrapping the connection error, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/nicograef/jotti/backend/api/command"
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/audit"
+	"github.com/nicograef/jotti/backend/domain/device"
+	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/tracing"
+	"github.com/rs/zerolog"
+)
+
+type deviceRepoCommand interface {
+	GetDevice(ctx context.Context, id int) (device.Device, error)
+	CreateDevice(ctx context.Context, d device.Device) (int, error)
+	UpdateDevice(ctx context.Context, d device.Device) error
+}
+
+type sessionRepoCommand interface {
+	RevokeDeviceSessions(ctx context.Context, deviceID int) error
+}
+
+type eventRepoCommand interface {
+	WriteEvent(ctx context.Context, e event.Event) (int, error)
+}
+
+type Command struct {
+	Transactor  db.TxRunner
+	DeviceRepo  deviceRepoCommand
+	SessionRepo sessionRepoCommand
+	EventRepo   eventRepoCommand
+}
+
+// RegisterDevice registers a shared device for PIN login and returns its ID and device token.
+// The device token is only returned once; the device has to store it.
+func (c Command) RegisterDevice(ctx context.Context, actorID int, name string) (int, string, error) {
+	ctx, span := tracing.Start(ctx, "device.RegisterDevice")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	d, token, err := device.NewDevice(name)
+	if err != nil {
+		log.Warn().Err(err).Str("device_name", name).Msg("Invalid device data")
+		return 0, "", fmt.Errorf("%w: %w", ErrInvalidDeviceData, err)
+	}
+
+	// the device and its audit event are written together
+	err = command.InTransaction(ctx, c.Transactor, audit.DeviceEntity, "", func(ctx context.Context) error {
+		id, err := c.DeviceRepo.CreateDevice(ctx, d)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to create device")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
+
+		d.ID = id
+		return command.WriteAuditEvent(ctx, c.EventRepo, actorID, audit.EventTypeDeviceRegisteredV1, audit.DeviceEntity, strconv.Itoa(id), nil, d)
+	})
+	if err != nil {
+		return 0, "", err
+	}
+
+	log.Info().Int("device_id", d.ID).Msg("Device registered")
+	return d.ID, token, nil
+}
+
+// RevokeDevice disables a device and logs out everyone who is logged in on it.
+func (c Command) RevokeDevice(ctx context.Context, actorID, id int) error {
+	ctx, span := tracing.Start(ctx, "device.RevokeDevice")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	// a revoked device must not keep its sessions, so the device, the revocation and the audit event are written
+	// together
+	err := command.InTransaction(ctx, c.Transactor, audit.DeviceEntity, strconv.Itoa(id), func(ctx context.Context) error {
+		d, err := c.DeviceRepo.GetDevice(ctx, id)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				log.Warn().Int("device_id", id).Msg("Device not found")
+				return ErrDeviceNotFound
+			} else {
+				log.Error().Err(err).Int("device_id", id).Msg("Failed to retrieve device")
+				return fmt.Errorf("%w: %w", ErrDatabase, err)
+			}
+		}
+
+		before := d
+		if err := d.Revoke(); err != nil {
+			log.Warn().Err(err).Int("device_id", id).Msg("Device already revoked")
+			return ErrDeviceAlreadyRevoked
+		}
+
+		if err := c.DeviceRepo.UpdateDevice(ctx, d); err != nil {
+			log.Error().Err(err).Int("device_id", id).Msg("Failed to update device")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
+
+		if err := c.SessionRepo.RevokeDeviceSessions(ctx, id); err != nil {
+			log.Error().Err(err).Int("device_id", id).Msg("Failed to revoke sessions of device")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
+
+		return command.WriteAuditEvent(ctx, c.EventRepo, actorID, audit.EventTypeDeviceRevokedV1, audit.DeviceEntity, strconv.Itoa(id), before, d)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Int("device_id", id).Msg("Device revoked")
+	return nil
+}
@@ -0,0 +1,57 @@
+//go:build unit
+
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/device"
+	"github.com/nicograef/jotti/backend/domain/session"
+	"github.com/nicograef/jotti/backend/repository/device_repo"
+	"github.com/nicograef/jotti/backend/repository/event_repo"
+	"github.com/nicograef/jotti/backend/repository/session_repo"
+)
+
+func TestRegisterDevice(t *testing.T) {
+	command := Command{Transactor: db.NewMockTransactor(), DeviceRepo: device_repo.NewMock([]device.Device{}, nil), EventRepo: event_repo.NewMock(nil, nil)}
+
+	id, token, err := command.RegisterDevice(context.Background(), 1, "Tablet Theke")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if id != 1 || token == "" {
+		t.Fatalf("expected device 1 with token, got %d %q", id, token)
+	}
+
+	if _, _, err := command.RegisterDevice(context.Background(), 1, "T"); !errors.Is(err, ErrInvalidDeviceData) {
+		t.Fatalf("expected invalid device data error, got %v", err)
+	}
+}
+
+func TestRevokeDevice_RevokesSessions(t *testing.T) {
+	d, _, _ := device.NewDevice("Tablet Theke")
+	d.ID = 1
+	s, _, _ := session.NewDeviceSession(7, 1)
+	sessionRepo := session_repo.NewMock([]session.Session{s}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), DeviceRepo: device_repo.NewMock([]device.Device{d}, nil), SessionRepo: sessionRepo, EventRepo: event_repo.NewMock(nil, nil)}
+
+	if err := command.RevokeDevice(context.Background(), 1, 1); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got, _ := sessionRepo.GetSession(context.Background(), s.ID)
+	if got.RevokedAt == nil {
+		t.Fatal("expected device session to be revoked")
+	}
+
+	if err := command.RevokeDevice(context.Background(), 1, 1); err != ErrDeviceAlreadyRevoked {
+		t.Fatalf("expected already revoked error, got %v", err)
+	}
+	if err := command.RevokeDevice(context.Background(), 1, 2); err != ErrDeviceNotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
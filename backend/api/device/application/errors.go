@@ -0,0 +1,22 @@
+package application
+
+import (
+	"errors"
+
+	"github.com/nicograef/jotti/backend/api/command"
+)
+
+// ErrDeviceNotFound is returned when a device is not found.
+var ErrDeviceNotFound = errors.New("device not found")
+
+// ErrDeviceAlreadyRevoked is returned when a revoked device is revoked again.
+var ErrDeviceAlreadyRevoked = errors.New("device already revoked")
+
+// ErrRetryLater is returned when a change conflicted with a concurrent change or the database was not reachable.
+var ErrRetryLater = command.ErrRetryLater
+
+// ErrDatabase is returned when there is a database error.
+var ErrDatabase = command.ErrDatabase
+
+// ErrInvalidDeviceData is returned when the provided device data is invalid.
+var ErrInvalidDeviceData = errors.New("invalid device data")
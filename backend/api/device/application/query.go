@@ -0,0 +1,32 @@
+package application
+
+import (
+	"context"
+
+	"github.com/nicograef/jotti/backend/domain/device"
+	"github.com/nicograef/jotti/backend/tracing"
+	"github.com/rs/zerolog"
+)
+
+type deviceRepoQuery interface {
+	GetAllDevices(ctx context.Context) ([]device.Device, error)
+}
+
+type Query struct {
+	DeviceRepo deviceRepoQuery
+}
+
+func (q Query) GetAllDevices(ctx context.Context) ([]device.Device, error) {
+	ctx, span := tracing.Start(ctx, "device.GetAllDevices")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	devices, err := q.DeviceRepo.GetAllDevices(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to retrieve devices")
+		return nil, ErrDatabase
+	}
+
+	return devices, nil
+}
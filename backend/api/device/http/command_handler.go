@@ -0,0 +1,88 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/nicograef/jotti/backend/api/device/application"
+	"github.com/nicograef/jotti/backend/api/helper"
+	"github.com/nicograef/jotti/backend/api/middleware"
+)
+
+type command interface {
+	RegisterDevice(ctx context.Context, actorID int, name string) (int, string, error)
+	RevokeDevice(ctx context.Context, actorID, id int) error
+}
+
+type CommandHandler struct {
+	Command command
+}
+
+type registerDevice struct {
+	Name string `json:"name"`
+}
+
+type registerDeviceResponse struct {
+	ID          int    `json:"id"`
+	DeviceToken string `json:"deviceToken"`
+}
+
+func (h *CommandHandler) RegisterDeviceHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := registerDevice{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		actorID := r.Context().Value(middleware.UserIDKey).(int)
+		id, token, err := h.Command.RegisterDevice(r.Context(), actorID, body.Name)
+		if err != nil {
+			if errors.Is(err, application.ErrInvalidDeviceData) {
+				helper.SendValidationError(w, "invalid_device_data", err)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendResponse(w, registerDeviceResponse{ID: id, DeviceToken: token})
+	}
+}
+
+type revokeDevice struct {
+	ID int `json:"id"`
+}
+
+func (h *CommandHandler) RevokeDeviceHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := revokeDevice{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		actorID := r.Context().Value(middleware.UserIDKey).(int)
+		err := h.Command.RevokeDevice(r.Context(), actorID, body.ID)
+		if err != nil {
+			if errors.Is(err, application.ErrDeviceNotFound) {
+				helper.SendClientError(w, "device_not_found", nil)
+				return
+			} else if errors.Is(err, application.ErrDeviceAlreadyRevoked) {
+				helper.SendClientError(w, "device_already_revoked", nil)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendEmptyResponse(w)
+	}
+}
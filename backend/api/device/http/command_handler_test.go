@@ -0,0 +1,59 @@
+//go:build unit
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nicograef/jotti/backend/api/device/application"
+	"github.com/nicograef/jotti/backend/api/middleware"
+)
+
+type mockCommand struct {
+	err error
+}
+
+func (m *mockCommand) RegisterDevice(ctx context.Context, actorID int, name string) (int, string, error) {
+	return 1, "device-token", m.err
+}
+
+func (m *mockCommand) RevokeDevice(ctx context.Context, actorID, id int) error {
+	return m.err
+}
+
+func TestRegisterDeviceHandler_Success(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{}}
+
+	body := `{"name":"Tablet Theke"}`
+	req := httptest.NewRequest(http.MethodPost, "/register-device", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
+	rec := httptest.NewRecorder()
+
+	handler.RegisterDeviceHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "device-token") {
+		t.Errorf("expected device token in response, got %s", rec.Body.String())
+	}
+}
+
+func TestRevokeDeviceHandler_NotFound(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{err: application.ErrDeviceNotFound}}
+
+	body := `{"id":99}`
+	req := httptest.NewRequest(http.MethodPost, "/revoke-device", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
+	rec := httptest.NewRecorder()
+
+	handler.RevokeDeviceHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
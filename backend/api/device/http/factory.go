@@ -0,0 +1,27 @@
+package http
+
+import (
+	"database/sql"
+
+	"github.com/nicograef/jotti/backend/api/device/application"
+	dbpkg "github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/repository/device_repo"
+	"github.com/nicograef/jotti/backend/repository/event_repo"
+	"github.com/nicograef/jotti/backend/repository/session_repo"
+)
+
+func NewCommandHandler(db *sql.DB) CommandHandler {
+	deviceRepo := device_repo.Repository{DB: db}
+	sessionRepo := session_repo.Repository{DB: db}
+	eventRepo := event_repo.Repository{DB: db}
+	// lost updates of concurrent changes fail and are retried
+	transactor := dbpkg.Transactor{DB: db, Isolation: sql.LevelRepeatableRead}
+	command := application.Command{Transactor: transactor, DeviceRepo: deviceRepo, SessionRepo: sessionRepo, EventRepo: eventRepo}
+	return CommandHandler{Command: command}
+}
+
+func NewQueryHandler(db *sql.DB) QueryHandler {
+	repo := device_repo.Repository{DB: db}
+	query := application.Query{DeviceRepo: repo}
+	return QueryHandler{Query: query}
+}
@@ -0,0 +1,23 @@
+package http
+
+import "github.com/nicograef/jotti/backend/api/openapi"
+
+// Operations of the handlers in the OpenAPI document.
+
+var RegisterDeviceOperation = openapi.Operation{
+	Summary:  "Register a device for PIN logins, the device token is only returned once",
+	Request:  registerDevice{},
+	Response: registerDeviceResponse{},
+	Errors:   []string{"invalid_device_data", "retry_later"},
+}
+
+var RevokeDeviceOperation = openapi.Operation{
+	Summary: "Revoke a device and end the sessions of its PIN logins",
+	Request: revokeDevice{},
+	Errors:  []string{"device_already_revoked", "device_not_found", "retry_later"},
+}
+
+var GetAllDevicesOperation = openapi.Operation{
+	Summary:  "List all devices",
+	Response: getAllDevicesResponse{},
+}
@@ -0,0 +1,33 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/nicograef/jotti/backend/api/helper"
+	"github.com/nicograef/jotti/backend/domain/device"
+)
+
+type query interface {
+	GetAllDevices(ctx context.Context) ([]device.Device, error)
+}
+
+type QueryHandler struct {
+	Query query
+}
+
+type getAllDevicesResponse struct {
+	Devices []device.Device `json:"devices"`
+}
+
+func (h *QueryHandler) GetAllDevicesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		devices, err := h.Query.GetAllDevices(r.Context())
+		if err != nil {
+			helper.SendServerError(w)
+			return
+		}
+
+		helper.SendResponse(w, getAllDevicesResponse{Devices: devices})
+	}
+}
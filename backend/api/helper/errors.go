@@ -0,0 +1,123 @@
+package helper
+
+import "net/http"
+
+// ErrorCode is an error code the API sends to clients together with its HTTP status.
+type ErrorCode struct {
+	Code        string
+	Status      int
+	Description string
+}
+
+// ErrorCodes is the catalog of all error codes of the API, see "API Errors" in DEVELOPMENT.md.
+// SendClientError answers with the status of the code, so the same code always has the same status.
+var ErrorCodes = []ErrorCode{
+	// malformed requests
+	{"invalid_json", http.StatusBadRequest, "The request body is not valid JSON or has unknown fields."},
+	{"invalid_date", http.StatusBadRequest, "The date of the report is invalid."},
+	{"invalid_time_range", http.StatusBadRequest, "The start of the time range is not before its end."},
+	{"invalid_sync_batch", http.StatusBadRequest, "The sync batch has too many items."},
+
+	// authentication
+	{"missing_authorization", http.StatusUnauthorized, "The Authorization header is missing."},
+	{"invalid_jwt", http.StatusUnauthorized, "The access token is invalid or expired."},
+	{"session_revoked", http.StatusUnauthorized, "The session of the access token was revoked."},
+	{"invalid_credentials", http.StatusUnauthorized, "The username, password or PIN is wrong."},
+	{"invalid_refresh_token", http.StatusUnauthorized, "The refresh token is invalid, expired or revoked."},
+	{"invalid_device", http.StatusUnauthorized, "The device token is invalid or the device was revoked."},
+	{"onetime_password_expired", http.StatusUnauthorized, "The one-time password expired or was used too often."},
+
+	// authorization
+	{"insufficient_permissions", http.StatusForbidden, "The role of the user lacks a permission of the route."},
+	{"user_inactive", http.StatusForbidden, "The user is deactivated."},
+	{"password_change_required", http.StatusForbidden, "An admin requires the user to choose a new password."},
+	{"role_protected", http.StatusForbidden, "The admin role can't be changed or deleted."},
+	{"role_not_allowed", http.StatusForbidden, "The role grants permissions the user lacks."},
+	{"reversal_not_allowed", http.StatusForbidden, "The user may not reverse the payment."},
+
+	// missing resources
+	{"user_not_found", http.StatusNotFound, "The user does not exist."},
+	{"role_not_found", http.StatusNotFound, "The role does not exist."},
+	{"product_not_found", http.StatusNotFound, "The product does not exist."},
+	{"product_history_not_found", http.StatusNotFound, "The product has no history."},
+	{"table_not_found", http.StatusNotFound, "The table does not exist."},
+	{"session_not_found", http.StatusNotFound, "The session of the table does not exist."},
+	{"payment_not_found", http.StatusNotFound, "The payment does not exist."},
+	{"period_not_found", http.StatusNotFound, "The period does not exist."},
+	{"device_not_found", http.StatusNotFound, "The device does not exist."},
+	{"login_attempts_not_found", http.StatusNotFound, "There are no failed login attempts for the username or client IP."},
+
+	// wrong HTTP method
+	{"method_not_allowed", http.StatusMethodNotAllowed, "The route only accepts POST requests."},
+
+	// conflicts with the current state
+	{"username_already_exists", http.StatusConflict, "Another user has the username."},
+	{"role_already_exists", http.StatusConflict, "Another role has the name."},
+	{"product_already_exists", http.StatusConflict, "Another product has the name."},
+	{"table_already_exists", http.StatusConflict, "Another table has the name."},
+	{"role_in_use", http.StatusConflict, "The role is still assigned to users."},
+	{"table_already_open", http.StatusConflict, "The table already has an open session."},
+	{"table_not_open", http.StatusConflict, "The table has no open session."},
+	{"table_has_open_balance", http.StatusConflict, "The table has an open balance that has to be paid or written off before closing."},
+	{"items_not_unpaid", http.StatusConflict, "Some of the written-off products are not open on the table."},
+	{"payment_exceeds_balance", http.StatusConflict, "The payment is higher than the open balance."},
+	{"payment_already_reversed", http.StatusConflict, "The payment was already reversed."},
+	{"payment_of_earlier_session", http.StatusConflict, "The payment belongs to an earlier session of the table."},
+	{"idempotency_key_reused", http.StatusConflict, "The ID of the order or payment was already used for another table."},
+	{"period_already_open", http.StatusConflict, "Another period is still open."},
+	{"period_already_closed", http.StatusConflict, "The period is already closed."},
+	{"no_open_period", http.StatusConflict, "No period is open."},
+	{"device_already_revoked", http.StatusConflict, "The device was already revoked."},
+	{"no_password_set", http.StatusConflict, "The user has no password yet and has to set one with the one-time password."},
+	{"no_pin_set", http.StatusConflict, "The user has no PIN yet."},
+	{"already_has_password", http.StatusConflict, "The user has no one-time password, probably because a password is already set."},
+
+	// invalid data, details lists the invalid fields if known
+	{"invalid_user_data", http.StatusUnprocessableEntity, "The name, username or role of the user is invalid."},
+	{"invalid_role_data", http.StatusUnprocessableEntity, "The name or permissions of the role are invalid."},
+	{"invalid_product_data", http.StatusUnprocessableEntity, "The name, description, price or category of the product is invalid."},
+	{"invalid_table_data", http.StatusUnprocessableEntity, "The name of the table is invalid."},
+	{"invalid_period_data", http.StatusUnprocessableEntity, "The name of the period is invalid."},
+	{"invalid_device_data", http.StatusUnprocessableEntity, "The name of the device is invalid."},
+	{"invalid_order_data", http.StatusUnprocessableEntity, "The order is empty or has invalid products."},
+	{"invalid_payment_data", http.StatusUnprocessableEntity, "The payment is empty or has invalid products or amounts."},
+	{"invalid_write_off_data", http.StatusUnprocessableEntity, "The write-off is empty or has an invalid category or products."},
+	{"invalid_login_attempts_data", http.StatusUnprocessableEntity, "The kind of the login attempts is invalid."},
+	{"invalid_reference", http.StatusUnprocessableEntity, "The data references something that does not exist (anymore)."},
+	{"invalid_write_off_reason", http.StatusUnprocessableEntity, "A table with an open balance can only be closed with a write-off reason."},
+	{"invalid_reversal_reason", http.StatusUnprocessableEntity, "The reason for the reversal is missing or too long."},
+	{"invalid_split", http.StatusUnprocessableEntity, "The balance can't be split across the requested number of payers."},
+	{"invalid_pin", http.StatusUnprocessableEntity, "The PIN does not have 4 to 6 digits."},
+	{"password_too_short", http.StatusUnprocessableEntity, "The new password is too short."},
+	{"password_too_long", http.StatusUnprocessableEntity, "The new password is too long."},
+	{"password_too_common", http.StatusUnprocessableEntity, "The new password is too common."},
+	{"password_contains_username", http.StatusUnprocessableEntity, "The new password contains the username."},
+	{"password_unchanged", http.StatusUnprocessableEntity, "The new password is the same as the old one."},
+
+	// rejected sync items only, see the sync route
+	{"invalid_sync_item", http.StatusUnprocessableEntity, "The sync item has an unknown type or misses its ID."},
+	{"product_inactive", http.StatusConflict, "The product is deactivated and can't be ordered."},
+	{"sync_item_too_old", http.StatusUnprocessableEntity, "The sync item was entered too long ago or before the open period began."},
+
+	// limits and server errors
+	{"login_blocked", http.StatusTooManyRequests, "Too many failed login attempts, the Retry-After header says when to try again."},
+	{"rate_limited", http.StatusTooManyRequests, "The client or user sent too many requests, the Retry-After header says when to try again."},
+	{"internal_server_error", http.StatusInternalServerError, "An unexpected error, the request can be sent again later."},
+	{"retry_later", http.StatusServiceUnavailable, "A temporary database problem, the request can be sent again right away."},
+}
+
+var errorStatus = func() map[string]int {
+	statuses := make(map[string]int, len(ErrorCodes))
+	for _, c := range ErrorCodes {
+		statuses[c.Code] = c.Status
+	}
+	return statuses
+}()
+
+// errorStatusOf returns the HTTP status of the error code, 400 if the code is not in the catalog.
+func errorStatusOf(code string) int {
+	if status, ok := errorStatus[code]; ok {
+		return status
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,125 @@
+//go:build unit
+
+package helper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nicograef/jotti/backend/domain/validation"
+)
+
+func TestSendClientError_Status(t *testing.T) {
+	for _, tc := range []struct {
+		code     string
+		expected int
+	}{
+		{"invalid_json", http.StatusBadRequest},
+		{"invalid_jwt", http.StatusUnauthorized},
+		{"insufficient_permissions", http.StatusForbidden},
+		{"table_not_found", http.StatusNotFound},
+		{"table_already_exists", http.StatusConflict},
+		{"invalid_table_data", http.StatusUnprocessableEntity},
+		{"unknown_code", http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		SendClientError(rec, tc.code, nil)
+		if rec.Code != tc.expected {
+			t.Errorf("%s: expected status %d, got %d", tc.code, tc.expected, rec.Code)
+		}
+	}
+}
+
+func TestSendValidationError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := fmt.Errorf("%w: %w", errors.New("invalid table data"), validation.New("name", "Name too short"))
+	SendValidationError(rec, "invalid_table_data", err)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422, got %d", rec.Code)
+	}
+	expected := `{"code":"invalid_table_data","details":[{"field":"name","message":"Name too short"}]}`
+	if body := strings.TrimSpace(rec.Body.String()); body != expected {
+		t.Errorf("expected body %s, got %s", expected, body)
+	}
+
+	rec = httptest.NewRecorder()
+	SendValidationError(rec, "invalid_table_data", errors.New("check constraint violated"))
+	var resp map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if _, ok := resp["details"]; ok {
+		t.Errorf("expected no details, got %v", resp)
+	}
+}
+
+func TestErrorCodes_Unique(t *testing.T) {
+	seen := map[string]bool{}
+	for _, c := range ErrorCodes {
+		if seen[c.Code] {
+			t.Errorf("error code %s is in the catalog twice", c.Code)
+		}
+		seen[c.Code] = true
+		if c.Description == "" {
+			t.Errorf("error code %s has no description", c.Code)
+		}
+	}
+}
+
+// TestErrorCodes_Complete checks that every code sent with SendClientError or SendValidationError in the api
+// packages is in the catalog, so new codes are documented and get a deliberate status.
+func TestErrorCodes_Complete(t *testing.T) {
+	catalog := map[string]bool{}
+	for _, c := range ErrorCodes {
+		catalog[c.Code] = true
+	}
+
+	err := filepath.WalkDir("..", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return err
+		}
+
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+		if err != nil {
+			return err
+		}
+
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) < 2 {
+				return true
+			}
+			// only calls of other packages, the helpers pass the code of their callers on
+			fn, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || (fn.Sel.Name != "SendClientError" && fn.Sel.Name != "SendValidationError") {
+				return true
+			}
+
+			literal, ok := call.Args[1].(*ast.BasicLit)
+			if !ok || literal.Kind != token.STRING {
+				t.Errorf("%s: error code is not a string literal", path)
+				return true
+			}
+			if code, _ := strconv.Unquote(literal.Value); !catalog[code] {
+				t.Errorf("%s: error code %s is not in the catalog", path, code)
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read the api packages: %v", err)
+	}
+}
@@ -2,8 +2,12 @@ package helper
 
 import (
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/nicograef/jotti/backend/domain/validation"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -29,14 +33,38 @@ func SendEmptyResponse(w http.ResponseWriter) {
 	SendJSONResponse(w, struct{}{}, http.StatusOK)
 }
 
+// SendClientError sends the error code with the HTTP status of the code in ErrorCodes. Details are optional, e.g. a
+// message for the user.
 func SendClientError(w http.ResponseWriter, code string, details any) {
-	SendJSONResponse(w, errorResponse{Code: code, Details: details}, http.StatusBadRequest)
+	SendJSONResponse(w, errorResponse{Code: code, Details: details}, errorStatusOf(code))
+}
+
+// SendValidationError sends the error code with the invalid fields of err (see validation.Error) as details.
+func SendValidationError(w http.ResponseWriter, code string, err error) {
+	var details any
+	if fields := validation.Fields(err); fields != nil {
+		details = fields
+	}
+	SendClientError(w, code, details)
 }
 
 func SendServerError(w http.ResponseWriter) {
 	SendJSONResponse(w, errorResponse{Code: "internal_server_error"}, http.StatusInternalServerError)
 }
 
+// SendTooManyRequests tells the client that it exceeded its rate limit and when it may send the next request.
+func SendTooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(retryAfter.Seconds())))))
+	SendJSONResponse(w, errorResponse{Code: "rate_limited"}, http.StatusTooManyRequests)
+}
+
+// SendRetryLater tells the client that a temporary database problem (a conflict with a concurrent change or a lost
+// connection) kept the request from being processed and it can be sent again.
+func SendRetryLater(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "1")
+	SendJSONResponse(w, errorResponse{Code: "retry_later"}, http.StatusServiceUnavailable)
+}
+
 // ReadBody reads the JSON request body into the provided struct
 func ReadBody[T any](w http.ResponseWriter, r *http.Request, body *T) bool {
 	log := zerolog.Ctx(r.Context())
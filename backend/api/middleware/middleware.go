@@ -2,24 +2,41 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/nicograef/jotti/backend/api/helper"
+	"github.com/nicograef/jotti/backend/db"
 	"github.com/nicograef/jotti/backend/domain/jwt"
+	"github.com/nicograef/jotti/backend/domain/role"
+	"github.com/nicograef/jotti/backend/domain/session"
+	"github.com/nicograef/jotti/backend/metrics"
+	"github.com/nicograef/jotti/backend/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-	"golang.org/x/time/rate"
 )
 
 type ContextKey string
 
 const (
 	UserIDKey        ContextKey = "userid"
+	UserRoleKey      ContextKey = "userrole"
+	PermissionsKey   ContextKey = "permissions"
 	CorrelationIDKey ContextKey = "correlation_id"
+	ClientIPKey      ContextKey = "client_ip"
 )
 
 // CorrelationIDMiddleware adds a correlation ID to each request for tracing
@@ -37,41 +54,119 @@ func CorrelationIDMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// LoggingMiddleware logs HTTP requests with correlation ID
+// TracingMiddleware starts the server span of a request. The trace continues the trace of the client if the request
+// has a W3C traceparent header. LoggingMiddleware names the span after the route.
+func TracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Start(ctx, r.Method+" "+unmatchedRoute,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// unmatchedRoute is the route label of requests that matched no route, so unknown paths don't create new series.
+const unmatchedRoute = "unmatched"
+
+type routeKey struct{}
+
+// LoggingMiddleware logs HTTP requests with correlation ID and trace ID and records their count and duration per route.
+// The route is set by RouteMiddleware.
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
 		correlationID, _ := r.Context().Value(CorrelationIDKey).(string)
-		logger := log.With().Str("correlation", correlationID).Logger()
-		r = r.WithContext(logger.WithContext(r.Context()))
+		loggerContext := log.With().Str("correlation", correlationID)
+		if traceID := tracing.TraceID(r.Context()); traceID != "" {
+			loggerContext = loggerContext.Str("trace_id", traceID)
+		}
+		logger := loggerContext.Logger()
+		route := unmatchedRoute
+		ctx := context.WithValue(logger.WithContext(r.Context()), routeKey{}, &route)
+		r = r.WithContext(ctx)
 
 		// Create a response writer wrapper to capture status code
 		ww := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 		next.ServeHTTP(ww, r)
 
+		duration := time.Since(start)
+		metrics.HTTPRequests.WithLabelValues(route, strconv.Itoa(ww.statusCode)).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(route).Observe(duration.Seconds())
+
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(attribute.String("http.route", route), attribute.Int("http.response.status_code", ww.statusCode))
+		if ww.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(ww.statusCode))
+		}
+
 		logger.Info().
 			Str("path", r.URL.Path).
 			Int("status", ww.statusCode).
-			Int64("duration_ms", time.Since(start).Milliseconds()).
+			Int64("duration_ms", duration.Milliseconds()).
 			Msg("Request completed")
 	})
 }
 
-// RateLimitMiddleware limits requests per IP
-func RateLimitMiddleware(requestsPerSecond int) func(http.Handler) http.Handler {
-	limiter := rate.NewLimiter(rate.Limit(requestsPerSecond), requestsPerSecond*2)
+// RouteMiddleware records the pattern of mux that matches the request as route for LoggingMiddleware.
+// prefix is the path that was stripped before the request reached mux. Nested muxes overwrite the route
+// of the outer mux with their more specific pattern.
+func RouteMiddleware(prefix string, mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route, ok := r.Context().Value(routeKey{}).(*string); ok {
+			if _, pattern := mux.Handler(r); pattern != "" {
+				*route = prefix + pattern
+			}
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// ClientIPMiddleware determines the client IP and adds it to the request context.
+// X-Forwarded-For is only used if the request comes from a trusted proxy. The header is read from right to left
+// and the first address that is not a trusted proxy is the client, so clients cannot spoof their IP.
+func ClientIPMiddleware(trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	isTrusted := func(addr netip.Addr) bool {
+		for _, prefix := range trustedProxies {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			logger := zerolog.Ctx(r.Context())
+			clientIP := ""
+			if addrPort, err := netip.ParseAddrPort(r.RemoteAddr); err == nil {
+				addr := addrPort.Addr().Unmap()
+				clientIP = addr.String()
 
-			if !limiter.Allow() {
-				logger.Warn().Msg("Rate limit exceeded")
-				http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
-				return
+				if isTrusted(addr) {
+					hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+					for i := len(hops) - 1; i >= 0; i-- {
+						hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+						if err != nil {
+							break
+						}
+						clientIP = hop.Unmap().String()
+						if !isTrusted(hop.Unmap()) {
+							break
+						}
+					}
+				}
 			}
-			next.ServeHTTP(w, r)
+
+			ctx := context.WithValue(r.Context(), ClientIPKey, clientIP)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
@@ -83,6 +178,7 @@ func PostMethodOnlyMiddleware(next http.Handler) http.HandlerFunc {
 
 		if r.Method != http.MethodPost {
 			logger.Error().Str("method", r.Method).Msg("Invalid method.")
+			w.Header().Set("Allow", http.MethodPost)
 			helper.SendClientError(w, "method_not_allowed", nil)
 			return
 		}
@@ -102,43 +198,93 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-// NewJwtMiddleware validates the JWT Token in the Authorization header.
-// If valid, it adds the user information to the request context.
-func NewJwtMiddleware(jwtSecret string, allowedRoles []string) func(http.Handler) http.HandlerFunc {
+type sessionRepo interface {
+	GetSession(ctx context.Context, id string) (session.Session, error)
+	TouchSession(ctx context.Context, id string, at time.Time) error
+}
+
+// NewJwtMiddleware validates the JWT Token in the Authorization header and checks that its session is not revoked.
+// If valid, it adds the user information and permissions to the request context and records the activity of the session.
+// Permissions are checked per endpoint with RequirePermission.
+func NewJwtMiddleware(jwtKeys jwt.Keys, sessions sessionRepo) func(http.Handler) http.HandlerFunc {
 	return func(h http.Handler) http.HandlerFunc {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			token := r.Header.Get("Authorization")
-			if token == "" {
+			header := r.Header.Get("Authorization")
+			if header == "" {
 				log.Error().Msg("Missing Authorization header")
 				helper.SendClientError(w, "missing_authorization", nil)
 				return
 			}
 
 			// get jwt token, remove "Bearer " prefix
-			token = token[len("Bearer "):]
-			userID, userRole, err := jwt.ParseAndValidateJWTToken(token, jwtSecret)
+			token, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok {
+				log.Error().Msg("Authorization header without Bearer token")
+				helper.SendClientError(w, "invalid_jwt", nil)
+				return
+			}
+			claims, err := jwt.ParseAndValidateJWTToken(token, jwtKeys)
 			if err != nil {
 				log.Error().Err(err).Msg("Invalid JWT token")
 				helper.SendClientError(w, "invalid_jwt", nil)
 				return
 			}
 
-			// check if role is allowed
-			roleAllowed := false
-			for _, role := range allowedRoles {
-				if userRole == role {
-					roleAllowed = true
-					break
-				}
+			ctx := r.Context()
+
+			// check if session was revoked (logout, deactivation, password reset) or idle on a device
+			now := time.Now()
+			s, err := sessions.GetSession(ctx, claims.SessionID)
+			if err != nil && !errors.Is(err, db.ErrNotFound) {
+				log.Error().Err(err).Str("session_id", claims.SessionID).Msg("Failed to retrieve session")
+				helper.SendServerError(w)
+				return
 			}
-			if !roleAllowed {
-				helper.SendClientError(w, "insufficient_permissions", fmt.Sprintf("Insufficient permissions for role %s", userRole))
+			if err != nil || !s.IsActive(now) || s.UserID != claims.UserID {
+				log.Warn().Str("session_id", claims.SessionID).Msg("JWT token of revoked session")
+				helper.SendClientError(w, "session_revoked", nil)
 				return
 			}
 
-			ctx := r.Context()
-			ctx = context.WithValue(ctx, UserIDKey, userID)
+			if s.NeedsTouch(now) {
+				if err := sessions.TouchSession(ctx, s.ID, now.UTC()); err != nil {
+					log.Error().Err(err).Str("session_id", s.ID).Msg("Failed to record session activity")
+				}
+			}
+
+			ctx = context.WithValue(ctx, UserIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, UserRoleKey, claims.Role)
+			ctx = context.WithValue(ctx, PermissionsKey, claims.Permissions)
 			h.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// HasPermission reports whether the permissions of the access token in the context include the permission.
+func HasPermission(ctx context.Context, p role.Permission) bool {
+	permissions, _ := ctx.Value(PermissionsKey).([]string)
+	return slices.Contains(permissions, string(p))
+}
+
+// Permissions returns the permissions of the access token in the context.
+func Permissions(ctx context.Context) []role.Permission {
+	names, _ := ctx.Value(PermissionsKey).([]string)
+	permissions := make([]role.Permission, len(names))
+	for i, name := range names {
+		permissions[i] = role.Permission(name)
+	}
+	return permissions
+}
+
+// RequirePermission only lets requests through whose access token has the permission.
+// It has to run behind NewJwtMiddleware.
+func RequirePermission(p role.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !HasPermission(r.Context(), p) {
+			zerolog.Ctx(r.Context()).Warn().Str("permission", string(p)).Msg("Missing permission")
+			helper.SendClientError(w, "insufficient_permissions", fmt.Sprintf("Missing permission %s", p))
+			return
+		}
+		next(w, r)
+	}
+}
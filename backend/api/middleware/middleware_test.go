@@ -3,11 +3,25 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/nicograef/jotti/backend/domain/jwt"
+	"github.com/nicograef/jotti/backend/domain/role"
+	"github.com/nicograef/jotti/backend/domain/session"
+	"github.com/nicograef/jotti/backend/metrics"
+	"github.com/nicograef/jotti/backend/repository/session_repo"
+	"github.com/nicograef/jotti/backend/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestCorrelationIDMiddleware_GeneratesID(t *testing.T) {
@@ -49,13 +63,21 @@ func TestCorrelationIDMiddleware_UsesExisting(t *testing.T) {
 	}
 }
 
-func TestRateLimitMiddleware_AllowsWithinLimit(t *testing.T) {
+func TestJwtMiddleware_ValidToken(t *testing.T) {
+	keys := jwt.NewSecretKeys("test-secret")
+	s, _, _ := session.NewSession(1)
+	token, err := jwt.GenerateJWTTokenForUser(1, "admin", []string{"users.manage"}, s.ID, keys)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	middleware := RateLimitMiddleware(10)(handler)
-	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	middleware := NewJwtMiddleware(keys, session_repo.NewMock([]session.Session{s}, nil))(handler)
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
 	rec := httptest.NewRecorder()
 
 	middleware.ServeHTTP(rec, req)
@@ -65,33 +87,77 @@ func TestRateLimitMiddleware_AllowsWithinLimit(t *testing.T) {
 	}
 }
 
-func TestRateLimitMiddleware_BlocksExceedingLimit(t *testing.T) {
+func TestJwtMiddleware_NoToken(t *testing.T) {
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	middleware := RateLimitMiddleware(1)(handler)
+	middleware := NewJwtMiddleware(jwt.NewSecretKeys("test-secret"), session_repo.NewMock([]session.Session{}, nil))(handler)
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	rec := httptest.NewRecorder()
+
+	middleware.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", rec.Code)
+	}
+}
+
+func TestJwtMiddleware_NoBearerToken(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
 
-	// Fill the limiter
-	for i := 0; i < 10; i++ {
-		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	middleware := NewJwtMiddleware(jwt.NewSecretKeys("test-secret"), session_repo.NewMock([]session.Session{}, nil))(handler)
+	for _, header := range []string{"x", "Bearer", "Basic dXNlcjpwYXNzd29yZA=="} {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.Header.Set("Authorization", header)
 		rec := httptest.NewRecorder()
+
 		middleware.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status 401 for header %q, got %d", header, rec.Code)
+		}
 	}
+}
 
-	// This request should be rate limited
-	req := httptest.NewRequest(http.MethodGet, "/test", nil)
-	rec := httptest.NewRecorder()
-	middleware.ServeHTTP(rec, req)
+func TestRequirePermission(t *testing.T) {
+	keys := jwt.NewSecretKeys("test-secret")
+	s, _, _ := session.NewSession(2)
+	token, err := jwt.GenerateJWTTokenForUser(2, "service", []string{"tables.view"}, s.ID, keys)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
 
-	if rec.Code != http.StatusTooManyRequests {
-		t.Errorf("expected status 429, got %d", rec.Code)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	auth := NewJwtMiddleware(keys, session_repo.NewMock([]session.Session{s}, nil))
+
+	for _, tc := range []struct {
+		permission role.Permission
+		expected   int
+	}{
+		{role.ViewTables, http.StatusOK},
+		{role.ManageUsers, http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+
+		auth(RequirePermission(tc.permission, handler)).ServeHTTP(rec, req)
+
+		if rec.Code != tc.expected {
+			t.Errorf("%s: expected status %d, got %d", tc.permission, tc.expected, rec.Code)
+		}
 	}
 }
 
-func TestJwtMiddleware_ValidToken(t *testing.T) {
-	secret := "test-secret"
-	token, err := jwt.GenerateJWTTokenForUser(1, "admin", secret)
+func TestServiceMiddleware_ValidToken(t *testing.T) {
+	keys := jwt.NewSecretKeys("test-secret")
+	s, _, _ := session.NewSession(2)
+	token, err := jwt.GenerateJWTTokenForUser(2, "service", []string{"tables.view"}, s.ID, keys)
 	if err != nil {
 		t.Fatalf("failed to generate token: %v", err)
 	}
@@ -100,8 +166,8 @@ func TestJwtMiddleware_ValidToken(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	middleware := NewJwtMiddleware(secret, []string{"admin"})(handler)
-	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	middleware := NewJwtMiddleware(keys, session_repo.NewMock([]session.Session{s}, nil))(handler)
+	req := httptest.NewRequest(http.MethodGet, "/service", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	rec := httptest.NewRecorder()
 
@@ -112,57 +178,74 @@ func TestJwtMiddleware_ValidToken(t *testing.T) {
 	}
 }
 
-func TestJwtMiddleware_NoToken(t *testing.T) {
+func TestJwtMiddleware_RevokedSession(t *testing.T) {
+	keys := jwt.NewSecretKeys("test-secret")
+	s, _, _ := session.NewSession(1)
+	token, err := jwt.GenerateJWTTokenForUser(1, "admin", []string{"users.manage"}, s.ID, keys)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	s.Revoke()
+
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	middleware := NewJwtMiddleware("test-secret", []string{"admin"})(handler)
+	middleware := NewJwtMiddleware(keys, session_repo.NewMock([]session.Session{s}, nil))(handler)
 	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
 	rec := httptest.NewRecorder()
 
 	middleware.ServeHTTP(rec, req)
 
-	if rec.Code != http.StatusBadRequest {
-		t.Errorf("expected status 400, got %d", rec.Code)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "session_revoked") {
+		t.Errorf("expected session_revoked error, got %s", rec.Body.String())
 	}
 }
 
-func TestJwtMiddleware_ServiceRole(t *testing.T) {
-	secret := "test-secret"
-	token, err := jwt.GenerateJWTTokenForUser(2, "service", secret)
+func TestJwtMiddleware_IdleDeviceSession(t *testing.T) {
+	keys := jwt.NewSecretKeys("test-secret")
+	s, _, _ := session.NewDeviceSession(1, 2)
+	token, err := jwt.GenerateJWTTokenForUser(1, "service", []string{"tables.view"}, s.ID, keys)
 	if err != nil {
 		t.Fatalf("failed to generate token: %v", err)
 	}
+	s.LastActiveAt = time.Now().Add(-session.DeviceIdleTimeout - time.Minute)
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	middleware := NewJwtMiddleware(secret, []string{"admin"})(handler)
-	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	middleware := NewJwtMiddleware(keys, session_repo.NewMock([]session.Session{s}, nil))(handler)
+	req := httptest.NewRequest(http.MethodGet, "/service", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	rec := httptest.NewRecorder()
 
 	middleware.ServeHTTP(rec, req)
 
-	if rec.Code != http.StatusBadRequest {
-		t.Errorf("expected status 400, got %d", rec.Code)
+	if !strings.Contains(rec.Body.String(), "session_revoked") {
+		t.Errorf("expected session_revoked error, got %s", rec.Body.String())
 	}
 }
 
-func TestServiceMiddleware_ValidToken(t *testing.T) {
-	secret := "test-secret"
-	token, err := jwt.GenerateJWTTokenForUser(2, "service", secret)
+func TestJwtMiddleware_TouchesSession(t *testing.T) {
+	keys := jwt.NewSecretKeys("test-secret")
+	s, _, _ := session.NewDeviceSession(1, 2)
+	token, err := jwt.GenerateJWTTokenForUser(1, "service", []string{"tables.view"}, s.ID, keys)
 	if err != nil {
 		t.Fatalf("failed to generate token: %v", err)
 	}
+	s.LastActiveAt = time.Now().Add(-5 * time.Minute)
+	sessionRepo := session_repo.NewMock([]session.Session{s}, nil)
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	middleware := NewJwtMiddleware(secret, []string{"service"})(handler)
+	middleware := NewJwtMiddleware(keys, sessionRepo)(handler)
 	req := httptest.NewRequest(http.MethodGet, "/service", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	rec := httptest.NewRecorder()
@@ -170,6 +253,116 @@ func TestServiceMiddleware_ValidToken(t *testing.T) {
 	middleware.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusOK {
-		t.Errorf("expected status 200, got %d", rec.Code)
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	got, _ := sessionRepo.GetSession(context.Background(), s.ID)
+	if time.Since(got.LastActiveAt) > time.Minute {
+		t.Errorf("expected last activity to be updated, got %s", got.LastActiveAt)
+	}
+}
+
+func TestClientIPMiddleware(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("172.16.0.0/12")}
+
+	cases := []struct {
+		name          string
+		remoteAddr    string
+		xForwardedFor string
+		expected      string
+	}{
+		{"direct client", "192.0.2.1:1234", "", "192.0.2.1"},
+		{"untrusted proxy header ignored", "192.0.2.1:1234", "198.51.100.7", "192.0.2.1"},
+		{"trusted proxy", "172.18.0.5:1234", "198.51.100.7", "198.51.100.7"},
+		{"spoofed header", "172.18.0.5:1234", "203.0.113.9, 198.51.100.7", "198.51.100.7"},
+		{"chained trusted proxies", "172.18.0.5:1234", "198.51.100.7, 172.18.0.6", "198.51.100.7"},
+		{"invalid header", "172.18.0.5:1234", "garbage", "172.18.0.5"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var clientIP string
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				clientIP, _ = r.Context().Value(ClientIPKey).(string)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/test", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.xForwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tc.xForwardedFor)
+			}
+
+			ClientIPMiddleware(trusted)(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+			if clientIP != tc.expected {
+				t.Errorf("expected client IP %s, got %s", tc.expected, clientIP)
+			}
+		})
+	}
+}
+
+func TestLoggingMiddleware_RecordsRouteMetrics(t *testing.T) {
+	api := http.NewServeMux()
+	api.HandleFunc("/create-table", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/admin/", http.StripPrefix("/admin", RouteMiddleware("/admin", api)))
+	handler := LoggingMiddleware(RouteMiddleware("", mux))
+
+	for _, path := range []string{"/admin/create-table", "/admin/wp-login.php", "/.env"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	b := rec.Body
+	for _, expected := range []string{
+		`jotti_http_requests_total{route="/admin/create-table",status="200"} 1`,
+		`jotti_http_requests_total{route="/admin/",status="404"} 1`,
+		`jotti_http_requests_total{route="unmatched",status="404"} 1`,
+		`jotti_http_request_duration_seconds_count{route="/admin/create-table"} 1`,
+	} {
+		if !strings.Contains(b.String(), expected+"\n") {
+			t.Errorf("expected %s in metrics, got\n%s", expected, b.String())
+		}
+	}
+	if strings.Contains(b.String(), "wp-login") {
+		t.Error("expected unknown paths not to be used as route")
+	}
+}
+
+func TestTracingMiddleware_ContinuesClientTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	api := http.NewServeMux()
+	api.HandleFunc("/get-table", func(w http.ResponseWriter, r *http.Request) {
+		if traceID := tracing.TraceID(r.Context()); traceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("expected trace ID of client, got %q", traceID)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/service/", http.StripPrefix("/service", RouteMiddleware("/service", api)))
+	handler := TracingMiddleware(LoggingMiddleware(RouteMiddleware("", mux)))
+
+	req := httptest.NewRequest(http.MethodPost, "/service/get-table", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "POST /service/get-table" {
+		t.Errorf("expected span named after route, got %s", span.Name())
+	}
+	if span.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("expected span of client as parent, got %s", span.Parent().SpanID())
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("expected error status for 500, got %v", span.Status())
 	}
 }
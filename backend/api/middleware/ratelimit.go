@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nicograef/jotti/backend/api/helper"
+	"github.com/rs/zerolog"
+	"golang.org/x/time/rate"
+)
+
+// RateBudget is how many requests a client may send per second on average and in a burst.
+type RateBudget struct {
+	PerSecond float64
+	Burst     int
+}
+
+// RateLimiter keeps a token bucket per client. Buckets of clients that were idle for idleTimeout are evicted,
+// so the limiter does not grow with every client ever seen. The idle timeout must be long enough to refill a bucket
+// (Burst / PerSecond), then evicting it does not reset a limit.
+type RateLimiter struct {
+	budget      RateBudget
+	idleTimeout time.Duration
+	now         func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*rateClient
+	lastEvict time.Time
+}
+
+type rateClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter creates a rate limiter that grants each client the budget. The burst is at least one request.
+func NewRateLimiter(budget RateBudget, idleTimeout time.Duration) *RateLimiter {
+	budget.Burst = max(1, budget.Burst)
+	return &RateLimiter{
+		budget:      budget,
+		idleTimeout: idleTimeout,
+		now:         time.Now,
+		clients:     map[string]*rateClient{},
+		lastEvict:   time.Now(),
+	}
+}
+
+// Allow takes a request from the bucket of the client. If the bucket is empty, it returns false and the time until
+// the next request is allowed.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastEvict) >= l.idleTimeout {
+		l.evictIdle(now)
+	}
+
+	client, ok := l.clients[key]
+	if !ok {
+		client = &rateClient{limiter: rate.NewLimiter(rate.Limit(l.budget.PerSecond), l.budget.Burst)}
+		l.clients[key] = client
+	}
+	client.lastSeen = now
+
+	reservation := client.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+
+	return true, 0
+}
+
+// Clients returns the number of clients with a bucket.
+func (l *RateLimiter) Clients() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.clients)
+}
+
+func (l *RateLimiter) evictIdle(now time.Time) {
+	for key, client := range l.clients {
+		if now.Sub(client.lastSeen) >= l.idleTimeout {
+			delete(l.clients, key)
+		}
+	}
+	l.lastEvict = now
+}
+
+// RateLimitKey returns the client a request is counted for.
+type RateLimitKey func(r *http.Request) string
+
+// ClientIPRateLimitKey counts requests per client IP, which ClientIPMiddleware takes from trusted proxy headers.
+func ClientIPRateLimitKey(r *http.Request) string {
+	clientIP, _ := r.Context().Value(ClientIPKey).(string)
+	return "ip:" + clientIP
+}
+
+// UserRateLimitKey counts requests per logged in user, so users behind the same IP (e.g. the Wi-Fi of the venue)
+// don't share a budget. Requests without user are counted per client IP.
+func UserRateLimitKey(r *http.Request) string {
+	if userID, ok := r.Context().Value(UserIDKey).(int); ok {
+		return "user:" + strconv.Itoa(userID)
+	}
+	return ClientIPRateLimitKey(r)
+}
+
+// RateLimitMiddleware rejects requests of clients that exceeded their budget with 429 and a Retry-After header.
+func RateLimitMiddleware(limiter *RateLimiter, key RateLimitKey) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := key(r)
+			if ok, retryAfter := limiter.Allow(client); !ok {
+				zerolog.Ctx(r.Context()).Warn().Str("client", client).Dur("retry_after", retryAfter).Msg("Rate limit exceeded")
+				helper.SendTooManyRequests(w, retryAfter)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
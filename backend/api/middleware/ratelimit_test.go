@@ -0,0 +1,100 @@
+//go:build unit
+
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func requestFrom(clientIP string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/test", nil)
+	return req.WithContext(context.WithValue(req.Context(), ClientIPKey, clientIP))
+}
+
+func TestRateLimitMiddleware_AllowsWithinLimit(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	limiter := NewRateLimiter(RateBudget{PerSecond: 10, Burst: 20}, time.Minute)
+	middleware := RateLimitMiddleware(limiter, ClientIPRateLimitKey)(handler)
+	rec := httptest.NewRecorder()
+
+	middleware.ServeHTTP(rec, requestFrom("10.0.0.1"))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitMiddleware_BlocksExceedingLimit(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	limiter := NewRateLimiter(RateBudget{PerSecond: 0.5, Burst: 2}, time.Minute)
+	middleware := RateLimitMiddleware(limiter, ClientIPRateLimitKey)(handler)
+
+	// Fill the limiter
+	for i := 0; i < 2; i++ {
+		middleware.ServeHTTP(httptest.NewRecorder(), requestFrom("10.0.0.1"))
+	}
+
+	// This request should be rate limited
+	rec := httptest.NewRecorder()
+	middleware.ServeHTTP(rec, requestFrom("10.0.0.1"))
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429, got %d", rec.Code)
+	}
+	if retryAfter := rec.Header().Get("Retry-After"); retryAfter != "2" {
+		t.Errorf("expected Retry-After 2, got %q", retryAfter)
+	}
+
+	// Other clients have their own budget
+	rec = httptest.NewRecorder()
+	middleware.ServeHTTP(rec, requestFrom("10.0.0.2"))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200 for other client, got %d", rec.Code)
+	}
+}
+
+func TestRateLimiter_EvictsIdleClients(t *testing.T) {
+	now := time.Now()
+	limiter := NewRateLimiter(RateBudget{PerSecond: 1, Burst: 1}, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("ip:10.0.0.1")
+	limiter.Allow("ip:10.0.0.2")
+	if limiter.Clients() != 2 {
+		t.Fatalf("expected 2 clients, got %d", limiter.Clients())
+	}
+
+	now = now.Add(30 * time.Second)
+	limiter.Allow("ip:10.0.0.2")
+	now = now.Add(45 * time.Second)
+	if ok, _ := limiter.Allow("ip:10.0.0.3"); !ok {
+		t.Error("expected request of new client to be allowed")
+	}
+
+	if limiter.Clients() != 2 {
+		t.Errorf("expected idle client to be evicted, got %d clients", limiter.Clients())
+	}
+}
+
+func TestUserRateLimitKey(t *testing.T) {
+	req := requestFrom("10.0.0.1")
+	if key := UserRateLimitKey(req); key != "ip:10.0.0.1" {
+		t.Errorf("expected client IP without user, got %s", key)
+	}
+
+	req = req.WithContext(context.WithValue(req.Context(), UserIDKey, 7))
+	if key := UserRateLimitKey(req); key != "user:7" {
+		t.Errorf("expected user key, got %s", key)
+	}
+}
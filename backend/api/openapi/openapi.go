@@ -0,0 +1,285 @@
+// Package openapi builds the OpenAPI 3 document of the API from the request and response types of the handlers.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nicograef/jotti/backend/api/helper"
+	"github.com/nicograef/jotti/backend/domain/validation"
+	"github.com/rs/zerolog"
+)
+
+// Operation documents a handler. Handler packages declare one next to the request and response types of the
+// handler; a test of package api compares them with the code of the handler.
+type Operation struct {
+	Summary string
+	// Request is a value of the type of the request body, nil if the handler reads no body.
+	Request any
+	// Response is a value of the type of the response body, nil if the handler sends an empty object.
+	Response any
+	// Errors are the codes the handler sends (see helper.ErrorCodes). Codes of the middleware are added by the
+	// document.
+	Errors []string
+}
+
+// Route is an operation at a path of the API.
+type Route struct {
+	Path string
+	// Authenticated routes need an access token in the Authorization header.
+	Authenticated bool
+	// Permission is the permission the user needs, "" if every logged in user may call the route.
+	Permission string
+	// Handler is the name of the handler function, e.g. "CreateTableHandler".
+	Handler   string
+	Operation Operation
+}
+
+// Document is the OpenAPI 3.0 document of the routes. All routes are POST requests with JSON bodies.
+type Document struct {
+	title   string
+	version string
+	routes  []Route
+}
+
+// New creates a document without routes.
+func New(title, version string) *Document {
+	return &Document{title: title, version: version}
+}
+
+// Add documents a route.
+func (d *Document) Add(route Route) {
+	d.routes = append(d.routes, route)
+}
+
+// Routes returns the documented routes in the order they were added.
+func (d *Document) Routes() []Route {
+	return slices.Clone(d.routes)
+}
+
+// Errors returns the codes a route sends: the codes of its handler and of the middleware in front of it.
+func (route Route) Errors() []string {
+	codes := slices.Clone(route.Operation.Errors)
+	if route.Operation.Request != nil {
+		codes = append(codes, "invalid_json")
+	}
+	if route.Authenticated {
+		codes = append(codes, "missing_authorization", "invalid_jwt", "session_revoked")
+	}
+	if route.Permission != "" {
+		codes = append(codes, "insufficient_permissions")
+	}
+	codes = append(codes, "method_not_allowed", "rate_limited", "internal_server_error")
+
+	slices.Sort(codes)
+	return slices.Compact(codes)
+}
+
+const description = "All routes are POST requests with a JSON body. Fields missing in a request body are read as " +
+	"their zero value, required fields of responses are always sent. Errors are sent as `{\"code\": ..., " +
+	"\"details\": ...}` with the HTTP status of the code, see \"API Errors\" in DEVELOPMENT.md."
+
+// JSON renders the document.
+func (d *Document) JSON() ([]byte, error) {
+	s := newSchemas()
+	s.components["ErrorDetails"] = &schema{
+		Description: "A message for the user or the invalid fields of a validation error.",
+		OneOf: []*schema{
+			{Type: "string"},
+			{Type: "array", Items: s.of(reflect.TypeFor[validation.FieldError]())},
+		},
+	}
+
+	doc := document{
+		OpenAPI: "3.0.3",
+		Info:    info{Title: d.title, Version: d.version, Description: description},
+		Paths:   map[string]pathItem{},
+		Components: components{
+			Schemas: s.components,
+			SecuritySchemes: map[string]securityScheme{
+				"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+
+	for _, route := range d.routes {
+		doc.Paths[route.Path] = pathItem{Post: d.operation(s, route)}
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func (d *Document) operation(s *schemas, route Route) operation {
+	op := operation{
+		OperationID: operationID(route.Path),
+		Summary:     route.Operation.Summary,
+		Tags:        []string{strings.Split(strings.TrimPrefix(route.Path, "/"), "/")[0]},
+		Permission:  route.Permission,
+		Responses:   map[string]response{},
+	}
+
+	if route.Authenticated {
+		op.Security = []map[string][]string{{"bearer": {}}}
+	}
+
+	if route.Operation.Request != nil {
+		op.RequestBody = &requestBody{
+			Required: true,
+			Content:  jsonContent(s.of(reflect.TypeOf(route.Operation.Request))),
+		}
+	}
+
+	ok := &schema{Type: "object"}
+	if route.Operation.Response != nil {
+		ok = s.of(reflect.TypeOf(route.Operation.Response))
+	}
+	op.Responses["200"] = response{Description: "OK", Content: jsonContent(ok)}
+
+	for status, codes := range errorsByStatus(route.Errors()) {
+		descriptions := make([]string, len(codes))
+		for i, c := range codes {
+			descriptions[i] = "`" + c.Code + "`: " + c.Description
+		}
+
+		errorSchema := &schema{
+			Type:     "object",
+			Required: []string{"code"},
+			Properties: map[string]*schema{
+				"code":    {Type: "string", Enum: codeNames(codes)},
+				"details": {Ref: "#/components/schemas/ErrorDetails"},
+			},
+		}
+
+		resp := response{Description: strings.Join(descriptions, "\n\n"), Content: jsonContent(errorSchema)}
+		if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+			resp.Headers = map[string]header{
+				"Retry-After": {Description: "Seconds until the request can be sent again.", Schema: &schema{Type: "integer"}},
+			}
+		}
+		op.Responses[strconv.Itoa(status)] = resp
+	}
+
+	return op
+}
+
+// errorsByStatus groups the codes by their HTTP status. Unknown codes are sent as 400 (see helper.SendClientError).
+func errorsByStatus(codes []string) map[int][]helper.ErrorCode {
+	catalog := map[string]helper.ErrorCode{}
+	for _, c := range helper.ErrorCodes {
+		catalog[c.Code] = c
+	}
+
+	byStatus := map[int][]helper.ErrorCode{}
+	for _, code := range codes {
+		c, ok := catalog[code]
+		if !ok {
+			c = helper.ErrorCode{Code: code, Status: http.StatusBadRequest}
+		}
+		byStatus[c.Status] = append(byStatus[c.Status], c)
+	}
+	return byStatus
+}
+
+func codeNames(codes []helper.ErrorCode) []string {
+	names := make([]string, len(codes))
+	for i, c := range codes {
+		names[i] = c.Code
+	}
+	return names
+}
+
+// operationID turns a path like "/admin/create-user" into "adminCreateUser".
+func operationID(path string) string {
+	var b strings.Builder
+	for i, word := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' }) {
+		if i > 0 {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
+
+func jsonContent(s *schema) map[string]mediaType {
+	return map[string]mediaType{"application/json": {Schema: s}}
+}
+
+// Handler serves the document as JSON.
+func (d *Document) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := d.JSON()
+		if err != nil {
+			zerolog.Ctx(r.Context()).Error().Err(err).Msg("Failed to render OpenAPI document")
+			helper.SendServerError(w)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(b); err != nil {
+			zerolog.Ctx(r.Context()).Error().Err(err).Msg("Failed to write OpenAPI document")
+		}
+	})
+}
+
+type document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       info                `json:"info"`
+	Paths      map[string]pathItem `json:"paths"`
+	Components components          `json:"components"`
+}
+
+type info struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type pathItem struct {
+	Post operation `json:"post"`
+}
+
+type operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Tags        []string              `json:"tags"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	// Permission is the permission the role of the user needs
+	Permission  string              `json:"x-permission,omitempty"`
+	RequestBody *requestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]response `json:"responses"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Headers     map[string]header    `json:"headers,omitempty"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type header struct {
+	Description string  `json:"description"`
+	Schema      *schema `json:"schema"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type components struct {
+	Schemas         map[string]*schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat"`
+}
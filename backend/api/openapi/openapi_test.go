@@ -0,0 +1,183 @@
+//go:build unit
+
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type base struct {
+	ID int64 `json:"id"`
+}
+
+type item struct {
+	base
+	Name      string         `json:"name"`
+	Note      *string        `json:"note,omitempty"`
+	Parent    *item          `json:"parent"`
+	CreatedAt time.Time      `json:"createdAt"`
+	Price     int            `json:"price,string"`
+	Tags      map[string]int `json:"tags,omitempty"`
+	Secret    string         `json:"-"`
+	internal  string
+}
+
+type createItem struct {
+	Name string `json:"name"`
+}
+
+func testDocument() *Document {
+	doc := New("Test API", "1.0.0")
+	doc.Add(Route{
+		Path:          "/admin/create-item",
+		Authenticated: true,
+		Permission:    "items.manage",
+		Operation: Operation{
+			Summary:  "Create an item",
+			Request:  createItem{},
+			Response: item{},
+			Errors:   []string{"table_not_found", "product_not_found", "retry_later"},
+		},
+	})
+	doc.Add(Route{Path: "/auth/logout", Operation: Operation{Summary: "Log out"}})
+	return doc
+}
+
+// render returns the document as generic JSON, the way clients read it.
+func render(t *testing.T, doc *Document) map[string]any {
+	t.Helper()
+	b, err := doc.JSON()
+	if err != nil {
+		t.Fatalf("failed to render document: %v", err)
+	}
+	var spec map[string]any
+	if err := json.Unmarshal(b, &spec); err != nil {
+		t.Fatalf("failed to decode document: %v", err)
+	}
+	return spec
+}
+
+// get returns the value at the path of keys.
+func get(t *testing.T, v any, keys ...string) any {
+	t.Helper()
+	for _, key := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			t.Fatalf("no object at %v", keys)
+		}
+		v, ok = m[key]
+		if !ok {
+			t.Fatalf("missing key %s of %v", key, keys)
+		}
+	}
+	return v
+}
+
+func TestDocument_JSON_Operation(t *testing.T) {
+	spec := render(t, testDocument())
+	op := get(t, spec, "paths", "/admin/create-item", "post")
+
+	if id := get(t, op, "operationId"); id != "adminCreateItem" {
+		t.Errorf("expected operationId adminCreateItem, got %v", id)
+	}
+	if permission := get(t, op, "x-permission"); permission != "items.manage" {
+		t.Errorf("expected permission items.manage, got %v", permission)
+	}
+	if security := get(t, op, "security"); !reflect.DeepEqual(security, []any{map[string]any{"bearer": []any{}}}) {
+		t.Errorf("expected bearer security, got %v", security)
+	}
+	if ref := get(t, op, "requestBody", "content", "application/json", "schema", "$ref"); ref != "#/components/schemas/openapi.createItem" {
+		t.Errorf("unexpected request schema %v", ref)
+	}
+
+	// handler codes and codes of the middleware are grouped by their status
+	expected := map[string][]any{
+		"400": {"invalid_json"},
+		"401": {"invalid_jwt", "missing_authorization", "session_revoked"},
+		"403": {"insufficient_permissions"},
+		"404": {"product_not_found", "table_not_found"},
+		"405": {"method_not_allowed"},
+		"429": {"rate_limited"},
+		"500": {"internal_server_error"},
+		"503": {"retry_later"},
+	}
+	responses := get(t, op, "responses").(map[string]any)
+	if len(responses) != len(expected)+1 {
+		t.Errorf("expected %d responses, got %v", len(expected)+1, responses)
+	}
+	for status, codes := range expected {
+		enum := get(t, responses, status, "content", "application/json", "schema", "properties", "code", "enum")
+		if !reflect.DeepEqual(enum, codes) {
+			t.Errorf("%s: expected codes %v, got %v", status, codes, enum)
+		}
+	}
+	get(t, responses, "429", "headers", "Retry-After")
+	get(t, responses, "503", "headers", "Retry-After")
+}
+
+func TestDocument_JSON_Unauthenticated(t *testing.T) {
+	spec := render(t, testDocument())
+	op := get(t, spec, "paths", "/auth/logout", "post").(map[string]any)
+
+	for _, key := range []string{"security", "x-permission", "requestBody"} {
+		if _, ok := op[key]; ok {
+			t.Errorf("expected no %s, got %v", key, op[key])
+		}
+	}
+	if schema := get(t, op, "responses", "200", "content", "application/json", "schema"); !reflect.DeepEqual(schema, map[string]any{"type": "object"}) {
+		t.Errorf("expected empty object response, got %v", schema)
+	}
+	responses := get(t, op, "responses").(map[string]any)
+	for _, status := range []string{"400", "401", "403"} {
+		if _, ok := responses[status]; ok {
+			t.Errorf("expected no %s response", status)
+		}
+	}
+}
+
+func TestDocument_JSON_Schema(t *testing.T) {
+	spec := render(t, testDocument())
+	schema := get(t, spec, "components", "schemas", "openapi.item")
+
+	expected := map[string]any{
+		"id":        map[string]any{"type": "integer", "format": "int64"},
+		"name":      map[string]any{"type": "string"},
+		"note":      map[string]any{"type": "string", "nullable": true},
+		"parent":    map[string]any{"allOf": []any{map[string]any{"$ref": "#/components/schemas/openapi.item"}}, "nullable": true},
+		"createdAt": map[string]any{"type": "string", "format": "date-time"},
+		"price":     map[string]any{"type": "string"},
+		"tags":      map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "integer"}},
+	}
+	if properties := get(t, schema, "properties"); !reflect.DeepEqual(properties, expected) {
+		t.Errorf("expected properties %v, got %v", expected, properties)
+	}
+
+	required := []any{"id", "name", "parent", "createdAt", "price"}
+	if r := get(t, schema, "required"); !reflect.DeepEqual(r, required) {
+		t.Errorf("expected required %v, got %v", required, r)
+	}
+}
+
+func TestDocument_Handler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	testDocument().Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+	if contentType := rec.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("expected JSON, got %s", contentType)
+	}
+	var spec map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&spec); err != nil {
+		t.Fatalf("failed to decode document: %v", err)
+	}
+	if version := get(t, spec, "openapi"); version != "3.0.3" {
+		t.Errorf("expected OpenAPI 3.0.3, got %v", version)
+	}
+}
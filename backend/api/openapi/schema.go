@@ -0,0 +1,172 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schema is a JSON schema as used by OpenAPI 3.0.
+type schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*schema          `json:"allOf,omitempty"`
+	OneOf                []*schema          `json:"oneOf,omitempty"`
+}
+
+// schemas derives schemas from Go types the way encoding/json marshals them. Named structs become components that
+// are referenced, so types shared by several routes are described once.
+type schemas struct {
+	components map[string]*schema
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{components: map[string]*schema{}, names: map[reflect.Type]string{}}
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// of returns the schema of t.
+func (s *schemas) of(t reflect.Type) *schema {
+	switch {
+	case t == timeType:
+		return &schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &schema{}
+	case t.Kind() != reflect.Pointer && t.Implements(textMarshalerType):
+		// e.g. UUIDs
+		return &schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		elem := s.of(t.Elem())
+		if elem.Ref != "" {
+			return &schema{AllOf: []*schema{elem}, Nullable: true}
+		}
+		elem.Nullable = true
+		return elem
+	case reflect.Bool:
+		return &schema{Type: "boolean"}
+	case reflect.Int64, reflect.Uint64:
+		return &schema{Type: "integer", Format: "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32:
+		return &schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &schema{Type: "number"}
+	case reflect.String:
+		return &schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &schema{Type: "string", Format: "byte"}
+		}
+		return &schema{Type: "array", Items: s.of(t.Elem())}
+	case reflect.Map:
+		return &schema{Type: "object", AdditionalProperties: s.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return &schema{Ref: "#/components/schemas/" + s.component(t)}
+	default:
+		// interfaces can hold any value
+		return &schema{}
+	}
+}
+
+// component adds the schema of the named struct t to the components once and returns its name.
+func (s *schemas) component(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+
+	// the http and application packages of all contexts have the same names, their directory names the context
+	pkg := path.Base(t.PkgPath())
+	if pkg == "http" || pkg == "application" {
+		pkg = path.Base(path.Dir(t.PkgPath()))
+	}
+	name := pkg + "." + t.Name()
+	for i := 2; s.components[name] != nil; i++ {
+		name = pkg + "." + t.Name() + strconv.Itoa(i)
+	}
+
+	// registered before the fields, so recursive types end in a reference
+	s.names[t] = name
+	s.components[name] = &schema{}
+	*s.components[name] = *s.object(t)
+	return name
+}
+
+// object returns the schema of the fields of the struct t. Fields of embedded structs are inlined.
+func (s *schemas) object(t reflect.Type) *schema {
+	obj := &schema{Type: "object", Properties: map[string]*schema{}}
+	s.addFields(obj, t)
+	return obj
+}
+
+func (s *schemas) addFields(obj *schema, t reflect.Type) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				s.addFields(obj, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		var prop *schema
+		if hasOption(options, "string") {
+			prop = &schema{Type: "string"}
+		} else {
+			prop = s.of(field.Type)
+		}
+		obj.Properties[name] = prop
+
+		if !hasOption(options, "omitempty") && !hasOption(options, "omitzero") {
+			obj.Required = append(obj.Required, name)
+		}
+	}
+}
+
+func hasOption(options, option string) bool {
+	for o := range strings.SplitSeq(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,334 @@
+//go:build unit
+
+package api
+
+import (
+	"database/sql"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nicograef/jotti/backend/api/openapi"
+	"github.com/nicograef/jotti/backend/config"
+	"github.com/nicograef/jotti/backend/domain/jwt"
+)
+
+const modulePath = "github.com/nicograef/jotti/backend/"
+
+// newDocumentedApis registers all APIs like the app does and returns their muxes by prefix.
+func newDocumentedApis() (*openapi.Document, map[string]*http.ServeMux) {
+	doc := NewDocument()
+	var db *sql.DB
+	cfg := config.Config{}
+	jwtKeys := jwt.NewSecretKeys("test-secret")
+
+	return doc, map[string]*http.ServeMux{
+		"/auth":    NewAuthApi(cfg, db, jwtKeys, doc),
+		"/admin":   NewAdminApi(cfg, db, jwtKeys, doc),
+		"/service": NewServiceApi(cfg, db, doc),
+	}
+}
+
+func TestDocument_RoutesAreRegistered(t *testing.T) {
+	doc, muxes := newDocumentedApis()
+
+	seen := map[string]bool{}
+	for _, route := range doc.Routes() {
+		if seen[route.Path] {
+			t.Errorf("%s is documented twice", route.Path)
+		}
+		seen[route.Path] = true
+
+		prefix := "/" + strings.Split(route.Path, "/")[1]
+		mux, ok := muxes[prefix]
+		if !ok {
+			t.Errorf("%s belongs to no API", route.Path)
+			continue
+		}
+		req := httptest.NewRequest(http.MethodPost, strings.TrimPrefix(route.Path, prefix), nil)
+		if _, pattern := mux.Handler(req); pattern == "" {
+			t.Errorf("%s is documented but not registered", route.Path)
+		}
+		if route.Operation.Summary == "" {
+			t.Errorf("%s has no summary", route.Path)
+		}
+	}
+}
+
+// TestDocument_AllRoutesDocumented checks that the APIs register their handlers only with the router, which
+// documents every route.
+func TestDocument_AllRoutesDocumented(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") || file == "router.go" {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				if fn, ok := call.Fun.(*ast.SelectorExpr); ok && (fn.Sel.Name == "HandleFunc" || fn.Sel.Name == "Handle") {
+					t.Errorf("%s registers a route with %s instead of the router, so it's not documented", file, fn.Sel.Name)
+				}
+			}
+			return true
+		})
+	}
+}
+
+// TestDocument_MatchesHandlers compares the documented operations with the code of their handlers: the type of the
+// request body, the type of the response and the error codes.
+func TestDocument_MatchesHandlers(t *testing.T) {
+	doc, _ := newDocumentedApis()
+	packages := map[string]*handlerPackage{}
+
+	for _, route := range doc.Routes() {
+		pkgPath, method := splitHandlerName(route.Handler)
+		if !strings.HasPrefix(pkgPath, modulePath) {
+			t.Errorf("%s: unexpected handler %s", route.Path, route.Handler)
+			continue
+		}
+
+		pkg, ok := packages[pkgPath]
+		if !ok {
+			pkg = parseHandlerPackage(t, filepath.Join("..", strings.TrimPrefix(pkgPath, modulePath)))
+			packages[pkgPath] = pkg
+		}
+
+		h, ok := pkg.analyze(method)
+		if !ok {
+			t.Errorf("%s: handler %s not found", route.Path, route.Handler)
+			continue
+		}
+
+		op := route.Operation
+		if documented := typeName(op.Request, pkgPath); documented != h.request {
+			t.Errorf("%s: documented request %q, handler reads %q", route.Path, documented, h.request)
+		}
+		if documented := typeName(op.Response, pkgPath); documented != h.response {
+			t.Errorf("%s: documented response %q, handler sends %q", route.Path, documented, h.response)
+		}
+
+		for _, code := range h.codes {
+			if !slices.Contains(op.Errors, code) {
+				t.Errorf("%s: error code %s is sent but not documented", route.Path, code)
+			}
+		}
+		for _, code := range op.Errors {
+			if !slices.Contains(h.codes, code) {
+				t.Errorf("%s: error code %s is documented but not sent", route.Path, code)
+			}
+		}
+	}
+}
+
+// splitHandlerName splits "<pkg>.(*CommandHandler).CreateTableHandler" or "<pkg>.CommandHandler.CreateTableHandler"
+// into the package and the method.
+func splitHandlerName(name string) (string, string) {
+	slash := strings.LastIndex(name, "/")
+	pkgEnd := slash + strings.Index(name[slash:], ".")
+	return name[:pkgEnd], name[strings.LastIndex(name, ".")+1:]
+}
+
+// typeName returns the name of the type of v as it is written in the package pkgPath, "" for nil.
+func typeName(v any, pkgPath string) string {
+	if v == nil {
+		return ""
+	}
+	t := reflect.TypeOf(v)
+	if t.PkgPath() == pkgPath {
+		return t.Name()
+	}
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
+
+// unknownType is the type of values whose type the test can't read from the code.
+const unknownType = "?"
+
+type handlerPackage struct {
+	funcs map[string]*ast.FuncDecl
+	// results are the result types of the methods of the interfaces the handlers call, e.g. their queries
+	results map[string][]ast.Expr
+}
+
+func parseHandlerPackage(t *testing.T, dir string) *handlerPackage {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := &handlerPackage{funcs: map[string]*ast.FuncDecl{}, results: map[string][]ast.Expr{}}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+				pkg.funcs[fn.Name.Name] = fn
+			}
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			if iface, ok := n.(*ast.InterfaceType); ok {
+				for _, method := range iface.Methods.List {
+					if fn, ok := method.Type.(*ast.FuncType); ok && fn.Results != nil && len(method.Names) > 0 {
+						for _, result := range fn.Results.List {
+							for range max(len(result.Names), 1) {
+								pkg.results[method.Names[0].Name] = append(pkg.results[method.Names[0].Name], result.Type)
+							}
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+	return pkg
+}
+
+type handlerCode struct {
+	request  string
+	response string
+	codes    []string
+}
+
+// analyze reads the request type, the response type and the error codes from the code of the handler method.
+// Package-local functions the handler calls are followed, e.g. helpers that send an error for an application error.
+func (pkg *handlerPackage) analyze(method string) (handlerCode, bool) {
+	fn, ok := pkg.funcs[method]
+	if !ok {
+		return handlerCode{}, false
+	}
+
+	var h handlerCode
+	visited := map[string]bool{}
+	var visit func(fn *ast.FuncDecl)
+	visit = func(fn *ast.FuncDecl) {
+		visited[fn.Name.Name] = true
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+
+			// name of the called function, local is true for functions of the package and methods of the handler
+			var name string
+			var local bool
+			switch f := call.Fun.(type) {
+			case *ast.Ident:
+				name, local = f.Name, true
+			case *ast.SelectorExpr:
+				name = f.Sel.Name
+				if x, ok := f.X.(*ast.Ident); ok && fn.Recv != nil && len(fn.Recv.List[0].Names) > 0 {
+					local = x.Name == fn.Recv.List[0].Names[0].Name
+				}
+			case *ast.IndexExpr: // generic function with explicit type argument
+				if sel, ok := f.X.(*ast.SelectorExpr); ok {
+					name = sel.Sel.Name
+				}
+			}
+
+			switch name {
+			case "SendClientError", "SendValidationError":
+				if lit, ok := call.Args[1].(*ast.BasicLit); ok {
+					code, _ := strconv.Unquote(lit.Value)
+					h.codes = append(h.codes, code)
+				}
+			case "SendRetryLater":
+				h.codes = append(h.codes, "retry_later")
+			case "ReadBody":
+				if unary, ok := call.Args[2].(*ast.UnaryExpr); ok {
+					if ident, ok := unary.X.(*ast.Ident); ok {
+						h.request = pkg.declaredType(fn.Body, ident.Name)
+					}
+				}
+			case "SendResponse":
+				h.response = pkg.valueType(fn.Body, call.Args[1])
+			default:
+				if f, ok := pkg.funcs[name]; ok && local && !visited[name] {
+					visit(f)
+				}
+			}
+			return true
+		})
+	}
+	visit(fn)
+
+	slices.Sort(h.codes)
+	h.codes = slices.Compact(h.codes)
+	return h, true
+}
+
+// valueType returns the type of a composite literal or of a variable declared in body, unknownType if it's not
+// visible in the code.
+func (pkg *handlerPackage) valueType(body *ast.BlockStmt, expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.CompositeLit:
+		return exprString(e.Type)
+	case *ast.Ident:
+		return pkg.declaredType(body, e.Name)
+	}
+	return unknownType
+}
+
+// declaredType returns the type of the variable name declared in body with "name := T{}", "var name T" or
+// "name, err := h.Query.Method(...)" of an interface of the package.
+func (pkg *handlerPackage) declaredType(body *ast.BlockStmt, name string) string {
+	found := unknownType
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.AssignStmt:
+			for i, lhs := range s.Lhs {
+				ident, ok := lhs.(*ast.Ident)
+				if !ok || ident.Name != name || s.Tok != token.DEFINE {
+					continue
+				}
+				if len(s.Rhs) == len(s.Lhs) {
+					if lit, ok := s.Rhs[i].(*ast.CompositeLit); ok {
+						found = exprString(lit.Type)
+					}
+				} else if call, ok := s.Rhs[0].(*ast.CallExpr); ok && len(s.Rhs) == 1 {
+					if sel, ok := call.Fun.(*ast.SelectorExpr); ok && i < len(pkg.results[sel.Sel.Name]) {
+						found = exprString(pkg.results[sel.Sel.Name][i])
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			for _, ident := range s.Names {
+				if ident.Name == name && s.Type != nil {
+					found = exprString(s.Type)
+				}
+			}
+		}
+		return true
+	})
+	return found
+}
+
+func exprString(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return exprString(e.X) + "." + e.Sel.Name
+	}
+	return unknownType
+}
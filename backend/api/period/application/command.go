@@ -0,0 +1,83 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/period"
+	"github.com/nicograef/jotti/backend/tracing"
+	"github.com/rs/zerolog"
+)
+
+type periodRepoCommand interface {
+	GetPeriod(ctx context.Context, id int) (period.Period, error)
+	CreatePeriod(ctx context.Context, p period.Period) (int, error)
+	UpdatePeriod(ctx context.Context, p period.Period) error
+}
+
+type Command struct {
+	PeriodRepo periodRepoCommand
+}
+
+// OpenPeriod starts a new period (Veranstaltung). Only one period can be open at a time.
+func (c Command) OpenPeriod(ctx context.Context, name string) (int, error) {
+	ctx, span := tracing.Start(ctx, "period.OpenPeriod")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	p, err := period.NewPeriod(name)
+	if err != nil {
+		log.Warn().Err(err).Str("period_name", name).Msg("Invalid period data")
+		return 0, fmt.Errorf("%w: %w", ErrInvalidPeriodData, err)
+	}
+
+	id, err := c.PeriodRepo.CreatePeriod(ctx, p)
+	if err != nil {
+		if errors.Is(err, db.ErrAlreadyExists) {
+			log.Warn().Err(err).Msg("Another period is still open")
+			return 0, ErrPeriodAlreadyOpen
+		} else {
+			log.Error().Err(err).Msg("Failed to create period")
+			return 0, ErrDatabase
+		}
+	}
+
+	log.Info().Int("period_id", id).Msg("Period opened")
+	return id, nil
+}
+
+// ClosePeriod ends a period. Its products, tables and events stay available for reports.
+func (c Command) ClosePeriod(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "period.ClosePeriod")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	p, err := c.PeriodRepo.GetPeriod(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			log.Warn().Int("period_id", id).Msg("Period not found")
+			return ErrPeriodNotFound
+		} else {
+			log.Error().Err(err).Int("period_id", id).Msg("Failed to retrieve period")
+			return ErrDatabase
+		}
+	}
+
+	if err := p.Close(); err != nil {
+		log.Warn().Err(err).Int("period_id", id).Msg("Period already closed")
+		return ErrPeriodAlreadyClosed
+	}
+
+	err = c.PeriodRepo.UpdatePeriod(ctx, p)
+	if err != nil {
+		log.Error().Err(err).Int("period_id", id).Msg("Failed to update period")
+		return ErrDatabase
+	}
+
+	log.Info().Int("period_id", id).Msg("Period closed")
+	return nil
+}
@@ -0,0 +1,60 @@
+//go:build unit
+
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nicograef/jotti/backend/domain/period"
+	"github.com/nicograef/jotti/backend/repository/period_repo"
+)
+
+func TestOpenPeriod(t *testing.T) {
+	ctx := context.Background()
+	repo := period_repo.NewMock([]period.Period{}, nil)
+	command := Command{PeriodRepo: repo}
+	query := Query{PeriodRepo: repo}
+
+	if _, err := query.GetCurrentPeriod(ctx); err != ErrNoOpenPeriod {
+		t.Fatalf("expected ErrNoOpenPeriod, got %v", err)
+	}
+
+	id, err := command.OpenPeriod(ctx, "Sommerfest 2025")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err := command.OpenPeriod(ctx, "Weihnachtsmarkt 2025"); err != ErrPeriodAlreadyOpen {
+		t.Fatalf("expected ErrPeriodAlreadyOpen, got %v", err)
+	}
+
+	current, err := query.GetCurrentPeriod(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if current.ID != id {
+		t.Errorf("expected current period %d, got %d", id, current.ID)
+	}
+}
+
+func TestClosePeriod(t *testing.T) {
+	ctx := context.Background()
+	repo := period_repo.NewMock([]period.Period{}, nil)
+	command := Command{PeriodRepo: repo}
+
+	if err := command.ClosePeriod(ctx, 1); err != ErrPeriodNotFound {
+		t.Fatalf("expected ErrPeriodNotFound, got %v", err)
+	}
+
+	id, _ := command.OpenPeriod(ctx, "Sommerfest 2025")
+	if err := command.ClosePeriod(ctx, id); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := command.ClosePeriod(ctx, id); err != ErrPeriodAlreadyClosed {
+		t.Fatalf("expected ErrPeriodAlreadyClosed, got %v", err)
+	}
+	if _, err := command.OpenPeriod(ctx, "Weihnachtsmarkt 2025"); err != nil {
+		t.Fatalf("expected no error opening the next period, got %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package application
+
+import (
+	"errors"
+)
+
+// ErrPeriodNotFound is returned when a period is not found.
+var ErrPeriodNotFound = errors.New("period not found")
+
+// ErrPeriodAlreadyOpen is returned when a period is opened while another period is still open.
+var ErrPeriodAlreadyOpen = errors.New("period already open")
+
+// ErrPeriodAlreadyClosed is returned when a closed period is closed again.
+var ErrPeriodAlreadyClosed = errors.New("period already closed")
+
+// ErrNoOpenPeriod is returned when there is no open period.
+var ErrNoOpenPeriod = errors.New("no open period")
+
+// ErrDatabase is returned when there is a database error.
+var ErrDatabase = errors.New("database error")
+
+// ErrInvalidPeriodData is returned when the provided period data is invalid.
+var ErrInvalidPeriodData = errors.New("invalid period data")
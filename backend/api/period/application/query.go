@@ -0,0 +1,58 @@
+package application
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/period"
+	"github.com/nicograef/jotti/backend/tracing"
+	"github.com/rs/zerolog"
+)
+
+type periodRepoQuery interface {
+	GetOpenPeriod(ctx context.Context) (period.Period, error)
+	GetAllPeriods(ctx context.Context) ([]period.Period, error)
+}
+
+type Query struct {
+	PeriodRepo periodRepoQuery
+}
+
+// GetAllPeriods returns all periods, the newest first.
+func (q Query) GetAllPeriods(ctx context.Context) ([]period.Period, error) {
+	ctx, span := tracing.Start(ctx, "period.GetAllPeriods")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	periods, err := q.PeriodRepo.GetAllPeriods(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to retrieve all periods")
+		return nil, ErrDatabase
+	}
+
+	log.Debug().Int("count", len(periods)).Msg("Retrieved all periods")
+	return periods, nil
+}
+
+// GetCurrentPeriod returns the open period.
+func (q Query) GetCurrentPeriod(ctx context.Context) (period.Period, error) {
+	ctx, span := tracing.Start(ctx, "period.GetCurrentPeriod")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	p, err := q.PeriodRepo.GetOpenPeriod(ctx)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			log.Debug().Msg("No open period")
+			return period.Period{}, ErrNoOpenPeriod
+		} else {
+			log.Error().Err(err).Msg("Failed to retrieve open period")
+			return period.Period{}, ErrDatabase
+		}
+	}
+
+	return p, nil
+}
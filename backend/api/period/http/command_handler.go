@@ -0,0 +1,81 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/nicograef/jotti/backend/api/helper"
+	"github.com/nicograef/jotti/backend/api/period/application"
+)
+
+type command interface {
+	OpenPeriod(ctx context.Context, name string) (int, error)
+	ClosePeriod(ctx context.Context, id int) error
+}
+
+type CommandHandler struct {
+	Command command
+}
+
+type openPeriod struct {
+	Name string `json:"name"`
+}
+
+type openPeriodResponse struct {
+	ID int `json:"id"`
+}
+
+func (h *CommandHandler) OpenPeriodHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := openPeriod{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		id, err := h.Command.OpenPeriod(r.Context(), body.Name)
+		if err != nil {
+			if errors.Is(err, application.ErrPeriodAlreadyOpen) {
+				helper.SendClientError(w, "period_already_open", nil)
+				return
+			} else if errors.Is(err, application.ErrInvalidPeriodData) {
+				helper.SendValidationError(w, "invalid_period_data", err)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendResponse(w, openPeriodResponse{ID: id})
+	}
+}
+
+type closePeriod struct {
+	ID int `json:"id"`
+}
+
+func (h *CommandHandler) ClosePeriodHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := closePeriod{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		err := h.Command.ClosePeriod(r.Context(), body.ID)
+		if err != nil {
+			if errors.Is(err, application.ErrPeriodNotFound) {
+				helper.SendClientError(w, "period_not_found", nil)
+				return
+			} else if errors.Is(err, application.ErrPeriodAlreadyClosed) {
+				helper.SendClientError(w, "period_already_closed", nil)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendEmptyResponse(w)
+	}
+}
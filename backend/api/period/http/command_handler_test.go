@@ -0,0 +1,70 @@
+//go:build unit
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nicograef/jotti/backend/api/period/application"
+)
+
+type mockCommand struct {
+	err error
+}
+
+func (m *mockCommand) OpenPeriod(ctx context.Context, name string) (int, error) {
+	return 1, m.err
+}
+
+func (m *mockCommand) ClosePeriod(ctx context.Context, id int) error {
+	return m.err
+}
+
+func TestOpenPeriodHandler_Success(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{}}
+
+	body := `{"name":"Sommerfest 2025"}`
+	req := httptest.NewRequest(http.MethodPost, "/open-period", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.OpenPeriodHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestOpenPeriodHandler_AlreadyOpen(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{err: application.ErrPeriodAlreadyOpen}}
+
+	body := `{"name":"Sommerfest 2025"}`
+	req := httptest.NewRequest(http.MethodPost, "/open-period", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.OpenPeriodHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "period_already_open") {
+		t.Errorf("expected period_already_open error, got %s", rec.Body.String())
+	}
+}
+
+func TestClosePeriodHandler_NotFound(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{err: application.ErrPeriodNotFound}}
+
+	body := `{"id":42}`
+	req := httptest.NewRequest(http.MethodPost, "/close-period", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.ClosePeriodHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,20 @@
+package http
+
+import (
+	"database/sql"
+
+	"github.com/nicograef/jotti/backend/api/period/application"
+	"github.com/nicograef/jotti/backend/repository/period_repo"
+)
+
+func NewCommandHandler(db *sql.DB) CommandHandler {
+	repo := period_repo.Repository{DB: db}
+	command := application.Command{PeriodRepo: repo}
+	return CommandHandler{Command: command}
+}
+
+func NewQueryHandler(db *sql.DB) QueryHandler {
+	repo := period_repo.Repository{DB: db}
+	query := application.Query{PeriodRepo: repo}
+	return QueryHandler{Query: query}
+}
@@ -0,0 +1,29 @@
+package http
+
+import "github.com/nicograef/jotti/backend/api/openapi"
+
+// Operations of the handlers in the OpenAPI document.
+
+var OpenPeriodOperation = openapi.Operation{
+	Summary:  "Open a period (Veranstaltung)",
+	Request:  openPeriod{},
+	Response: openPeriodResponse{},
+	Errors:   []string{"invalid_period_data", "period_already_open"},
+}
+
+var ClosePeriodOperation = openapi.Operation{
+	Summary: "Close a period",
+	Request: closePeriod{},
+	Errors:  []string{"period_already_closed", "period_not_found"},
+}
+
+var GetAllPeriodsOperation = openapi.Operation{
+	Summary:  "List all periods",
+	Response: getAllPeriodsResponse{},
+}
+
+var GetCurrentPeriodOperation = openapi.Operation{
+	Summary:  "Get the open period",
+	Response: getCurrentPeriodResponse{},
+	Errors:   []string{"no_open_period"},
+}
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/nicograef/jotti/backend/api/helper"
+	"github.com/nicograef/jotti/backend/api/period/application"
+	"github.com/nicograef/jotti/backend/domain/period"
+)
+
+type query interface {
+	GetAllPeriods(ctx context.Context) ([]period.Period, error)
+	GetCurrentPeriod(ctx context.Context) (period.Period, error)
+}
+
+type QueryHandler struct {
+	Query query
+}
+
+type getAllPeriodsResponse struct {
+	Periods []period.Period `json:"periods"`
+}
+
+func (h *QueryHandler) GetAllPeriodsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		periods, err := h.Query.GetAllPeriods(r.Context())
+		if err != nil {
+			helper.SendServerError(w)
+			return
+		}
+
+		helper.SendResponse(w, getAllPeriodsResponse{Periods: periods})
+	}
+}
+
+type getCurrentPeriodResponse struct {
+	Period period.Period `json:"period"`
+}
+
+func (h *QueryHandler) GetCurrentPeriodHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, err := h.Query.GetCurrentPeriod(r.Context())
+		if err != nil {
+			if errors.Is(err, application.ErrNoOpenPeriod) {
+				helper.SendClientError(w, "no_open_period", nil)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendResponse(w, getCurrentPeriodResponse{Period: p})
+	}
+}
@@ -0,0 +1,51 @@
+//go:build unit
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nicograef/jotti/backend/api/period/application"
+	"github.com/nicograef/jotti/backend/domain/period"
+)
+
+type mockQuery struct {
+	err error
+}
+
+func (m *mockQuery) GetAllPeriods(ctx context.Context) ([]period.Period, error) {
+	return []period.Period{{ID: 1, Name: "Sommerfest 2025", Status: period.OpenStatus}}, m.err
+}
+
+func (m *mockQuery) GetCurrentPeriod(ctx context.Context) (period.Period, error) {
+	return period.Period{ID: 1, Name: "Sommerfest 2025", Status: period.OpenStatus}, m.err
+}
+
+func TestGetAllPeriodsHandler_Success(t *testing.T) {
+	handler := &QueryHandler{Query: &mockQuery{}}
+
+	req := httptest.NewRequest(http.MethodPost, "/get-all-periods", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetAllPeriodsHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestGetCurrentPeriodHandler_NoOpenPeriod(t *testing.T) {
+	handler := &QueryHandler{Query: &mockQuery{err: application.ErrNoOpenPeriod}}
+
+	req := httptest.NewRequest(http.MethodPost, "/get-current-period", nil)
+	rec := httptest.NewRecorder()
+
+	handler.GetCurrentPeriodHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", rec.Code)
+	}
+}
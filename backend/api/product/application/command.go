@@ -3,9 +3,15 @@ package application
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 
+	"github.com/nicograef/jotti/backend/api/command"
 	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/audit"
+	"github.com/nicograef/jotti/backend/domain/event"
 	"github.com/nicograef/jotti/backend/domain/product"
+	"github.com/nicograef/jotti/backend/tracing"
 	"github.com/rs/zerolog"
 )
 
@@ -15,112 +21,158 @@ type commandProductRepo interface {
 	UpdateProduct(ctx context.Context, product product.Product) error
 }
 
+type periodRepo interface {
+	GetOpenPeriodID(ctx context.Context) (int, error)
+}
+
+type eventRepo interface {
+	WriteEvent(ctx context.Context, e event.Event) (int, error)
+}
+
 type Command struct {
+	Transactor  db.TxRunner
 	ProductRepo commandProductRepo
+	PeriodRepo  periodRepo
+	EventRepo   eventRepo
 }
 
-func (c Command) CreateProduct(ctx context.Context, name, description string, netPriceCents int, category product.Category) (int, error) {
+func (c Command) CreateProduct(ctx context.Context, actorID int, name, description string, netPriceCents int, category product.Category) (int, error) {
+	ctx, span := tracing.Start(ctx, "product.CreateProduct")
+	defer span.End()
+
 	log := zerolog.Ctx(ctx)
 
 	product, err := product.NewProduct(name, description, netPriceCents, category)
 	if err != nil {
 		log.Warn().Err(err).Str("product_name", name).Msg("Invalid product data")
-		return 0, ErrInvalidProductData
+		return 0, fmt.Errorf("%w: %w", ErrInvalidProductData, err)
 	}
 
-	productID, err := c.ProductRepo.CreateProduct(ctx, product)
-	if err != nil {
-		if errors.Is(err, db.ErrAlreadyExists) {
-			log.Warn().Err(err).Str("name", product.Name).Msg("Product name already exists")
-			return 0, ErrProductAlreadyExists
-		} else {
-			log.Error().Str("name", product.Name).Msg("Failed to create product")
-			return 0, ErrDatabase
+	// the product and its audit event are written together
+	err = c.Transactor.InTransaction(ctx, func(ctx context.Context) error {
+		// new products belong to the open period (if any)
+		periodID, err := c.PeriodRepo.GetOpenPeriodID(ctx)
+		if err != nil {
+			return err
+		}
+		product.PeriodID = nil
+		if periodID != 0 {
+			product.PeriodID = &periodID
 		}
-	}
-
-	log.Info().Int("product_id", productID).Msg("Product created")
-	return productID, nil
-}
-
-func (c Command) UpdateProduct(ctx context.Context, productID int, name, description string, netPriceCents int, category product.Category) error {
-	log := zerolog.Ctx(ctx)
 
-	product, err := c.ProductRepo.GetProduct(ctx, productID)
-	if err != nil {
-		if errors.Is(err, db.ErrNotFound) {
-			log.Warn().Int("product_id", productID).Msg("Product not found for update")
-			return ErrProductNotFound
-		} else {
-			log.Error().Int("product_id", productID).Msg("Failed to retrieve product for update")
-			return ErrDatabase
+		product.ID, err = c.ProductRepo.CreateProduct(ctx, product)
+		if err != nil {
+			return err
 		}
-	}
 
-	err = product.UpdateDetails(name, description, netPriceCents, category)
-	if err != nil {
-		log.Warn().Err(err).Int("product_id", productID).Msg("Invalid product data for update")
-		return ErrInvalidProductData
+		return command.WriteAuditEvent(ctx, c.EventRepo, actorID, audit.EventTypeProductCreatedV1, audit.ProductEntity, strconv.Itoa(product.ID), nil, product)
+	})
+	if errors.Is(err, db.ErrAlreadyExists) {
+		log.Warn().Err(err).Str("name", product.Name).Msg("Product name already exists")
+		return 0, ErrProductAlreadyExists
+	} else if errors.Is(err, db.ErrCheckViolation) {
+		log.Warn().Err(err).Str("constraint", db.Constraint(err)).Msg("Invalid product data")
+		return 0, ErrInvalidProductData
+	} else if db.IsTemporary(err) {
+		log.Warn().Err(err).Str("name", product.Name).Msg("Temporary database error while creating product")
+		return 0, ErrRetryLater
+	} else if err != nil {
+		log.Error().Err(err).Str("name", product.Name).Msg("Failed to create product")
+		return 0, ErrDatabase
 	}
 
-	err = c.ProductRepo.UpdateProduct(ctx, product)
+	log.Info().Int("product_id", product.ID).Msg("Product created")
+	return product.ID, nil
+}
+
+func (c Command) UpdateProduct(ctx context.Context, actorID, productID int, name, description string, netPriceCents int, category product.Category) error {
+	ctx, span := tracing.Start(ctx, "product.UpdateProduct")
+	defer span.End()
+
+	err := c.changeProduct(ctx, actorID, productID, audit.EventTypeProductUpdatedV1, func(p *product.Product) error {
+		return p.UpdateDetails(name, description, netPriceCents, category)
+	})
 	if err != nil {
-		log.Error().Err(err).Int("product_id", productID).Msg("Failed to update product")
-		return ErrDatabase
+		return err
 	}
 
-	log.Info().Int("product_id", productID).Msg("Product updated")
+	zerolog.Ctx(ctx).Info().Int("product_id", productID).Msg("Product updated")
 	return nil
 }
 
-func (c Command) ActivateProduct(ctx context.Context, productID int) error {
-	log := zerolog.Ctx(ctx)
+func (c Command) ActivateProduct(ctx context.Context, actorID, productID int) error {
+	ctx, span := tracing.Start(ctx, "product.ActivateProduct")
+	defer span.End()
 
-	product, err := c.ProductRepo.GetProduct(ctx, productID)
+	err := c.changeProduct(ctx, actorID, productID, audit.EventTypeProductActivatedV1, func(p *product.Product) error {
+		p.Activate()
+		return nil
+	})
 	if err != nil {
-		if errors.Is(err, db.ErrNotFound) {
-			log.Warn().Int("product_id", productID).Msg("Product not found for activation")
-			return ErrProductNotFound
-		} else {
-			log.Error().Int("product_id", productID).Msg("Failed to retrieve product for activation")
-			return ErrDatabase
-		}
+		return err
 	}
 
-	product.Activate()
+	zerolog.Ctx(ctx).Info().Int("product_id", productID).Msg("Product activated")
+	return nil
+}
+
+func (c Command) DeactivateProduct(ctx context.Context, actorID, productID int) error {
+	ctx, span := tracing.Start(ctx, "product.DeactivateProduct")
+	defer span.End()
 
-	err = c.ProductRepo.UpdateProduct(ctx, product)
+	err := c.changeProduct(ctx, actorID, productID, audit.EventTypeProductDeactivatedV1, func(p *product.Product) error {
+		p.Deactivate()
+		return nil
+	})
 	if err != nil {
-		log.Error().Err(err).Int("product_id", productID).Msg("Failed to update product")
-		return ErrDatabase
+		return err
 	}
 
-	log.Info().Int("product_id", productID).Msg("Product activated")
+	zerolog.Ctx(ctx).Info().Int("product_id", productID).Msg("Product deactivated")
 	return nil
 }
 
-func (c Command) DeactivateProduct(ctx context.Context, productID int) error {
+// changeProduct applies change to a product and writes the product and the audit event in one transaction.
+// An error of change means invalid product data.
+func (c Command) changeProduct(ctx context.Context, actorID, productID int, eventType audit.EventType, change func(p *product.Product) error) error {
 	log := zerolog.Ctx(ctx)
 
-	product, err := c.ProductRepo.GetProduct(ctx, productID)
-	if err != nil {
-		if errors.Is(err, db.ErrNotFound) {
-			log.Warn().Int("product_id", productID).Msg("Product not found for deactivation")
-			return ErrProductNotFound
-		} else {
-			log.Error().Int("product_id", productID).Msg("Failed to retrieve product for activation")
-			return ErrDatabase
+	err := c.Transactor.InTransaction(ctx, func(ctx context.Context) error {
+		p, err := c.ProductRepo.GetProduct(ctx, productID)
+		if err != nil {
+			return err
 		}
-	}
 
-	product.Deactivate()
+		before := p
+		if err := change(&p); err != nil {
+			log.Warn().Err(err).Int("product_id", productID).Msg("Invalid product data for update")
+			return fmt.Errorf("%w: %w", ErrInvalidProductData, err)
+		}
 
-	err = c.ProductRepo.UpdateProduct(ctx, product)
-	if err != nil {
+		if err := c.ProductRepo.UpdateProduct(ctx, p); err != nil {
+			return err
+		}
+
+		return command.WriteAuditEvent(ctx, c.EventRepo, actorID, eventType, audit.ProductEntity, strconv.Itoa(productID), before, p)
+	})
+	if errors.Is(err, ErrInvalidProductData) {
+		return err
+	} else if errors.Is(err, db.ErrNotFound) {
+		log.Warn().Int("product_id", productID).Msg("Product not found")
+		return ErrProductNotFound
+	} else if errors.Is(err, db.ErrAlreadyExists) {
+		log.Warn().Int("product_id", productID).Msg("Product name already exists")
+		return ErrProductAlreadyExists
+	} else if errors.Is(err, db.ErrCheckViolation) {
+		log.Warn().Err(err).Int("product_id", productID).Str("constraint", db.Constraint(err)).Msg("Invalid product data for update")
+		return ErrInvalidProductData
+	} else if db.IsTemporary(err) {
+		log.Warn().Err(err).Int("product_id", productID).Msg("Temporary database error while updating product")
+		return ErrRetryLater
+	} else if err != nil {
 		log.Error().Err(err).Int("product_id", productID).Msg("Failed to update product")
 		return ErrDatabase
 	}
 
-	log.Info().Int("product_id", productID).Msg("Product deactivated")
 	return nil
 }
@@ -2,6 +2,8 @@ package application
 
 import (
 	"errors"
+
+	"github.com/nicograef/jotti/backend/api/command"
 )
 
 // ErrProductNotFound is returned when a product is not found.
@@ -10,8 +12,14 @@ var ErrProductNotFound = errors.New("product not found")
 // ErrProductAlreadyExists is returned when trying to create a product that already exists.
 var ErrProductAlreadyExists = errors.New("product already exists")
 
+// ErrRetryLater is returned when a change conflicted with a concurrent change or the database was not reachable.
+var ErrRetryLater = command.ErrRetryLater
+
 // ErrDatabase is returned when there is a database error.
-var ErrDatabase = errors.New("database error")
+var ErrDatabase = command.ErrDatabase
 
 // ErrInvalidProductData is returned when the provided product data is invalid.
 var ErrInvalidProductData = errors.New("invalid product data")
+
+// ErrNoProductHistory is returned when no state of a product was recorded before the requested time.
+var ErrNoProductHistory = errors.New("no product history")
@@ -2,21 +2,35 @@ package application
 
 import (
 	"context"
+	"strconv"
+	"time"
 
+	"github.com/nicograef/jotti/backend/domain/audit"
+	"github.com/nicograef/jotti/backend/domain/event"
 	"github.com/nicograef/jotti/backend/domain/product"
+	"github.com/nicograef/jotti/backend/tracing"
 	"github.com/rs/zerolog"
 )
 
 type productRepoQuery interface {
 	GetAllProducts(ctx context.Context) ([]product.Product, error)
-	GetActiveProducts(ctx context.Context) ([]product.Product, error)
+	GetActiveProducts(ctx context.Context, periodID int) ([]product.Product, error)
+}
+
+type eventRepoQuery interface {
+	ReadEventsBySubject(ctx context.Context, subject string) ([]event.Event, error)
 }
 
 type Query struct {
 	ProductRepo productRepoQuery
+	PeriodRepo  periodRepo
+	EventRepo   eventRepoQuery
 }
 
 func (q Query) GetAllProducts(ctx context.Context) ([]product.Product, error) {
+	ctx, span := tracing.Start(ctx, "product.GetAllProducts")
+	defer span.End()
+
 	log := zerolog.Ctx(ctx)
 
 	products, err := q.ProductRepo.GetAllProducts(ctx)
@@ -29,10 +43,21 @@ func (q Query) GetAllProducts(ctx context.Context) ([]product.Product, error) {
 	return products, nil
 }
 
+// GetActiveProducts returns the active products of the open period, including products without a period.
+// If no period is open, active products of all periods are returned.
 func (q Query) GetActiveProducts(ctx context.Context) ([]product.Product, error) {
+	ctx, span := tracing.Start(ctx, "product.GetActiveProducts")
+	defer span.End()
+
 	log := zerolog.Ctx(ctx)
 
-	products, err := q.ProductRepo.GetActiveProducts(ctx)
+	periodID, err := q.PeriodRepo.GetOpenPeriodID(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get open period")
+		return nil, ErrDatabase
+	}
+
+	products, err := q.ProductRepo.GetActiveProducts(ctx, periodID)
 	if err != nil {
 		log.Error().Msg("Failed to retrieve active products")
 		return nil, ErrDatabase
@@ -41,3 +66,36 @@ func (q Query) GetActiveProducts(ctx context.Context) ([]product.Product, error)
 	log.Info().Int("count", len(products)).Msg("Retrieved active products")
 	return products, nil
 }
+
+// GetProductAt returns the product with the name and price it had at the given time, according to its price history.
+func (q Query) GetProductAt(ctx context.Context, productID int, at time.Time) (product.Product, error) {
+	ctx, span := tracing.Start(ctx, "product.GetProductAt")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	events, err := q.EventRepo.ReadEventsBySubject(ctx, audit.Subject(audit.ProductEntity, strconv.Itoa(productID)))
+	if err != nil {
+		log.Error().Err(err).Int("product_id", productID).Msg("Failed to read product events")
+		return product.Product{}, ErrDatabase
+	}
+	if len(events) == 0 {
+		log.Warn().Int("product_id", productID).Msg("Product not found for history")
+		return product.Product{}, ErrProductNotFound
+	}
+
+	histories, err := product.NewHistories(events)
+	if err != nil {
+		log.Error().Err(err).Int("product_id", productID).Msg("Failed to build product history")
+		return product.Product{}, err
+	}
+
+	p, ok := histories[productID].At(at)
+	if !ok {
+		log.Warn().Int("product_id", productID).Time("at", at).Msg("No product history at requested time")
+		return product.Product{}, ErrNoProductHistory
+	}
+
+	log.Info().Int("product_id", productID).Time("at", at).Msg("Retrieved product at time")
+	return p, nil
+}
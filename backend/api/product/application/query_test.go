@@ -0,0 +1,66 @@
+//go:build unit
+
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/product"
+	"github.com/nicograef/jotti/backend/repository/event_repo"
+	"github.com/nicograef/jotti/backend/repository/period_repo"
+	"github.com/nicograef/jotti/backend/repository/product_repo"
+)
+
+func TestGetProductAt(t *testing.T) {
+	ctx := context.Background()
+	eventRepo := event_repo.NewMock(nil, nil)
+	productRepo := product_repo.NewMock([]product.Product{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), ProductRepo: productRepo, PeriodRepo: period_repo.NewMock(nil, nil), EventRepo: eventRepo}
+	query := Query{EventRepo: eventRepo}
+
+	id, err := command.CreateProduct(ctx, 1, "Weizen", "", 400, product.BeverageCategory)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	createdAt := time.Now().UTC()
+	time.Sleep(time.Millisecond)
+	if err := command.UpdateProduct(ctx, 1, id, "Weizen", "", 450, product.BeverageCategory); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	p, err := query.GetProductAt(ctx, id, createdAt)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if p.NetPriceCents != 400 {
+		t.Errorf("expected price 400 before the update, got %d", p.NetPriceCents)
+	}
+
+	p, err = query.GetProductAt(ctx, id, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if p.NetPriceCents != 450 {
+		t.Errorf("expected price 450 after the update, got %d", p.NetPriceCents)
+	}
+
+	if _, err := query.GetProductAt(ctx, id, createdAt.AddDate(0, 0, -1)); err != ErrNoProductHistory {
+		t.Errorf("expected no product history error, got %v", err)
+	}
+	if _, err := query.GetProductAt(ctx, 99, createdAt); err != ErrProductNotFound {
+		t.Errorf("expected product not found error, got %v", err)
+	}
+}
+
+func TestGetProductAt_Error(t *testing.T) {
+	query := Query{EventRepo: event_repo.NewMock([]event.Event{}, ErrDatabase)}
+
+	_, err := query.GetProductAt(context.Background(), 1, time.Now())
+	if err != ErrDatabase {
+		t.Fatalf("expected database error, got %v", err)
+	}
+}
@@ -6,15 +6,16 @@ import (
 	"net/http"
 
 	"github.com/nicograef/jotti/backend/api/helper"
+	"github.com/nicograef/jotti/backend/api/middleware"
 	"github.com/nicograef/jotti/backend/api/product/application"
 	"github.com/nicograef/jotti/backend/domain/product"
 )
 
 type command interface {
-	CreateProduct(ctx context.Context, name, description string, netPriceCents int, category product.Category) (int, error)
-	UpdateProduct(ctx context.Context, id int, name, description string, netPriceCents int, category product.Category) error
-	ActivateProduct(ctx context.Context, id int) error
-	DeactivateProduct(ctx context.Context, id int) error
+	CreateProduct(ctx context.Context, actorID int, name, description string, netPriceCents int, category product.Category) (int, error)
+	UpdateProduct(ctx context.Context, actorID, id int, name, description string, netPriceCents int, category product.Category) error
+	ActivateProduct(ctx context.Context, actorID, id int) error
+	DeactivateProduct(ctx context.Context, actorID, id int) error
 }
 
 type CommandHandler struct {
@@ -39,13 +40,17 @@ func (h *CommandHandler) CreateProductHandler() http.HandlerFunc {
 			return
 		}
 
-		id, err := h.Command.CreateProduct(r.Context(), body.Name, body.Description, body.NetPriceCents, body.Category)
+		actorID := r.Context().Value(middleware.UserIDKey).(int)
+		id, err := h.Command.CreateProduct(r.Context(), actorID, body.Name, body.Description, body.NetPriceCents, body.Category)
 		if err != nil {
 			if errors.Is(err, application.ErrProductAlreadyExists) {
 				helper.SendClientError(w, "product_already_exists", nil)
 				return
 			} else if errors.Is(err, application.ErrInvalidProductData) {
-				helper.SendClientError(w, "invalid_product_data", nil)
+				helper.SendValidationError(w, "invalid_product_data", err)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
 				return
 			} else {
 				helper.SendServerError(w)
@@ -72,13 +77,17 @@ func (h *CommandHandler) UpdateProductHandler() http.HandlerFunc {
 			return
 		}
 
-		err := h.Command.UpdateProduct(r.Context(), body.ID, body.Name, body.Description, body.NetPriceCents, body.Category)
+		actorID := r.Context().Value(middleware.UserIDKey).(int)
+		err := h.Command.UpdateProduct(r.Context(), actorID, body.ID, body.Name, body.Description, body.NetPriceCents, body.Category)
 		if err != nil {
 			if errors.Is(err, application.ErrProductNotFound) {
 				helper.SendClientError(w, "product_not_found", nil)
 				return
 			} else if errors.Is(err, application.ErrInvalidProductData) {
-				helper.SendClientError(w, "invalid_product_data", nil)
+				helper.SendValidationError(w, "invalid_product_data", err)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
 				return
 			} else {
 				helper.SendServerError(w)
@@ -101,11 +110,15 @@ func (h *CommandHandler) ActivateProductHandler() http.HandlerFunc {
 			return
 		}
 
-		err := h.Command.ActivateProduct(r.Context(), body.ID)
+		actorID := r.Context().Value(middleware.UserIDKey).(int)
+		err := h.Command.ActivateProduct(r.Context(), actorID, body.ID)
 		if err != nil {
 			if errors.Is(err, application.ErrProductNotFound) {
 				helper.SendClientError(w, "product_not_found", nil)
 				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
 			} else {
 				helper.SendServerError(w)
 				return
@@ -127,11 +140,15 @@ func (h *CommandHandler) DeactivateProductHandler() http.HandlerFunc {
 			return
 		}
 
-		err := h.Command.DeactivateProduct(r.Context(), body.ID)
+		actorID := r.Context().Value(middleware.UserIDKey).(int)
+		err := h.Command.DeactivateProduct(r.Context(), actorID, body.ID)
 		if err != nil {
 			if errors.Is(err, application.ErrProductNotFound) {
 				helper.SendClientError(w, "product_not_found", nil)
 				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
 			} else {
 				helper.SendServerError(w)
 				return
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/nicograef/jotti/backend/api/middleware"
 	"github.com/nicograef/jotti/backend/api/product/application"
 	"github.com/nicograef/jotti/backend/domain/product"
 )
@@ -17,19 +18,19 @@ type mockCommand struct {
 	err error
 }
 
-func (m *mockCommand) CreateProduct(ctx context.Context, name, description string, netPriceCents int, category product.Category) (int, error) {
+func (m *mockCommand) CreateProduct(ctx context.Context, actorID int, name, description string, netPriceCents int, category product.Category) (int, error) {
 	return 1, m.err
 }
 
-func (m *mockCommand) UpdateProduct(ctx context.Context, id int, name, description string, netPriceCents int, category product.Category) error {
+func (m *mockCommand) UpdateProduct(ctx context.Context, actorID, id int, name, description string, netPriceCents int, category product.Category) error {
 	return m.err
 }
 
-func (m *mockCommand) ActivateProduct(ctx context.Context, id int) error {
+func (m *mockCommand) ActivateProduct(ctx context.Context, actorID, id int) error {
 	return m.err
 }
 
-func (m *mockCommand) DeactivateProduct(ctx context.Context, id int) error {
+func (m *mockCommand) DeactivateProduct(ctx context.Context, actorID, id int) error {
 	return m.err
 }
 
@@ -39,6 +40,7 @@ func TestCreateProductHandler_Success(t *testing.T) {
 	body := `{"name":"French Fries","description":"The most delicious fries.","netPriceCents":1999,"category":"food"}`
 	req := httptest.NewRequest(http.MethodPost, "/admin/create-product", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
 	rec := httptest.NewRecorder()
 
 	handler.CreateProductHandler().ServeHTTP(rec, req)
@@ -54,6 +56,7 @@ func TestCreateProductHandler_Failure(t *testing.T) {
 	body := `{"name":"French Fries","description":"The most delicious fries.","netPriceCents":1999,"category":"food"}`
 	req := httptest.NewRequest(http.MethodPost, "/admin/create-product", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
 	rec := httptest.NewRecorder()
 
 	handler.CreateProductHandler().ServeHTTP(rec, req)
@@ -69,6 +72,7 @@ func TestUpdateProductHandler_Success(t *testing.T) {
 	body := `{"id":1,"name":"French Fries","description":"The most delicious fries.","netPriceCents":1999,"category":"food"}`
 	req := httptest.NewRequest(http.MethodPost, "/admin/update-product", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
 	rec := httptest.NewRecorder()
 
 	handler.UpdateProductHandler().ServeHTTP(rec, req)
@@ -84,6 +88,7 @@ func TestUpdateProductHandler_Failure(t *testing.T) {
 	body := `{"id":1,"name":"French Fries","description":"The most delicious fries.","netPriceCents":1999,"category":"food"}`
 	req := httptest.NewRequest(http.MethodPost, "/admin/update-product", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
 	rec := httptest.NewRecorder()
 
 	handler.UpdateProductHandler().ServeHTTP(rec, req)
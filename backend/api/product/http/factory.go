@@ -4,17 +4,26 @@ import (
 	"database/sql"
 
 	"github.com/nicograef/jotti/backend/api/product/application"
+	dbpkg "github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/repository/event_repo"
+	"github.com/nicograef/jotti/backend/repository/period_repo"
 	"github.com/nicograef/jotti/backend/repository/product_repo"
 )
 
 func NewCommandHandler(db *sql.DB) CommandHandler {
 	repo := product_repo.Repository{DB: db}
-	command := application.Command{ProductRepo: repo}
+	periodRepo := period_repo.Repository{DB: db}
+	eventRepo := event_repo.Repository{DB: db}
+	// lost updates of concurrent changes fail and are retried
+	transactor := dbpkg.Transactor{DB: db, Isolation: sql.LevelRepeatableRead}
+	command := application.Command{Transactor: transactor, ProductRepo: repo, PeriodRepo: periodRepo, EventRepo: eventRepo}
 	return CommandHandler{Command: command}
 }
 
 func NewQueryHandler(db *sql.DB) QueryHandler {
 	repo := product_repo.Repository{DB: db}
-	query := application.Query{ProductRepo: repo}
+	periodRepo := period_repo.Repository{DB: db}
+	eventRepo := event_repo.Repository{DB: db}
+	query := application.Query{ProductRepo: repo, PeriodRepo: periodRepo, EventRepo: eventRepo}
 	return QueryHandler{Query: query}
 }
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"github.com/nicograef/jotti/backend/api/openapi"
+	"github.com/nicograef/jotti/backend/domain/product"
+)
+
+// Operations of the handlers in the OpenAPI document.
+
+var CreateProductOperation = openapi.Operation{
+	Summary:  "Create a product",
+	Request:  createProduct{},
+	Response: createProductResponse{},
+	Errors:   []string{"invalid_product_data", "product_already_exists", "retry_later"},
+}
+
+var UpdateProductOperation = openapi.Operation{
+	Summary: "Change the name, description, price or category of a product",
+	Request: updateProduct{},
+	Errors:  []string{"invalid_product_data", "product_not_found", "retry_later"},
+}
+
+var ActivateProductOperation = openapi.Operation{
+	Summary: "Activate a product so it can be ordered",
+	Request: activateProduct{},
+	Errors:  []string{"product_not_found", "retry_later"},
+}
+
+var DeactivateProductOperation = openapi.Operation{
+	Summary: "Deactivate a product so it can't be ordered",
+	Request: deactivateTable{},
+	Errors:  []string{"product_not_found", "retry_later"},
+}
+
+var GetAllProductsOperation = openapi.Operation{
+	Summary:  "List all products",
+	Response: getAllProductsResponse{},
+}
+
+var GetActiveProductsOperation = openapi.Operation{
+	Summary:  "List the products that can be ordered",
+	Response: getActiveProductsResponse{},
+}
+
+var GetProductAtOperation = openapi.Operation{
+	Summary:  "Get a product with the name and price it had at the given time",
+	Request:  getProductAt{},
+	Response: product.Product{},
+	Errors:   []string{"product_history_not_found", "product_not_found"},
+}
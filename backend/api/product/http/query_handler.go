@@ -2,15 +2,19 @@ package http
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/nicograef/jotti/backend/api/helper"
+	"github.com/nicograef/jotti/backend/api/product/application"
 	"github.com/nicograef/jotti/backend/domain/product"
 )
 
 type query interface {
 	GetAllProducts(ctx context.Context) ([]product.Product, error)
 	GetActiveProducts(ctx context.Context) ([]product.Product, error)
+	GetProductAt(ctx context.Context, productID int, at time.Time) (product.Product, error)
 }
 
 type QueryHandler struct {
@@ -69,3 +73,34 @@ func (h *QueryHandler) GetActiveProductsHandler() http.HandlerFunc {
 		helper.SendResponse(w, getActiveProductsResponse{Products: convertedProducts})
 	}
 }
+
+type getProductAt struct {
+	ID int       `json:"id"`
+	At time.Time `json:"at"` // RFC 3339
+}
+
+// GetProductAtHandler returns a product with the name and price it had at the given time.
+func (h *QueryHandler) GetProductAtHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := getProductAt{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		p, err := h.Query.GetProductAt(r.Context(), body.ID, body.At)
+		if err != nil {
+			if errors.Is(err, application.ErrProductNotFound) {
+				helper.SendClientError(w, "product_not_found", nil)
+				return
+			} else if errors.Is(err, application.ErrNoProductHistory) {
+				helper.SendClientError(w, "product_history_not_found", nil)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendResponse(w, p)
+	}
+}
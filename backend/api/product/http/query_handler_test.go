@@ -6,7 +6,9 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/nicograef/jotti/backend/api/product/application"
 	"github.com/nicograef/jotti/backend/domain/product"
@@ -24,6 +26,10 @@ func (m *mockQuery) GetActiveProducts(ctx context.Context) ([]product.Product, e
 	return []product.Product{{ID: 1, Name: "French Fries", Description: "The most delicious fries.", NetPriceCents: 1999, Status: product.ActiveStatus, Category: product.FoodCategory}}, m.err
 }
 
+func (m *mockQuery) GetProductAt(ctx context.Context, productID int, at time.Time) (product.Product, error) {
+	return product.Product{ID: productID, Name: "Weizen", NetPriceCents: 400}, m.err
+}
+
 func TestGetAllProductsHandler_Success(t *testing.T) {
 	handler := &QueryHandler{Query: &mockQuery{}}
 
@@ -50,3 +56,34 @@ func TestGetAllProductsHandler_Failure(t *testing.T) {
 		t.Errorf("expected status 500, got %d", rec.Code)
 	}
 }
+
+func TestGetProductAtHandler_Success(t *testing.T) {
+	handler := &QueryHandler{Query: &mockQuery{}}
+
+	body := `{"id":1,"at":"2025-07-12T18:00:00Z"}`
+	req := httptest.NewRequest(http.MethodPost, "/get-product-at", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.GetProductAtHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"netPriceCents":400`) {
+		t.Errorf("expected price in response, got %s", rec.Body.String())
+	}
+}
+
+func TestGetProductAtHandler_NoHistory(t *testing.T) {
+	handler := &QueryHandler{Query: &mockQuery{err: application.ErrNoProductHistory}}
+
+	body := `{"id":1,"at":"2020-01-01T00:00:00Z"}`
+	req := httptest.NewRequest(http.MethodPost, "/get-product-at", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.GetProductAtHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,17 @@
+package application
+
+import (
+	"errors"
+)
+
+// ErrDatabase is returned when there is a database error.
+var ErrDatabase = errors.New("database error")
+
+// ErrInvalidDate is returned when the requested report date is invalid.
+var ErrInvalidDate = errors.New("invalid date")
+
+// ErrPeriodNotFound is returned when the requested period does not exist.
+var ErrPeriodNotFound = errors.New("period not found")
+
+// ErrNoOpenPeriod is returned when the report of the current period is requested but no period is open.
+var ErrNoOpenPeriod = errors.New("no open period")
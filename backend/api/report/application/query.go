@@ -0,0 +1,152 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/audit"
+	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/period"
+	"github.com/nicograef/jotti/backend/domain/product"
+	"github.com/nicograef/jotti/backend/domain/report"
+	"github.com/nicograef/jotti/backend/tracing"
+	"github.com/rs/zerolog"
+)
+
+type eventRepoQuery interface {
+	ReadEventsInTimeRange(ctx context.Context, from, to time.Time) ([]event.Event, error)
+	ReadEventsByPeriod(ctx context.Context, periodID int) ([]event.Event, error)
+	ReadEvents(ctx context.Context, f event.Filter) ([]event.Event, error)
+}
+
+type periodRepoQuery interface {
+	GetPeriod(ctx context.Context, id int) (period.Period, error)
+	GetOpenPeriod(ctx context.Context) (period.Period, error)
+}
+
+type Query struct {
+	EventRepo  eventRepoQuery
+	PeriodRepo periodRepoQuery
+	// Location defines where a business day starts and ends.
+	Location *time.Location
+}
+
+// GetDailyReport builds the report of the given day (YYYY-MM-DD).
+func (q Query) GetDailyReport(ctx context.Context, date string) (report.DailyReport, error) {
+	ctx, span := tracing.Start(ctx, "report.GetDailyReport")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	day, err := time.ParseInLocation(time.DateOnly, date, q.location())
+	if err != nil {
+		log.Warn().Err(err).Str("date", date).Msg("Invalid report date")
+		return report.DailyReport{}, ErrInvalidDate
+	}
+
+	events, err := q.EventRepo.ReadEventsInTimeRange(ctx, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		log.Error().Err(err).Str("date", date).Msg("Failed to read events for daily report")
+		return report.DailyReport{}, ErrDatabase
+	}
+
+	products, err := q.readProductHistories(ctx, day.AddDate(0, 0, 1))
+	if err != nil {
+		return report.DailyReport{}, err
+	}
+
+	dailyReport, err := report.NewDailyReport(day, events, products)
+	if err != nil {
+		log.Error().Err(err).Str("date", date).Msg("Failed to build daily report from events")
+		return report.DailyReport{}, err
+	}
+
+	log.Info().Str("date", date).Int("events", len(events)).Msg("Retrieved daily report")
+	return dailyReport, nil
+}
+
+// GetPeriodReport builds the report of the given period (Veranstaltung). A periodID of 0 means the open period.
+func (q Query) GetPeriodReport(ctx context.Context, periodID int) (report.PeriodReport, error) {
+	ctx, span := tracing.Start(ctx, "report.GetPeriodReport")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	var p period.Period
+	var err error
+	if periodID == 0 {
+		p, err = q.PeriodRepo.GetOpenPeriod(ctx)
+	} else {
+		p, err = q.PeriodRepo.GetPeriod(ctx, periodID)
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) && periodID == 0 {
+			log.Warn().Msg("No open period for report")
+			return report.PeriodReport{}, ErrNoOpenPeriod
+		} else if errors.Is(err, db.ErrNotFound) {
+			log.Warn().Int("period_id", periodID).Msg("Period not found")
+			return report.PeriodReport{}, ErrPeriodNotFound
+		} else {
+			log.Error().Err(err).Int("period_id", periodID).Msg("Failed to retrieve period for report")
+			return report.PeriodReport{}, ErrDatabase
+		}
+	}
+
+	events, err := q.EventRepo.ReadEventsByPeriod(ctx, p.ID)
+	if err != nil {
+		log.Error().Err(err).Int("period_id", p.ID).Msg("Failed to read events for period report")
+		return report.PeriodReport{}, ErrDatabase
+	}
+
+	to := time.Time{}
+	if p.ClosedAt != nil {
+		to = *p.ClosedAt
+	}
+	products, err := q.readProductHistories(ctx, to)
+	if err != nil {
+		return report.PeriodReport{}, err
+	}
+
+	periodReport, err := report.NewPeriodReport(p, events, products)
+	if err != nil {
+		log.Error().Err(err).Int("period_id", p.ID).Msg("Failed to build period report from events")
+		return report.PeriodReport{}, err
+	}
+
+	log.Info().Int("period_id", p.ID).Int("events", len(events)).Msg("Retrieved period report")
+	return periodReport, nil
+}
+
+// readProductHistories returns the histories of all products up to the given time (zero for now),
+// so reports show the names and prices products had when they were ordered.
+func (q Query) readProductHistories(ctx context.Context, to time.Time) (map[int]product.History, error) {
+	log := zerolog.Ctx(ctx)
+
+	types := []string{}
+	for _, t := range audit.EventTypesOf(audit.ProductEntity) {
+		types = append(types, string(t))
+	}
+
+	events, err := q.EventRepo.ReadEvents(ctx, event.Filter{Types: types, To: to})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to read product events for report")
+		return nil, ErrDatabase
+	}
+
+	histories, err := product.NewHistories(events)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to build product histories for report")
+		return nil, err
+	}
+
+	return histories, nil
+}
+
+func (q Query) location() *time.Location {
+	if q.Location == nil {
+		return time.UTC
+	}
+	return q.Location
+}
@@ -0,0 +1,93 @@
+//go:build unit
+
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/period"
+	"github.com/nicograef/jotti/backend/domain/table"
+	"github.com/nicograef/jotti/backend/repository/event_repo"
+	"github.com/nicograef/jotti/backend/repository/period_repo"
+)
+
+func TestGetDailyReport_UsesBusinessDayOfLocation(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("expected no error loading location, got %v", err)
+	}
+
+	inside, err := table.NewItemsWrittenOffEvent(1, 1, table.StaffWriteOff, "", []table.WriteOffProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}}, 0)
+	if err != nil {
+		t.Fatalf("expected no error creating event, got %v", err)
+	}
+	inside.ID = 1
+	inside.Time = time.Date(2025, 6, 13, 22, 30, 0, 0, time.UTC) // 00:30 in Berlin
+	outside, err := table.NewItemsWrittenOffEvent(1, 1, table.StaffWriteOff, "", []table.WriteOffProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}}, 0)
+	if err != nil {
+		t.Fatalf("expected no error creating event, got %v", err)
+	}
+	outside.ID = 2
+	outside.Time = time.Date(2025, 6, 13, 21, 30, 0, 0, time.UTC) // 23:30 the day before in Berlin
+
+	query := Query{EventRepo: event_repo.NewMock([]event.Event{inside, outside}, nil), Location: location}
+
+	report, err := query.GetDailyReport(context.Background(), "2025-06-14")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if report.WriteOffs.TotalCents != 400 || len(report.WriteOffs.WriteOffs) != 1 {
+		t.Errorf("expected one write-off of 400, got %+v", report.WriteOffs)
+	}
+}
+
+func TestGetDailyReport_InvalidDate(t *testing.T) {
+	query := Query{EventRepo: event_repo.NewMock([]event.Event{}, nil)}
+
+	_, err := query.GetDailyReport(context.Background(), "14.06.2025")
+	if err != ErrInvalidDate {
+		t.Fatalf("expected ErrInvalidDate, got %v", err)
+	}
+}
+
+func TestGetPeriodReport_DefaultsToOpenPeriod(t *testing.T) {
+	closed, open := 1, 2
+	order, err := table.NewOrderPlacedEvent(1, 1, "", []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}})
+	if err != nil {
+		t.Fatalf("expected no error creating event, got %v", err)
+	}
+	order.ID = 1
+	order.PeriodID = &closed
+	otherOrder := order
+	otherOrder.ID = 2
+	otherOrder.PeriodID = &open
+
+	periodRepo := period_repo.NewMock([]period.Period{
+		{ID: 1, Name: "Sommerfest", Status: period.ClosedStatus},
+		{ID: 2, Name: "Weihnachtsmarkt", Status: period.OpenStatus},
+	}, nil)
+	query := Query{EventRepo: event_repo.NewMock([]event.Event{order, otherOrder}, nil), PeriodRepo: periodRepo}
+
+	report, err := query.GetPeriodReport(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if report.Period.ID != 2 || report.OrderedCents != 400 {
+		t.Errorf("expected report of open period 2 with 400 ordered, got %+v", report)
+	}
+
+	if _, err := query.GetPeriodReport(context.Background(), 7); err != ErrPeriodNotFound {
+		t.Fatalf("expected ErrPeriodNotFound, got %v", err)
+	}
+}
+
+func TestGetPeriodReport_NoOpenPeriod(t *testing.T) {
+	query := Query{EventRepo: event_repo.NewMock([]event.Event{}, nil), PeriodRepo: period_repo.NewMock([]period.Period{}, nil)}
+
+	if _, err := query.GetPeriodReport(context.Background(), 0); err != ErrNoOpenPeriod {
+		t.Fatalf("expected ErrNoOpenPeriod, got %v", err)
+	}
+}
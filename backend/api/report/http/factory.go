@@ -0,0 +1,17 @@
+package http
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/nicograef/jotti/backend/api/report/application"
+	"github.com/nicograef/jotti/backend/repository/event_repo"
+	"github.com/nicograef/jotti/backend/repository/period_repo"
+)
+
+func NewQueryHandler(db *sql.DB, location *time.Location) QueryHandler {
+	eventRepo := event_repo.Repository{DB: db}
+	periodRepo := period_repo.Repository{DB: db}
+	query := application.Query{EventRepo: eventRepo, PeriodRepo: periodRepo, Location: location}
+	return QueryHandler{Query: query}
+}
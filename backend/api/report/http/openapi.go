@@ -0,0 +1,22 @@
+package http
+
+import (
+	"github.com/nicograef/jotti/backend/api/openapi"
+	"github.com/nicograef/jotti/backend/domain/report"
+)
+
+// Operations of the handlers in the OpenAPI document.
+
+var GetDailyReportOperation = openapi.Operation{
+	Summary:  "Get the sales, payments and write-offs of one day",
+	Request:  getDailyReport{},
+	Response: report.DailyReport{},
+	Errors:   []string{"invalid_date"},
+}
+
+var GetPeriodReportOperation = openapi.Operation{
+	Summary:  "Get the sales, payments and write-offs of one period",
+	Request:  getPeriodReport{},
+	Response: report.PeriodReport{},
+	Errors:   []string{"no_open_period", "period_not_found"},
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/nicograef/jotti/backend/api/helper"
+	"github.com/nicograef/jotti/backend/api/report/application"
+	"github.com/nicograef/jotti/backend/domain/report"
+)
+
+type query interface {
+	GetDailyReport(ctx context.Context, date string) (report.DailyReport, error)
+	GetPeriodReport(ctx context.Context, periodID int) (report.PeriodReport, error)
+}
+
+type QueryHandler struct {
+	Query query
+}
+
+type getDailyReport struct {
+	Date string `json:"date"` // YYYY-MM-DD
+}
+
+// GetDailyReportHandler returns sales, payments and write-offs of one day. Only available to admins.
+func (h *QueryHandler) GetDailyReportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := getDailyReport{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		dailyReport, err := h.Query.GetDailyReport(r.Context(), body.Date)
+		if err != nil {
+			if errors.Is(err, application.ErrInvalidDate) {
+				helper.SendClientError(w, "invalid_date", nil)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendResponse(w, dailyReport)
+	}
+}
+
+type getPeriodReport struct {
+	PeriodID int `json:"periodId"` // 0 or omitted for the open period
+}
+
+// GetPeriodReportHandler returns sales, payments and write-offs of one period (Veranstaltung). Only available to admins.
+func (h *QueryHandler) GetPeriodReportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := getPeriodReport{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		periodReport, err := h.Query.GetPeriodReport(r.Context(), body.PeriodID)
+		if err != nil {
+			if errors.Is(err, application.ErrPeriodNotFound) {
+				helper.SendClientError(w, "period_not_found", nil)
+				return
+			} else if errors.Is(err, application.ErrNoOpenPeriod) {
+				helper.SendClientError(w, "no_open_period", nil)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendResponse(w, periodReport)
+	}
+}
@@ -0,0 +1,73 @@
+//go:build unit
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nicograef/jotti/backend/api/report/application"
+	"github.com/nicograef/jotti/backend/domain/report"
+)
+
+type mockQuery struct {
+	err error
+}
+
+func (m *mockQuery) GetDailyReport(ctx context.Context, date string) (report.DailyReport, error) {
+	return report.DailyReport{Date: date}, m.err
+}
+
+func (m *mockQuery) GetPeriodReport(ctx context.Context, periodID int) (report.PeriodReport, error) {
+	return report.PeriodReport{}, m.err
+}
+
+func TestGetDailyReportHandler_Success(t *testing.T) {
+	handler := &QueryHandler{Query: &mockQuery{}}
+
+	body := `{"date":"2025-06-14"}`
+	req := httptest.NewRequest(http.MethodPost, "/get-daily-report", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.GetDailyReportHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"writeOffs"`) {
+		t.Errorf("expected write-off section in response, got %s", rec.Body.String())
+	}
+}
+
+func TestGetDailyReportHandler_InvalidDate(t *testing.T) {
+	handler := &QueryHandler{Query: &mockQuery{err: application.ErrInvalidDate}}
+
+	body := `{"date":"14.06.2025"}`
+	req := httptest.NewRequest(http.MethodPost, "/get-daily-report", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.GetDailyReportHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestGetPeriodReportHandler_NoOpenPeriod(t *testing.T) {
+	handler := &QueryHandler{Query: &mockQuery{err: application.ErrNoOpenPeriod}}
+
+	req := httptest.NewRequest(http.MethodPost, "/get-period-report", strings.NewReader(`{}`))
+	rec := httptest.NewRecorder()
+
+	handler.GetPeriodReportHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "no_open_period") {
+		t.Errorf("expected no_open_period error, got %s", rec.Body.String())
+	}
+}
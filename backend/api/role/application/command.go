@@ -0,0 +1,175 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nicograef/jotti/backend/api/command"
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/audit"
+	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/role"
+	"github.com/nicograef/jotti/backend/tracing"
+	"github.com/rs/zerolog"
+)
+
+type roleRepoCommand interface {
+	GetRole(ctx context.Context, name string) (role.Role, error)
+	CreateRole(ctx context.Context, r role.Role) error
+	UpdateRole(ctx context.Context, r role.Role) error
+	DeleteRole(ctx context.Context, name string) error
+	IsRoleInUse(ctx context.Context, name string) (bool, error)
+}
+
+type eventRepoCommand interface {
+	WriteEvent(ctx context.Context, e event.Event) (int, error)
+}
+
+type Command struct {
+	Transactor db.TxRunner
+	RoleRepo   roleRepoCommand
+	EventRepo  eventRepoCommand
+}
+
+func (c Command) CreateRole(ctx context.Context, actorID int, name string, permissions []role.Permission) error {
+	ctx, span := tracing.Start(ctx, "role.CreateRole")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	r, err := role.NewRole(name, permissions)
+	if err != nil {
+		log.Warn().Err(err).Str("role", name).Msg("Invalid role data")
+		return fmt.Errorf("%w: %w", ErrInvalidRoleData, err)
+	}
+
+	// the role and its audit event are written together
+	err = command.InTransaction(ctx, c.Transactor, audit.RoleEntity, name, func(ctx context.Context) error {
+		err := c.RoleRepo.CreateRole(ctx, r)
+		if err != nil {
+			if errors.Is(err, db.ErrAlreadyExists) {
+				log.Warn().Str("role", r.Name).Msg("Role already exists")
+				return ErrRoleAlreadyExists
+			} else {
+				log.Error().Err(err).Str("role", r.Name).Msg("Failed to create role")
+				return fmt.Errorf("%w: %w", ErrDatabase, err)
+			}
+		}
+
+		return command.WriteAuditEvent(ctx, c.EventRepo, actorID, audit.EventTypeRoleCreatedV1, audit.RoleEntity, r.Name, nil, r)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("role", r.Name).Msg("Role created successfully")
+	return nil
+}
+
+// UpdateRole replaces the permissions of a role. Users get the new permissions with their next access token.
+func (c Command) UpdateRole(ctx context.Context, actorID int, name string, permissions []role.Permission) error {
+	ctx, span := tracing.Start(ctx, "role.UpdateRole")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	err := command.InTransaction(ctx, c.Transactor, audit.RoleEntity, name, func(ctx context.Context) error {
+		r, err := c.getRole(ctx, name)
+		if err != nil {
+			return err
+		}
+
+		before := r
+		err = r.SetPermissions(permissions)
+		if err != nil {
+			if errors.Is(err, role.ErrProtected) {
+				log.Warn().Str("role", name).Msg("Attempt to change protected role")
+				return ErrRoleProtected
+			} else {
+				log.Warn().Err(err).Str("role", name).Msg("Invalid role data for update")
+				return fmt.Errorf("%w: %w", ErrInvalidRoleData, err)
+			}
+		}
+
+		if err := c.RoleRepo.UpdateRole(ctx, r); err != nil {
+			log.Error().Err(err).Str("role", name).Msg("Failed to update role")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
+
+		return command.WriteAuditEvent(ctx, c.EventRepo, actorID, audit.EventTypeRoleUpdatedV1, audit.RoleEntity, name, before, r)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("role", name).Msg("Role updated successfully")
+	return nil
+}
+
+// DeleteRole deletes a role that is not assigned to any user.
+func (c Command) DeleteRole(ctx context.Context, actorID int, name string) error {
+	ctx, span := tracing.Start(ctx, "role.DeleteRole")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	err := command.InTransaction(ctx, c.Transactor, audit.RoleEntity, name, func(ctx context.Context) error {
+		r, err := c.getRole(ctx, name)
+		if err != nil {
+			return err
+		}
+
+		if r.IsProtected() || r.Name == role.ServiceName {
+			log.Warn().Str("role", name).Msg("Attempt to delete built-in role")
+			return ErrRoleProtected
+		}
+
+		inUse, err := c.RoleRepo.IsRoleInUse(ctx, name)
+		if err != nil {
+			log.Error().Err(err).Str("role", name).Msg("Failed to check if role is in use")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
+		if inUse {
+			log.Warn().Str("role", name).Msg("Attempt to delete role that is assigned to users")
+			return ErrRoleInUse
+		}
+
+		err = c.RoleRepo.DeleteRole(ctx, name)
+		if err != nil {
+			if errors.Is(err, db.ErrForeignKeyViolation) {
+				// the role was assigned to a user after it was checked
+				log.Warn().Err(err).Str("constraint", db.Constraint(err)).Str("role", name).Msg("Attempt to delete role that is assigned to users")
+				return ErrRoleInUse
+			} else {
+				log.Error().Err(err).Str("role", name).Msg("Failed to delete role")
+				return fmt.Errorf("%w: %w", ErrDatabase, err)
+			}
+		}
+
+		return command.WriteAuditEvent(ctx, c.EventRepo, actorID, audit.EventTypeRoleDeletedV1, audit.RoleEntity, name, r, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("role", name).Msg("Role deleted successfully")
+	return nil
+}
+
+func (c Command) getRole(ctx context.Context, name string) (role.Role, error) {
+	log := zerolog.Ctx(ctx)
+
+	r, err := c.RoleRepo.GetRole(ctx, name)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			log.Warn().Str("role", name).Msg("Role not found")
+			return role.Role{}, ErrRoleNotFound
+		} else {
+			log.Error().Err(err).Str("role", name).Msg("Failed to retrieve role")
+			return role.Role{}, fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
+	}
+
+	return r, nil
+}
@@ -0,0 +1,73 @@
+//go:build unit
+
+package application
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/role"
+	"github.com/nicograef/jotti/backend/repository/event_repo"
+	"github.com/nicograef/jotti/backend/repository/role_repo"
+)
+
+func TestCreateRole(t *testing.T) {
+	repo := role_repo.NewMock([]role.Role{}, nil, nil)
+	command := Command{Transactor: db.NewMockTransactor(), RoleRepo: repo, EventRepo: event_repo.NewMock(nil, nil)}
+
+	err := command.CreateRole(context.Background(), 1, "schichtleitung", []role.Permission{role.ViewTables, role.WriteOffItems})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	err = command.CreateRole(context.Background(), 1, "schichtleitung", nil)
+	if err != ErrRoleAlreadyExists {
+		t.Fatalf("expected role already exists error, got %v", err)
+	}
+
+	err = command.CreateRole(context.Background(), 1, "kueche", []role.Permission{"orders.cook"})
+	if !errors.Is(err, ErrInvalidRoleData) {
+		t.Fatalf("expected invalid role data error, got %v", err)
+	}
+}
+
+func TestUpdateRole(t *testing.T) {
+	repo := role_repo.NewMock([]role.Role{{Name: role.AdminName}, {Name: "kueche"}}, nil, nil)
+	command := Command{Transactor: db.NewMockTransactor(), RoleRepo: repo, EventRepo: event_repo.NewMock(nil, nil)}
+
+	if err := command.UpdateRole(context.Background(), 1, "kueche", []role.Permission{role.ViewTables}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	got, _ := repo.GetRole(context.Background(), "kueche")
+	if !slices.Equal(got.Permissions, []role.Permission{role.ViewTables}) {
+		t.Errorf("expected updated permissions, got %v", got.Permissions)
+	}
+
+	if err := command.UpdateRole(context.Background(), 1, role.AdminName, nil); err != ErrRoleProtected {
+		t.Fatalf("expected role protected error, got %v", err)
+	}
+	if err := command.UpdateRole(context.Background(), 1, "bar", nil); err != ErrRoleNotFound {
+		t.Fatalf("expected role not found error, got %v", err)
+	}
+}
+
+func TestDeleteRole(t *testing.T) {
+	repo := role_repo.NewMock([]role.Role{{Name: role.AdminName}, {Name: "kueche"}, {Name: "bar"}}, []string{"bar"}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), RoleRepo: repo, EventRepo: event_repo.NewMock(nil, nil)}
+
+	if err := command.DeleteRole(context.Background(), 1, "bar"); err != ErrRoleInUse {
+		t.Fatalf("expected role in use error, got %v", err)
+	}
+	if err := command.DeleteRole(context.Background(), 1, role.AdminName); err != ErrRoleProtected {
+		t.Fatalf("expected role protected error, got %v", err)
+	}
+	if err := command.DeleteRole(context.Background(), 1, "kueche"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := repo.GetRole(context.Background(), "kueche"); err == nil {
+		t.Fatal("expected role to be deleted")
+	}
+}
@@ -0,0 +1,28 @@
+package application
+
+import (
+	"errors"
+
+	"github.com/nicograef/jotti/backend/api/command"
+)
+
+// ErrRoleNotFound is returned when a role is not found.
+var ErrRoleNotFound = errors.New("role not found")
+
+// ErrRoleAlreadyExists is returned when a role with the same name already exists.
+var ErrRoleAlreadyExists = errors.New("role already exists")
+
+// ErrRoleProtected is returned when the built-in admin role should be changed or deleted.
+var ErrRoleProtected = errors.New("role is protected")
+
+// ErrRoleInUse is returned when a role that is still assigned to users should be deleted.
+var ErrRoleInUse = errors.New("role in use")
+
+// ErrInvalidRoleData is returned when the provided role data is invalid.
+var ErrInvalidRoleData = errors.New("invalid role data")
+
+// ErrRetryLater is returned when a change conflicted with a concurrent change or the database was not reachable.
+var ErrRetryLater = command.ErrRetryLater
+
+// ErrDatabase is returned when there is a database error.
+var ErrDatabase = command.ErrDatabase
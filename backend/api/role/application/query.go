@@ -0,0 +1,37 @@
+package application
+
+import (
+	"context"
+
+	"github.com/nicograef/jotti/backend/domain/role"
+	"github.com/nicograef/jotti/backend/tracing"
+	"github.com/rs/zerolog"
+)
+
+type roleRepoQuery interface {
+	GetAllRoles(ctx context.Context) ([]role.Role, error)
+}
+
+type Query struct {
+	RoleRepo roleRepoQuery
+}
+
+// GetAllRoles returns all roles with their effective permissions, i.e. all permissions for the admin role.
+func (q Query) GetAllRoles(ctx context.Context) ([]role.Role, error) {
+	ctx, span := tracing.Start(ctx, "role.GetAllRoles")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	roles, err := q.RoleRepo.GetAllRoles(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to retrieve roles")
+		return nil, ErrDatabase
+	}
+
+	for i := range roles {
+		roles[i].Permissions = roles[i].EffectivePermissions()
+	}
+
+	return roles, nil
+}
@@ -0,0 +1,124 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/nicograef/jotti/backend/api/helper"
+	"github.com/nicograef/jotti/backend/api/middleware"
+	"github.com/nicograef/jotti/backend/api/role/application"
+	"github.com/nicograef/jotti/backend/domain/role"
+)
+
+type command interface {
+	CreateRole(ctx context.Context, actorID int, name string, permissions []role.Permission) error
+	UpdateRole(ctx context.Context, actorID int, name string, permissions []role.Permission) error
+	DeleteRole(ctx context.Context, actorID int, name string) error
+}
+
+type CommandHandler struct {
+	Command command
+}
+
+type roleBody struct {
+	Name        string            `json:"name"`
+	Permissions []role.Permission `json:"permissions"`
+}
+
+func (h *CommandHandler) CreateRoleHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := roleBody{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		actorID := r.Context().Value(middleware.UserIDKey).(int)
+		err := h.Command.CreateRole(r.Context(), actorID, body.Name, body.Permissions)
+		if err != nil {
+			if errors.Is(err, application.ErrInvalidRoleData) {
+				helper.SendValidationError(w, "invalid_role_data", err)
+				return
+			} else if errors.Is(err, application.ErrRoleAlreadyExists) {
+				helper.SendClientError(w, "role_already_exists", nil)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendEmptyResponse(w)
+	}
+}
+
+func (h *CommandHandler) UpdateRoleHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := roleBody{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		actorID := r.Context().Value(middleware.UserIDKey).(int)
+		err := h.Command.UpdateRole(r.Context(), actorID, body.Name, body.Permissions)
+		if err != nil {
+			if errors.Is(err, application.ErrRoleNotFound) {
+				helper.SendClientError(w, "role_not_found", nil)
+				return
+			} else if errors.Is(err, application.ErrRoleProtected) {
+				helper.SendClientError(w, "role_protected", nil)
+				return
+			} else if errors.Is(err, application.ErrInvalidRoleData) {
+				helper.SendValidationError(w, "invalid_role_data", err)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendEmptyResponse(w)
+	}
+}
+
+type deleteRole struct {
+	Name string `json:"name"`
+}
+
+func (h *CommandHandler) DeleteRoleHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := deleteRole{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		actorID := r.Context().Value(middleware.UserIDKey).(int)
+		err := h.Command.DeleteRole(r.Context(), actorID, body.Name)
+		if err != nil {
+			if errors.Is(err, application.ErrRoleNotFound) {
+				helper.SendClientError(w, "role_not_found", nil)
+				return
+			} else if errors.Is(err, application.ErrRoleProtected) {
+				helper.SendClientError(w, "role_protected", nil)
+				return
+			} else if errors.Is(err, application.ErrRoleInUse) {
+				helper.SendClientError(w, "role_in_use", "Role is still assigned to users.")
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendEmptyResponse(w)
+	}
+}
@@ -0,0 +1,64 @@
+//go:build unit
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nicograef/jotti/backend/api/middleware"
+	"github.com/nicograef/jotti/backend/api/role/application"
+	"github.com/nicograef/jotti/backend/domain/role"
+)
+
+type mockCommand struct {
+	err error
+}
+
+func (m *mockCommand) CreateRole(ctx context.Context, actorID int, name string, permissions []role.Permission) error {
+	return m.err
+}
+
+func (m *mockCommand) UpdateRole(ctx context.Context, actorID int, name string, permissions []role.Permission) error {
+	return m.err
+}
+
+func (m *mockCommand) DeleteRole(ctx context.Context, actorID int, name string) error {
+	return m.err
+}
+
+func TestCreateRoleHandler_Success(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{}}
+
+	body := `{"name":"schichtleitung","permissions":["tables.view","items.write_off"]}`
+	req := httptest.NewRequest(http.MethodPost, "/create-role", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
+	rec := httptest.NewRecorder()
+
+	handler.CreateRoleHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestDeleteRoleHandler_InUse(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{err: application.ErrRoleInUse}}
+
+	body := `{"name":"kueche"}`
+	req := httptest.NewRequest(http.MethodPost, "/delete-role", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
+	rec := httptest.NewRecorder()
+
+	handler.DeleteRoleHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "role_in_use") {
+		t.Errorf("expected role_in_use error, got %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,25 @@
+package http
+
+import (
+	"database/sql"
+
+	"github.com/nicograef/jotti/backend/api/role/application"
+	dbpkg "github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/repository/event_repo"
+	"github.com/nicograef/jotti/backend/repository/role_repo"
+)
+
+func NewCommandHandler(db *sql.DB) CommandHandler {
+	repo := role_repo.Repository{DB: db}
+	eventRepo := event_repo.Repository{DB: db}
+	// lost updates of concurrent changes fail and are retried
+	transactor := dbpkg.Transactor{DB: db, Isolation: sql.LevelRepeatableRead}
+	command := application.Command{Transactor: transactor, RoleRepo: repo, EventRepo: eventRepo}
+	return CommandHandler{Command: command}
+}
+
+func NewQueryHandler(db *sql.DB) QueryHandler {
+	repo := role_repo.Repository{DB: db}
+	query := application.Query{RoleRepo: repo}
+	return QueryHandler{Query: query}
+}
@@ -0,0 +1,28 @@
+package http
+
+import "github.com/nicograef/jotti/backend/api/openapi"
+
+// Operations of the handlers in the OpenAPI document.
+
+var CreateRoleOperation = openapi.Operation{
+	Summary: "Create a role with permissions",
+	Request: roleBody{},
+	Errors:  []string{"invalid_role_data", "retry_later", "role_already_exists"},
+}
+
+var UpdateRoleOperation = openapi.Operation{
+	Summary: "Change the permissions of a role",
+	Request: roleBody{},
+	Errors:  []string{"invalid_role_data", "retry_later", "role_not_found", "role_protected"},
+}
+
+var DeleteRoleOperation = openapi.Operation{
+	Summary: "Delete a role that is not assigned to any user",
+	Request: deleteRole{},
+	Errors:  []string{"retry_later", "role_in_use", "role_not_found", "role_protected"},
+}
+
+var GetAllRolesOperation = openapi.Operation{
+	Summary:  "List all roles and the permissions that can be assigned to roles",
+	Response: getAllRolesResponse{},
+}
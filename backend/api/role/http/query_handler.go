@@ -0,0 +1,35 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/nicograef/jotti/backend/api/helper"
+	"github.com/nicograef/jotti/backend/domain/role"
+)
+
+type query interface {
+	GetAllRoles(ctx context.Context) ([]role.Role, error)
+}
+
+type QueryHandler struct {
+	Query query
+}
+
+type getAllRolesResponse struct {
+	Roles       []role.Role       `json:"roles"`
+	Permissions []role.Permission `json:"permissions"`
+}
+
+// GetAllRolesHandler returns all roles together with all permissions that can be assigned to roles.
+func (h *QueryHandler) GetAllRolesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roles, err := h.Query.GetAllRoles(r.Context())
+		if err != nil {
+			helper.SendServerError(w)
+			return
+		}
+
+		helper.SendResponse(w, getAllRolesResponse{Roles: roles, Permissions: role.AllPermissions})
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/nicograef/jotti/backend/api/middleware"
+	"github.com/nicograef/jotti/backend/api/openapi"
+	"github.com/nicograef/jotti/backend/domain/role"
+)
+
+// Version is the version of the API in the OpenAPI document.
+const Version = "0.0.0"
+
+// NewDocument returns an empty OpenAPI document that the APIs add their routes to.
+func NewDocument() *openapi.Document {
+	return openapi.New("jotti API", Version)
+}
+
+// router registers the handlers of an API and documents them, so the document can't miss a route.
+type router struct {
+	mux           *http.ServeMux
+	doc           *openapi.Document
+	prefix        string
+	authenticated bool
+}
+
+func newRouter(doc *openapi.Document, prefix string, authenticated bool) *router {
+	return &router{mux: http.NewServeMux(), doc: doc, prefix: prefix, authenticated: authenticated}
+}
+
+// handle registers the handler at the path. With a permission the handler is only called for users whose role has it.
+func (r *router) handle(path string, permission role.Permission, handler http.HandlerFunc, op openapi.Operation) {
+	r.doc.Add(openapi.Route{
+		Path:          r.prefix + path,
+		Authenticated: r.authenticated,
+		Permission:    string(permission),
+		Handler:       handlerName(handler),
+		Operation:     op,
+	})
+
+	if permission != "" {
+		handler = middleware.RequirePermission(permission, handler)
+	}
+	r.mux.HandleFunc(path, handler)
+}
+
+// handlerName returns the name of the method that created the handler, e.g.
+// "github.com/nicograef/jotti/backend/api/table/http.(*CommandHandler).CreateTableHandler".
+func handlerName(handler http.HandlerFunc) string {
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	return strings.TrimSuffix(name, ".func1")
+}
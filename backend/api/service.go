@@ -4,27 +4,49 @@ import (
 	"database/sql"
 	"net/http"
 
+	"github.com/nicograef/jotti/backend/api/openapi"
+	period "github.com/nicograef/jotti/backend/api/period/http"
 	product "github.com/nicograef/jotti/backend/api/product/http"
 	table "github.com/nicograef/jotti/backend/api/table/http"
+	user "github.com/nicograef/jotti/backend/api/user/http"
+	"github.com/nicograef/jotti/backend/config"
+	"github.com/nicograef/jotti/backend/domain/role"
 )
 
-func NewServiceApi(db *sql.DB) http.Handler {
-	r := http.NewServeMux()
+func NewServiceApi(cfg config.Config, db *sql.DB, doc *openapi.Document) *http.ServeMux {
+	r := newRouter(doc, "/service", true)
+
+	// Endpoints without permission are available to every logged in user
+	uc := user.NewCommandHandler(db, passwordPolicy(cfg))
+	r.handle("/set-pin", "", uc.SetPinHandler(), user.SetPinOperation)
+	r.handle("/remove-pin", "", uc.RemovePinHandler(), user.RemovePinOperation)
+
+	perq := period.NewQueryHandler(db)
+	r.handle("/get-current-period", "", perq.GetCurrentPeriodHandler(), period.GetCurrentPeriodOperation)
 
 	pq := product.NewQueryHandler(db)
-	r.HandleFunc("/get-active-products", pq.GetActiveProductsHandler())
+	r.handle("/get-active-products", "", pq.GetActiveProductsHandler(), product.GetActiveProductsOperation)
 
-	tc := table.NewCommandHandler(db)
-	r.HandleFunc("/place-table-order", tc.PlaceTableOrderHandler())
-	r.HandleFunc("/register-table-payment", tc.RegisterTablePaymentHandler())
+	tc := table.NewCommandHandler(db, cfg.SyncMaxItemAge)
+	r.handle("/place-table-order", role.ServeTables, tc.PlaceTableOrderHandler(), table.PlaceTableOrderOperation)
+	r.handle("/register-table-payment", role.RegisterPayments, tc.RegisterTablePaymentHandler(), table.RegisterTablePaymentOperation)
+	r.handle("/register-table-amount-payment", role.RegisterPayments, tc.RegisterTableAmountPaymentHandler(), table.RegisterTableAmountPaymentOperation)
+	r.handle("/reverse-table-payment", role.RegisterPayments, tc.ReverseTablePaymentHandler(), table.ReverseTablePaymentOperation)
+	r.handle("/open-table", role.ServeTables, tc.OpenTableHandler(), table.OpenTableOperation)
+	r.handle("/reopen-table", role.ServeTables, tc.ReopenTableHandler(), table.ReopenTableOperation)
+	r.handle("/close-table", role.ServeTables, tc.CloseTableHandler(), table.CloseTableOperation)
+	// Offline clients upload buffered orders and payments, the permissions are checked per item
+	r.handle("/sync", role.ViewTables, tc.SyncHandler(), table.SyncOperation)
 
 	tq := table.NewQueryHandler(db)
-	r.HandleFunc("/get-table", tq.GetTableHandler())
-	r.HandleFunc("/get-active-tables", tq.GetActiveTablesHandler())
-	r.HandleFunc("/get-table-orders", tq.GetTableOrdersHandler())
-	r.HandleFunc("/get-table-payments", tq.GetTablePaymentsHandler())
-	r.HandleFunc("/get-table-balance", tq.GetTableBalanceHandler())
-	r.HandleFunc("/get-table-unpaid-products", tq.GetTableUnpaidProductsHandler())
-
-	return r
+	r.handle("/get-table", role.ViewTables, tq.GetTableHandler(), table.GetTableOperation)
+	r.handle("/get-active-tables", role.ViewTables, tq.GetActiveTablesHandler(), table.GetActiveTablesOperation)
+	r.handle("/get-table-sessions", role.ViewTables, tq.GetTableSessionsHandler(), table.GetTableSessionsOperation)
+	r.handle("/get-table-orders", role.ViewTables, tq.GetTableOrdersHandler(), table.GetTableOrdersOperation)
+	r.handle("/get-table-payments", role.ViewTables, tq.GetTablePaymentsHandler(), table.GetTablePaymentsOperation)
+	r.handle("/get-table-balance", role.ViewTables, tq.GetTableBalanceHandler(), table.GetTableBalanceOperation)
+	r.handle("/get-table-unpaid-products", role.ViewTables, tq.GetTableUnpaidProductsHandler(), table.GetTableUnpaidProductsOperation)
+	r.handle("/get-table-split", role.ViewTables, tq.GetTableSplitHandler(), table.GetTableSplitOperation)
+
+	return r.mux
 }
@@ -2,9 +2,21 @@ package application
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/nicograef/jotti/backend/api/command"
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/audit"
 	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/period"
+	"github.com/nicograef/jotti/backend/domain/product"
 	"github.com/nicograef/jotti/backend/domain/table"
+	"github.com/nicograef/jotti/backend/tracing"
 	"github.com/rs/zerolog"
 )
 
@@ -16,125 +28,769 @@ type tableRepoCommand interface {
 
 type eventRepoCommand interface {
 	WriteEvent(ctx context.Context, event event.Event) (int, error)
+	ReadEventsBySubject(ctx context.Context, subject string) ([]event.Event, error)
+	ReadEventsAfter(ctx context.Context, afterID int, types []string, limit int) ([]event.Event, error)
+}
+
+type productRepo interface {
+	GetProduct(ctx context.Context, id int) (product.Product, error)
+}
+
+type periodRepo interface {
+	GetOpenPeriod(ctx context.Context) (period.Period, error)
+	GetOpenPeriodID(ctx context.Context) (int, error)
 }
 
 type Command struct {
-	TableRepo tableRepoCommand
-	EventRepo eventRepoCommand
+	Transactor  db.TxRunner
+	TableRepo   tableRepoCommand
+	EventRepo   eventRepoCommand
+	PeriodRepo  periodRepo
+	ProductRepo productRepo
+	// MaxSyncItemAge is how long ago an offline client may have entered an order or payment it uploads
+	MaxSyncItemAge time.Duration
 }
 
-func (c Command) CreateTable(ctx context.Context, name string) (int, error) {
+func (c Command) CreateTable(ctx context.Context, userID int, name string) (int, error) {
+	ctx, span := tracing.Start(ctx, "table.CreateTable")
+	defer span.End()
+
 	log := zerolog.Ctx(ctx)
 
 	table, err := table.NewTable(name)
 	if err != nil {
 		log.Warn().Err(err).Str("table_name", name).Msg("Invalid table data")
-		return 0, ErrInvalidTableData
+		return 0, fmt.Errorf("%w: %w", ErrInvalidTableData, err)
 	}
 
-	id, err := c.TableRepo.CreateTable(ctx, table)
+	// the table and its audit event are written together
+	err = c.Transactor.InTransaction(ctx, func(ctx context.Context) error {
+		// new tables belong to the open period (if any)
+		periodID, err := c.PeriodRepo.GetOpenPeriodID(ctx)
+		if err != nil {
+			return err
+		}
+		table.PeriodID = nil
+		if periodID != 0 {
+			table.PeriodID = &periodID
+		}
+
+		table.ID, err = c.TableRepo.CreateTable(ctx, table)
+		if err != nil {
+			return err
+		}
+
+		return command.WriteAuditEvent(ctx, c.EventRepo, userID, audit.EventTypeTableCreatedV1, audit.TableEntity, strconv.Itoa(table.ID), nil, table)
+	})
 	if err != nil {
 		return 0, fromRepositoryError(err, log, 0)
 	}
 
-	log.Info().Int("table_id", id).Msg("Table created")
-	return id, nil
+	log.Info().Int("table_id", table.ID).Msg("Table created")
+	return table.ID, nil
 }
 
-func (c Command) UpdateTable(ctx context.Context, id int, name string) error {
-	log := zerolog.Ctx(ctx)
+func (c Command) UpdateTable(ctx context.Context, userID, id int, name string) error {
+	ctx, span := tracing.Start(ctx, "table.UpdateTable")
+	defer span.End()
 
-	table, err := c.TableRepo.GetTable(ctx, id)
+	err := c.changeTable(ctx, userID, id, audit.EventTypeTableUpdatedV1, func(t *table.Table) error {
+		return t.Rename(name)
+	})
 	if err != nil {
-		return fromRepositoryError(err, log, id)
+		return err
 	}
 
-	err = table.Rename(name)
+	zerolog.Ctx(ctx).Info().Int("table_id", id).Msg("Table updated")
+	return nil
+}
+
+func (c Command) ActivateTable(ctx context.Context, userID, id int) error {
+	ctx, span := tracing.Start(ctx, "table.ActivateTable")
+	defer span.End()
+
+	err := c.changeTable(ctx, userID, id, audit.EventTypeTableActivatedV1, func(t *table.Table) error {
+		t.Activate()
+		return nil
+	})
 	if err != nil {
-		log.Warn().Err(err).Int("table_id", id).Msg("Invalid table data for update")
-		return ErrInvalidTableData
+		return err
 	}
 
-	err = c.TableRepo.UpdateTable(ctx, table)
+	zerolog.Ctx(ctx).Info().Int("table_id", id).Msg("Table activated")
+	return nil
+}
+
+func (c Command) DeactivateTable(ctx context.Context, userID, id int) error {
+	ctx, span := tracing.Start(ctx, "table.DeactivateTable")
+	defer span.End()
+
+	err := c.changeTable(ctx, userID, id, audit.EventTypeTableDeactivatedV1, func(t *table.Table) error {
+		t.Deactivate()
+		return nil
+	})
 	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Int("table_id", id).Msg("Table deactivated")
+	return nil
+}
+
+// changeTable applies change to a table and writes the table and the audit event in one transaction.
+// An error of change means invalid table data.
+func (c Command) changeTable(ctx context.Context, userID, id int, eventType audit.EventType, change func(t *table.Table) error) error {
+	log := zerolog.Ctx(ctx)
+
+	err := c.Transactor.InTransaction(ctx, func(ctx context.Context) error {
+		t, err := c.TableRepo.GetTable(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		before := t
+		if err := change(&t); err != nil {
+			log.Warn().Err(err).Int("table_id", id).Msg("Invalid table data for update")
+			return fmt.Errorf("%w: %w", ErrInvalidTableData, err)
+		}
+
+		if err := c.TableRepo.UpdateTable(ctx, t); err != nil {
+			return err
+		}
+
+		return command.WriteAuditEvent(ctx, c.EventRepo, userID, eventType, audit.TableEntity, strconv.Itoa(id), before, t)
+	})
+	if errors.Is(err, ErrInvalidTableData) {
+		return err
+	} else if err != nil {
 		return fromRepositoryError(err, log, id)
 	}
 
-	log.Info().Int("table_id", id).Msg("Table updated")
 	return nil
 }
 
-func (c Command) ActivateTable(ctx context.Context, id int) error {
+// PlaceTableOrder books an order on the current session of a table. A new session is opened if the table is not open.
+// The client may choose the order ID (e.g. from an Idempotency-Key). If an order with this ID was already placed on the
+// table, nothing is written and the ID is returned again, so retries don't duplicate the order.
+func (c Command) PlaceTableOrder(ctx context.Context, userID, tableID int, orderID string, products []table.OrderProduct) (string, error) {
+	ctx, span := tracing.Start(ctx, "table.PlaceTableOrder")
+	defer span.End()
+
+	return c.placeTableOrder(ctx, userID, tableID, orderID, products, time.Time{})
+}
+
+// placeTableOrder places the order at the given time, e.g. when it was buffered by an offline client. A zero time is now.
+func (c Command) placeTableOrder(ctx context.Context, userID, tableID int, orderID string, products []table.OrderProduct, at time.Time) (string, error) {
 	log := zerolog.Ctx(ctx)
 
-	table, err := c.TableRepo.GetTable(ctx, id)
+	if orderID == "" {
+		orderID = uuid.New().String()
+	}
+
+	event, err := table.NewOrderPlacedEvent(userID, tableID, orderID, products)
 	if err != nil {
-		return fromRepositoryError(err, log, id)
+		log.Warn().Err(err).Int("table_id", tableID).Msg("Invalid order data")
+		return "", fmt.Errorf("%w: %w", ErrInvalidOrderData, err)
+	}
+	if !at.IsZero() {
+		event.Time = at
+	}
+
+	placed := false
+	err = c.inTransaction(ctx, log, tableID, func(ctx context.Context) error {
+		sessions, err := c.readSessions(ctx, log, tableID)
+		if err != nil {
+			return err
+		}
+
+		if placed, err = containsOrder(sessions, orderID); err != nil {
+			log.Error().Err(err).Int("table_id", tableID).Msg("Failed to build orders from events")
+			return err
+		} else if placed {
+			return nil
+		}
+
+		if _, ok := table.GetCurrentSession(sessions); !ok {
+			if err := c.writeTableOpenedEvent(ctx, log, userID, tableID, len(sessions)+1, 1); err != nil {
+				return err
+			}
+		}
+
+		if _, err := c.EventRepo.WriteEvent(ctx, event); err != nil {
+			log.Error().Err(err).Int("table_id", tableID).Msg("Failed to write order placed event to database")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
+		return nil
+	})
+	if errors.Is(err, db.ErrAlreadyExists) {
+		return c.resolveConcurrentRetry(ctx, log, tableID, orderID, containsOrder)
+	} else if err != nil {
+		return "", err
+	}
+
+	if placed {
+		log.Info().Int("table_id", tableID).Str("order_id", orderID).Msg("Order already placed")
+	} else {
+		log.Info().Int("table_id", tableID).Str("order_id", orderID).Msg("Order placed")
 	}
+	return orderID, nil
+}
+
+// RegisterTablePayment registers a payment of products on the current session of a table. Like orders, a payment
+// with a known payment ID is not registered again and its ID is returned instead.
+func (c Command) RegisterTablePayment(ctx context.Context, userID, tableID int, paymentID string, products []table.PaymentProduct) (string, error) {
+	ctx, span := tracing.Start(ctx, "table.RegisterTablePayment")
+	defer span.End()
+
+	return c.registerTablePayment(ctx, userID, tableID, paymentID, products, time.Time{})
+}
+
+// registerTablePayment registers the payment at the given time. A zero time is now.
+func (c Command) registerTablePayment(ctx context.Context, userID, tableID int, paymentID string, products []table.PaymentProduct, at time.Time) (string, error) {
+	log := zerolog.Ctx(ctx)
 
-	table.Activate()
+	if paymentID == "" {
+		paymentID = uuid.New().String()
+	}
 
-	err = c.TableRepo.UpdateTable(ctx, table)
+	event, err := table.NewPaymentRegisteredEvent(userID, tableID, paymentID, products)
 	if err != nil {
-		return fromRepositoryError(err, log, id)
+		log.Warn().Err(err).Int("table_id", tableID).Msg("Invalid payment data")
+		return "", fmt.Errorf("%w: %w", ErrInvalidPaymentData, err)
+	}
+	if !at.IsZero() {
+		event.Time = at
 	}
 
-	log.Info().Int("table_id", id).Msg("Table activated")
-	return nil
+	registered := false
+	err = c.inTransaction(ctx, log, tableID, func(ctx context.Context) error {
+		sessions, err := c.readSessions(ctx, log, tableID)
+		if err != nil {
+			return err
+		}
+
+		// a retry may arrive after the table was closed, so look for the payment first
+		if registered, err = containsPayment(sessions, paymentID); err != nil {
+			log.Error().Err(err).Int("table_id", tableID).Msg("Failed to build payments from events")
+			return err
+		} else if registered {
+			return nil
+		}
+
+		session, ok := table.GetCurrentSession(sessions)
+		if !ok {
+			log.Warn().Int("table_id", tableID).Msg("Payment registered for table that is not open")
+			return ErrTableNotOpen
+		}
+
+		unpaidProducts, err := table.GetUnpaidProductsFromEvents(session.Events)
+		if err != nil {
+			log.Error().Err(err).Int("table_id", tableID).Msg("Failed to get unpaid products from events")
+			return err
+		}
+
+		if !table.AreProductsUnpaid(unpaidProducts, paidProducts(products)) {
+			log.Warn().Int("table_id", tableID).Msg("Paid products are not unpaid")
+			return ErrItemsNotUnpaid
+		}
+
+		// an amount payment may have settled part of a product that is still listed as unpaid
+		totalCents := 0
+		for _, p := range products {
+			totalCents += p.NetPriceCents * p.Quantity
+		}
+		if totalCents > session.BalanceCents {
+			log.Warn().Int("table_id", tableID).Int("total_cents", totalCents).Int("balance_cents", session.BalanceCents).Msg("Payment exceeds table balance")
+			return ErrPaymentExceedsBalance
+		}
+
+		if _, err := c.EventRepo.WriteEvent(ctx, event); err != nil {
+			log.Error().Err(err).Int("table_id", tableID).Msg("Failed to write payment registered event to database")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
+		return nil
+	})
+	if errors.Is(err, db.ErrAlreadyExists) {
+		return c.resolveConcurrentRetry(ctx, log, tableID, paymentID, containsPayment)
+	} else if err != nil {
+		return "", err
+	}
+
+	if registered {
+		log.Info().Int("table_id", tableID).Str("payment_id", paymentID).Msg("Payment already registered")
+	} else {
+		log.Info().Int("table_id", tableID).Str("payment_id", paymentID).Msg("Payment registered")
+	}
+	return paymentID, nil
 }
 
-func (c Command) DeactivateTable(ctx context.Context, id int) error {
+// resolveConcurrentRetry handles a unique violation of an order or payment ID. Either a concurrent retry of the same
+// request was faster, then its ID is returned, or the ID was already used for another table.
+func (c Command) resolveConcurrentRetry(ctx context.Context, log *zerolog.Logger, tableID int, id string, contains func([]table.Session, string) (bool, error)) (string, error) {
+	sessions, err := c.readSessions(ctx, log, tableID)
+	if err != nil {
+		return "", err
+	}
+
+	found, err := contains(sessions, id)
+	if err != nil {
+		log.Error().Err(err).Int("table_id", tableID).Msg("Failed to build sessions from events")
+		return "", err
+	}
+	if !found {
+		log.Warn().Int("table_id", tableID).Str("id", id).Msg("Idempotency key already used for another table")
+		return "", ErrIdempotencyKeyReused
+	}
+
+	return id, nil
+}
+
+// RegisterTableAmountPayment registers a partial payment of a fixed amount, e.g. one share of an evenly split balance.
+// The amount must not exceed the open balance of the current session of the table. Like payments of products, a
+// payment with a known payment ID is not registered again and its ID is returned instead.
+func (c Command) RegisterTableAmountPayment(ctx context.Context, userID, tableID int, paymentID string, amountCents int) (string, error) {
+	ctx, span := tracing.Start(ctx, "table.RegisterTableAmountPayment")
+	defer span.End()
+
+	return c.registerTableAmountPayment(ctx, userID, tableID, paymentID, amountCents, time.Time{})
+}
+
+// registerTableAmountPayment registers the amount payment at the given time. A zero time is now.
+func (c Command) registerTableAmountPayment(ctx context.Context, userID, tableID int, paymentID string, amountCents int, at time.Time) (string, error) {
 	log := zerolog.Ctx(ctx)
-	table, err := c.TableRepo.GetTable(ctx, id)
+
+	if paymentID == "" {
+		paymentID = uuid.New().String()
+	}
+
+	event, err := table.NewAmountPaymentRegisteredEvent(userID, tableID, paymentID, amountCents)
 	if err != nil {
-		return fromRepositoryError(err, log, id)
+		log.Warn().Err(err).Int("table_id", tableID).Msg("Invalid amount payment data")
+		return "", fmt.Errorf("%w: %w", ErrInvalidPaymentData, err)
 	}
+	if !at.IsZero() {
+		event.Time = at
+	}
+
+	registered := false
+	err = c.inTransaction(ctx, log, tableID, func(ctx context.Context) error {
+		sessions, err := c.readSessions(ctx, log, tableID)
+		if err != nil {
+			return err
+		}
+
+		if registered, err = containsPayment(sessions, paymentID); err != nil {
+			log.Error().Err(err).Int("table_id", tableID).Msg("Failed to build payments from events")
+			return err
+		} else if registered {
+			return nil
+		}
 
-	table.Deactivate()
+		session, ok := table.GetCurrentSession(sessions)
+		if !ok {
+			log.Warn().Int("table_id", tableID).Msg("Amount payment registered for table that is not open")
+			return ErrTableNotOpen
+		}
 
-	err = c.TableRepo.UpdateTable(ctx, table)
+		if amountCents > session.BalanceCents {
+			log.Warn().Int("table_id", tableID).Int("amount_cents", amountCents).Int("balance_cents", session.BalanceCents).Msg("Payment exceeds table balance")
+			return ErrPaymentExceedsBalance
+		}
+
+		if _, err := c.EventRepo.WriteEvent(ctx, event); err != nil {
+			log.Error().Err(err).Int("table_id", tableID).Msg("Failed to write amount payment registered event to database")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
+		return nil
+	})
+	if errors.Is(err, db.ErrAlreadyExists) {
+		return c.resolveConcurrentRetry(ctx, log, tableID, paymentID, containsPayment)
+	} else if err != nil {
+		return "", err
+	}
+
+	if registered {
+		log.Info().Int("table_id", tableID).Str("payment_id", paymentID).Msg("Amount payment already registered")
+	} else {
+		log.Info().Int("table_id", tableID).Str("payment_id", paymentID).Int("amount_cents", amountCents).Msg("Amount payment registered")
+	}
+	return paymentID, nil
+}
+
+// OpenTable starts a new session (guest visit) at a table.
+func (c Command) OpenTable(ctx context.Context, userID, tableID int) error {
+	ctx, span := tracing.Start(ctx, "table.OpenTable")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	var sessionNumber int
+	err := c.inTransaction(ctx, log, tableID, func(ctx context.Context) error {
+		sessions, err := c.readSessions(ctx, log, tableID)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := table.GetCurrentSession(sessions); ok {
+			log.Warn().Int("table_id", tableID).Msg("Table is already open")
+			return ErrTableAlreadyOpen
+		}
+
+		sessionNumber = len(sessions) + 1
+		return c.writeTableOpenedEvent(ctx, log, userID, tableID, sessionNumber, 1)
+	})
 	if err != nil {
-		return fromRepositoryError(err, log, id)
+		return err
 	}
 
-	log.Info().Int("table_id", id).Msg("Table deactivated")
+	log.Info().Int("table_id", tableID).Int("session", sessionNumber).Msg("Table opened")
 	return nil
 }
 
-func (c Command) PlaceTableOrder(ctx context.Context, userID, tableID int, products []table.OrderProduct) error {
+// ReopenTable reopens the last closed session of a table, e.g. when it was closed by mistake.
+func (c Command) ReopenTable(ctx context.Context, userID, tableID int) error {
+	ctx, span := tracing.Start(ctx, "table.ReopenTable")
+	defer span.End()
+
 	log := zerolog.Ctx(ctx)
 
-	event, err := table.NewOrderPlacedEvent(userID, tableID, products)
+	var sessionNumber int
+	err := c.inTransaction(ctx, log, tableID, func(ctx context.Context) error {
+		sessions, err := c.readSessions(ctx, log, tableID)
+		if err != nil {
+			return err
+		}
+
+		if len(sessions) == 0 {
+			log.Warn().Int("table_id", tableID).Msg("No session to reopen")
+			return ErrSessionNotFound
+		}
+
+		if _, ok := table.GetCurrentSession(sessions); ok {
+			log.Warn().Int("table_id", tableID).Msg("Table is already open")
+			return ErrTableAlreadyOpen
+		}
+
+		last := sessions[len(sessions)-1]
+		sessionNumber = last.Number
+		return c.writeTableOpenedEvent(ctx, log, userID, tableID, sessionNumber, last.Openings()+1)
+	})
 	if err != nil {
-		log.Error().Err(err).Int("table_id", tableID).Msg("Failed to create order placed event")
 		return err
 	}
 
-	_, err = c.EventRepo.WriteEvent(ctx, event)
+	log.Info().Int("table_id", tableID).Int("session", sessionNumber).Msg("Table reopened")
+	return nil
+}
+
+// CloseTable closes the current session of a table. The session must not have an open balance.
+func (c Command) CloseTable(ctx context.Context, userID, tableID int) error {
+	ctx, span := tracing.Start(ctx, "table.CloseTable")
+	defer span.End()
+
+	return c.closeTable(ctx, userID, tableID, "", "", false)
+}
+
+// ForceCloseTable closes the current session of a table and writes off its unpaid products with the given category
+// and reason, so the report lists them like other write-offs.
+func (c Command) ForceCloseTable(ctx context.Context, userID, tableID int, category table.WriteOffCategory, writeOffReason string) error {
+	ctx, span := tracing.Start(ctx, "table.ForceCloseTable")
+	defer span.End()
+
+	return c.closeTable(ctx, userID, tableID, category, writeOffReason, true)
+}
+
+func (c Command) closeTable(ctx context.Context, userID, tableID int, category table.WriteOffCategory, writeOffReason string, force bool) error {
+	log := zerolog.Ctx(ctx)
+
+	var session table.Session
+	err := c.inTransaction(ctx, log, tableID, func(ctx context.Context) error {
+		sessions, err := c.readSessions(ctx, log, tableID)
+		if err != nil {
+			return err
+		}
+
+		var ok bool
+		session, ok = table.GetCurrentSession(sessions)
+		if !ok {
+			log.Warn().Int("table_id", tableID).Msg("Table is not open")
+			return ErrTableNotOpen
+		}
+
+		if session.BalanceCents != 0 && !force {
+			log.Warn().Int("table_id", tableID).Int("balance_cents", session.BalanceCents).Msg("Table with open balance cannot be closed")
+			return ErrOpenBalance
+		}
+
+		closed, err := table.NewTableClosedEvent(userID, tableID, session.Number, session.BalanceCents, writeOffReason)
+		if err != nil {
+			log.Warn().Err(err).Int("table_id", tableID).Msg("Invalid table closed data")
+			return ErrInvalidWriteOffReason
+		}
+
+		// the unpaid products are written off before the session ends, events after the close would start a new one
+		unpaidProducts, err := table.GetUnpaidProductsFromEvents(session.Events)
+		if err != nil {
+			log.Error().Err(err).Int("table_id", tableID).Msg("Failed to get unpaid products from events")
+			return err
+		}
+		if force && len(unpaidProducts) > 0 {
+			products := writeOffProducts(unpaidProducts)
+			writtenOff, err := table.NewItemsWrittenOffEvent(userID, tableID, category, writeOffReason, products, writeOffCredit(products, session.BalanceCents))
+			if err != nil {
+				log.Warn().Err(err).Int("table_id", tableID).Msg("Invalid write-off data for forced close")
+				return fmt.Errorf("%w: %w", ErrInvalidWriteOffData, err)
+			}
+
+			if _, err := c.EventRepo.WriteEvent(ctx, writtenOff); err != nil {
+				log.Error().Err(err).Int("table_id", tableID).Msg("Failed to write items written off event to database")
+				return fmt.Errorf("%w: %w", ErrDatabase, err)
+			}
+		}
+
+		if _, err := c.EventRepo.WriteEvent(ctx, closed); err != nil {
+			log.Error().Err(err).Int("table_id", tableID).Msg("Failed to write table closed event to database")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
+		return nil
+	})
 	if err != nil {
-		log.Error().Int("table_id", tableID).Msg("Failed to write order placed event to database")
-		return ErrDatabase
+		return err
 	}
 
-	log.Info().Int("table_id", tableID).Msg("Order placed")
+	log.Info().Int("table_id", tableID).Int("session", session.Number).Int("written_off_cents", session.BalanceCents).Msg("Table closed")
 	return nil
 }
 
-func (c Command) RegisterTablePayment(ctx context.Context, userID, tableID int, products []table.PaymentProduct) error {
+// WriteOffTableItems removes open items from the current session of a table without a payment,
+// e.g. because guests left without paying or items were spilled. Only available to admins.
+func (c Command) WriteOffTableItems(ctx context.Context, userID, tableID int, category table.WriteOffCategory, note string, products []table.WriteOffProduct) error {
+	ctx, span := tracing.Start(ctx, "table.WriteOffTableItems")
+	defer span.End()
+
 	log := zerolog.Ctx(ctx)
 
-	event, err := table.NewPaymentRegisteredEvent(userID, tableID, products)
+	if _, err := table.NewItemsWrittenOffEvent(userID, tableID, category, note, products, 0); err != nil {
+		log.Warn().Err(err).Int("table_id", tableID).Msg("Invalid write-off data")
+		return fmt.Errorf("%w: %w", ErrInvalidWriteOffData, err)
+	}
+
+	err := c.inTransaction(ctx, log, tableID, func(ctx context.Context) error {
+		sessions, err := c.readSessions(ctx, log, tableID)
+		if err != nil {
+			return err
+		}
+
+		session, ok := table.GetCurrentSession(sessions)
+		if !ok {
+			log.Warn().Int("table_id", tableID).Msg("Write-off for table that is not open")
+			return ErrTableNotOpen
+		}
+
+		unpaidProducts, err := table.GetUnpaidProductsFromEvents(session.Events)
+		if err != nil {
+			log.Error().Err(err).Int("table_id", tableID).Msg("Failed to get unpaid products from events")
+			return err
+		}
+
+		if !table.AreProductsUnpaid(unpaidProducts, products) {
+			log.Warn().Int("table_id", tableID).Msg("Written off products are not unpaid")
+			return ErrItemsNotUnpaid
+		}
+
+		event, err := table.NewItemsWrittenOffEvent(userID, tableID, category, note, products, writeOffCredit(products, session.BalanceCents))
+		if err != nil {
+			log.Warn().Err(err).Int("table_id", tableID).Msg("Invalid write-off data")
+			return fmt.Errorf("%w: %w", ErrInvalidWriteOffData, err)
+		}
+
+		if _, err := c.EventRepo.WriteEvent(ctx, event); err != nil {
+			log.Error().Err(err).Int("table_id", tableID).Msg("Failed to write items written off event to database")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
+		return nil
+	})
 	if err != nil {
-		log.Error().Err(err).Int("table_id", tableID).Msg("Failed to create payment registered event")
+		return err
+	}
+
+	log.Info().Int("table_id", tableID).Str("category", string(category)).Msg("Items written off")
+	return nil
+}
+
+// ReversePayment refunds a payment of a table, e.g. when it was booked on the wrong table. Only payments of the last
+// session can be reversed. If that session is closed already, it is reopened, so its products can be paid again.
+// With reverseAny, any payment may be reversed, otherwise only the own payments within table.ReversalWindow.
+func (c Command) ReversePayment(ctx context.Context, userID int, reverseAny bool, tableID int, paymentID string, reason string) error {
+	ctx, span := tracing.Start(ctx, "table.ReversePayment")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	var payment table.Payment
+	err := c.inTransaction(ctx, log, tableID, func(ctx context.Context) error {
+		sessions, err := c.readSessions(ctx, log, tableID)
+		if err != nil {
+			return err
+		}
+
+		// the payment may belong to an earlier session, e.g. when the table was closed meanwhile
+		sessionNumber := 0
+		for _, session := range sessions {
+			payments, err := table.GetPaymentsFromEvents(session.Events)
+			if err != nil {
+				log.Error().Err(err).Int("table_id", tableID).Int("session", session.Number).Msg("Failed to get payments from events")
+				return err
+			}
+
+			if index := slices.IndexFunc(payments, func(p table.Payment) bool { return p.ID == paymentID }); index >= 0 {
+				payment = payments[index]
+				sessionNumber = session.Number
+				break
+			}
+		}
+		if sessionNumber == 0 {
+			log.Warn().Int("table_id", tableID).Str("payment_id", paymentID).Msg("Payment not found")
+			return ErrPaymentNotFound
+		}
+
+		if payment.ReversedAt != nil {
+			log.Warn().Int("table_id", tableID).Str("payment_id", paymentID).Msg("Payment already reversed")
+			return ErrPaymentAlreadyReversed
+		}
+
+		if !table.CanReversePayment(payment, userID, reverseAny, time.Now()) {
+			log.Warn().Int("table_id", tableID).Str("payment_id", paymentID).Int("user_id", userID).Msg("User is not allowed to reverse payment")
+			return ErrReversalNotAllowed
+		}
+
+		// the products of the payment are unpaid again, only the last session can still be reopened to pay them
+		last := sessions[len(sessions)-1]
+		if sessionNumber != last.Number {
+			log.Warn().Int("table_id", tableID).Str("payment_id", paymentID).Int("session", sessionNumber).Msg("Payment of earlier session cannot be reversed")
+			return ErrPaymentOfEarlierSession
+		}
+		if _, ok := table.GetCurrentSession(sessions); !ok {
+			if err := c.writeTableOpenedEvent(ctx, log, userID, tableID, last.Number, last.Openings()+1); err != nil {
+				return err
+			}
+		}
+
+		event, err := table.NewPaymentReversedEvent(userID, tableID, sessionNumber, payment, reason)
+		if err != nil {
+			log.Warn().Err(err).Int("table_id", tableID).Msg("Invalid payment reversal data")
+			return ErrInvalidReversalReason
+		}
+
+		if _, err := c.EventRepo.WriteEvent(ctx, event); err != nil {
+			log.Error().Err(err).Int("table_id", tableID).Msg("Failed to write payment reversed event to database")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Int("table_id", tableID).Str("payment_id", paymentID).Int("amount_cents", payment.TotalPaymentCents).Msg("Payment reversed")
+	return nil
+}
+
+// sessionOpeningIndex is the unique index of the openings of table sessions.
+const sessionOpeningIndex = "events_table_opened_idx"
+
+// inTransaction runs the reads, checks and writes of a command on the events of a table in one transaction. The
+// transactions are serializable (see http.NewCommandHandler), so of two concurrent commands on a table only one can
+// pass its checks; the other one runs again. Conflicts that remain after the retries return ErrRetryLater.
+func (c Command) inTransaction(ctx context.Context, log *zerolog.Logger, tableID int, fn func(ctx context.Context) error) error {
+	err := c.Transactor.InTransaction(ctx, fn)
+	if db.IsTemporary(err) || db.Constraint(err) == sessionOpeningIndex {
+		log.Warn().Err(err).Int("table_id", tableID).Msg("Concurrent change of table")
+		return ErrRetryLater
+	}
+	return err
+}
+
+// readSessions reads the sessions of a table. Errors of the database are wrapped, so a transaction of the command can
+// tell a serialization failure and retry.
+func (c Command) readSessions(ctx context.Context, log *zerolog.Logger, tableID int) ([]table.Session, error) {
+	events, err := c.EventRepo.ReadEventsBySubject(ctx, "table:"+strconv.Itoa(tableID))
+	if err != nil {
+		log.Error().Err(err).Int("table_id", tableID).Msg("Failed to read events for table")
+		return nil, fmt.Errorf("%w: %w", ErrDatabase, err)
+	}
+
+	sessions, err := table.GetSessionsFromEvents(events)
+	if err != nil {
+		log.Error().Err(err).Int("table_id", tableID).Msg("Failed to build sessions from events")
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (c Command) writeTableOpenedEvent(ctx context.Context, log *zerolog.Logger, userID, tableID, sessionNumber, opening int) error {
+	event, err := table.NewTableOpenedEvent(userID, tableID, sessionNumber, opening)
+	if err != nil {
+		log.Error().Err(err).Int("table_id", tableID).Msg("Failed to create table opened event")
 		return err
 	}
 
 	_, err = c.EventRepo.WriteEvent(ctx, event)
 	if err != nil {
-		log.Error().Int("table_id", tableID).Msg("Failed to write payment registered event to database")
-		return ErrDatabase
+		log.Error().Err(err).Int("table_id", tableID).Msg("Failed to write table opened event to database")
+		return fmt.Errorf("%w: %w", ErrDatabase, err)
 	}
 
-	log.Info().Int("table_id", tableID).Msg("Payment registered")
 	return nil
 }
+
+func containsOrder(sessions []table.Session, orderID string) (bool, error) {
+	for _, session := range sessions {
+		orders, err := table.GetOrdersFromEvents(session.Events)
+		if err != nil {
+			return false, err
+		}
+		if slices.ContainsFunc(orders, func(o table.Order) bool { return o.ID == orderID }) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func containsPayment(sessions []table.Session, paymentID string) (bool, error) {
+	for _, session := range sessions {
+		payments, err := table.GetPaymentsFromEvents(session.Events)
+		if err != nil {
+			return false, err
+		}
+		if slices.ContainsFunc(payments, func(p table.Payment) bool { return p.ID == paymentID }) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// writeOffProducts converts the unpaid products of a table to write them off.
+func writeOffProducts(products []table.OrderProduct) []table.WriteOffProduct {
+	converted := make([]table.WriteOffProduct, len(products))
+	for i, p := range products {
+		converted[i] = table.WriteOffProduct(p)
+	}
+	return converted
+}
+
+// writeOffCredit returns the part of the written off products that amount payments already settled. Amount payments
+// only settle whole products, their remaining credit lowers the balance below the total of the unpaid products.
+func writeOffCredit(products []table.WriteOffProduct, balanceCents int) int {
+	return max(0, table.GetWriteOffProductsTotal(products)-max(balanceCents, 0))
+}
+
+// paidProducts converts payment products to compare them with the unpaid products of a table.
+func paidProducts(products []table.PaymentProduct) []table.WriteOffProduct {
+	converted := make([]table.WriteOffProduct, len(products))
+	for i, p := range products {
+		converted[i] = table.WriteOffProduct(p)
+	}
+	return converted
+}
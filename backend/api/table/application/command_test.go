@@ -4,19 +4,28 @@ package application
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/audit"
+	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/period"
+	"github.com/nicograef/jotti/backend/domain/product"
 	"github.com/nicograef/jotti/backend/domain/table"
+	"github.com/nicograef/jotti/backend/repository/event_repo"
+	"github.com/nicograef/jotti/backend/repository/period_repo"
+	"github.com/nicograef/jotti/backend/repository/product_repo"
 	"github.com/nicograef/jotti/backend/repository/table_repo"
 )
 
 func TestCreateTable(t *testing.T) {
 	ctx := context.Background()
 	repo := table_repo.NewMock([]table.Table{}, nil)
-	command := Command{TableRepo: repo}
+	command := Command{Transactor: db.NewMockTransactor(), TableRepo: repo, PeriodRepo: period_repo.NewMock([]period.Period{}, nil), EventRepo: event_repo.NewMock(nil, nil)}
 
-	tableId, err := command.CreateTable(ctx, "Table 1")
+	tableId, err := command.CreateTable(ctx, 1, "Table 1")
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -35,19 +44,60 @@ func TestCreateTable(t *testing.T) {
 
 func TestCreateTable_Error(t *testing.T) {
 	repo := table_repo.NewMock([]table.Table{}, db.ErrAlreadyExists)
-	command := Command{TableRepo: repo}
+	command := Command{Transactor: db.NewMockTransactor(), TableRepo: repo, PeriodRepo: period_repo.NewMock([]period.Period{}, nil), EventRepo: event_repo.NewMock(nil, nil)}
 
-	_, err := command.CreateTable(context.Background(), "Table 1")
+	_, err := command.CreateTable(context.Background(), 1, "Table 1")
 	if err == nil {
 		t.Fatalf("expected error, got nil")
 	}
 }
 
+func TestCreateTable_RepositoryErrors(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected error
+	}{
+		{&db.ConstraintError{Err: db.ErrAlreadyExists, Constraint: "tables_name_key"}, ErrTableAlreadyExists},
+		{&db.ConstraintError{Err: db.ErrForeignKeyViolation, Constraint: "tables_period_id_fkey"}, ErrInvalidReference},
+		{db.ErrSerializationFailure, ErrRetryLater},
+		{db.ErrConnection, ErrRetryLater},
+		{db.ErrCanceled, ErrDatabase},
+		{db.ErrDatabase, ErrDatabase},
+	}
+
+	for _, tt := range tests {
+		repo := table_repo.NewMock([]table.Table{}, tt.err)
+		command := Command{Transactor: db.NewMockTransactor(), TableRepo: repo, PeriodRepo: period_repo.NewMock([]period.Period{}, nil), EventRepo: event_repo.NewMock(nil, nil)}
+
+		_, err := command.CreateTable(context.Background(), 1, "Table 1")
+		if !errors.Is(err, tt.expected) {
+			t.Errorf("expected %v for %v, got %v", tt.expected, tt.err, err)
+		}
+	}
+}
+
+func TestCreateTable_TaggedWithOpenPeriod(t *testing.T) {
+	ctx := context.Background()
+	repo := table_repo.NewMock([]table.Table{}, nil)
+	periodRepo := period_repo.NewMock([]period.Period{{ID: 3, Name: "Sommerfest", Status: period.OpenStatus}}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), TableRepo: repo, PeriodRepo: periodRepo, EventRepo: event_repo.NewMock(nil, nil)}
+
+	tableId, err := command.CreateTable(ctx, 1, "Table 1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	table, _ := repo.GetTable(ctx, tableId)
+	if table.PeriodID == nil || *table.PeriodID != 3 {
+		t.Errorf("expected table to belong to period 3, got %v", table.PeriodID)
+	}
+}
+
 func TestUpdateTable(t *testing.T) {
 	repo := table_repo.NewMock([]table.Table{{ID: 1, Name: "Old Name", Status: table.ActiveStatus}}, nil)
-	command := Command{TableRepo: repo}
+	command := Command{Transactor: db.NewMockTransactor(), TableRepo: repo, EventRepo: event_repo.NewMock(nil, nil)}
 
-	err := command.UpdateTable(context.Background(), 1, "New Name")
+	err := command.UpdateTable(context.Background(), 1, 1, "New Name")
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -63,9 +113,9 @@ func TestUpdateTable(t *testing.T) {
 
 func TestUpdateTable_NotFound(t *testing.T) {
 	repo := table_repo.NewMock([]table.Table{}, db.ErrNotFound)
-	command := Command{TableRepo: repo}
+	command := Command{Transactor: db.NewMockTransactor(), TableRepo: repo, EventRepo: event_repo.NewMock(nil, nil)}
 
-	err := command.UpdateTable(context.Background(), 999, "New Name")
+	err := command.UpdateTable(context.Background(), 1, 999, "New Name")
 	if err != ErrTableNotFound {
 		t.Fatalf("expected ErrTableNotFound, got %v", err)
 	}
@@ -73,9 +123,9 @@ func TestUpdateTable_NotFound(t *testing.T) {
 
 func TestActivateTable(t *testing.T) {
 	repo := table_repo.NewMock([]table.Table{{ID: 1, Name: "Table 1", Status: table.InactiveStatus}}, nil)
-	command := Command{TableRepo: repo}
+	command := Command{Transactor: db.NewMockTransactor(), TableRepo: repo, EventRepo: event_repo.NewMock(nil, nil)}
 
-	err := command.ActivateTable(context.Background(), 1)
+	err := command.ActivateTable(context.Background(), 1, 1)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -91,9 +141,9 @@ func TestActivateTable(t *testing.T) {
 
 func TestActivateTable_NotFound(t *testing.T) {
 	repo := table_repo.NewMock([]table.Table{}, db.ErrNotFound)
-	command := Command{TableRepo: repo}
+	command := Command{Transactor: db.NewMockTransactor(), TableRepo: repo, EventRepo: event_repo.NewMock(nil, nil)}
 
-	err := command.ActivateTable(context.Background(), 999)
+	err := command.ActivateTable(context.Background(), 1, 999)
 	if err != ErrTableNotFound {
 		t.Fatalf("expected ErrTableNotFound, got %v", err)
 	}
@@ -101,9 +151,9 @@ func TestActivateTable_NotFound(t *testing.T) {
 
 func TestDeactivateTable(t *testing.T) {
 	repo := table_repo.NewMock([]table.Table{{ID: 1, Name: "Table 1", Status: table.ActiveStatus}}, nil)
-	command := Command{TableRepo: repo}
+	command := Command{Transactor: db.NewMockTransactor(), TableRepo: repo, EventRepo: event_repo.NewMock(nil, nil)}
 
-	err := command.DeactivateTable(context.Background(), 1)
+	err := command.DeactivateTable(context.Background(), 1, 1)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -119,10 +169,627 @@ func TestDeactivateTable(t *testing.T) {
 
 func TestDeactivateTable_NotFound(t *testing.T) {
 	repo := table_repo.NewMock([]table.Table{}, db.ErrNotFound)
-	command := Command{TableRepo: repo}
+	command := Command{Transactor: db.NewMockTransactor(), TableRepo: repo, EventRepo: event_repo.NewMock(nil, nil)}
 
-	err := command.DeactivateTable(context.Background(), 999)
+	err := command.DeactivateTable(context.Background(), 1, 999)
 	if err != ErrTableNotFound {
 		t.Fatalf("expected ErrTableNotFound, got %v", err)
 	}
 }
+
+func TestDeactivateTable_WritesAuditEvent(t *testing.T) {
+	ctx := context.Background()
+	repo := table_repo.NewMock([]table.Table{{ID: 1, Name: "Table 1", Status: table.ActiveStatus}}, nil)
+	eventRepo := event_repo.NewMock(nil, nil)
+	command := Command{Transactor: db.NewMockTransactor(), TableRepo: repo, EventRepo: eventRepo}
+
+	err := command.DeactivateTable(ctx, 7, 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	events, _ := eventRepo.ReadEvents(ctx, event.Filter{UserID: 7, Subject: audit.Subject(audit.TableEntity, "1")})
+	if len(events) != 1 || events[0].Type != string(audit.EventTypeTableDeactivatedV1) {
+		t.Fatalf("expected 1 table deactivated audit event, got %v", events)
+	}
+
+	// audit events must not show up as activity in the sessions of the table
+	sessionEvents, _ := eventRepo.ReadEventsBySubject(ctx, "table:1")
+	if len(sessionEvents) != 0 {
+		t.Errorf("expected no session events, got %d", len(sessionEvents))
+	}
+}
+
+func TestRegisterTableAmountPayment(t *testing.T) {
+	order, err := table.NewOrderPlacedEvent(1, 1, "", []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 2}})
+	if err != nil {
+		t.Fatalf("expected no error creating order event, got %v", err)
+	}
+	order.ID = 1
+	repo := event_repo.NewMock([]event.Event{order}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+
+	_, err = command.RegisterTableAmountPayment(context.Background(), 1, 1, "", 400)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	events, _ := repo.ReadEventsBySubject(context.Background(), "table:1")
+	balance, err := table.GetBalanceFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error calculating balance, got %v", err)
+	}
+	if balance != 400 {
+		t.Errorf("expected balance 400, got %d", balance)
+	}
+}
+
+func TestRegisterTableAmountPayment_ExceedsBalance(t *testing.T) {
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+
+	_, err := command.PlaceTableOrder(context.Background(), 1, 1, "", []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}})
+	if err != nil {
+		t.Fatalf("expected no error placing order, got %v", err)
+	}
+
+	_, err = command.RegisterTableAmountPayment(context.Background(), 1, 1, "", 500)
+	if err != ErrPaymentExceedsBalance {
+		t.Fatalf("expected ErrPaymentExceedsBalance, got %v", err)
+	}
+}
+
+func TestRegisterTablePayment_AfterAmountPayment(t *testing.T) {
+	ctx := context.Background()
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+
+	_, err := command.PlaceTableOrder(ctx, 1, 1, "", []table.OrderProduct{
+		{ID: 1, Name: "Bier", NetPriceCents: 300, Quantity: 1},
+		{ID: 2, Name: "Wein", NetPriceCents: 500, Quantity: 1},
+	})
+	if err != nil {
+		t.Fatalf("expected no error placing order, got %v", err)
+	}
+	if _, err := command.RegisterTableAmountPayment(ctx, 1, 1, "", 400); err != nil {
+		t.Fatalf("expected no error registering amount payment, got %v", err)
+	}
+
+	// Wein is still listed as unpaid, but only 400 of the balance are left
+	_, err = command.RegisterTablePayment(ctx, 1, 1, "", []table.PaymentProduct{{ID: 2, Name: "Wein", NetPriceCents: 500, Quantity: 1}})
+	if err != ErrPaymentExceedsBalance {
+		t.Fatalf("expected ErrPaymentExceedsBalance, got %v", err)
+	}
+
+	if _, err := command.RegisterTableAmountPayment(ctx, 1, 1, "", 400); err != nil {
+		t.Fatalf("expected no error paying the rest, got %v", err)
+	}
+	if err := command.CloseTable(ctx, 1, 1); err != nil {
+		t.Fatalf("expected no error closing table, got %v", err)
+	}
+}
+
+func TestPlaceTableOrder_OpensTable(t *testing.T) {
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+
+	_, err := command.PlaceTableOrder(context.Background(), 1, 1, "", []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	events, _ := repo.ReadEventsBySubject(context.Background(), "table:1")
+	if len(events) != 2 || events[0].Type != string(table.EventTypeTableOpenedV1) {
+		t.Fatalf("expected table opened event before order, got %+v", events)
+	}
+}
+
+func TestPlaceTableOrder_Retry(t *testing.T) {
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+	orderID := "0b9f3c4e-6d7a-4f6b-9a8e-2c1d5e7f9a10"
+	products := []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}}
+
+	for range 2 {
+		id, err := command.PlaceTableOrder(context.Background(), 1, 1, orderID, products)
+		if err != nil || id != orderID {
+			t.Fatalf("expected order %s, got %s (%v)", orderID, id, err)
+		}
+	}
+
+	events, _ := repo.ReadEventsBySubject(context.Background(), "table:1")
+	if len(events) != 2 {
+		t.Fatalf("expected table opened and one order placed event, got %d events", len(events))
+	}
+
+	if _, err := command.PlaceTableOrder(context.Background(), 1, 1, "not-a-uuid", products); !errors.Is(err, ErrInvalidOrderData) {
+		t.Fatalf("expected ErrInvalidOrderData, got %v", err)
+	}
+}
+
+func TestRegisterTablePayment_Retry(t *testing.T) {
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+	paymentID := "0b9f3c4e-6d7a-4f6b-9a8e-2c1d5e7f9a10"
+
+	_, err := command.PlaceTableOrder(context.Background(), 1, 1, "", []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}})
+	if err != nil {
+		t.Fatalf("expected no error placing order, got %v", err)
+	}
+
+	products := []table.PaymentProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}}
+	if _, err := command.RegisterTablePayment(context.Background(), 1, 1, paymentID, products); err != nil {
+		t.Fatalf("expected no error registering payment, got %v", err)
+	}
+	if err := command.CloseTable(context.Background(), 1, 1); err != nil {
+		t.Fatalf("expected no error closing table, got %v", err)
+	}
+
+	// the retry arrives after the table was closed
+	id, err := command.RegisterTablePayment(context.Background(), 1, 1, paymentID, products)
+	if err != nil || id != paymentID {
+		t.Fatalf("expected payment %s, got %s (%v)", paymentID, id, err)
+	}
+}
+
+func TestRegisterTablePayment_Conflict(t *testing.T) {
+	repo := event_repo.NewMock([]event.Event{}, db.ErrSerializationFailure)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+
+	_, err := command.RegisterTablePayment(context.Background(), 1, 1, "", []table.PaymentProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}})
+	if err != ErrRetryLater {
+		t.Fatalf("expected ErrRetryLater, got %v", err)
+	}
+}
+
+func TestCloseTable_OpenBalance(t *testing.T) {
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+
+	_, err := command.PlaceTableOrder(context.Background(), 1, 1, "", []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}})
+	if err != nil {
+		t.Fatalf("expected no error placing order, got %v", err)
+	}
+
+	err = command.CloseTable(context.Background(), 1, 1)
+	if err != ErrOpenBalance {
+		t.Fatalf("expected ErrOpenBalance, got %v", err)
+	}
+
+	err = command.ForceCloseTable(context.Background(), 1, 1, table.UnpaidWriteOff, "Gäste ohne Bezahlung gegangen")
+	if err != nil {
+		t.Fatalf("expected no error force closing table, got %v", err)
+	}
+
+	events, _ := repo.ReadEventsBySubject(context.Background(), "table:1")
+	writeOffs, err := table.GetWriteOffsFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error reading write-offs, got %v", err)
+	}
+	if len(writeOffs) != 1 || writeOffs[0].Category != table.UnpaidWriteOff || writeOffs[0].TotalWriteOffCents != 400 {
+		t.Errorf("expected unpaid write-off of 400 cents, got %+v", writeOffs)
+	}
+
+	_, err = command.RegisterTableAmountPayment(context.Background(), 1, 1, "", 400)
+	if err != ErrTableNotOpen {
+		t.Fatalf("expected ErrTableNotOpen, got %v", err)
+	}
+}
+
+func TestForceCloseTable_AfterAmountPayment(t *testing.T) {
+	ctx := context.Background()
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+
+	_, err := command.PlaceTableOrder(ctx, 1, 1, "", []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 300, Quantity: 1}})
+	if err != nil {
+		t.Fatalf("expected no error placing order, got %v", err)
+	}
+	if _, err := command.RegisterTableAmountPayment(ctx, 1, 1, "", 100); err != nil {
+		t.Fatalf("expected no error registering amount payment, got %v", err)
+	}
+	if err := command.ForceCloseTable(ctx, 1, 1, table.UnpaidWriteOff, "Gäste ohne Bezahlung gegangen"); err != nil {
+		t.Fatalf("expected no error force closing table, got %v", err)
+	}
+
+	events, _ := repo.ReadEventsBySubject(ctx, "table:1")
+	writeOffs, err := table.GetWriteOffsFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error reading write-offs, got %v", err)
+	}
+	if len(writeOffs) != 1 || writeOffs[0].TotalWriteOffCents != 200 || writeOffs[0].CreditCents != 100 {
+		t.Fatalf("expected write-off of the open balance of 200 cents, got %+v", writeOffs)
+	}
+
+	sessions, err := table.GetSessionsFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error building sessions, got %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].BalanceCents != 0 {
+		t.Fatalf("expected closed session without balance, got %+v", sessions)
+	}
+}
+
+func TestWriteOffTableItems_AfterAmountPayment(t *testing.T) {
+	ctx := context.Background()
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+
+	_, err := command.PlaceTableOrder(ctx, 1, 1, "", []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 300, Quantity: 2}})
+	if err != nil {
+		t.Fatalf("expected no error placing order, got %v", err)
+	}
+	if _, err := command.RegisterTableAmountPayment(ctx, 1, 1, "", 100); err != nil {
+		t.Fatalf("expected no error registering amount payment, got %v", err)
+	}
+
+	// the first Bier is covered by the balance, the credit is only recorded against the second one
+	for _, expected := range []int{300, 200} {
+		err := command.WriteOffTableItems(ctx, 1, 1, table.BreakageWriteOff, "Glas umgefallen", []table.WriteOffProduct{{ID: 1, Name: "Bier", NetPriceCents: 300, Quantity: 1}})
+		if err != nil {
+			t.Fatalf("expected no error writing off items, got %v", err)
+		}
+
+		events, _ := repo.ReadEventsBySubject(ctx, "table:1")
+		writeOffs, _ := table.GetWriteOffsFromEvents(events)
+		if last := writeOffs[len(writeOffs)-1]; last.TotalWriteOffCents != expected {
+			t.Fatalf("expected write-off of %d cents, got %+v", expected, last)
+		}
+	}
+
+	if err := command.CloseTable(ctx, 1, 1); err != nil {
+		t.Fatalf("expected no error closing table after write-offs, got %v", err)
+	}
+}
+
+func TestForceCloseTable_InvalidCategory(t *testing.T) {
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+
+	_, err := command.PlaceTableOrder(context.Background(), 1, 1, "", []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}})
+	if err != nil {
+		t.Fatalf("expected no error placing order, got %v", err)
+	}
+
+	err = command.ForceCloseTable(context.Background(), 1, 1, "", "Gäste ohne Bezahlung gegangen")
+	if !errors.Is(err, ErrInvalidWriteOffData) {
+		t.Fatalf("expected ErrInvalidWriteOffData, got %v", err)
+	}
+}
+
+func TestReopenTable(t *testing.T) {
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+
+	if err := command.ReopenTable(context.Background(), 1, 1); err != ErrSessionNotFound {
+		t.Fatalf("expected ErrSessionNotFound, got %v", err)
+	}
+	if err := command.OpenTable(context.Background(), 1, 1); err != nil {
+		t.Fatalf("expected no error opening table, got %v", err)
+	}
+	if err := command.OpenTable(context.Background(), 1, 1); err != ErrTableAlreadyOpen {
+		t.Fatalf("expected ErrTableAlreadyOpen, got %v", err)
+	}
+	if err := command.CloseTable(context.Background(), 1, 1); err != nil {
+		t.Fatalf("expected no error closing table, got %v", err)
+	}
+	if err := command.ReopenTable(context.Background(), 1, 1); err != nil {
+		t.Fatalf("expected no error reopening table, got %v", err)
+	}
+
+	events, _ := repo.ReadEventsBySubject(context.Background(), "table:1")
+	sessions, err := table.GetSessionsFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error building sessions, got %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].Openings() != 2 {
+		t.Errorf("expected one session opened twice, got %+v", sessions)
+	}
+}
+
+func TestOpenTable_Conflict(t *testing.T) {
+	repo := event_repo.NewMock([]event.Event{}, db.ErrSerializationFailure)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+
+	if err := command.OpenTable(context.Background(), 1, 1); err != ErrRetryLater {
+		t.Fatalf("expected ErrRetryLater, got %v", err)
+	}
+}
+
+func TestWriteOffTableItems(t *testing.T) {
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+
+	_, err := command.PlaceTableOrder(context.Background(), 1, 1, "", []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 2}})
+	if err != nil {
+		t.Fatalf("expected no error placing order, got %v", err)
+	}
+
+	err = command.WriteOffTableItems(context.Background(), 1, 1, table.BreakageWriteOff, "", []table.WriteOffProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 3}})
+	if err != ErrItemsNotUnpaid {
+		t.Fatalf("expected ErrItemsNotUnpaid, got %v", err)
+	}
+
+	err = command.WriteOffTableItems(context.Background(), 1, 1, table.BreakageWriteOff, "Glas umgefallen", []table.WriteOffProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 2}})
+	if err != nil {
+		t.Fatalf("expected no error writing off items, got %v", err)
+	}
+
+	err = command.CloseTable(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatalf("expected no error closing table after write-off, got %v", err)
+	}
+}
+
+func TestWriteOffTableItems_InvalidCategory(t *testing.T) {
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+
+	err := command.WriteOffTableItems(context.Background(), 1, 1, "lost", "", []table.WriteOffProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}})
+	if !errors.Is(err, ErrInvalidWriteOffData) {
+		t.Fatalf("expected ErrInvalidWriteOffData, got %v", err)
+	}
+}
+
+func TestReversePayment(t *testing.T) {
+	order, err := table.NewOrderPlacedEvent(1, 1, "", []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 2}})
+	if err != nil {
+		t.Fatalf("expected no error creating order event, got %v", err)
+	}
+	order.ID = 1
+	payment, err := table.NewPaymentRegisteredEvent(1, 1, "", []table.PaymentProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 2}})
+	if err != nil {
+		t.Fatalf("expected no error creating payment event, got %v", err)
+	}
+	payment.ID = 2
+	payment.Time = time.Now().Add(-time.Hour)
+	repo := event_repo.NewMock([]event.Event{order, payment}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+
+	events, _ := repo.ReadEventsBySubject(context.Background(), "table:1")
+	payments, _ := table.GetPaymentsFromEvents(events)
+	paymentID := payments[0].ID
+
+	err = command.ReversePayment(context.Background(), 1, false, 1, paymentID, "Falscher Tisch")
+	if err != ErrReversalNotAllowed {
+		t.Fatalf("expected ErrReversalNotAllowed after reversal window, got %v", err)
+	}
+
+	err = command.ReversePayment(context.Background(), 2, true, 1, paymentID, "Falscher Tisch")
+	if err != nil {
+		t.Fatalf("expected no error reversing payment as admin, got %v", err)
+	}
+
+	err = command.ReversePayment(context.Background(), 2, true, 1, paymentID, "Falscher Tisch")
+	if err != ErrPaymentAlreadyReversed {
+		t.Fatalf("expected ErrPaymentAlreadyReversed, got %v", err)
+	}
+
+	events, _ = repo.ReadEventsBySubject(context.Background(), "table:1")
+	balance, err := table.GetBalanceFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error calculating balance, got %v", err)
+	}
+	if balance != 800 {
+		t.Errorf("expected balance 800 after reversal, got %d", balance)
+	}
+}
+
+func TestReversePayment_ClosedSession(t *testing.T) {
+	ctx := context.Background()
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+	products := []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}}
+	paid := []table.PaymentProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}}
+
+	if _, err := command.PlaceTableOrder(ctx, 1, 1, "", products); err != nil {
+		t.Fatalf("expected no error placing order, got %v", err)
+	}
+	paymentID, err := command.RegisterTablePayment(ctx, 1, 1, "", paid)
+	if err != nil {
+		t.Fatalf("expected no error registering payment, got %v", err)
+	}
+	if err := command.CloseTable(ctx, 1, 1); err != nil {
+		t.Fatalf("expected no error closing table, got %v", err)
+	}
+
+	if err := command.ReversePayment(ctx, 1, true, 1, paymentID, "Falscher Tisch"); err != nil {
+		t.Fatalf("expected no error reversing payment of closed session, got %v", err)
+	}
+
+	events, _ := repo.ReadEventsBySubject(ctx, "table:1")
+	sessions, err := table.GetSessionsFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].Status != table.SessionOpenStatus || sessions[0].BalanceCents != 400 {
+		t.Fatalf("expected the closed session to be reopened with the reversed balance, got %+v", sessions)
+	}
+
+	// the products of the reversed payment can be paid again
+	if _, err := command.RegisterTablePayment(ctx, 1, 1, "", paid); err != nil {
+		t.Fatalf("expected no error paying again, got %v", err)
+	}
+	if err := command.CloseTable(ctx, 1, 1); err != nil {
+		t.Fatalf("expected no error closing table again, got %v", err)
+	}
+}
+
+func TestReversePayment_EarlierSession(t *testing.T) {
+	ctx := context.Background()
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+
+	if _, err := command.PlaceTableOrder(ctx, 1, 1, "", []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}}); err != nil {
+		t.Fatalf("expected no error placing order, got %v", err)
+	}
+	paymentID, err := command.RegisterTablePayment(ctx, 1, 1, "", []table.PaymentProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}})
+	if err != nil {
+		t.Fatalf("expected no error registering payment, got %v", err)
+	}
+	if err := command.CloseTable(ctx, 1, 1); err != nil {
+		t.Fatalf("expected no error closing table, got %v", err)
+	}
+	if err := command.OpenTable(ctx, 1, 1); err != nil {
+		t.Fatalf("expected no error opening table again, got %v", err)
+	}
+
+	err = command.ReversePayment(ctx, 1, true, 1, paymentID, "Falscher Tisch")
+	if err != ErrPaymentOfEarlierSession {
+		t.Fatalf("expected ErrPaymentOfEarlierSession, got %v", err)
+	}
+}
+
+func TestReversePayment_NotFound(t *testing.T) {
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+
+	_, err := command.PlaceTableOrder(context.Background(), 1, 1, "", []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}})
+	if err != nil {
+		t.Fatalf("expected no error placing order, got %v", err)
+	}
+
+	err = command.ReversePayment(context.Background(), 1, true, 1, "8f14e45f-ceea-4e6b-9a5e-0c1d2e3f4a5b", "Falscher Tisch")
+	if err != ErrPaymentNotFound {
+		t.Fatalf("expected ErrPaymentNotFound, got %v", err)
+	}
+}
+
+func TestSync(t *testing.T) {
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	products := product_repo.NewMock([]product.Product{
+		{ID: 1, Name: "Bier", NetPriceCents: 400, Status: product.ActiveStatus},
+		{ID: 2, Name: "Wein", NetPriceCents: 600, Status: product.InactiveStatus},
+	}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo, ProductRepo: products, PeriodRepo: period_repo.NewMock([]period.Period{}, nil), MaxSyncItemAge: 24 * time.Hour}
+	permissions := SyncPermissions{PlaceOrders: true, RegisterPayments: true}
+	clientTime := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+
+	items := []SyncItem{
+		{Type: SyncPlaceOrder, ID: "0b9f3c4e-6d7a-4f6b-9a8e-2c1d5e7f9a10", TableID: 1, ClientTime: clientTime, Products: []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}}},
+		{Type: SyncPlaceOrder, ID: "1c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4e5f", TableID: 1, ClientTime: clientTime, Products: []table.OrderProduct{{ID: 2, Name: "Wein", NetPriceCents: 600, Quantity: 1}}},
+		{Type: SyncRegisterPayment, ID: "2d3e4f5a-6b7c-4d8e-9f0a-1b2c3d4e5f6a", TableID: 1, ClientTime: clientTime, Products: []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 2}}},
+		{Type: SyncRegisterPayment, ID: "3e4f5a6b-7c8d-4e9f-8a1b-2c3d4e5f6a7b", TableID: 1, ClientTime: clientTime, Products: []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}}},
+	}
+
+	results, events, err := command.Sync(context.Background(), 1, permissions, items, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []struct {
+		status SyncStatus
+		err    error
+	}{{SyncApplied, nil}, {SyncRejected, ErrProductInactive}, {SyncRejected, ErrItemsNotUnpaid}, {SyncApplied, nil}}
+	for i, e := range expected {
+		if results[i].Status != e.status || results[i].Err != e.err {
+			t.Errorf("expected item %d to be %s (%v), got %s (%v)", i, e.status, e.err, results[i].Status, results[i].Err)
+		}
+	}
+
+	// table opened, order placed and payment registered
+	if len(events) != 3 || !events[1].Time.Equal(clientTime) {
+		t.Fatalf("expected 3 events with client time for the order, got %+v", events)
+	}
+
+	// uploading the batch again books nothing new
+	results, events, err = command.Sync(context.Background(), 1, permissions, items, events[2].ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if results[0].Status != SyncApplied || results[3].Status != SyncApplied || len(events) != 0 {
+		t.Fatalf("expected applied items without new events, got %+v and %d events", results, len(events))
+	}
+}
+
+func TestSync_AmountPayment(t *testing.T) {
+	ctx := context.Background()
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo, PeriodRepo: period_repo.NewMock([]period.Period{}, nil), MaxSyncItemAge: 24 * time.Hour}
+	permissions := SyncPermissions{PlaceOrders: true, RegisterPayments: true}
+
+	if _, err := command.PlaceTableOrder(ctx, 1, 1, "", []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 2}}); err != nil {
+		t.Fatalf("expected no error placing order, got %v", err)
+	}
+
+	items := []SyncItem{
+		{Type: SyncRegisterAmountPayment, ID: "4f5a6b7c-8d9e-4f0a-9b1c-2d3e4f5a6b7c", TableID: 1, AmountCents: 500},
+		{Type: SyncRegisterAmountPayment, ID: "5a6b7c8d-9e0f-4a1b-8c2d-3e4f5a6b7c8d", TableID: 1, AmountCents: 500},
+	}
+	results, _, err := command.Sync(ctx, 1, permissions, items, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if results[0].Status != SyncApplied || results[1].Status != SyncRejected || results[1].Err != ErrPaymentExceedsBalance {
+		t.Fatalf("expected first amount payment applied and second rejected, got %+v", results)
+	}
+
+	// uploading the first item again books nothing new
+	results, _, _ = command.Sync(ctx, 1, permissions, items[:1], 0)
+	events, _ := repo.ReadEventsBySubject(ctx, "table:1")
+	balance, _ := table.GetBalanceFromEvents(events)
+	if results[0].Status != SyncApplied || balance != 300 {
+		t.Fatalf("expected amount payment to be booked once, got %+v and balance %d", results, balance)
+	}
+}
+
+func TestSync_ItemTooOld(t *testing.T) {
+	ctx := context.Background()
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	openedAt := time.Now().UTC().Add(-2 * time.Hour)
+	periods := period_repo.NewMock([]period.Period{{ID: 1, Name: "Sommerfest", Status: period.OpenStatus, OpenedAt: openedAt}}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo, ProductRepo: product_repo.NewMock([]product.Product{{ID: 1, Name: "Bier", NetPriceCents: 400, Status: product.ActiveStatus}}, nil), PeriodRepo: periods, MaxSyncItemAge: 24 * time.Hour}
+	permissions := SyncPermissions{PlaceOrders: true, RegisterPayments: true}
+	products := []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}}
+
+	placed, err := command.PlaceTableOrder(ctx, 1, 1, "6b7c8d9e-0f1a-4b2c-9d3e-4f5a6b7c8d9e", products)
+	if err != nil {
+		t.Fatalf("expected no error placing order, got %v", err)
+	}
+
+	items := []SyncItem{
+		// before the open period began
+		{Type: SyncPlaceOrder, ID: "7c8d9e0f-1a2b-4c3d-8e4f-5a6b7c8d9e0f", TableID: 1, ClientTime: openedAt.Add(-time.Minute), Products: products},
+		// older than the window
+		{Type: SyncPlaceOrder, ID: "8d9e0f1a-2b3c-4d4e-9f5a-6b7c8d9e0f1a", TableID: 1, ClientTime: time.Now().Add(-48 * time.Hour), Products: products},
+		// already booked orders stay applied
+		{Type: SyncPlaceOrder, ID: placed, TableID: 1, ClientTime: time.Now().Add(-48 * time.Hour), Products: products},
+		{Type: SyncPlaceOrder, ID: "9e0f1a2b-3c4d-4e5f-8a6b-7c8d9e0f1a2b", TableID: 1, ClientTime: openedAt.Add(time.Minute), Products: products},
+	}
+	results, _, err := command.Sync(ctx, 1, permissions, items, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []SyncStatus{SyncRejected, SyncRejected, SyncApplied, SyncApplied}
+	for i, status := range expected {
+		if results[i].Status != status {
+			t.Errorf("expected item %d to be %s, got %s (%v)", i, status, results[i].Status, results[i].Err)
+		}
+	}
+	if results[0].Err != ErrSyncItemTooOld || results[1].Err != ErrSyncItemTooOld {
+		t.Errorf("expected ErrSyncItemTooOld, got %v and %v", results[0].Err, results[1].Err)
+	}
+}
+
+func TestSync_PendingAfterServerError(t *testing.T) {
+	repo := event_repo.NewMock([]event.Event{}, db.ErrDatabase)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo, ProductRepo: product_repo.NewMock([]product.Product{}, nil), PeriodRepo: period_repo.NewMock([]period.Period{}, nil), MaxSyncItemAge: 24 * time.Hour}
+	permissions := SyncPermissions{PlaceOrders: true, RegisterPayments: true}
+
+	items := []SyncItem{
+		{Type: SyncPlaceOrder, ID: "0b9f3c4e-6d7a-4f6b-9a8e-2c1d5e7f9a10", TableID: 1, Products: []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}}},
+		{Type: "unknown", ID: "1c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4e5f", TableID: 1},
+	}
+
+	results, _, _ := command.Sync(context.Background(), 1, permissions, items, 0)
+	for _, result := range results {
+		if result.Status != SyncPending {
+			t.Errorf("expected item %s to stay pending, got %s", result.ID, result.Status)
+		}
+	}
+
+	if _, _, err := command.Sync(context.Background(), 1, permissions, make([]SyncItem, MaxSyncItems+1), 0); err != ErrInvalidSyncBatch {
+		t.Fatalf("expected ErrInvalidSyncBatch, got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package application
 import (
 	"errors"
 
+	"github.com/nicograef/jotti/backend/api/command"
 	"github.com/nicograef/jotti/backend/db"
 	"github.com/rs/zerolog"
 )
@@ -14,11 +15,86 @@ var ErrTableNotFound = errors.New("table not found")
 var ErrTableAlreadyExists = errors.New("table already exists")
 
 // ErrDatabase is returned when there is a database error.
-var ErrDatabase = errors.New("database error")
+var ErrDatabase = command.ErrDatabase
+
+// ErrInvalidReference is returned when a table references a record that does not exist (anymore).
+var ErrInvalidReference = errors.New("invalid reference")
+
+// ErrRetryLater is returned when a change conflicted with a concurrent change or the database was not reachable.
+var ErrRetryLater = command.ErrRetryLater
 
 // ErrInvalidTableData is returned when the provided table data is invalid.
 var ErrInvalidTableData = errors.New("invalid table data")
 
+// ErrInvalidOrderData is returned when the provided order data is invalid.
+var ErrInvalidOrderData = errors.New("invalid order data")
+
+// ErrIdempotencyKeyReused is returned when an order or payment ID was already used for another table.
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused")
+
+// ErrInvalidPaymentData is returned when the provided payment data is invalid.
+var ErrInvalidPaymentData = errors.New("invalid payment data")
+
+// ErrPaymentExceedsBalance is returned when a payment is higher than the open balance of a table.
+var ErrPaymentExceedsBalance = errors.New("payment exceeds balance")
+
+// ErrInvalidSplit is returned when a balance cannot be split across the requested number of payers.
+var ErrInvalidSplit = errors.New("invalid split")
+
+// ErrSessionNotFound is returned when a session of a table does not exist.
+var ErrSessionNotFound = errors.New("session not found")
+
+// ErrTableNotOpen is returned when a table has no open session.
+var ErrTableNotOpen = errors.New("table not open")
+
+// ErrTableAlreadyOpen is returned when a table that already has an open session is opened.
+var ErrTableAlreadyOpen = errors.New("table already open")
+
+// ErrOpenBalance is returned when a table with an open balance is closed without writing off the balance.
+var ErrOpenBalance = errors.New("table has open balance")
+
+// ErrInvalidWriteOffReason is returned when an open balance is written off without a valid reason.
+var ErrInvalidWriteOffReason = errors.New("invalid write-off reason")
+
+// ErrInvalidWriteOffData is returned when the provided write-off data is invalid.
+var ErrInvalidWriteOffData = errors.New("invalid write-off data")
+
+// ErrItemsNotUnpaid is returned when items are written off that are not open on the table.
+var ErrItemsNotUnpaid = errors.New("items not unpaid")
+
+// ErrPaymentNotFound is returned when a payment does not exist in the current session of a table.
+var ErrPaymentNotFound = errors.New("payment not found")
+
+// ErrPaymentAlreadyReversed is returned when a payment that was already reversed is reversed again.
+var ErrPaymentAlreadyReversed = errors.New("payment already reversed")
+
+// ErrPaymentOfEarlierSession is returned when a payment of a session is reversed after a later session was started.
+var ErrPaymentOfEarlierSession = errors.New("payment of earlier session")
+
+// ErrReversalNotAllowed is returned when a user may not reverse a payment.
+var ErrReversalNotAllowed = errors.New("reversal not allowed")
+
+// ErrInvalidReversalReason is returned when a payment is reversed without a valid reason.
+var ErrInvalidReversalReason = errors.New("invalid reversal reason")
+
+// ErrInvalidSyncBatch is returned when a sync batch has too many items.
+var ErrInvalidSyncBatch = errors.New("invalid sync batch")
+
+// ErrInvalidSyncItem is returned when a sync item has no ID or an unknown type.
+var ErrInvalidSyncItem = errors.New("invalid sync item")
+
+// ErrSyncNotAllowed is returned when a user uploads a sync item without the permission for its command.
+var ErrSyncNotAllowed = errors.New("sync item not allowed")
+
+// ErrSyncItemTooOld is returned when a sync item was entered too long ago or before the open period began.
+var ErrSyncItemTooOld = errors.New("sync item too old")
+
+// ErrProductNotFound is returned when an ordered product does not exist.
+var ErrProductNotFound = errors.New("product not found")
+
+// ErrProductInactive is returned when an ordered product is no longer active.
+var ErrProductInactive = errors.New("product inactive")
+
 func fromRepositoryError(err error, log *zerolog.Logger, id int) error {
 	if errors.Is(err, db.ErrNotFound) {
 		log.Warn().Err(err).Int("table_id", id).Msg("Table not found")
@@ -30,6 +106,26 @@ func fromRepositoryError(err error, log *zerolog.Logger, id int) error {
 		return ErrTableAlreadyExists
 	}
 
+	if errors.Is(err, db.ErrForeignKeyViolation) {
+		log.Warn().Err(err).Int("table_id", id).Str("constraint", db.Constraint(err)).Msg("Table references missing record")
+		return ErrInvalidReference
+	}
+
+	if errors.Is(err, db.ErrCheckViolation) {
+		log.Warn().Err(err).Int("table_id", id).Str("constraint", db.Constraint(err)).Msg("Invalid table data")
+		return ErrInvalidTableData
+	}
+
+	if db.IsTemporary(err) {
+		log.Warn().Err(err).Int("table_id", id).Msg("Temporary database error")
+		return ErrRetryLater
+	}
+
+	if errors.Is(err, db.ErrCanceled) {
+		log.Warn().Err(err).Int("table_id", id).Msg("Database query canceled")
+		return ErrDatabase
+	}
+
 	log.Error().Err(err).Int("table_id", id).Msg("Database error")
 	return ErrDatabase
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/nicograef/jotti/backend/db"
 	e "github.com/nicograef/jotti/backend/domain/event"
 	t "github.com/nicograef/jotti/backend/domain/table"
+	"github.com/nicograef/jotti/backend/tracing"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -15,7 +16,7 @@ import (
 type tableRepoQuery interface {
 	GetTable(ctx context.Context, id int) (t.Table, error)
 	GetAllTables(ctx context.Context) ([]t.Table, error)
-	GetActiveTables(ctx context.Context) ([]t.Table, error)
+	GetActiveTables(ctx context.Context, periodID int) ([]t.Table, error)
 }
 
 type eventRepoQuery interface {
@@ -23,11 +24,15 @@ type eventRepoQuery interface {
 }
 
 type Query struct {
-	TableRepo tableRepoQuery
-	EventRepo eventRepoQuery
+	TableRepo  tableRepoQuery
+	EventRepo  eventRepoQuery
+	PeriodRepo periodRepo
 }
 
 func (q Query) GetTable(ctx context.Context, id int) (t.Table, error) {
+	ctx, span := tracing.Start(ctx, "table.GetTable")
+	defer span.End()
+
 	log := zerolog.Ctx(ctx)
 
 	table, err := q.TableRepo.GetTable(ctx, id)
@@ -46,6 +51,9 @@ func (q Query) GetTable(ctx context.Context, id int) (t.Table, error) {
 }
 
 func (q Query) GetAllTables(ctx context.Context) ([]t.Table, error) {
+	ctx, span := tracing.Start(ctx, "table.GetAllTables")
+	defer span.End()
+
 	log := zerolog.Ctx(ctx)
 
 	tables, err := q.TableRepo.GetAllTables(ctx)
@@ -58,10 +66,21 @@ func (q Query) GetAllTables(ctx context.Context) ([]t.Table, error) {
 	return tables, nil
 }
 
+// GetActiveTables returns the active tables of the open period, including tables without a period.
+// If no period is open, active tables of all periods are returned.
 func (q Query) GetActiveTables(ctx context.Context) ([]t.Table, error) {
+	ctx, span := tracing.Start(ctx, "table.GetActiveTables")
+	defer span.End()
+
 	log := zerolog.Ctx(ctx)
 
-	tables, err := q.TableRepo.GetActiveTables(ctx)
+	periodID, err := q.PeriodRepo.GetOpenPeriodID(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get open period")
+		return nil, ErrDatabase
+	}
+
+	tables, err := q.TableRepo.GetActiveTables(ctx, periodID)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to retrieve active tables")
 		return nil, ErrDatabase
@@ -71,14 +90,97 @@ func (q Query) GetActiveTables(ctx context.Context) ([]t.Table, error) {
 	return tables, nil
 }
 
-func (q Query) GetTableBalance(ctx context.Context, tableID int) (int, error) {
+// OpenTables is the number of tables with an open session and the sum of their unpaid balances.
+type OpenTables struct {
+	Count        int
+	BalanceCents int
+}
+
+// GetOpenTables sums up the current sessions of the active tables of the open period.
+func (q Query) GetOpenTables(ctx context.Context) (OpenTables, error) {
+	ctx, span := tracing.Start(ctx, "table.GetOpenTables")
+	defer span.End()
+
+	tables, err := q.GetActiveTables(ctx)
+	if err != nil {
+		return OpenTables{}, err
+	}
+
+	open := OpenTables{}
+	for _, table := range tables {
+		sessions, err := q.GetTableSessions(ctx, table.ID)
+		if err != nil {
+			return OpenTables{}, err
+		}
+
+		if current, ok := t.GetCurrentSession(sessions); ok {
+			open.Count++
+			open.BalanceCents += current.BalanceCents
+		}
+	}
+
+	return open, nil
+}
+
+// GetTableSessions returns all sessions (guest visits) of a table, the oldest first.
+func (q Query) GetTableSessions(ctx context.Context, tableID int) ([]t.Session, error) {
+	ctx, span := tracing.Start(ctx, "table.GetTableSessions")
+	defer span.End()
+
 	logger := zerolog.Ctx(ctx)
 
 	subject := "table:" + strconv.Itoa(tableID)
 	events, err := q.EventRepo.ReadEventsBySubject(ctx, subject)
 	if err != nil {
-		logger.Error().Err(err).Int("table_id", tableID).Msg("Failed to read order events for table")
-		return 0, ErrDatabase
+		logger.Error().Err(err).Int("table_id", tableID).Msg("Failed to read events for table")
+		return []t.Session{}, ErrDatabase
+	}
+
+	sessions, err := t.GetSessionsFromEvents(events)
+	if err != nil {
+		logger.Error().Err(err).Int("table_id", tableID).Msg("Failed to build sessions from events")
+		return []t.Session{}, err
+	}
+
+	logger.Info().Int("table_id", tableID).Int("session_count", len(sessions)).Msg("Retrieved sessions for table")
+	return sessions, nil
+}
+
+// readSessionEvents returns the events of the given session of a table.
+// Session 0 refers to the current session, which has no events if the table is not open.
+func (q Query) readSessionEvents(ctx context.Context, tableID, session int) ([]e.Event, error) {
+	logger := zerolog.Ctx(ctx)
+
+	sessions, err := q.GetTableSessions(ctx, tableID)
+	if err != nil {
+		return []e.Event{}, err
+	}
+
+	if session == 0 {
+		current, ok := t.GetCurrentSession(sessions)
+		if !ok {
+			return []e.Event{}, nil
+		}
+		return current.Events, nil
+	}
+
+	if session < 0 || session > len(sessions) {
+		logger.Warn().Int("table_id", tableID).Int("session", session).Msg("Session not found")
+		return []e.Event{}, ErrSessionNotFound
+	}
+
+	return sessions[session-1].Events, nil
+}
+
+func (q Query) GetTableBalance(ctx context.Context, tableID, session int) (int, error) {
+	ctx, span := tracing.Start(ctx, "table.GetTableBalance")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx)
+
+	events, err := q.readSessionEvents(ctx, tableID, session)
+	if err != nil {
+		return 0, err
 	}
 
 	balanceCents, err := t.GetBalanceFromEvents(events)
@@ -91,14 +193,15 @@ func (q Query) GetTableBalance(ctx context.Context, tableID int) (int, error) {
 	return balanceCents, nil
 }
 
-func (q Query) GetTableOrders(ctx context.Context, tableID int) ([]t.Order, error) {
+func (q Query) GetTableOrders(ctx context.Context, tableID, session int) ([]t.Order, error) {
+	ctx, span := tracing.Start(ctx, "table.GetTableOrders")
+	defer span.End()
+
 	logger := zerolog.Ctx(ctx)
 
-	subject := "table:" + strconv.Itoa(tableID)
-	events, err := q.EventRepo.ReadEventsBySubject(ctx, subject)
+	events, err := q.readSessionEvents(ctx, tableID, session)
 	if err != nil {
-		logger.Error().Int("table_id", tableID).Msg("Failed to read order events for table")
-		return []t.Order{}, ErrDatabase
+		return []t.Order{}, err
 	}
 
 	orders, err := t.GetOrdersFromEvents(events)
@@ -111,14 +214,15 @@ func (q Query) GetTableOrders(ctx context.Context, tableID int) ([]t.Order, erro
 	return orders, nil
 }
 
-func (q Query) GetTablePayments(ctx context.Context, tableID int) ([]t.Payment, error) {
+func (q Query) GetTablePayments(ctx context.Context, tableID, session int) ([]t.Payment, error) {
+	ctx, span := tracing.Start(ctx, "table.GetTablePayments")
+	defer span.End()
+
 	logger := zerolog.Ctx(ctx)
 
-	subject := "table:" + strconv.Itoa(tableID)
-	events, err := q.EventRepo.ReadEventsBySubject(ctx, subject)
+	events, err := q.readSessionEvents(ctx, tableID, session)
 	if err != nil {
-		logger.Error().Int("table_id", tableID).Msg("Failed to read payment events for table")
-		return []t.Payment{}, ErrDatabase
+		return []t.Payment{}, err
 	}
 
 	payments, err := t.GetPaymentsFromEvents(events)
@@ -131,14 +235,15 @@ func (q Query) GetTablePayments(ctx context.Context, tableID int) ([]t.Payment,
 	return payments, nil
 }
 
-func (q Query) GetTableUnpaidProducts(ctx context.Context, tableID int) ([]t.OrderProduct, error) {
+func (q Query) GetTableUnpaidProducts(ctx context.Context, tableID, session int) ([]t.OrderProduct, error) {
+	ctx, span := tracing.Start(ctx, "table.GetTableUnpaidProducts")
+	defer span.End()
+
 	logger := zerolog.Ctx(ctx)
 
-	subject := "table:" + strconv.Itoa(tableID)
-	events, err := q.EventRepo.ReadEventsBySubject(ctx, subject)
+	events, err := q.readSessionEvents(ctx, tableID, session)
 	if err != nil {
-		logger.Error().Int("table_id", tableID).Msg("Failed to read order events for table")
-		return []t.OrderProduct{}, ErrDatabase
+		return []t.OrderProduct{}, err
 	}
 
 	unpaidProducts, err := t.GetUnpaidProductsFromEvents(events)
@@ -150,3 +255,25 @@ func (q Query) GetTableUnpaidProducts(ctx context.Context, tableID int) ([]t.Ord
 	log.Info().Int("table_id", tableID).Int("unpaid_product_count", len(unpaidProducts)).Msg("Retrieved unpaid products for table")
 	return unpaidProducts, nil
 }
+
+// GetTableSplit splits the open balance of the current session of a table evenly across the given number of payers.
+func (q Query) GetTableSplit(ctx context.Context, tableID, parts int) ([]int, error) {
+	ctx, span := tracing.Start(ctx, "table.GetTableSplit")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx)
+
+	balanceCents, err := q.GetTableBalance(ctx, tableID, 0)
+	if err != nil {
+		return []int{}, err
+	}
+
+	sharesCents, err := t.SplitAmount(balanceCents, parts)
+	if err != nil {
+		logger.Warn().Err(err).Int("table_id", tableID).Int("parts", parts).Msg("Invalid split of table balance")
+		return []int{}, ErrInvalidSplit
+	}
+
+	logger.Info().Int("table_id", tableID).Int("parts", parts).Msg("Split table balance")
+	return sharesCents, nil
+}
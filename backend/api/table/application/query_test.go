@@ -6,7 +6,12 @@ import (
 	"context"
 	"testing"
 
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/period"
 	"github.com/nicograef/jotti/backend/domain/table"
+	"github.com/nicograef/jotti/backend/repository/event_repo"
+	"github.com/nicograef/jotti/backend/repository/period_repo"
 	"github.com/nicograef/jotti/backend/repository/table_repo"
 )
 
@@ -26,3 +31,97 @@ func TestGetAllTables(t *testing.T) {
 		t.Errorf("expected name 'Table 1', got %s", tables[0].Name)
 	}
 }
+
+func TestGetActiveTables_DefaultsToOpenPeriod(t *testing.T) {
+	other, current := 1, 2
+	repo := table_repo.NewMock([]table.Table{
+		{ID: 1, Name: "Sommerfest 1", Status: table.ActiveStatus, PeriodID: &other},
+		{ID: 2, Name: "Weihnachtsmarkt 1", Status: table.ActiveStatus, PeriodID: &current},
+		{ID: 3, Name: "Theke", Status: table.ActiveStatus},
+	}, nil)
+	periodRepo := period_repo.NewMock([]period.Period{
+		{ID: 1, Name: "Sommerfest", Status: period.ClosedStatus},
+		{ID: 2, Name: "Weihnachtsmarkt", Status: period.OpenStatus},
+	}, nil)
+	query := Query{TableRepo: repo, PeriodRepo: periodRepo}
+
+	tables, err := query.GetActiveTables(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(tables) != 2 {
+		t.Fatalf("expected tables of the open period and without period, got %+v", tables)
+	}
+	for _, tbl := range tables {
+		if tbl.ID == 1 {
+			t.Errorf("expected table of closed period to be hidden")
+		}
+	}
+}
+
+func TestGetTableOrders_DefaultsToCurrentSession(t *testing.T) {
+	ctx := context.Background()
+	repo := event_repo.NewMock([]event.Event{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: repo}
+	query := Query{EventRepo: repo}
+
+	products := []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}}
+	if _, err := command.PlaceTableOrder(ctx, 1, 1, "", products); err != nil {
+		t.Fatalf("expected no error placing order, got %v", err)
+	}
+	if err := command.ForceCloseTable(ctx, 1, 1, table.BreakageWriteOff, "Verlust"); err != nil {
+		t.Fatalf("expected no error closing table, got %v", err)
+	}
+
+	orders, err := query.GetTableOrders(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(orders) != 0 {
+		t.Errorf("expected no orders in current session, got %d", len(orders))
+	}
+
+	orders, err = query.GetTableOrders(ctx, 1, 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(orders) != 1 {
+		t.Errorf("expected 1 order in session 1, got %d", len(orders))
+	}
+
+	if _, err := query.GetTableOrders(ctx, 1, 2); err != ErrSessionNotFound {
+		t.Errorf("expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+func TestGetOpenTables(t *testing.T) {
+	ctx := context.Background()
+	tableRepo := table_repo.NewMock([]table.Table{
+		{ID: 1, Name: "Table 1", Status: table.ActiveStatus},
+		{ID: 2, Name: "Table 2", Status: table.ActiveStatus},
+	}, nil)
+	eventRepo := event_repo.NewMock([]event.Event{}, nil)
+	periodRepo := period_repo.NewMock([]period.Period{}, nil)
+	command := Command{Transactor: db.NewMockTransactor(), EventRepo: eventRepo}
+	query := Query{TableRepo: tableRepo, EventRepo: eventRepo, PeriodRepo: periodRepo}
+
+	products := []table.OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 2}}
+	if _, err := command.PlaceTableOrder(ctx, 1, 1, "", products); err != nil {
+		t.Fatalf("expected no error placing order, got %v", err)
+	}
+	balanceCents, err := query.GetTableBalance(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	open, err := query.GetOpenTables(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if open.Count != 1 {
+		t.Errorf("expected 1 open table, got %d", open.Count)
+	}
+	if open.BalanceCents != balanceCents || balanceCents == 0 {
+		t.Errorf("expected open balance %d, got %d", balanceCents, open.BalanceCents)
+	}
+}
@@ -0,0 +1,243 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/product"
+	"github.com/nicograef/jotti/backend/domain/table"
+	"github.com/nicograef/jotti/backend/tracing"
+	"github.com/rs/zerolog"
+)
+
+// MaxSyncItems is the maximum number of commands in one sync batch.
+const MaxSyncItems = 100
+
+// syncEventLimit is the maximum number of events returned by one sync. Clients continue with the last event ID.
+const syncEventLimit = 500
+
+// SyncItemType is the kind of command buffered by an offline client.
+type SyncItemType string
+
+const (
+	SyncPlaceOrder            SyncItemType = "place-order"
+	SyncRegisterPayment       SyncItemType = "register-payment"
+	SyncRegisterAmountPayment SyncItemType = "register-amount-payment"
+)
+
+// SyncItem is a command buffered by an offline client. The ID is the order or payment ID chosen by the client,
+// so an item can be uploaded again without being booked twice. Orders and payments of products have products,
+// payments of an amount have the amount instead.
+type SyncItem struct {
+	Type        SyncItemType
+	ID          string
+	TableID     int
+	Products    []table.OrderProduct
+	AmountCents int
+	ClientTime  time.Time
+}
+
+// SyncStatus is the outcome of a sync item.
+type SyncStatus string
+
+const (
+	// SyncApplied means the item is booked, either now or by an earlier upload.
+	SyncApplied SyncStatus = "applied"
+	// SyncRejected means the item can't be booked, Err tells why. The client has to resolve it.
+	SyncRejected SyncStatus = "rejected"
+	// SyncPending means the item was not processed because of a server error. The client uploads it again.
+	SyncPending SyncStatus = "pending"
+)
+
+// SyncResult is the outcome of one sync item.
+type SyncResult struct {
+	ID     string
+	Status SyncStatus
+	Err    error
+}
+
+// SyncPermissions are the permissions of the uploading user. They are checked for each item.
+type SyncPermissions struct {
+	PlaceOrders      bool
+	RegisterPayments bool
+}
+
+// syncRejections are the errors that reject an item. Other errors leave the item and all following items pending.
+var syncRejections = []error{
+	ErrInvalidSyncItem, ErrSyncNotAllowed, ErrProductNotFound, ErrProductInactive,
+	ErrInvalidOrderData, ErrInvalidPaymentData, ErrTableNotOpen, ErrItemsNotUnpaid, ErrPaymentExceedsBalance,
+	ErrIdempotencyKeyReused, ErrSyncItemTooOld,
+}
+
+// Sync books the orders and payments an offline client buffered, in the order of the batch. Each item is applied
+// idempotently, so uploading a batch again does not duplicate anything. After a server error, the remaining items
+// stay pending instead of being dropped. New items entered more than MaxSyncItemAge ago or before the open period
+// began are rejected. Sync returns the table events with a sequence number greater than lastEventID, which includes
+// the events of this batch.
+func (c Command) Sync(ctx context.Context, userID int, permissions SyncPermissions, items []SyncItem, lastEventID int) ([]SyncResult, []event.Event, error) {
+	ctx, span := tracing.Start(ctx, "table.Sync")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	if len(items) > MaxSyncItems {
+		log.Warn().Int("items", len(items)).Msg("Sync batch too large")
+		return nil, nil, ErrInvalidSyncBatch
+	}
+
+	notBefore, err := c.syncNotBefore(ctx, log)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make([]SyncResult, len(items))
+	failed := false
+	for i, item := range items {
+		results[i] = SyncResult{ID: item.ID, Status: SyncPending}
+		if failed {
+			continue
+		}
+
+		err := c.applySyncItem(ctx, log, userID, permissions, item, notBefore)
+		if err == nil {
+			results[i].Status = SyncApplied
+		} else if isSyncRejection(err) {
+			results[i].Status = SyncRejected
+			results[i].Err = err
+		} else {
+			log.Error().Err(err).Str("id", item.ID).Msg("Sync stopped after server error")
+			failed = true
+		}
+	}
+
+	types := make([]string, len(table.EventTypes))
+	for i, t := range table.EventTypes {
+		types[i] = string(t)
+	}
+
+	events, err := c.EventRepo.ReadEventsAfter(ctx, lastEventID, types, syncEventLimit)
+	if err != nil {
+		log.Error().Err(err).Int("last_event_id", lastEventID).Msg("Failed to read events for sync")
+		return results, nil, ErrDatabase
+	}
+
+	log.Info().Int("items", len(items)).Int("events", len(events)).Bool("failed", failed).Msg("Sync processed")
+	return results, events, nil
+}
+
+// syncNotBefore returns the time before which new sync items are rejected: MaxSyncItemAge ago, or the start of the open
+// period if it began later. Older items would be booked on a business day or period that was already reported.
+func (c Command) syncNotBefore(ctx context.Context, log *zerolog.Logger) (time.Time, error) {
+	notBefore := time.Now().UTC().Add(-c.MaxSyncItemAge)
+
+	p, err := c.PeriodRepo.GetOpenPeriod(ctx)
+	if errors.Is(err, db.ErrNotFound) {
+		return notBefore, nil
+	} else if err != nil {
+		log.Error().Err(err).Msg("Failed to get open period for sync")
+		return time.Time{}, ErrDatabase
+	}
+
+	if p.OpenedAt.After(notBefore) {
+		notBefore = p.OpenedAt
+	}
+	return notBefore, nil
+}
+
+func (c Command) applySyncItem(ctx context.Context, log *zerolog.Logger, userID int, permissions SyncPermissions, item SyncItem, notBefore time.Time) error {
+	if item.ID == "" {
+		log.Warn().Int("table_id", item.TableID).Msg("Sync item without ID")
+		return ErrInvalidSyncItem
+	}
+
+	contains := containsPayment
+	switch item.Type {
+	case SyncPlaceOrder:
+		if !permissions.PlaceOrders {
+			return ErrSyncNotAllowed
+		}
+		contains = containsOrder
+	case SyncRegisterPayment, SyncRegisterAmountPayment:
+		if !permissions.RegisterPayments {
+			return ErrSyncNotAllowed
+		}
+	default:
+		log.Warn().Str("type", string(item.Type)).Msg("Unknown sync item type")
+		return ErrInvalidSyncItem
+	}
+
+	// an item booked by an earlier upload stays applied, even if its products changed or it is too old by now
+	sessions, err := c.readSessions(ctx, log, item.TableID)
+	if err != nil {
+		return err
+	}
+	if booked, err := contains(sessions, item.ID); err != nil {
+		return err
+	} else if booked {
+		return nil
+	}
+
+	// buffered commands happened when they were entered, but never in the future
+	at := item.ClientTime
+	if at.IsZero() || at.After(time.Now()) {
+		at = time.Now().UTC()
+	}
+	if at.Before(notBefore) {
+		log.Warn().Str("id", item.ID).Time("client_time", at).Time("not_before", notBefore).Msg("Sync item too old")
+		return ErrSyncItemTooOld
+	}
+
+	switch item.Type {
+	case SyncPlaceOrder:
+		if err := c.checkProductsActive(ctx, log, item.Products); err != nil {
+			return err
+		}
+
+		_, err = c.placeTableOrder(ctx, userID, item.TableID, item.ID, item.Products, at)
+		return err
+	case SyncRegisterPayment:
+		products := make([]table.PaymentProduct, len(item.Products))
+		for i, p := range item.Products {
+			products[i] = table.PaymentProduct(p)
+		}
+
+		_, err = c.registerTablePayment(ctx, userID, item.TableID, item.ID, products, at)
+		return err
+	default:
+		_, err = c.registerTableAmountPayment(ctx, userID, item.TableID, item.ID, item.AmountCents, at)
+		return err
+	}
+}
+
+// checkProductsActive rejects orders of products that were deactivated or deleted while the client was offline.
+func (c Command) checkProductsActive(ctx context.Context, log *zerolog.Logger, products []table.OrderProduct) error {
+	for _, p := range products {
+		current, err := c.ProductRepo.GetProduct(ctx, p.ID)
+		if errors.Is(err, db.ErrNotFound) {
+			log.Warn().Int("product_id", p.ID).Msg("Ordered product not found")
+			return ErrProductNotFound
+		} else if err != nil {
+			log.Error().Err(err).Int("product_id", p.ID).Msg("Failed to get product")
+			return ErrDatabase
+		}
+
+		if current.Status != product.ActiveStatus {
+			log.Warn().Int("product_id", p.ID).Msg("Ordered product is inactive")
+			return ErrProductInactive
+		}
+	}
+
+	return nil
+}
+
+func isSyncRejection(err error) bool {
+	for _, rejection := range syncRejections {
+		if errors.Is(err, rejection) {
+			return true
+		}
+	}
+	return false
+}
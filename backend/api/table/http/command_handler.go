@@ -4,20 +4,31 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/nicograef/jotti/backend/api/helper"
 	"github.com/nicograef/jotti/backend/api/middleware"
 	"github.com/nicograef/jotti/backend/api/table/application"
+	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/role"
 	"github.com/nicograef/jotti/backend/domain/table"
 )
 
 type command interface {
-	CreateTable(ctx context.Context, name string) (int, error)
-	UpdateTable(ctx context.Context, id int, name string) error
-	ActivateTable(ctx context.Context, id int) error
-	DeactivateTable(ctx context.Context, id int) error
-	PlaceTableOrder(ctx context.Context, userID int, tableID int, products []table.OrderProduct) error
-	RegisterTablePayment(ctx context.Context, userID int, tableID int, products []table.PaymentProduct) error
+	CreateTable(ctx context.Context, userID int, name string) (int, error)
+	UpdateTable(ctx context.Context, userID int, id int, name string) error
+	ActivateTable(ctx context.Context, userID int, id int) error
+	DeactivateTable(ctx context.Context, userID int, id int) error
+	PlaceTableOrder(ctx context.Context, userID int, tableID int, orderID string, products []table.OrderProduct) (string, error)
+	RegisterTablePayment(ctx context.Context, userID int, tableID int, paymentID string, products []table.PaymentProduct) (string, error)
+	RegisterTableAmountPayment(ctx context.Context, userID int, tableID int, paymentID string, amountCents int) (string, error)
+	OpenTable(ctx context.Context, userID int, tableID int) error
+	ReopenTable(ctx context.Context, userID int, tableID int) error
+	CloseTable(ctx context.Context, userID int, tableID int) error
+	ForceCloseTable(ctx context.Context, userID int, tableID int, category table.WriteOffCategory, writeOffReason string) error
+	WriteOffTableItems(ctx context.Context, userID int, tableID int, category table.WriteOffCategory, note string, products []table.WriteOffProduct) error
+	ReversePayment(ctx context.Context, userID int, reverseAny bool, tableID int, paymentID string, reason string) error
+	Sync(ctx context.Context, userID int, permissions application.SyncPermissions, items []application.SyncItem, lastEventID int) ([]application.SyncResult, []event.Event, error)
 }
 
 type CommandHandler struct {
@@ -39,11 +50,21 @@ func (h *CommandHandler) CreateTableHandler() http.HandlerFunc {
 			return
 		}
 
-		id, err := h.Command.CreateTable(r.Context(), body.Name)
+		userID := r.Context().Value(middleware.UserIDKey).(int)
+		id, err := h.Command.CreateTable(r.Context(), userID, body.Name)
 		if err != nil {
 			if errors.Is(err, application.ErrTableAlreadyExists) {
 				helper.SendClientError(w, "table_already_exists", nil)
 				return
+			} else if errors.Is(err, application.ErrInvalidTableData) {
+				helper.SendValidationError(w, "invalid_table_data", err)
+				return
+			} else if errors.Is(err, application.ErrInvalidReference) {
+				helper.SendClientError(w, "invalid_reference", nil)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
 			} else {
 				helper.SendServerError(w)
 				return
@@ -66,11 +87,21 @@ func (h *CommandHandler) UpdateTableHandler() http.HandlerFunc {
 			return
 		}
 
-		err := h.Command.UpdateTable(r.Context(), body.ID, body.Name)
+		userID := r.Context().Value(middleware.UserIDKey).(int)
+		err := h.Command.UpdateTable(r.Context(), userID, body.ID, body.Name)
 		if err != nil {
 			if errors.Is(err, application.ErrTableNotFound) {
 				helper.SendClientError(w, "table_not_found", nil)
 				return
+			} else if errors.Is(err, application.ErrTableAlreadyExists) {
+				helper.SendClientError(w, "table_already_exists", nil)
+				return
+			} else if errors.Is(err, application.ErrInvalidTableData) {
+				helper.SendValidationError(w, "invalid_table_data", err)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
 			} else {
 				helper.SendServerError(w)
 				return
@@ -92,11 +123,15 @@ func (h *CommandHandler) ActivateTableHandler() http.HandlerFunc {
 			return
 		}
 
-		err := h.Command.ActivateTable(r.Context(), body.ID)
+		userID := r.Context().Value(middleware.UserIDKey).(int)
+		err := h.Command.ActivateTable(r.Context(), userID, body.ID)
 		if err != nil {
 			if errors.Is(err, application.ErrTableNotFound) {
 				helper.SendClientError(w, "table_not_found", nil)
 				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
 			} else {
 				helper.SendServerError(w)
 				return
@@ -118,11 +153,15 @@ func (h *CommandHandler) DeactivateTableHandler() http.HandlerFunc {
 			return
 		}
 
-		err := h.Command.DeactivateTable(r.Context(), body.ID)
+		userID := r.Context().Value(middleware.UserIDKey).(int)
+		err := h.Command.DeactivateTable(r.Context(), userID, body.ID)
 		if err != nil {
 			if errors.Is(err, application.ErrTableNotFound) {
 				helper.SendClientError(w, "table_not_found", nil)
 				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
 			} else {
 				helper.SendServerError(w)
 				return
@@ -133,32 +172,60 @@ func (h *CommandHandler) DeactivateTableHandler() http.HandlerFunc {
 	}
 }
 
+// idempotencyKeyHeader can be sent instead of an order or payment ID in the body. A retried request with the same key
+// returns the result of the first request instead of booking again.
+const idempotencyKeyHeader = "Idempotency-Key"
+
 type placeTableOrder struct {
 	TableID  int                  `json:"tableId"`
+	OrderID  string               `json:"orderId"`
 	Products []table.OrderProduct `json:"products"`
 }
 
+type placeTableOrderResponse struct {
+	OrderID string `json:"orderId"`
+}
+
 func (h *CommandHandler) PlaceTableOrderHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		body := placeTableOrder{}
 		if !helper.ReadBody(w, r, &body) {
 			return
 		}
+		if body.OrderID == "" {
+			body.OrderID = r.Header.Get(idempotencyKeyHeader)
+		}
 
 		userID := r.Context().Value(middleware.UserIDKey).(int)
-		err := h.Command.PlaceTableOrder(r.Context(), userID, body.TableID, body.Products)
+		orderID, err := h.Command.PlaceTableOrder(r.Context(), userID, body.TableID, body.OrderID, body.Products)
 		if err != nil {
-			helper.SendServerError(w)
-			return
+			if errors.Is(err, application.ErrInvalidOrderData) {
+				helper.SendValidationError(w, "invalid_order_data", err)
+				return
+			} else if errors.Is(err, application.ErrIdempotencyKeyReused) {
+				helper.SendClientError(w, "idempotency_key_reused", nil)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
 		}
 
-		helper.SendEmptyResponse(w)
+		helper.SendResponse(w, placeTableOrderResponse{OrderID: orderID})
 	}
 }
 
 type registerTablePayment struct {
-	TableID  int                    `json:"tableId"`
-	Products []table.PaymentProduct `json:"products"`
+	TableID   int                    `json:"tableId"`
+	PaymentID string                 `json:"paymentId"`
+	Products  []table.PaymentProduct `json:"products"`
+}
+
+type registerTablePaymentResponse struct {
+	PaymentID string `json:"paymentId"`
 }
 
 func (h *CommandHandler) RegisterTablePaymentHandler() http.HandlerFunc {
@@ -167,14 +234,398 @@ func (h *CommandHandler) RegisterTablePaymentHandler() http.HandlerFunc {
 		if !helper.ReadBody(w, r, &body) {
 			return
 		}
+		if body.PaymentID == "" {
+			body.PaymentID = r.Header.Get(idempotencyKeyHeader)
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(int)
+		paymentID, err := h.Command.RegisterTablePayment(r.Context(), userID, body.TableID, body.PaymentID, body.Products)
+		if err != nil {
+			if errors.Is(err, application.ErrTableNotOpen) {
+				helper.SendClientError(w, "table_not_open", nil)
+				return
+			} else if errors.Is(err, application.ErrInvalidPaymentData) {
+				helper.SendValidationError(w, "invalid_payment_data", err)
+				return
+			} else if errors.Is(err, application.ErrItemsNotUnpaid) {
+				helper.SendClientError(w, "items_not_unpaid", nil)
+				return
+			} else if errors.Is(err, application.ErrPaymentExceedsBalance) {
+				helper.SendClientError(w, "payment_exceeds_balance", nil)
+				return
+			} else if errors.Is(err, application.ErrIdempotencyKeyReused) {
+				helper.SendClientError(w, "idempotency_key_reused", nil)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendResponse(w, registerTablePaymentResponse{PaymentID: paymentID})
+	}
+}
+
+type registerTableAmountPayment struct {
+	TableID     int    `json:"tableId"`
+	PaymentID   string `json:"paymentId"`
+	AmountCents int    `json:"amountCents"`
+}
+
+func (h *CommandHandler) RegisterTableAmountPaymentHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := registerTableAmountPayment{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+		if body.PaymentID == "" {
+			body.PaymentID = r.Header.Get(idempotencyKeyHeader)
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(int)
+		paymentID, err := h.Command.RegisterTableAmountPayment(r.Context(), userID, body.TableID, body.PaymentID, body.AmountCents)
+		if err != nil {
+			if errors.Is(err, application.ErrInvalidPaymentData) {
+				helper.SendValidationError(w, "invalid_payment_data", err)
+				return
+			} else if errors.Is(err, application.ErrPaymentExceedsBalance) {
+				helper.SendClientError(w, "payment_exceeds_balance", nil)
+				return
+			} else if errors.Is(err, application.ErrTableNotOpen) {
+				helper.SendClientError(w, "table_not_open", nil)
+				return
+			} else if errors.Is(err, application.ErrIdempotencyKeyReused) {
+				helper.SendClientError(w, "idempotency_key_reused", nil)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendResponse(w, registerTablePaymentResponse{PaymentID: paymentID})
+	}
+}
+
+type openTable struct {
+	TableID int `json:"tableId"`
+}
+
+func (h *CommandHandler) OpenTableHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := openTable{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(int)
+		err := h.Command.OpenTable(r.Context(), userID, body.TableID)
+		if err != nil {
+			if errors.Is(err, application.ErrTableAlreadyOpen) {
+				helper.SendClientError(w, "table_already_open", nil)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendEmptyResponse(w)
+	}
+}
+
+type reopenTable struct {
+	TableID int `json:"tableId"`
+}
+
+func (h *CommandHandler) ReopenTableHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := reopenTable{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(int)
+		err := h.Command.ReopenTable(r.Context(), userID, body.TableID)
+		if err != nil {
+			if errors.Is(err, application.ErrTableAlreadyOpen) {
+				helper.SendClientError(w, "table_already_open", nil)
+				return
+			} else if errors.Is(err, application.ErrSessionNotFound) {
+				helper.SendClientError(w, "session_not_found", nil)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendEmptyResponse(w)
+	}
+}
+
+type closeTable struct {
+	TableID int `json:"tableId"`
+}
+
+func (h *CommandHandler) CloseTableHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := closeTable{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(int)
+		err := h.Command.CloseTable(r.Context(), userID, body.TableID)
+		if err != nil {
+			if errors.Is(err, application.ErrTableNotOpen) {
+				helper.SendClientError(w, "table_not_open", nil)
+				return
+			} else if errors.Is(err, application.ErrOpenBalance) {
+				helper.SendClientError(w, "table_has_open_balance", nil)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendEmptyResponse(w)
+	}
+}
+
+type forceCloseTable struct {
+	TableID int `json:"tableId"`
+	// Category of the write-off of the unpaid products
+	Category       table.WriteOffCategory `json:"category"`
+	WriteOffReason string                 `json:"writeOffReason"`
+}
+
+// ForceCloseTableHandler closes a table regardless of its open balance and writes off its unpaid products.
+// Only available to admins.
+func (h *CommandHandler) ForceCloseTableHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := forceCloseTable{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(int)
+		err := h.Command.ForceCloseTable(r.Context(), userID, body.TableID, body.Category, body.WriteOffReason)
+		if err != nil {
+			if errors.Is(err, application.ErrTableNotOpen) {
+				helper.SendClientError(w, "table_not_open", nil)
+				return
+			} else if errors.Is(err, application.ErrInvalidWriteOffReason) {
+				helper.SendClientError(w, "invalid_write_off_reason", nil)
+				return
+			} else if errors.Is(err, application.ErrInvalidWriteOffData) {
+				helper.SendValidationError(w, "invalid_write_off_data", err)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendEmptyResponse(w)
+	}
+}
+
+type writeOffTableItems struct {
+	TableID  int                     `json:"tableId"`
+	Category table.WriteOffCategory  `json:"category"`
+	Note     string                  `json:"note"`
+	Products []table.WriteOffProduct `json:"products"`
+}
+
+// WriteOffTableItemsHandler removes open items from a table without a payment. Only available to admins.
+func (h *CommandHandler) WriteOffTableItemsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := writeOffTableItems{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
 
 		userID := r.Context().Value(middleware.UserIDKey).(int)
-		err := h.Command.RegisterTablePayment(r.Context(), userID, body.TableID, body.Products)
+		err := h.Command.WriteOffTableItems(r.Context(), userID, body.TableID, body.Category, body.Note, body.Products)
 		if err != nil {
-			helper.SendServerError(w)
+			if errors.Is(err, application.ErrInvalidWriteOffData) {
+				helper.SendValidationError(w, "invalid_write_off_data", err)
+				return
+			} else if errors.Is(err, application.ErrTableNotOpen) {
+				helper.SendClientError(w, "table_not_open", nil)
+				return
+			} else if errors.Is(err, application.ErrItemsNotUnpaid) {
+				helper.SendClientError(w, "items_not_unpaid", nil)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendEmptyResponse(w)
+	}
+}
+
+type reverseTablePayment struct {
+	TableID   int    `json:"tableId"`
+	PaymentID string `json:"paymentId"`
+	Reason    string `json:"reason"`
+}
+
+// ReverseTablePaymentHandler refunds a payment. Users with the permission to reverse any payment may do so,
+// other users only their own recent payments.
+func (h *CommandHandler) ReverseTablePaymentHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := reverseTablePayment{}
+		if !helper.ReadBody(w, r, &body) {
 			return
 		}
 
+		userID := r.Context().Value(middleware.UserIDKey).(int)
+		reverseAny := middleware.HasPermission(r.Context(), role.ReverseAnyPayment)
+		err := h.Command.ReversePayment(r.Context(), userID, reverseAny, body.TableID, body.PaymentID, body.Reason)
+		if err != nil {
+			if errors.Is(err, application.ErrPaymentNotFound) {
+				helper.SendClientError(w, "payment_not_found", nil)
+				return
+			} else if errors.Is(err, application.ErrPaymentAlreadyReversed) {
+				helper.SendClientError(w, "payment_already_reversed", nil)
+				return
+			} else if errors.Is(err, application.ErrPaymentOfEarlierSession) {
+				helper.SendClientError(w, "payment_of_earlier_session", nil)
+				return
+			} else if errors.Is(err, application.ErrReversalNotAllowed) {
+				helper.SendClientError(w, "reversal_not_allowed", nil)
+				return
+			} else if errors.Is(err, application.ErrInvalidReversalReason) {
+				helper.SendClientError(w, "invalid_reversal_reason", nil)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
 		helper.SendEmptyResponse(w)
 	}
 }
+
+type syncItem struct {
+	Type        application.SyncItemType `json:"type"`
+	ID          string                   `json:"id"`
+	TableID     int                      `json:"tableId"`
+	Products    []table.OrderProduct     `json:"products"`
+	AmountCents int                      `json:"amountCents,omitempty"`
+	ClientTime  time.Time                `json:"clientTime"`
+}
+
+type syncBatch struct {
+	LastEventID int        `json:"lastEventId"`
+	Items       []syncItem `json:"items"`
+}
+
+type syncResult struct {
+	ID     string                 `json:"id"`
+	Status application.SyncStatus `json:"status"`
+	Reason string                 `json:"reason,omitempty"`
+}
+
+type syncResponse struct {
+	Results     []syncResult  `json:"results"`
+	Events      []event.Event `json:"events"`
+	LastEventID int           `json:"lastEventId"`
+}
+
+// syncRejectionReasons are the error codes of rejected sync items, the same as for the single commands.
+var syncRejectionReasons = []struct {
+	err    error
+	reason string
+}{
+	{application.ErrInvalidSyncItem, "invalid_sync_item"},
+	{application.ErrSyncNotAllowed, "insufficient_permissions"},
+	{application.ErrProductNotFound, "product_not_found"},
+	{application.ErrProductInactive, "product_inactive"},
+	{application.ErrInvalidOrderData, "invalid_order_data"},
+	{application.ErrInvalidPaymentData, "invalid_payment_data"},
+	{application.ErrTableNotOpen, "table_not_open"},
+	{application.ErrItemsNotUnpaid, "items_not_unpaid"},
+	{application.ErrPaymentExceedsBalance, "payment_exceeds_balance"},
+	{application.ErrIdempotencyKeyReused, "idempotency_key_reused"},
+	{application.ErrSyncItemTooOld, "sync_item_too_old"},
+}
+
+// SyncHandler uploads the orders and payments an offline client buffered and returns the table events since the
+// last event the client knows. Each item gets its own result, so one rejected item doesn't block the batch.
+func (h *CommandHandler) SyncHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := syncBatch{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		items := make([]application.SyncItem, len(body.Items))
+		for i, item := range body.Items {
+			items[i] = application.SyncItem(item)
+		}
+
+		permissions := application.SyncPermissions{
+			PlaceOrders:      middleware.HasPermission(r.Context(), role.ServeTables),
+			RegisterPayments: middleware.HasPermission(r.Context(), role.RegisterPayments),
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(int)
+		results, events, err := h.Command.Sync(r.Context(), userID, permissions, items, body.LastEventID)
+		if err != nil {
+			if errors.Is(err, application.ErrInvalidSyncBatch) {
+				helper.SendClientError(w, "invalid_sync_batch", nil)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		response := syncResponse{Results: make([]syncResult, len(results)), Events: events, LastEventID: body.LastEventID}
+		for i, result := range results {
+			response.Results[i] = syncResult{ID: result.ID, Status: result.Status}
+			for _, rejection := range syncRejectionReasons {
+				if errors.Is(result.Err, rejection.err) {
+					response.Results[i].Reason = rejection.reason
+					break
+				}
+			}
+		}
+		if len(events) > 0 {
+			response.LastEventID = events[len(events)-1].ID
+		}
+
+		helper.SendResponse(w, response)
+	}
+}
@@ -4,12 +4,16 @@ package http
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/nicograef/jotti/backend/api/middleware"
 	"github.com/nicograef/jotti/backend/api/table/application"
+	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/role"
 	"github.com/nicograef/jotti/backend/domain/table"
 )
 
@@ -17,26 +21,58 @@ type mockCommand struct {
 	err error
 }
 
-func (m *mockCommand) CreateTable(ctx context.Context, name string) (int, error) {
+func (m *mockCommand) CreateTable(ctx context.Context, userID int, name string) (int, error) {
 	return 1, m.err
 }
 
-func (m *mockCommand) UpdateTable(ctx context.Context, id int, name string) error {
+func (m *mockCommand) UpdateTable(ctx context.Context, userID int, id int, name string) error {
 	return m.err
 }
 
-func (m *mockCommand) ActivateTable(ctx context.Context, id int) error {
+func (m *mockCommand) ActivateTable(ctx context.Context, userID int, id int) error {
 	return m.err
 }
 
-func (m *mockCommand) DeactivateTable(ctx context.Context, id int) error {
+func (m *mockCommand) DeactivateTable(ctx context.Context, userID int, id int) error {
 	return m.err
 }
 
-func (m *mockCommand) PlaceTableOrder(ctx context.Context, userID int, tableID int, products []table.OrderProduct) error {
+func (m *mockCommand) PlaceTableOrder(ctx context.Context, userID int, tableID int, orderID string, products []table.OrderProduct) (string, error) {
+	return orderID, m.err
+}
+func (m *mockCommand) RegisterTablePayment(ctx context.Context, userID int, tableID int, paymentID string, products []table.PaymentProduct) (string, error) {
+	return paymentID, m.err
+}
+func (m *mockCommand) RegisterTableAmountPayment(ctx context.Context, userID int, tableID int, paymentID string, amountCents int) (string, error) {
+	return paymentID, m.err
+}
+func (m *mockCommand) OpenTable(ctx context.Context, userID int, tableID int) error {
+	return m.err
+}
+func (m *mockCommand) ReopenTable(ctx context.Context, userID int, tableID int) error {
 	return m.err
 }
-func (m *mockCommand) RegisterTablePayment(ctx context.Context, userID int, tableID int, products []table.PaymentProduct) error {
+func (m *mockCommand) CloseTable(ctx context.Context, userID int, tableID int) error {
+	return m.err
+}
+func (m *mockCommand) ForceCloseTable(ctx context.Context, userID int, tableID int, category table.WriteOffCategory, writeOffReason string) error {
+	return m.err
+}
+func (m *mockCommand) ReversePayment(ctx context.Context, userID int, reverseAny bool, tableID int, paymentID string, reason string) error {
+	return m.err
+}
+func (m *mockCommand) Sync(ctx context.Context, userID int, permissions application.SyncPermissions, items []application.SyncItem, lastEventID int) ([]application.SyncResult, []event.Event, error) {
+	results := []application.SyncResult{}
+	for _, item := range items {
+		if item.Type == application.SyncRegisterPayment && !permissions.RegisterPayments {
+			results = append(results, application.SyncResult{ID: item.ID, Status: application.SyncRejected, Err: application.ErrSyncNotAllowed})
+		} else {
+			results = append(results, application.SyncResult{ID: item.ID, Status: application.SyncApplied})
+		}
+	}
+	return results, []event.Event{{ID: lastEventID + 1, Type: string(table.EventTypeOrderPlacedV1)}}, m.err
+}
+func (m *mockCommand) WriteOffTableItems(ctx context.Context, userID int, tableID int, category table.WriteOffCategory, note string, products []table.WriteOffProduct) error {
 	return m.err
 }
 
@@ -46,6 +82,7 @@ func TestCreateTableHandler_Success(t *testing.T) {
 	body := `{"name":"Table 1"}`
 	req := httptest.NewRequest(http.MethodPost, "/create-table", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
 	rec := httptest.NewRecorder()
 
 	handler.CreateTableHandler().ServeHTTP(rec, req)
@@ -61,6 +98,7 @@ func TestCreateTabkeHandler_Failure(t *testing.T) {
 	body := `{"name":"Table 1"}`
 	req := httptest.NewRequest(http.MethodPost, "/create-table", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
 	rec := httptest.NewRecorder()
 
 	handler.CreateTableHandler().ServeHTTP(rec, req)
@@ -70,12 +108,72 @@ func TestCreateTabkeHandler_Failure(t *testing.T) {
 	}
 }
 
+func TestCreateTableHandler_RetryLater(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{err: application.ErrRetryLater}}
+
+	body := `{"name":"Table 1"}`
+	req := httptest.NewRequest(http.MethodPost, "/create-table", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
+	rec := httptest.NewRecorder()
+
+	handler.CreateTableHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+	if !strings.Contains(rec.Body.String(), "retry_later") {
+		t.Errorf("expected retry_later, got %s", rec.Body.String())
+	}
+}
+
+func TestCreateTableHandler_InvalidReference(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{err: application.ErrInvalidReference}}
+
+	body := `{"name":"Table 1"}`
+	req := httptest.NewRequest(http.MethodPost, "/create-table", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
+	rec := httptest.NewRecorder()
+
+	handler.CreateTableHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "invalid_reference") {
+		t.Errorf("expected invalid_reference, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCreateTableHandler_InvalidData(t *testing.T) {
+	_, err := table.NewTable("T")
+	handler := &CommandHandler{Command: &mockCommand{err: fmt.Errorf("%w: %w", application.ErrInvalidTableData, err)}}
+
+	body := `{"name":"T"}`
+	req := httptest.NewRequest(http.MethodPost, "/create-table", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
+	rec := httptest.NewRecorder()
+
+	handler.CreateTableHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422, got %d", rec.Code)
+	}
+	expected := `{"code":"invalid_table_data","details":[{"field":"name","message":"Name too short"}]}`
+	if body := strings.TrimSpace(rec.Body.String()); body != expected {
+		t.Errorf("expected body %s, got %s", expected, body)
+	}
+}
+
 func TestUpdateTableHandler_Success(t *testing.T) {
 	handler := &CommandHandler{Command: &mockCommand{}}
 
 	body := `{"id":1,"name":"Updated Table"}`
 	req := httptest.NewRequest(http.MethodPost, "/update-table", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
 	rec := httptest.NewRecorder()
 
 	handler.UpdateTableHandler().ServeHTTP(rec, req)
@@ -91,12 +189,13 @@ func TestUpdateTableHandler_NotFound(t *testing.T) {
 	body := `{"id":999,"name":"Updated Table"}`
 	req := httptest.NewRequest(http.MethodPost, "/update-table", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
 	rec := httptest.NewRecorder()
 
 	handler.UpdateTableHandler().ServeHTTP(rec, req)
 
-	if rec.Code != http.StatusBadRequest {
-		t.Errorf("expected status 400, got %d", rec.Code)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
 	}
 }
 
@@ -106,6 +205,7 @@ func TestActivateTableHandler_Success(t *testing.T) {
 	body := `{"id":1}`
 	req := httptest.NewRequest(http.MethodPost, "/activate-table", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
 	rec := httptest.NewRecorder()
 
 	handler.ActivateTableHandler().ServeHTTP(rec, req)
@@ -121,12 +221,13 @@ func TestActivateTableHandler_NotFound(t *testing.T) {
 	body := `{"id":999}`
 	req := httptest.NewRequest(http.MethodPost, "/activate-table", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
 	rec := httptest.NewRecorder()
 
 	handler.ActivateTableHandler().ServeHTTP(rec, req)
 
-	if rec.Code != http.StatusBadRequest {
-		t.Errorf("expected status 400, got %d", rec.Code)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
 	}
 }
 
@@ -136,6 +237,7 @@ func TestDeactivateTableHandler_Success(t *testing.T) {
 	body := `{"id":1}`
 	req := httptest.NewRequest(http.MethodPost, "/deactivate-table", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
 	rec := httptest.NewRecorder()
 
 	handler.DeactivateTableHandler().ServeHTTP(rec, req)
@@ -151,11 +253,199 @@ func TestDeactivateTableHandler_NotFound(t *testing.T) {
 	body := `{"id":999}`
 	req := httptest.NewRequest(http.MethodPost, "/deactivate-table", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
 	rec := httptest.NewRecorder()
 
 	handler.DeactivateTableHandler().ServeHTTP(rec, req)
 
-	if rec.Code != http.StatusBadRequest {
-		t.Errorf("expected status 400, got %d", rec.Code)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestPlaceTableOrderHandler_IdempotencyKey(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{}}
+
+	body := `{"tableId":1,"products":[{"id":1,"name":"Bier","netPriceCents":400,"quantity":1}]}`
+	req := httptest.NewRequest(http.MethodPost, "/place-table-order", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", "0b9f3c4e-6d7a-4f6b-9a8e-2c1d5e7f9a10")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
+	rec := httptest.NewRecorder()
+
+	handler.PlaceTableOrderHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"orderId":"0b9f3c4e-6d7a-4f6b-9a8e-2c1d5e7f9a10"`) {
+		t.Errorf("expected order ID from idempotency key, got %s", rec.Body.String())
+	}
+}
+
+func TestRegisterTablePaymentHandler_IdempotencyKeyReused(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{err: application.ErrIdempotencyKeyReused}}
+
+	body := `{"tableId":1,"paymentId":"0b9f3c4e-6d7a-4f6b-9a8e-2c1d5e7f9a10","products":[{"id":1,"name":"Bier","netPriceCents":400,"quantity":1}]}`
+	req := httptest.NewRequest(http.MethodPost, "/register-table-payment", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
+	rec := httptest.NewRecorder()
+
+	handler.RegisterTablePaymentHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", rec.Code)
+	}
+}
+
+func TestRegisterTableAmountPaymentHandler_Success(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{}}
+
+	body := `{"tableId":1,"amountCents":1250}`
+	req := httptest.NewRequest(http.MethodPost, "/register-table-amount-payment", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
+	rec := httptest.NewRecorder()
+
+	handler.RegisterTableAmountPaymentHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestRegisterTableAmountPaymentHandler_ExceedsBalance(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{err: application.ErrPaymentExceedsBalance}}
+
+	body := `{"tableId":1,"amountCents":999999}`
+	req := httptest.NewRequest(http.MethodPost, "/register-table-amount-payment", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
+	rec := httptest.NewRecorder()
+
+	handler.RegisterTableAmountPaymentHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", rec.Code)
+	}
+}
+
+func TestCloseTableHandler_Success(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{}}
+
+	body := `{"tableId":1}`
+	req := httptest.NewRequest(http.MethodPost, "/close-table", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
+	rec := httptest.NewRecorder()
+
+	handler.CloseTableHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestCloseTableHandler_OpenBalance(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{err: application.ErrOpenBalance}}
+
+	body := `{"tableId":1}`
+	req := httptest.NewRequest(http.MethodPost, "/close-table", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
+	rec := httptest.NewRecorder()
+
+	handler.CloseTableHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", rec.Code)
+	}
+}
+
+func TestWriteOffTableItemsHandler_Success(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{}}
+
+	body := `{"tableId":1,"category":"breakage","products":[{"id":1,"name":"Beer","netPriceCents":400,"quantity":1}]}`
+	req := httptest.NewRequest(http.MethodPost, "/write-off-table-items", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
+	rec := httptest.NewRecorder()
+
+	handler.WriteOffTableItemsHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestWriteOffTableItemsHandler_ItemsNotUnpaid(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{err: application.ErrItemsNotUnpaid}}
+
+	body := `{"tableId":1,"category":"unpaid","products":[{"id":1,"name":"Beer","netPriceCents":400,"quantity":5}]}`
+	req := httptest.NewRequest(http.MethodPost, "/write-off-table-items", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
+	rec := httptest.NewRecorder()
+
+	handler.WriteOffTableItemsHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "items_not_unpaid") {
+		t.Errorf("expected items_not_unpaid error, got %s", rec.Body.String())
+	}
+}
+
+func TestReverseTablePaymentHandler_Success(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{}}
+
+	body := `{"tableId":1,"paymentId":"8f14e45f-ceea-4e6b-9a5e-0c1d2e3f4a5b","reason":"Falscher Tisch"}`
+	req := httptest.NewRequest(http.MethodPost, "/reverse-table-payment", strings.NewReader(body))
+	ctx := context.WithValue(req.Context(), middleware.UserIDKey, 1)
+	ctx = context.WithValue(ctx, middleware.UserRoleKey, "service")
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	handler.ReverseTablePaymentHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestReverseTablePaymentHandler_NotAllowed(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{err: application.ErrReversalNotAllowed}}
+
+	body := `{"tableId":1,"paymentId":"8f14e45f-ceea-4e6b-9a5e-0c1d2e3f4a5b","reason":"Falscher Tisch"}`
+	req := httptest.NewRequest(http.MethodPost, "/reverse-table-payment", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
+	rec := httptest.NewRecorder()
+
+	handler.ReverseTablePaymentHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "reversal_not_allowed") {
+		t.Errorf("expected reversal_not_allowed error, got %s", rec.Body.String())
+	}
+}
+
+func TestSyncHandler(t *testing.T) {
+	handler := &CommandHandler{Command: &mockCommand{}}
+
+	body := `{"lastEventId":41,"items":[
+		{"type":"place-order","id":"0b9f3c4e-6d7a-4f6b-9a8e-2c1d5e7f9a10","tableId":1,"clientTime":"2026-07-04T18:30:00Z","products":[{"id":1,"name":"Bier","netPriceCents":400,"quantity":1}]},
+		{"type":"register-payment","id":"7c1e2d3f-4a5b-4c6d-8e9f-0a1b2c3d4e5f","tableId":1,"clientTime":"2026-07-04T18:45:00Z","products":[{"id":1,"name":"Bier","netPriceCents":400,"quantity":1}]}
+	]}`
+	req := httptest.NewRequest(http.MethodPost, "/sync", strings.NewReader(body))
+	ctx := context.WithValue(req.Context(), middleware.UserIDKey, 1)
+	ctx = context.WithValue(ctx, middleware.PermissionsKey, []string{string(role.ViewTables), string(role.ServeTables)})
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	handler.SyncHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	for _, expected := range []string{`"status":"applied"`, `"reason":"insufficient_permissions"`, `"lastEventId":42`} {
+		if !strings.Contains(rec.Body.String(), expected) {
+			t.Errorf("expected %s in response, got %s", expected, rec.Body.String())
+		}
 	}
 }
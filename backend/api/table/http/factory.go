@@ -1,23 +1,58 @@
 package http
 
 import (
+	"context"
 	"database/sql"
+	"time"
 
 	"github.com/nicograef/jotti/backend/api/table/application"
+	dbpkg "github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/metrics"
 	"github.com/nicograef/jotti/backend/repository/event_repo"
+	"github.com/nicograef/jotti/backend/repository/period_repo"
+	"github.com/nicograef/jotti/backend/repository/product_repo"
 	"github.com/nicograef/jotti/backend/repository/table_repo"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
-func NewCommandHandler(db *sql.DB) CommandHandler {
+func NewCommandHandler(db *sql.DB, syncMaxItemAge time.Duration) CommandHandler {
 	tableRepo := table_repo.Repository{DB: db}
 	eventRepo := event_repo.Repository{DB: db}
-	command := application.Command{TableRepo: tableRepo, EventRepo: eventRepo}
+	periodRepo := period_repo.Repository{DB: db}
+	productRepo := product_repo.Repository{DB: db}
+	// commands check the events of a table before they write new ones, concurrent commands that would both pass their
+	// checks (write skew) fail and are retried
+	transactor := dbpkg.Transactor{DB: db, Isolation: sql.LevelSerializable}
+	command := application.Command{
+		Transactor:     transactor,
+		TableRepo:      tableRepo,
+		EventRepo:      eventRepo,
+		PeriodRepo:     periodRepo,
+		ProductRepo:    productRepo,
+		MaxSyncItemAge: syncMaxItemAge,
+	}
 	return CommandHandler{Command: command}
 }
 
 func NewQueryHandler(db *sql.DB) QueryHandler {
+	return QueryHandler{Query: newQuery(db)}
+}
+
+// openTablesMetricsTTL is how long the open tables metrics are cached. Collecting them reads the events of all tables.
+const openTablesMetricsTTL = 30 * time.Second
+
+// NewMetricsCollector collects the open tables and their balance, at most once per openTablesMetricsTTL.
+func NewMetricsCollector(db *sql.DB) prometheus.Collector {
+	query := newQuery(db)
+	return metrics.Cached(openTablesMetricsTTL, metrics.OpenTables(func(ctx context.Context) (int, int, error) {
+		open, err := query.GetOpenTables(ctx)
+		return open.Count, open.BalanceCents, err
+	}))
+}
+
+func newQuery(db *sql.DB) application.Query {
 	tableRepo := table_repo.Repository{DB: db}
 	eventRepo := event_repo.Repository{DB: db}
-	query := application.Query{TableRepo: tableRepo, EventRepo: eventRepo}
-	return QueryHandler{Query: query}
+	periodRepo := period_repo.Repository{DB: db}
+	return application.Query{TableRepo: tableRepo, EventRepo: eventRepo, PeriodRepo: periodRepo}
 }
@@ -0,0 +1,156 @@
+package http
+
+import "github.com/nicograef/jotti/backend/api/openapi"
+
+// Operations of the handlers in the OpenAPI document.
+
+var CreateTableOperation = openapi.Operation{
+	Summary:  "Create a table",
+	Request:  createTable{},
+	Response: createTableResponse{},
+	Errors:   []string{"invalid_reference", "invalid_table_data", "retry_later", "table_already_exists"},
+}
+
+var UpdateTableOperation = openapi.Operation{
+	Summary: "Rename a table",
+	Request: updateTable{},
+	Errors:  []string{"invalid_table_data", "retry_later", "table_already_exists", "table_not_found"},
+}
+
+var ActivateTableOperation = openapi.Operation{
+	Summary: "Activate a table so it can be served",
+	Request: activateTable{},
+	Errors:  []string{"retry_later", "table_not_found"},
+}
+
+var DeactivateTableOperation = openapi.Operation{
+	Summary: "Deactivate a table so it can't be served",
+	Request: deactivateTable{},
+	Errors:  []string{"retry_later", "table_not_found"},
+}
+
+var ForceCloseTableOperation = openapi.Operation{
+	Summary: "Close a table regardless of its open balance and write off its unpaid products",
+	Request: forceCloseTable{},
+	Errors:  []string{"invalid_write_off_data", "invalid_write_off_reason", "retry_later", "table_not_open"},
+}
+
+var WriteOffTableItemsOperation = openapi.Operation{
+	Summary: "Remove open items from a table without a payment",
+	Request: writeOffTableItems{},
+	Errors:  []string{"invalid_write_off_data", "items_not_unpaid", "retry_later", "table_not_open"},
+}
+
+var OpenTableOperation = openapi.Operation{
+	Summary: "Open a session of a table",
+	Request: openTable{},
+	Errors:  []string{"retry_later", "table_already_open"},
+}
+
+var ReopenTableOperation = openapi.Operation{
+	Summary: "Reopen the last session of a closed table",
+	Request: reopenTable{},
+	Errors:  []string{"retry_later", "session_not_found", "table_already_open"},
+}
+
+var CloseTableOperation = openapi.Operation{
+	Summary: "Close the session of a table without open balance",
+	Request: closeTable{},
+	Errors:  []string{"retry_later", "table_has_open_balance", "table_not_open"},
+}
+
+var PlaceTableOrderOperation = openapi.Operation{
+	Summary:  "Order products for a table",
+	Request:  placeTableOrder{},
+	Response: placeTableOrderResponse{},
+	Errors:   []string{"idempotency_key_reused", "invalid_order_data", "retry_later"},
+}
+
+var RegisterTablePaymentOperation = openapi.Operation{
+	Summary:  "Pay products of a table",
+	Request:  registerTablePayment{},
+	Response: registerTablePaymentResponse{},
+	Errors:   []string{"idempotency_key_reused", "invalid_payment_data", "items_not_unpaid", "payment_exceeds_balance", "retry_later", "table_not_open"},
+}
+
+var RegisterTableAmountPaymentOperation = openapi.Operation{
+	Summary:  "Pay an amount of the open balance of a table",
+	Request:  registerTableAmountPayment{},
+	Response: registerTablePaymentResponse{},
+	Errors:   []string{"idempotency_key_reused", "invalid_payment_data", "payment_exceeds_balance", "retry_later", "table_not_open"},
+}
+
+var ReverseTablePaymentOperation = openapi.Operation{
+	Summary: "Reverse a payment of a table",
+	Request: reverseTablePayment{},
+	Errors: []string{
+		"invalid_reversal_reason", "payment_already_reversed", "payment_not_found", "payment_of_earlier_session",
+		"retry_later", "reversal_not_allowed",
+	},
+}
+
+var SyncOperation = openapi.Operation{
+	Summary: "Upload the orders and payments an offline client buffered and get the table events since the last " +
+		"sync, rejected items have an error code each",
+	Request:  syncBatch{},
+	Response: syncResponse{},
+	Errors:   []string{"invalid_sync_batch"},
+}
+
+var GetAllTablesOperation = openapi.Operation{
+	Summary:  "List all tables",
+	Response: getAllTablesResponse{},
+}
+
+var GetActiveTablesOperation = openapi.Operation{
+	Summary:  "List the tables that can be served",
+	Response: getActiveTablesResponse{},
+}
+
+var GetTableOperation = openapi.Operation{
+	Summary:  "Get a table",
+	Request:  getTable{},
+	Response: getTableResponse{},
+	Errors:   []string{"table_not_found"},
+}
+
+var GetTableSessionsOperation = openapi.Operation{
+	Summary:  "List the sessions of a table",
+	Request:  getTableSessions{},
+	Response: getTableSessionsResponse{},
+}
+
+var GetTableOrdersOperation = openapi.Operation{
+	Summary:  "List the orders of a table session",
+	Request:  getTableOrders{},
+	Response: getTableOrdersResponse{},
+	Errors:   []string{"session_not_found"},
+}
+
+var GetTablePaymentsOperation = openapi.Operation{
+	Summary:  "List the payments of a table session",
+	Request:  getTablePayments{},
+	Response: getTablePaymentsResponse{},
+	Errors:   []string{"session_not_found"},
+}
+
+var GetTableBalanceOperation = openapi.Operation{
+	Summary:  "Get the open balance of a table session",
+	Request:  getTableBalance{},
+	Response: getTableBalanceResponse{},
+	Errors:   []string{"session_not_found"},
+}
+
+var GetTableUnpaidProductsOperation = openapi.Operation{
+	Summary:  "List the unpaid products of a table session",
+	Request:  getTableUnpaidProducts{},
+	Response: getTableUnpaidProductsResponse{},
+	Errors:   []string{"session_not_found"},
+}
+
+var GetTableSplitOperation = openapi.Operation{
+	Summary:  "Split the open balance of a table across a number of payers",
+	Request:  getTableSplit{},
+	Response: getTableSplitResponse{},
+	Errors:   []string{"invalid_split"},
+}
@@ -14,10 +14,12 @@ type query interface {
 	GetTable(ctx context.Context, id int) (t.Table, error)
 	GetAllTables(ctx context.Context) ([]t.Table, error)
 	GetActiveTables(ctx context.Context) ([]t.Table, error)
-	GetTableOrders(ctx context.Context, tableID int) ([]t.Order, error)
-	GetTablePayments(ctx context.Context, tableID int) ([]t.Payment, error)
-	GetTableBalance(ctx context.Context, tableID int) (int, error)
-	GetTableUnpaidProducts(ctx context.Context, tableID int) ([]t.OrderProduct, error)
+	GetTableSessions(ctx context.Context, tableID int) ([]t.Session, error)
+	GetTableOrders(ctx context.Context, tableID int, session int) ([]t.Order, error)
+	GetTablePayments(ctx context.Context, tableID int, session int) ([]t.Payment, error)
+	GetTableBalance(ctx context.Context, tableID int, session int) (int, error)
+	GetTableUnpaidProducts(ctx context.Context, tableID int, session int) ([]t.OrderProduct, error)
+	GetTableSplit(ctx context.Context, tableID int, parts int) ([]int, error)
 }
 
 type QueryHandler struct {
@@ -99,8 +101,35 @@ func (h QueryHandler) GetActiveTablesHandler() http.HandlerFunc {
 	}
 }
 
+type getTableSessions struct {
+	TableID int `json:"tableId"`
+}
+
+type getTableSessionsResponse struct {
+	Sessions []t.Session `json:"sessions"`
+}
+
+func (h QueryHandler) GetTableSessionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := getTableSessions{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		sessions, err := h.Query.GetTableSessions(r.Context(), body.TableID)
+		if err != nil {
+			helper.SendServerError(w)
+			return
+		}
+
+		helper.SendResponse(w, getTableSessionsResponse{Sessions: sessions})
+	}
+}
+
 type getTableOrders struct {
 	TableID int `json:"tableId"`
+	// Session is the number of the session to read; 0 (default) is the current session.
+	Session int `json:"session"`
 }
 
 type getTableOrdersResponse struct {
@@ -114,10 +143,15 @@ func (h QueryHandler) GetTableOrdersHandler() http.HandlerFunc {
 			return
 		}
 
-		orders, err := h.Query.GetTableOrders(r.Context(), body.TableID)
+		orders, err := h.Query.GetTableOrders(r.Context(), body.TableID, body.Session)
 		if err != nil {
-			helper.SendServerError(w)
-			return
+			if errors.Is(err, application.ErrSessionNotFound) {
+				helper.SendClientError(w, "session_not_found", nil)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
 		}
 
 		helper.SendResponse(w, getTableOrdersResponse{Orders: orders})
@@ -126,6 +160,8 @@ func (h QueryHandler) GetTableOrdersHandler() http.HandlerFunc {
 
 type getTablePayments struct {
 	TableID int `json:"tableId"`
+	// Session is the number of the session to read; 0 (default) is the current session.
+	Session int `json:"session"`
 }
 
 type getTablePaymentsResponse struct {
@@ -139,10 +175,15 @@ func (h QueryHandler) GetTablePaymentsHandler() http.HandlerFunc {
 			return
 		}
 
-		payments, err := h.Query.GetTablePayments(r.Context(), body.TableID)
+		payments, err := h.Query.GetTablePayments(r.Context(), body.TableID, body.Session)
 		if err != nil {
-			helper.SendServerError(w)
-			return
+			if errors.Is(err, application.ErrSessionNotFound) {
+				helper.SendClientError(w, "session_not_found", nil)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
 		}
 
 		helper.SendResponse(w, getTablePaymentsResponse{Payments: payments})
@@ -151,6 +192,8 @@ func (h QueryHandler) GetTablePaymentsHandler() http.HandlerFunc {
 
 type getTableBalance struct {
 	TableID int `json:"tableId"`
+	// Session is the number of the session to read; 0 (default) is the current session.
+	Session int `json:"session"`
 }
 
 type getTableBalanceResponse struct {
@@ -164,10 +207,15 @@ func (h QueryHandler) GetTableBalanceHandler() http.HandlerFunc {
 			return
 		}
 
-		balanceCents, err := h.Query.GetTableBalance(r.Context(), body.TableID)
+		balanceCents, err := h.Query.GetTableBalance(r.Context(), body.TableID, body.Session)
 		if err != nil {
-			helper.SendServerError(w)
-			return
+			if errors.Is(err, application.ErrSessionNotFound) {
+				helper.SendClientError(w, "session_not_found", nil)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
 		}
 
 		helper.SendResponse(w, getTableBalanceResponse{BalanceCents: balanceCents})
@@ -176,6 +224,8 @@ func (h QueryHandler) GetTableBalanceHandler() http.HandlerFunc {
 
 type getTableUnpaidProducts struct {
 	TableID int `json:"tableId"`
+	// Session is the number of the session to read; 0 (default) is the current session.
+	Session int `json:"session"`
 }
 
 type getTableUnpaidProductsResponse struct {
@@ -189,12 +239,48 @@ func (h QueryHandler) GetTableUnpaidProductsHandler() http.HandlerFunc {
 			return
 		}
 
-		products, err := h.Query.GetTableUnpaidProducts(r.Context(), body.TableID)
+		products, err := h.Query.GetTableUnpaidProducts(r.Context(), body.TableID, body.Session)
 		if err != nil {
-			helper.SendServerError(w)
-			return
+			if errors.Is(err, application.ErrSessionNotFound) {
+				helper.SendClientError(w, "session_not_found", nil)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
 		}
 
 		helper.SendResponse(w, getTableUnpaidProductsResponse{Products: products})
 	}
 }
+
+type getTableSplit struct {
+	TableID int `json:"tableId"`
+	Parts   int `json:"parts"`
+}
+
+type getTableSplitResponse struct {
+	SharesCents []int `json:"sharesCents"`
+}
+
+func (h QueryHandler) GetTableSplitHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := getTableSplit{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		sharesCents, err := h.Query.GetTableSplit(r.Context(), body.TableID, body.Parts)
+		if err != nil {
+			if errors.Is(err, application.ErrInvalidSplit) {
+				helper.SendClientError(w, "invalid_split", nil)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendResponse(w, getTableSplitResponse{SharesCents: sharesCents})
+	}
+}
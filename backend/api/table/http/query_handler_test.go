@@ -6,9 +6,11 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/nicograef/jotti/backend/api/product/application"
+	tableapp "github.com/nicograef/jotti/backend/api/table/application"
 	"github.com/nicograef/jotti/backend/domain/table"
 )
 
@@ -29,18 +31,24 @@ func (m mockQuery) GetAllTables(ctx context.Context) ([]table.Table, error) {
 func (m mockQuery) GetActiveTables(ctx context.Context) ([]table.Table, error) {
 	return []table.Table{m.table}, m.err
 }
-func (m mockQuery) GetTableOrders(ctx context.Context, tableID int) ([]table.Order, error) {
+func (m mockQuery) GetTableSessions(ctx context.Context, tableID int) ([]table.Session, error) {
+	return []table.Session{}, m.err
+}
+func (m mockQuery) GetTableOrders(ctx context.Context, tableID int, session int) ([]table.Order, error) {
 	return []table.Order{m.order}, m.err
 }
-func (m mockQuery) GetTablePayments(ctx context.Context, tableID int) ([]table.Payment, error) {
+func (m mockQuery) GetTablePayments(ctx context.Context, tableID int, session int) ([]table.Payment, error) {
 	return []table.Payment{}, m.err
 }
-func (m mockQuery) GetTableBalance(ctx context.Context, tableID int) (int, error) {
+func (m mockQuery) GetTableBalance(ctx context.Context, tableID int, session int) (int, error) {
 	return m.balance, m.err
 }
-func (m mockQuery) GetTableUnpaidProducts(ctx context.Context, tableID int) ([]table.OrderProduct, error) {
+func (m mockQuery) GetTableUnpaidProducts(ctx context.Context, tableID int, session int) ([]table.OrderProduct, error) {
 	return []table.OrderProduct{m.product}, m.err
 }
+func (m mockQuery) GetTableSplit(ctx context.Context, tableID int, parts int) ([]int, error) {
+	return []int{m.balance}, m.err
+}
 
 func TestGetAllTablesHandler_Success(t *testing.T) {
 	handler := &QueryHandler{Query: mockQuery{}}
@@ -67,3 +75,45 @@ func TestGetAllTablesHandler_Failure(t *testing.T) {
 		t.Errorf("expected status 500, got %d", rec.Code)
 	}
 }
+
+func TestGetTableSplitHandler_Success(t *testing.T) {
+	handler := &QueryHandler{Query: mockQuery{balance: 500}}
+
+	body := `{"tableId":1,"parts":1}`
+	req := httptest.NewRequest(http.MethodPost, "/get-table-split", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.GetTableSplitHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestGetTableSplitHandler_InvalidSplit(t *testing.T) {
+	handler := &QueryHandler{Query: mockQuery{err: tableapp.ErrInvalidSplit}}
+
+	body := `{"tableId":1,"parts":0}`
+	req := httptest.NewRequest(http.MethodPost, "/get-table-split", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.GetTableSplitHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422, got %d", rec.Code)
+	}
+}
+
+func TestGetTableOrdersHandler_SessionNotFound(t *testing.T) {
+	handler := &QueryHandler{Query: mockQuery{err: tableapp.ErrSessionNotFound}}
+
+	body := `{"tableId":1,"session":7}`
+	req := httptest.NewRequest(http.MethodPost, "/get-table-orders", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.GetTableOrdersHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
@@ -3,9 +3,16 @@ package application
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 
+	"github.com/nicograef/jotti/backend/api/command"
 	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/audit"
+	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/role"
 	"github.com/nicograef/jotti/backend/domain/user"
+	"github.com/nicograef/jotti/backend/tracing"
 	"github.com/rs/zerolog"
 )
 
@@ -15,143 +22,383 @@ type commandUserRepo interface {
 	UpdateUser(ctx context.Context, u user.User) error
 }
 
+type commandSessionRepo interface {
+	RevokeUserSessions(ctx context.Context, userID int) error
+}
+
+type commandRoleRepo interface {
+	GetRole(ctx context.Context, name string) (role.Role, error)
+}
+
+type commandEventRepo interface {
+	WriteEvent(ctx context.Context, e event.Event) (int, error)
+}
+
 type Command struct {
-	UserRepo commandUserRepo
+	Transactor  db.TxRunner
+	UserRepo    commandUserRepo
+	SessionRepo commandSessionRepo
+	RoleRepo    commandRoleRepo
+	EventRepo   commandEventRepo
+	// PasswordPolicy holds the Argon2id parameters for PIN hashes.
+	PasswordPolicy user.PasswordPolicy
 }
 
-func (c Command) CreateUser(ctx context.Context, name, username string, role user.Role) (int, string, error) {
+// CreateUser creates a user with a one-time password. The actor may only assign roles within their own permissions.
+func (c Command) CreateUser(ctx context.Context, actorID int, actorPermissions []role.Permission, name, username string, role user.Role) (int, string, error) {
+	ctx, span := tracing.Start(ctx, "user.CreateUser")
+	defer span.End()
+
 	log := zerolog.Ctx(ctx)
 
 	user, onetimePassword, err := user.NewUser(name, username, role)
 	if err != nil {
 		log.Warn().Err(err).Str("username", username).Msg("Invalid user data")
-		return 0, "", ErrInvalidUserData
+		return 0, "", fmt.Errorf("%w: %w", ErrInvalidUserData, err)
 	}
 
-	userID, err := c.UserRepo.CreateUser(ctx, user)
-	if err != nil {
-		if errors.Is(err, db.ErrAlreadyExists) {
-			log.Warn().Err(err).Str("username", user.Username).Msg("Username already exists")
-			return 0, "", ErrUsernameAlreadyExists
-		} else {
-			log.Error().Str("username", user.Username).Msg("Failed to create user")
-			return 0, "", ErrDatabase
+	// the user and its audit event are written together
+	err = command.InTransaction(ctx, c.Transactor, audit.UserEntity, "", func(ctx context.Context) error {
+		if err := c.checkRoleAllowed(ctx, user.Role, actorPermissions); err != nil {
+			return err
+		}
+
+		userID, err := c.UserRepo.CreateUser(ctx, user)
+		if err != nil {
+			return fromUpdateError(log, user, err)
 		}
+
+		user.ID = userID
+		return command.WriteAuditEvent(ctx, c.EventRepo, actorID, audit.EventTypeUserCreatedV1, audit.UserEntity, strconv.Itoa(userID), nil, user)
+	})
+	if err != nil {
+		return 0, "", err
 	}
 
 	log.Info().Str("username", user.Username).Msg("User created successfully")
-	return userID, onetimePassword, nil
+	return user.ID, onetimePassword, nil
 }
 
-func (c Command) UpdateUser(ctx context.Context, userID int, name, username string, role user.Role) error {
+// UpdateUser changes the details of a user. The actor may only change roles within their own permissions, i.e.
+// neither assign a role with more permissions nor change the role of a user who has more permissions.
+func (c Command) UpdateUser(ctx context.Context, actorID int, actorPermissions []role.Permission, userID int, name, username string, role user.Role) error {
+	ctx, span := tracing.Start(ctx, "user.UpdateUser")
+	defer span.End()
+
 	log := zerolog.Ctx(ctx)
 
-	user, err := c.UserRepo.GetUser(ctx, userID)
-	if err != nil {
-		if errors.Is(err, db.ErrNotFound) {
-			log.Warn().Int("user_id", userID).Msg("User not found for update")
-			return ErrUserNotFound
-		} else {
-			log.Error().Int("user_id", userID).Msg("Failed to retrieve user for update")
-			return ErrDatabase
+	err := command.InTransaction(ctx, c.Transactor, audit.UserEntity, strconv.Itoa(userID), func(ctx context.Context) error {
+		user, err := c.getUser(ctx, userID, "update")
+		if err != nil {
+			return err
 		}
-	}
 
-	err = user.UpdateDetails(name, username, role)
-	if err != nil {
-		log.Warn().Err(err).Int("user_id", userID).Msg("Invalid user data for update")
-		return ErrInvalidUserData
-	}
+		before := user
+		err = user.UpdateDetails(name, username, role)
+		if err != nil {
+			log.Warn().Err(err).Int("user_id", userID).Msg("Invalid user data for update")
+			return fmt.Errorf("%w: %w", ErrInvalidUserData, err)
+		}
 
-	err = c.UserRepo.UpdateUser(ctx, user)
-	if err != nil {
-		log.Error().Err(err).Int("user_id", userID).Msg("Failed to update user")
-		return ErrDatabase
+		if err := c.checkRoleAllowed(ctx, user.Role, actorPermissions); err != nil {
+			return err
+		}
+		if before.Role != user.Role {
+			if err := c.checkRoleAllowed(ctx, before.Role, actorPermissions); err != nil {
+				return err
+			}
+		}
+
+		if err := c.UserRepo.UpdateUser(ctx, user); err != nil {
+			return fromUpdateError(log, user, err)
+		}
 
+		return command.WriteAuditEvent(ctx, c.EventRepo, actorID, audit.EventTypeUserUpdatedV1, audit.UserEntity, strconv.Itoa(userID), before, user)
+	})
+	if err != nil {
+		return err
 	}
 
 	log.Info().Int("user_id", userID).Msg("User updated successfully")
 	return nil
 }
 
-func (c Command) ActivateUser(ctx context.Context, userID int) error {
+func (c Command) ActivateUser(ctx context.Context, actorID, userID int) error {
+	ctx, span := tracing.Start(ctx, "user.ActivateUser")
+	defer span.End()
+
 	log := zerolog.Ctx(ctx)
 
-	user, err := c.UserRepo.GetUser(ctx, userID)
-	if err != nil {
-		if errors.Is(err, db.ErrNotFound) {
-			log.Warn().Int("user_id", userID).Msg("User not found for activation")
-			return ErrUserNotFound
-		} else {
-			log.Error().Int("user_id", userID).Msg("Failed to retrieve user for activation")
-			return ErrDatabase
+	err := command.InTransaction(ctx, c.Transactor, audit.UserEntity, strconv.Itoa(userID), func(ctx context.Context) error {
+		user, err := c.getUser(ctx, userID, "activation")
+		if err != nil {
+			return err
 		}
-	}
 
-	user.Activate()
+		before := user
+		user.Activate()
+
+		if err := c.UserRepo.UpdateUser(ctx, user); err != nil {
+			return fromUpdateError(log, user, err)
+		}
 
-	err = c.UserRepo.UpdateUser(ctx, user)
+		return command.WriteAuditEvent(ctx, c.EventRepo, actorID, audit.EventTypeUserActivatedV1, audit.UserEntity, strconv.Itoa(userID), before, user)
+	})
 	if err != nil {
-		log.Error().Err(err).Int("user_id", userID).Msg("Failed to update user")
-		return ErrDatabase
+		return err
 	}
 
 	log.Info().Int("user_id", userID).Msg("User activated successfully")
 	return nil
 }
 
-func (c Command) DeactivateUser(ctx context.Context, userID int) error {
+func (c Command) DeactivateUser(ctx context.Context, actorID, userID int) error {
+	ctx, span := tracing.Start(ctx, "user.DeactivateUser")
+	defer span.End()
+
 	log := zerolog.Ctx(ctx)
 
-	user, err := c.UserRepo.GetUser(ctx, userID)
-	if err != nil {
-		if errors.Is(err, db.ErrNotFound) {
-			log.Warn().Int("user_id", userID).Msg("User not found for deactivation")
-			return ErrUserNotFound
-		} else {
-			log.Error().Int("user_id", userID).Msg("Failed to retrieve user for deactivation")
-			return ErrDatabase
+	// a deactivated user must not keep their sessions, so the user, the revocation and the audit event are written
+	// together
+	err := command.InTransaction(ctx, c.Transactor, audit.UserEntity, strconv.Itoa(userID), func(ctx context.Context) error {
+		user, err := c.getUser(ctx, userID, "deactivation")
+		if err != nil {
+			return err
 		}
-	}
 
-	user.Deactivate()
+		before := user
+		user.Deactivate()
+
+		if err := c.UserRepo.UpdateUser(ctx, user); err != nil {
+			return fromUpdateError(log, user, err)
+		}
 
-	err = c.UserRepo.UpdateUser(ctx, user)
+		if err := c.SessionRepo.RevokeUserSessions(ctx, userID); err != nil {
+			log.Error().Err(err).Int("user_id", userID).Msg("Failed to revoke sessions of deactivated user")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
+
+		return command.WriteAuditEvent(ctx, c.EventRepo, actorID, audit.EventTypeUserDeactivatedV1, audit.UserEntity, strconv.Itoa(userID), before, user)
+	})
 	if err != nil {
-		log.Error().Err(err).Int("user_id", userID).Msg("Failed to update user")
-		return ErrDatabase
+		return err
 	}
 
 	log.Info().Int("user_id", userID).Msg("User deactivated successfully")
 	return nil
 }
 
-func (c Command) ResetPassword(ctx context.Context, userID int) (string, error) {
+func (c Command) ResetPassword(ctx context.Context, actorID, userID int) (string, error) {
+	ctx, span := tracing.Start(ctx, "user.ResetPassword")
+	defer span.End()
+
 	log := zerolog.Ctx(ctx)
 
-	user, err := c.UserRepo.GetUser(ctx, userID)
+	var onetimePassword string
+	err := command.InTransaction(ctx, c.Transactor, audit.UserEntity, strconv.Itoa(userID), func(ctx context.Context) error {
+		user, err := c.getUser(ctx, userID, "password reset")
+		if err != nil {
+			return err
+		}
+
+		before := user
+		onetimePassword, err = user.ResetPassword()
+		if err != nil {
+			log.Error().Err(err).Int("user_id", userID).Msg("Failed to reset password")
+			return err
+		}
+
+		if err := c.UserRepo.UpdateUser(ctx, user); err != nil {
+			log.Error().Err(err).Int("user_id", userID).Msg("Failed to update user in persistence")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
+
+		if err := c.SessionRepo.RevokeUserSessions(ctx, userID); err != nil {
+			log.Error().Err(err).Int("user_id", userID).Msg("Failed to revoke sessions after password reset")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
+
+		return command.WriteAuditEvent(ctx, c.EventRepo, actorID, audit.EventTypeUserPasswordResetV1, audit.UserEntity, strconv.Itoa(userID), before, user)
+	})
 	if err != nil {
-		if errors.Is(err, db.ErrNotFound) {
-			log.Warn().Int("user_id", userID).Msg("User not found for password reset")
-			return "", ErrUserNotFound
-		} else {
-			log.Error().Int("user_id", userID).Msg("Failed to retrieve user for password reset")
-			return "", ErrDatabase
+		return "", err
+	}
+
+	log.Info().Int("user_id", userID).Msg("Password reset successfully")
+	return onetimePassword, nil
+}
+
+// RequirePasswordChange makes the user choose a new password before they can log in again.
+// All sessions of the user are revoked.
+func (c Command) RequirePasswordChange(ctx context.Context, actorID, userID int) error {
+	ctx, span := tracing.Start(ctx, "user.RequirePasswordChange")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	err := command.InTransaction(ctx, c.Transactor, audit.UserEntity, strconv.Itoa(userID), func(ctx context.Context) error {
+		u, err := c.getUser(ctx, userID, "requiring password change")
+		if err != nil {
+			return err
+		}
+
+		before := u
+		err = u.RequirePasswordChange()
+		if err != nil {
+			if errors.Is(err, user.ErrNoPassword) {
+				log.Warn().Int("user_id", userID).Msg("No password set for user when requiring password change")
+				return ErrNoPassword
+			} else {
+				log.Error().Err(err).Int("user_id", userID).Msg("Failed to require password change")
+				return err
+			}
+		}
+
+		if err := c.UserRepo.UpdateUser(ctx, u); err != nil {
+			log.Error().Err(err).Int("user_id", userID).Msg("Failed to update user in persistence")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
 		}
+
+		if err := c.SessionRepo.RevokeUserSessions(ctx, userID); err != nil {
+			log.Error().Err(err).Int("user_id", userID).Msg("Failed to revoke sessions after requiring password change")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
+
+		return command.WriteAuditEvent(ctx, c.EventRepo, actorID, audit.EventTypeUserPasswordChangeRequiredV1, audit.UserEntity, strconv.Itoa(userID), before, u)
+	})
+	if err != nil {
+		return err
 	}
 
-	onetimePassword, err := user.ResetPassword()
+	log.Info().Int("user_id", userID).Msg("Password change required successfully")
+	return nil
+}
+
+// SetPin sets the PIN of the user for quick login on registered devices.
+// The user has to confirm the change with their password.
+func (c Command) SetPin(ctx context.Context, userID int, password, pin string) error {
+	ctx, span := tracing.Start(ctx, "user.SetPin")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	err := command.InTransaction(ctx, c.Transactor, audit.UserEntity, strconv.Itoa(userID), func(ctx context.Context) error {
+		u, err := c.getUser(ctx, userID, "setting PIN")
+		if err != nil {
+			return err
+		}
+
+		err = u.SetPin(password, pin, c.PasswordPolicy.HashParams())
+		if err != nil {
+			if errors.Is(err, user.ErrNoPassword) {
+				log.Warn().Int("user_id", userID).Msg("No password set for user when setting PIN")
+				return ErrNoPassword
+			} else if errors.Is(err, user.ErrInvalidPassword) {
+				log.Warn().Int("user_id", userID).Msg("Password validation failed when setting PIN")
+				return ErrInvalidPassword
+			} else if errors.Is(err, user.ErrInvalidPin) {
+				log.Warn().Int("user_id", userID).Msg("Invalid PIN")
+				return ErrInvalidPin
+			} else {
+				log.Error().Err(err).Int("user_id", userID).Msg("Failed to set PIN")
+				return err
+			}
+		}
+
+		if err := c.UserRepo.UpdateUser(ctx, u); err != nil {
+			log.Error().Err(err).Int("user_id", userID).Msg("Failed to update user")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
+		return nil
+	})
 	if err != nil {
-		log.Error().Err(err).Int("user_id", userID).Msg("Failed to reset password")
-		return "", err
+		return err
+	}
+
+	log.Info().Int("user_id", userID).Msg("PIN set successfully")
+	return nil
+}
+
+// RemovePin removes the PIN of the user, so the user can no longer log in on registered devices.
+func (c Command) RemovePin(ctx context.Context, userID int) error {
+	ctx, span := tracing.Start(ctx, "user.RemovePin")
+	defer span.End()
+
+	log := zerolog.Ctx(ctx)
+
+	err := command.InTransaction(ctx, c.Transactor, audit.UserEntity, strconv.Itoa(userID), func(ctx context.Context) error {
+		u, err := c.getUser(ctx, userID, "removing PIN")
+		if err != nil {
+			return err
+		}
+
+		u.RemovePin()
+
+		if err := c.UserRepo.UpdateUser(ctx, u); err != nil {
+			log.Error().Err(err).Int("user_id", userID).Msg("Failed to update user")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Int("user_id", userID).Msg("PIN removed successfully")
+	return nil
+}
+
+// getUser reads the user a command changes, action describes the command in the logs.
+func (c Command) getUser(ctx context.Context, userID int, action string) (user.User, error) {
+	log := zerolog.Ctx(ctx)
+
+	u, err := c.UserRepo.GetUser(ctx, userID)
+	if errors.Is(err, db.ErrNotFound) {
+		log.Warn().Int("user_id", userID).Msg("User not found for " + action)
+		return user.User{}, ErrUserNotFound
+	} else if err != nil {
+		log.Error().Err(err).Int("user_id", userID).Msg("Failed to retrieve user for " + action)
+		return user.User{}, fmt.Errorf("%w: %w", ErrDatabase, err)
+	}
+
+	return u, nil
+}
+
+// fromUpdateError maps the error of creating or updating a user.
+func fromUpdateError(log *zerolog.Logger, u user.User, err error) error {
+	if errors.Is(err, db.ErrAlreadyExists) {
+		log.Warn().Err(err).Str("username", u.Username).Msg("Username already exists")
+		return ErrUsernameAlreadyExists
+	} else if errors.Is(err, db.ErrForeignKeyViolation) {
+		// the role was deleted after it was checked
+		log.Warn().Err(err).Str("constraint", db.Constraint(err)).Str("role", string(u.Role)).Msg("Role not found")
+		return ErrRoleNotFound
 	}
 
-	err = c.UserRepo.UpdateUser(ctx, user)
+	log.Error().Err(err).Int("user_id", u.ID).Str("username", u.Username).Msg("Failed to write user")
+	return fmt.Errorf("%w: %w", ErrDatabase, err)
+}
+
+// checkRoleAllowed makes sure users are only assigned to roles that exist and grant no permission beyond the
+// permissions of the actor, so nobody can give themselves or others more permissions than they have.
+func (c Command) checkRoleAllowed(ctx context.Context, name user.Role, actorPermissions []role.Permission) error {
+	log := zerolog.Ctx(ctx)
+
+	r, err := c.RoleRepo.GetRole(ctx, string(name))
 	if err != nil {
-		log.Error().Int("user_id", userID).Msg("Failed to update user in persistence")
-		return "", ErrDatabase
+		if errors.Is(err, db.ErrNotFound) {
+			log.Warn().Str("role", string(name)).Msg("Role not found")
+			return ErrRoleNotFound
+		} else {
+			log.Error().Err(err).Str("role", string(name)).Msg("Failed to retrieve role")
+			return fmt.Errorf("%w: %w", ErrDatabase, err)
+		}
 	}
 
-	log.Info().Int("user_id", userID).Msg("Password reset successfully")
-	return onetimePassword, nil
+	if !r.GrantsOnly(actorPermissions) {
+		log.Warn().Str("role", string(name)).Msg("Role grants more permissions than the actor has")
+		return ErrRoleNotAllowed
+	}
+
+	return nil
 }
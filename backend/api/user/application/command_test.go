@@ -5,18 +5,33 @@ package application
 import (
 	"context"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/audit"
+	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/role"
+	"github.com/nicograef/jotti/backend/domain/session"
 	"github.com/nicograef/jotti/backend/domain/user"
+	"github.com/nicograef/jotti/backend/repository/event_repo"
+	"github.com/nicograef/jotti/backend/repository/role_repo"
+	"github.com/nicograef/jotti/backend/repository/session_repo"
 	"github.com/nicograef/jotti/backend/repository/user_repo"
 )
 
+func newRoleRepo() commandRoleRepo {
+	return role_repo.NewMock([]role.Role{{Name: role.AdminName}, {Name: role.ServiceName}}, nil, nil)
+}
+
+// adminID is the admin who makes the changes in the tests.
+const adminID = 99
+
 func TestCreateUser(t *testing.T) {
 	repo := user_repo.NewMock([]user.User{}, nil)
-	userCommand := Command{UserRepo: repo}
+	userCommand := Command{Transactor: db.NewMockTransactor(), UserRepo: repo, RoleRepo: newRoleRepo(), EventRepo: event_repo.NewMock(nil, nil)}
 
-	userId, onetimePassword, err := userCommand.CreateUser(context.Background(), "Test User", "testuser", user.ServiceRole)
+	userId, onetimePassword, err := userCommand.CreateUser(context.Background(), adminID, role.AllPermissions, "Test User", "testuser", user.ServiceRole)
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -34,9 +49,9 @@ func TestCreateUser(t *testing.T) {
 
 func TestCreateUser_Error(t *testing.T) {
 	repo := user_repo.NewMock([]user.User{}, db.ErrDatabase)
-	userCommand := Command{UserRepo: repo}
+	userCommand := Command{Transactor: db.NewMockTransactor(), UserRepo: repo, RoleRepo: newRoleRepo(), EventRepo: event_repo.NewMock(nil, nil)}
 
-	_, _, err := userCommand.CreateUser(context.Background(), "Test User", "testuser", user.ServiceRole)
+	_, _, err := userCommand.CreateUser(context.Background(), adminID, role.AllPermissions, "Test User", "testuser", user.ServiceRole)
 
 	if err == nil {
 		t.Fatalf("expected error, got nil")
@@ -47,24 +62,187 @@ func TestCreateUser_Error(t *testing.T) {
 }
 
 func TestUpdateUser_Success(t *testing.T) {
-	repo := user_repo.NewMock([]user.User{user.User{ID: 1}}, nil)
-	userCommand := Command{UserRepo: repo}
+	repo := user_repo.NewMock([]user.User{{ID: 1, Role: user.ServiceRole}}, nil)
+	userCommand := Command{Transactor: db.NewMockTransactor(), UserRepo: repo, RoleRepo: newRoleRepo(), EventRepo: event_repo.NewMock(nil, nil)}
+
+	err := userCommand.UpdateUser(context.Background(), adminID, role.AllPermissions, 1, "Updated User", "updateduser", user.AdminRole)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestUpdateUser_WritesAuditEvent(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Name: "Old Name", Username: "olduser", Role: user.ServiceRole}}, nil)
+	eventRepo := event_repo.NewMock(nil, nil)
+	userCommand := Command{Transactor: db.NewMockTransactor(), UserRepo: repo, RoleRepo: newRoleRepo(), EventRepo: eventRepo}
 
-	err := userCommand.UpdateUser(context.Background(), 1, "Updated User", "updateduser", user.AdminRole)
+	err := userCommand.UpdateUser(context.Background(), adminID, role.AllPermissions, 1, "New Name", "newuser", user.ServiceRole)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
 
+	events, _ := eventRepo.ReadEvents(context.Background(), event.Filter{Subject: audit.Subject(audit.UserEntity, "1")})
+	if len(events) != 1 {
+		t.Fatalf("expected 1 audit event, got %d", len(events))
+	}
+	if events[0].Type != string(audit.EventTypeUserUpdatedV1) || events[0].UserID != adminID {
+		t.Errorf("unexpected audit event: %s by %d", events[0].Type, events[0].UserID)
+	}
+	change, err := audit.ParseChange(events[0])
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
+	if !strings.Contains(string(change.Before), `"username":"olduser"`) || !strings.Contains(string(change.After), `"username":"newuser"`) {
+		t.Errorf("unexpected change: %s -> %s", change.Before, change.After)
+	}
+}
+
+func TestCreateUser_UnknownRole(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{}, nil)
+	userCommand := Command{Transactor: db.NewMockTransactor(), UserRepo: repo, RoleRepo: newRoleRepo(), EventRepo: event_repo.NewMock(nil, nil)}
+
+	_, _, err := userCommand.CreateUser(context.Background(), adminID, role.AllPermissions, "Test User", "testuser", user.Role("kueche"))
+
+	if err != ErrRoleNotFound {
+		t.Fatalf("expected role not found error, got %v", err)
+	}
+}
+
+func TestCreateUser_RoleNotAllowed(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{}, nil)
+	userCommand := Command{Transactor: db.NewMockTransactor(), UserRepo: repo, RoleRepo: newRoleRepo(), EventRepo: event_repo.NewMock(nil, nil)}
+
+	// the actor may manage users, but not roles
+	_, _, err := userCommand.CreateUser(context.Background(), adminID, []role.Permission{role.ManageUsers}, "Test User", "testuser", user.AdminRole)
+
+	if err != ErrRoleNotAllowed {
+		t.Fatalf("expected role not allowed error, got %v", err)
+	}
+}
+
+func TestUpdateUser_RoleNotAllowed(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Name: "Test User", Username: "testuser", Role: user.ServiceRole}, {ID: 2, Name: "Admin", Username: "admin", Role: user.AdminRole}}, nil)
+	roleRepo := role_repo.NewMock([]role.Role{{Name: role.AdminName}, {Name: role.ServiceName, Permissions: []role.Permission{role.ViewTables}}}, nil, nil)
+	userCommand := Command{Transactor: db.NewMockTransactor(), UserRepo: repo, RoleRepo: roleRepo, EventRepo: event_repo.NewMock(nil, nil)}
+	permissions := []role.Permission{role.ManageUsers, role.ViewTables}
+
+	if err := userCommand.UpdateUser(context.Background(), 1, permissions, 1, "Test User", "testuser", user.AdminRole); err != ErrRoleNotAllowed {
+		t.Fatalf("expected role not allowed error for promotion to admin, got %v", err)
+	}
+	if err := userCommand.UpdateUser(context.Background(), 1, permissions, 2, "Admin", "admin", user.ServiceRole); err != ErrRoleNotAllowed {
+		t.Fatalf("expected role not allowed error for demotion of admin, got %v", err)
+	}
+	if err := userCommand.UpdateUser(context.Background(), 1, permissions, 1, "New Name", "testuser", user.ServiceRole); err != nil {
+		t.Fatalf("expected no error keeping an allowed role, got %v", err)
+	}
+}
+
+func TestDeactivateUser_Conflict(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{}, db.ErrSerializationFailure)
+	userCommand := Command{Transactor: db.NewMockTransactor(), UserRepo: repo}
+
+	if err := userCommand.DeactivateUser(context.Background(), adminID, 1); err != ErrRetryLater {
+		t.Fatalf("expected ErrRetryLater, got %v", err)
+	}
 }
 
 func TestUpdateUser_Error(t *testing.T) {
 	repo := user_repo.NewMock([]user.User{}, db.ErrDatabase)
-	userCommand := Command{UserRepo: repo}
+	userCommand := Command{Transactor: db.NewMockTransactor(), UserRepo: repo}
 
-	err := userCommand.UpdateUser(context.Background(), 1, "Updated User", "updateduser", user.AdminRole)
+	err := userCommand.UpdateUser(context.Background(), adminID, role.AllPermissions, 1, "Updated User", "updateduser", user.AdminRole)
 
 	if err != ErrDatabase {
 		t.Fatalf("expected database error, got %v", err)
 	}
 
 }
+
+func TestDeactivateUser_RevokesSessions(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Status: user.ActiveStatus}}, nil)
+	s, _, _ := session.NewSession(1)
+	sessionRepo := session_repo.NewMock([]session.Session{s}, nil)
+	userCommand := Command{Transactor: db.NewMockTransactor(), UserRepo: repo, SessionRepo: sessionRepo, EventRepo: event_repo.NewMock(nil, nil)}
+
+	err := userCommand.DeactivateUser(context.Background(), adminID, 1)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	got, _ := sessionRepo.GetSession(context.Background(), s.ID)
+	if got.RevokedAt == nil {
+		t.Fatal("expected session to be revoked")
+	}
+}
+
+func TestResetPassword_RevokesSessions(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Status: user.ActiveStatus}}, nil)
+	s, _, _ := session.NewSession(1)
+	sessionRepo := session_repo.NewMock([]session.Session{s}, nil)
+	userCommand := Command{Transactor: db.NewMockTransactor(), UserRepo: repo, SessionRepo: sessionRepo, EventRepo: event_repo.NewMock(nil, nil)}
+
+	_, err := userCommand.ResetPassword(context.Background(), adminID, 1)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	got, _ := sessionRepo.GetSession(context.Background(), s.ID)
+	if got.RevokedAt == nil {
+		t.Fatal("expected session to be revoked")
+	}
+}
+
+func TestRequirePasswordChange(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Status: user.ActiveStatus, PasswordHash: "$argon2id$v=19$m=64,t=2,p=4$QzFPUlMxVUd2Wm51a09BNA$WC7jqeO84JjhcPYJKIN6Ep71DLRc0wog7vjIwYq+EEk"}}, nil)
+	s, _, _ := session.NewSession(1)
+	sessionRepo := session_repo.NewMock([]session.Session{s}, nil)
+	eventRepo := event_repo.NewMock(nil, nil)
+	userCommand := Command{Transactor: db.NewMockTransactor(), UserRepo: repo, SessionRepo: sessionRepo, EventRepo: eventRepo}
+
+	err := userCommand.RequirePasswordChange(context.Background(), adminID, 1)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	u, _ := repo.GetUser(context.Background(), 1)
+	if !u.PasswordChangeRequired {
+		t.Fatal("expected password change to be required")
+	}
+	got, _ := sessionRepo.GetSession(context.Background(), s.ID)
+	if got.RevokedAt == nil {
+		t.Fatal("expected session to be revoked")
+	}
+	events, _ := eventRepo.ReadEventsBySubject(context.Background(), audit.Subject(audit.UserEntity, "1"))
+	if len(events) != 1 || events[0].Type != string(audit.EventTypeUserPasswordChangeRequiredV1) {
+		t.Fatalf("expected one password change required event, got %+v", events)
+	}
+}
+
+func TestSetPin(t *testing.T) {
+	repo := user_repo.NewMock([]user.User{{ID: 1, Status: user.ActiveStatus, PasswordHash: "$argon2id$v=19$m=64,t=2,p=4$QzFPUlMxVUd2Wm51a09BNA$WC7jqeO84JjhcPYJKIN6Ep71DLRc0wog7vjIwYq+EEk"}}, nil)
+	userCommand := Command{Transactor: db.NewMockTransactor(), UserRepo: repo}
+
+	if err := userCommand.SetPin(context.Background(), 1, "wrongpassword", "1234"); err != ErrInvalidPassword {
+		t.Fatalf("expected invalid password error, got %v", err)
+	}
+	if err := userCommand.SetPin(context.Background(), 1, "testpassword", "12"); err != ErrInvalidPin {
+		t.Fatalf("expected invalid pin error, got %v", err)
+	}
+	if err := userCommand.SetPin(context.Background(), 1, "testpassword", "1234"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got, _ := repo.GetUser(context.Background(), 1)
+	if got.PinHash == "" {
+		t.Fatal("expected PIN hash to be stored")
+	}
+
+	if err := userCommand.RemovePin(context.Background(), 1); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	got, _ = repo.GetUser(context.Background(), 1)
+	if got.PinHash != "" {
+		t.Fatal("expected PIN hash to be removed")
+	}
+}
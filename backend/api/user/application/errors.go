@@ -2,6 +2,8 @@ package application
 
 import (
 	"errors"
+
+	"github.com/nicograef/jotti/backend/api/command"
 )
 
 // ErrUserNotFound is returned when a user is not found.
@@ -13,10 +15,25 @@ var ErrUsernameAlreadyExists = errors.New("username already exists")
 // ErrNoPassword is returned when there is no password set for the user.
 var ErrNoPassword = errors.New("no password set")
 
+// ErrInvalidPassword is returned when the password of the user is wrong.
+var ErrInvalidPassword = errors.New("invalid password")
+
+// ErrInvalidPin is returned when the PIN does not have 4 to 6 digits.
+var ErrInvalidPin = errors.New("invalid pin")
+
+// ErrRoleNotFound is returned when a user is assigned to a role that does not exist.
+var ErrRoleNotFound = errors.New("role not found")
+
+// ErrRoleNotAllowed is returned when a role grants permissions the user who assigns it lacks.
+var ErrRoleNotAllowed = errors.New("role not allowed")
+
 var ErrInvalidUserData = errors.New("invalid user data")
 
 // ErrNoOnetimePassword is returned when there is no one-time password set for the user.
 var ErrNoOnetimePassword = errors.New("no onetime password set")
 
+// ErrRetryLater is returned when a change conflicted with a concurrent change or the database was not reachable.
+var ErrRetryLater = command.ErrRetryLater
+
 // ErrDatabase is returned when there is a database error.
-var ErrDatabase = errors.New("database error")
+var ErrDatabase = command.ErrDatabase
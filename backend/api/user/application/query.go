@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/nicograef/jotti/backend/domain/user"
+	"github.com/nicograef/jotti/backend/tracing"
 	"github.com/rs/zerolog"
 )
 
@@ -16,6 +17,9 @@ type Query struct {
 }
 
 func (q Query) GetAllUsers(ctx context.Context) ([]user.User, error) {
+	ctx, span := tracing.Start(ctx, "user.GetAllUsers")
+	defer span.End()
+
 	log := zerolog.Ctx(ctx)
 
 	users, err := q.UserRepo.GetAllUsers(ctx)
@@ -6,16 +6,21 @@ import (
 	"net/http"
 
 	"github.com/nicograef/jotti/backend/api/helper"
+	"github.com/nicograef/jotti/backend/api/middleware"
 	"github.com/nicograef/jotti/backend/api/user/application"
+	"github.com/nicograef/jotti/backend/domain/role"
 	"github.com/nicograef/jotti/backend/domain/user"
 )
 
 type command interface {
-	CreateUser(ctx context.Context, name, username string, role user.Role) (int, string, error)
-	UpdateUser(ctx context.Context, id int, name, username string, role user.Role) error
-	ActivateUser(ctx context.Context, id int) error
-	DeactivateUser(ctx context.Context, id int) error
-	ResetPassword(ctx context.Context, userID int) (string, error)
+	CreateUser(ctx context.Context, actorID int, actorPermissions []role.Permission, name, username string, role user.Role) (int, string, error)
+	UpdateUser(ctx context.Context, actorID int, actorPermissions []role.Permission, id int, name, username string, role user.Role) error
+	ActivateUser(ctx context.Context, actorID, id int) error
+	DeactivateUser(ctx context.Context, actorID, id int) error
+	ResetPassword(ctx context.Context, actorID, userID int) (string, error)
+	RequirePasswordChange(ctx context.Context, actorID, userID int) error
+	SetPin(ctx context.Context, userID int, password, pin string) error
+	RemovePin(ctx context.Context, userID int) error
 }
 
 type CommandHandler struct {
@@ -40,11 +45,24 @@ func (h CommandHandler) CreateUserHandler() http.HandlerFunc {
 			return
 		}
 
-		userID, onetimePassword, err := h.Command.CreateUser(r.Context(), body.Name, body.Username, body.Role)
+		actorID := r.Context().Value(middleware.UserIDKey).(int)
+		userID, onetimePassword, err := h.Command.CreateUser(r.Context(), actorID, middleware.Permissions(r.Context()), body.Name, body.Username, body.Role)
 		if err != nil {
 			if errors.Is(err, application.ErrUsernameAlreadyExists) {
 				helper.SendClientError(w, "username_already_exists", nil)
 				return
+			} else if errors.Is(err, application.ErrRoleNotFound) {
+				helper.SendClientError(w, "role_not_found", nil)
+				return
+			} else if errors.Is(err, application.ErrRoleNotAllowed) {
+				helper.SendClientError(w, "role_not_allowed", nil)
+				return
+			} else if errors.Is(err, application.ErrInvalidUserData) {
+				helper.SendValidationError(w, "invalid_user_data", err)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
 			} else {
 				helper.SendServerError(w)
 				return
@@ -69,7 +87,8 @@ func (h CommandHandler) UpdateUserHandler() http.HandlerFunc {
 			return
 		}
 
-		err := h.Command.UpdateUser(r.Context(), body.ID, body.Name, body.Username, body.Role)
+		actorID := r.Context().Value(middleware.UserIDKey).(int)
+		err := h.Command.UpdateUser(r.Context(), actorID, middleware.Permissions(r.Context()), body.ID, body.Name, body.Username, body.Role)
 		if err != nil {
 			if errors.Is(err, application.ErrUserNotFound) {
 				helper.SendClientError(w, "user_not_found", nil)
@@ -77,6 +96,18 @@ func (h CommandHandler) UpdateUserHandler() http.HandlerFunc {
 			} else if errors.Is(err, application.ErrUsernameAlreadyExists) {
 				helper.SendClientError(w, "username_already_exists", nil)
 				return
+			} else if errors.Is(err, application.ErrRoleNotFound) {
+				helper.SendClientError(w, "role_not_found", nil)
+				return
+			} else if errors.Is(err, application.ErrRoleNotAllowed) {
+				helper.SendClientError(w, "role_not_allowed", nil)
+				return
+			} else if errors.Is(err, application.ErrInvalidUserData) {
+				helper.SendValidationError(w, "invalid_user_data", err)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
 			} else {
 				helper.SendServerError(w)
 				return
@@ -103,11 +134,15 @@ func (h CommandHandler) ResetPasswordHandler() http.HandlerFunc {
 			return
 		}
 
-		onetimePassword, err := h.Command.ResetPassword(r.Context(), body.ID)
+		actorID := r.Context().Value(middleware.UserIDKey).(int)
+		onetimePassword, err := h.Command.ResetPassword(r.Context(), actorID, body.ID)
 		if err != nil {
 			if errors.Is(err, application.ErrUserNotFound) {
 				helper.SendClientError(w, "user_not_found", nil)
 				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
 			} else {
 				helper.SendServerError(w)
 				return
@@ -118,6 +153,40 @@ func (h CommandHandler) ResetPasswordHandler() http.HandlerFunc {
 	}
 }
 
+type requirePasswordChange struct {
+	ID int `json:"id"`
+}
+
+// RequirePasswordChangeHandler handles requests to make a user choose a new password on next login.
+func (h CommandHandler) RequirePasswordChangeHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := requirePasswordChange{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		actorID := r.Context().Value(middleware.UserIDKey).(int)
+		err := h.Command.RequirePasswordChange(r.Context(), actorID, body.ID)
+		if err != nil {
+			if errors.Is(err, application.ErrUserNotFound) {
+				helper.SendClientError(w, "user_not_found", nil)
+				return
+			} else if errors.Is(err, application.ErrNoPassword) {
+				helper.SendClientError(w, "no_password_set", "User has no password yet and has to set one anyway.")
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendEmptyResponse(w)
+	}
+}
+
 type activateUser struct {
 	ID int `json:"id"`
 }
@@ -130,11 +199,15 @@ func (h CommandHandler) ActivateUserHandler() http.HandlerFunc {
 			return
 		}
 
-		err := h.Command.ActivateUser(r.Context(), body.ID)
+		actorID := r.Context().Value(middleware.UserIDKey).(int)
+		err := h.Command.ActivateUser(r.Context(), actorID, body.ID)
 		if err != nil {
 			if errors.Is(err, application.ErrUserNotFound) {
 				helper.SendClientError(w, "user_not_found", nil)
 				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
 			} else {
 				helper.SendServerError(w)
 				return
@@ -157,11 +230,77 @@ func (h CommandHandler) DeactivateUserHandler() http.HandlerFunc {
 			return
 		}
 
-		err := h.Command.DeactivateUser(r.Context(), body.ID)
+		actorID := r.Context().Value(middleware.UserIDKey).(int)
+		err := h.Command.DeactivateUser(r.Context(), actorID, body.ID)
+		if err != nil {
+			if errors.Is(err, application.ErrUserNotFound) {
+				helper.SendClientError(w, "user_not_found", nil)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendEmptyResponse(w)
+	}
+}
+
+type setPin struct {
+	Password string `json:"password"`
+	Pin      string `json:"pin"`
+}
+
+// SetPinHandler handles requests of the logged in user to set their PIN.
+func (h CommandHandler) SetPinHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := setPin{}
+		if !helper.ReadBody(w, r, &body) {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(int)
+
+		err := h.Command.SetPin(r.Context(), userID, body.Password, body.Pin)
+		if err != nil {
+			if errors.Is(err, application.ErrInvalidPassword) || errors.Is(err, application.ErrNoPassword) {
+				helper.SendClientError(w, "invalid_credentials", nil)
+				return
+			} else if errors.Is(err, application.ErrInvalidPin) {
+				helper.SendClientError(w, "invalid_pin", "PIN must be 4 to 6 digits.")
+				return
+			} else if errors.Is(err, application.ErrUserNotFound) {
+				helper.SendClientError(w, "user_not_found", nil)
+				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
+			} else {
+				helper.SendServerError(w)
+				return
+			}
+		}
+
+		helper.SendEmptyResponse(w)
+	}
+}
+
+// RemovePinHandler handles requests of the logged in user to remove their PIN.
+func (h CommandHandler) RemovePinHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(middleware.UserIDKey).(int)
+
+		err := h.Command.RemovePin(r.Context(), userID)
 		if err != nil {
 			if errors.Is(err, application.ErrUserNotFound) {
 				helper.SendClientError(w, "user_not_found", nil)
 				return
+			} else if errors.Is(err, application.ErrRetryLater) {
+				helper.SendRetryLater(w)
+				return
 			} else {
 				helper.SendServerError(w)
 				return
@@ -4,12 +4,22 @@ import (
 	"database/sql"
 
 	"github.com/nicograef/jotti/backend/api/user/application"
+	dbpkg "github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/user"
+	"github.com/nicograef/jotti/backend/repository/event_repo"
+	"github.com/nicograef/jotti/backend/repository/role_repo"
+	"github.com/nicograef/jotti/backend/repository/session_repo"
 	"github.com/nicograef/jotti/backend/repository/user_repo"
 )
 
-func NewCommandHandler(db *sql.DB) CommandHandler {
-	repo := user_repo.Repository{DB: db}
-	command := application.Command{UserRepo: repo}
+func NewCommandHandler(db *sql.DB, passwordPolicy user.PasswordPolicy) CommandHandler {
+	userRepo := user_repo.Repository{DB: db}
+	sessionRepo := session_repo.Repository{DB: db}
+	roleRepo := role_repo.Repository{DB: db}
+	eventRepo := event_repo.Repository{DB: db}
+	// lost updates of concurrent changes fail and are retried
+	transactor := dbpkg.Transactor{DB: db, Isolation: sql.LevelRepeatableRead}
+	command := application.Command{Transactor: transactor, UserRepo: userRepo, SessionRepo: sessionRepo, RoleRepo: roleRepo, EventRepo: eventRepo, PasswordPolicy: passwordPolicy}
 	return CommandHandler{Command: command}
 }
 
@@ -0,0 +1,59 @@
+package http
+
+import "github.com/nicograef/jotti/backend/api/openapi"
+
+// Operations of the handlers in the OpenAPI document.
+
+var CreateUserOperation = openapi.Operation{
+	Summary:  "Create a user, the one-time password is only returned once",
+	Request:  createUser{},
+	Response: createUserResponse{},
+	Errors:   []string{"invalid_user_data", "retry_later", "role_not_allowed", "role_not_found", "username_already_exists"},
+}
+
+var UpdateUserOperation = openapi.Operation{
+	Summary: "Change the name, username or role of a user",
+	Request: updateUser{},
+	Errors:  []string{"invalid_user_data", "retry_later", "role_not_allowed", "role_not_found", "user_not_found", "username_already_exists"},
+}
+
+var ActivateUserOperation = openapi.Operation{
+	Summary: "Activate a user",
+	Request: activateUser{},
+	Errors:  []string{"retry_later", "user_not_found"},
+}
+
+var DeactivateUserOperation = openapi.Operation{
+	Summary: "Deactivate a user and end their sessions",
+	Request: deactivateUser{},
+	Errors:  []string{"retry_later", "user_not_found"},
+}
+
+var ResetPasswordOperation = openapi.Operation{
+	Summary:  "Replace the password of a user with a new one-time password",
+	Request:  resetPassword{},
+	Response: resetPasswordResponse{},
+	Errors:   []string{"retry_later", "user_not_found"},
+}
+
+var RequirePasswordChangeOperation = openapi.Operation{
+	Summary: "Make a user choose a new password on their next login",
+	Request: requirePasswordChange{},
+	Errors:  []string{"no_password_set", "retry_later", "user_not_found"},
+}
+
+var GetAllUsersOperation = openapi.Operation{
+	Summary:  "List all users",
+	Response: getUsersResponse{},
+}
+
+var SetPinOperation = openapi.Operation{
+	Summary: "Set the PIN of the logged in user",
+	Request: setPin{},
+	Errors:  []string{"invalid_credentials", "invalid_pin", "retry_later", "user_not_found"},
+}
+
+var RemovePinOperation = openapi.Operation{
+	Summary: "Remove the PIN of the logged in user",
+	Errors:  []string{"retry_later", "user_not_found"},
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/nicograef/jotti/backend/api/helper"
 	"github.com/nicograef/jotti/backend/domain/user"
@@ -16,8 +17,16 @@ type QueryHandler struct {
 	Query query
 }
 
-type getUsersResponse = struct {
-	Users []user.User `json:"users"`
+// userResponse is a user as admins see it.
+type userResponse struct {
+	user.User
+	// HasPendingOnetimePassword is false once the one-time password expired or was locked by wrong attempts,
+	// although onetimePasswordExpiresAt stays set until the next reset.
+	HasPendingOnetimePassword bool `json:"hasPendingOnetimePassword"`
+}
+
+type getUsersResponse struct {
+	Users []userResponse `json:"users"`
 }
 
 func (h *QueryHandler) GetAllUsersHandler() http.HandlerFunc {
@@ -28,6 +37,12 @@ func (h *QueryHandler) GetAllUsersHandler() http.HandlerFunc {
 			return
 		}
 
-		helper.SendResponse(w, getUsersResponse{Users: users})
+		now := time.Now().UTC()
+		response := getUsersResponse{Users: make([]userResponse, 0, len(users))}
+		for _, u := range users {
+			response.Users = append(response.Users, userResponse{User: u, HasPendingOnetimePassword: u.HasPendingOnetimePassword(now)})
+		}
+
+		helper.SendResponse(w, response)
 	}
 }
@@ -12,19 +12,32 @@ import (
 	"github.com/nicograef/jotti/backend/api"
 	"github.com/nicograef/jotti/backend/api/health"
 	"github.com/nicograef/jotti/backend/api/middleware"
+	"github.com/nicograef/jotti/backend/api/openapi"
+	table "github.com/nicograef/jotti/backend/api/table/http"
 	"github.com/nicograef/jotti/backend/config"
+	"github.com/nicograef/jotti/backend/domain/jwt"
+	"github.com/nicograef/jotti/backend/metrics"
+	"github.com/nicograef/jotti/backend/repository/session_repo"
 )
 
 // App represents the application with its configuration, router, server, and database connection.
 type App struct {
 	Server *http.Server
-	Config config.Config
-	DB     *sql.DB
+	// MetricsServer serves /metrics and the OpenAPI document /openapi.json on an internal port
+	MetricsServer *http.Server
+	Config        config.Config
+	DB            *sql.DB
 }
 
 // NewApp creates a new application instance
 func NewApp(cfg config.Config, db *sql.DB) (*App, error) {
-	router := SetupRoutes(cfg, db)
+	jwtKeys, err := jwt.LoadKeys(cfg.JWTKeysDir, cfg.JWTSigningKeyID, cfg.JWTSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWT keys: %w", err)
+	}
+
+	doc := api.NewDocument()
+	router := SetupRoutes(cfg, db, jwtKeys, doc)
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Port),
 		ReadTimeout:  30 * time.Second,
@@ -32,53 +45,106 @@ func NewApp(cfg config.Config, db *sql.DB) (*App, error) {
 		Handler:      router,
 	}
 
+	metricsServer := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.MetricsPort),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		Handler:      SetupMetricsRoutes(db, doc),
+	}
+
 	return &App{
-		Server: server,
-		Config: cfg,
-		DB:     db,
+		Server:        server,
+		MetricsServer: metricsServer,
+		Config:        cfg,
+		DB:            db,
 	}, nil
 }
 
-// SetupRoutes configures HTTP routes
-func SetupRoutes(cfg config.Config, db *sql.DB) http.Handler {
+// Rate limits of clients. The idle timeout is long enough to refill every budget.
+var (
+	// clientBudget limits all requests of a client IP
+	clientBudget = middleware.RateBudget{PerSecond: 50, Burst: 100}
+	// loginBudget limits password and PIN logins of a client IP
+	loginBudget = middleware.RateBudget{PerSecond: 0.5, Burst: 10}
+	// userBudget limits the requests of a logged in user to the admin and service APIs
+	userBudget = middleware.RateBudget{PerSecond: 20, Burst: 40}
+)
+
+const rateLimitIdleTimeout = 10 * time.Minute
+
+// SetupRoutes configures HTTP routes and adds the routes of the APIs to the OpenAPI document.
+func SetupRoutes(cfg config.Config, db *sql.DB, jwtKeys jwt.Keys, doc *openapi.Document) http.Handler {
 	r := http.NewServeMux()
 
 	// Health check with database connectivity
 	healthCheck := health.HealthCheck{DB: db}
 	r.HandleFunc("/health", healthCheck.Handler())
 
-	authApi := api.NewAuthApi(cfg, db)
-	r.Handle("/auth/", http.StripPrefix("/auth", authApi))
+	authApi := api.NewAuthApi(cfg, db, jwtKeys, doc)
+	authHandler := http.StripPrefix("/auth", middleware.RouteMiddleware("/auth", authApi))
+	r.Handle("/auth/", authHandler)
+
+	// Logins and password changes have a budget of their own per client IP, so guessing passwords (and one-time
+	// passwords) is slow without affecting other calls
+	loginLimit := middleware.RateLimitMiddleware(middleware.NewRateLimiter(loginBudget, rateLimitIdleTimeout), middleware.ClientIPRateLimitKey)
+	r.Handle("/auth/login", loginLimit(authHandler))
+	r.Handle("/auth/pin-login", loginLimit(authHandler))
+	r.Handle("/auth/set-password", loginLimit(authHandler))
+	r.Handle("/auth/change-password", loginLimit(authHandler))
+
+	sessionRepo := session_repo.Repository{DB: db}
 
-	admin := middleware.NewJwtMiddleware(cfg.JWTSecret, []string{"admin"})
-	adminApi := api.NewAdminApi(db)
-	r.Handle("/admin/", admin(http.StripPrefix("/admin", adminApi)))
+	// Both APIs require a valid session, permissions are checked per endpoint
+	authenticated := middleware.NewJwtMiddleware(jwtKeys, sessionRepo)
+	// Logged in users share one budget for both APIs, independent of their IP
+	userLimit := middleware.RateLimitMiddleware(middleware.NewRateLimiter(userBudget, rateLimitIdleTimeout), middleware.UserRateLimitKey)
 
-	servicesApi := api.NewServiceApi(db)
-	service := middleware.NewJwtMiddleware(cfg.JWTSecret, []string{"admin", "service"})
-	r.Handle("/service/", service(http.StripPrefix("/service", servicesApi)))
+	adminApi := api.NewAdminApi(cfg, db, jwtKeys, doc)
+	r.Handle("/admin/", authenticated(userLimit(http.StripPrefix("/admin", middleware.RouteMiddleware("/admin", adminApi)))))
+
+	servicesApi := api.NewServiceApi(cfg, db, doc)
+	r.Handle("/service/", authenticated(userLimit(http.StripPrefix("/service", middleware.RouteMiddleware("/service", servicesApi)))))
+
+	clientLimit := middleware.RateLimitMiddleware(middleware.NewRateLimiter(clientBudget, rateLimitIdleTimeout), middleware.ClientIPRateLimitKey)
 
 	// Wrap the entire router with middleware chain
 	// Note: Security headers (HSTS, CSP, X-Frame-Options, etc.) are set by nginx
-	var handler http.Handler = r
-	handler = middleware.PostMethodOnlyMiddleware(handler) // Enforce POST method
-	handler = middleware.RateLimitMiddleware(100)(handler) // Rate limiting
-	handler = middleware.LoggingMiddleware(handler)        // Logging
-	handler = middleware.CorrelationIDMiddleware(handler)  // Correlation ID
+	var handler http.Handler = middleware.RouteMiddleware("", r)         // Route of the request for metrics
+	handler = middleware.PostMethodOnlyMiddleware(handler)               // Enforce POST method
+	handler = clientLimit(handler)                                       // Rate limiting per client IP
+	handler = middleware.ClientIPMiddleware(cfg.TrustedProxies)(handler) // Client IP behind reverse proxy
+	handler = middleware.LoggingMiddleware(handler)                      // Logging
+	handler = middleware.TracingMiddleware(handler)                      // Tracing
+	handler = middleware.CorrelationIDMiddleware(handler)                // Correlation ID
 
 	return handler
 }
 
+// SetupMetricsRoutes serves the metrics of requests, events, the connection pool and open tables, and the OpenAPI
+// document. The main server only accepts POST requests.
+func SetupMetricsRoutes(db *sql.DB, doc *openapi.Document) http.Handler {
+	r := http.NewServeMux()
+	r.Handle("GET /metrics", metrics.Handler(metrics.DBStats(db), table.NewMetricsCollector(db)))
+	r.Handle("GET /openapi.json", doc.Handler())
+	return r
+}
+
 // Run starts the application with graceful shutdown
 func (app *App) Run(ctx context.Context) error {
 	// Start server in goroutine
-	errChan := make(chan error, 1)
+	errChan := make(chan error, 2)
 	go func() {
 		log.Info().Int("port", app.Config.Port).Msg("Starting server")
 		if err := app.Server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			errChan <- err
 		}
 	}()
+	go func() {
+		log.Info().Int("port", app.Config.MetricsPort).Msg("Starting metrics server")
+		if err := app.MetricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errChan <- fmt.Errorf("metrics server: %w", err)
+		}
+	}()
 
 	// Wait for context cancellation or server error
 	select {
@@ -100,6 +166,9 @@ func (app *App) Shutdown() error {
 	if err := app.Server.Shutdown(ctx); err != nil {
 		log.Printf("ERROR shutting down server: %v", err)
 	}
+	if err := app.MetricsServer.Shutdown(ctx); err != nil {
+		log.Printf("ERROR shutting down metrics server: %v", err)
+	}
 
 	fmt.Println("Shutdown complete")
 	return nil
@@ -5,11 +5,16 @@ package app
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/nicograef/jotti/backend/api"
 	"github.com/nicograef/jotti/backend/config"
+	"github.com/nicograef/jotti/backend/domain/jwt"
 )
 
 func TestNewApp(t *testing.T) {
@@ -33,13 +38,38 @@ func TestSetupRoutes(t *testing.T) {
 	os.Setenv("JWT_SECRET", "test-secret-for-app-tests")
 	cfg := config.Load()
 
-	handler := SetupRoutes(cfg, &sql.DB{})
+	handler := SetupRoutes(cfg, &sql.DB{}, jwt.NewSecretKeys(cfg.JWTSecret), api.NewDocument())
 
 	if handler == nil {
 		t.Error("Handler should not be nil")
 	}
 }
 
+func TestSetupMetricsRoutes_OpenAPI(t *testing.T) {
+	os.Setenv("JWT_SECRET", "test-secret-for-app-tests")
+	cfg := config.Load()
+	doc := api.NewDocument()
+	SetupRoutes(cfg, &sql.DB{}, jwt.NewSecretKeys(cfg.JWTSecret), doc)
+
+	rec := httptest.NewRecorder()
+	SetupMetricsRoutes(&sql.DB{}, doc).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var spec struct {
+		Paths map[string]any `json:"paths"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&spec); err != nil {
+		t.Fatalf("failed to decode document: %v", err)
+	}
+	for _, path := range []string{"/auth/login", "/admin/create-table", "/service/sync"} {
+		if _, ok := spec.Paths[path]; !ok {
+			t.Errorf("expected %s in the document", path)
+		}
+	}
+}
+
 func TestShutdown(t *testing.T) {
 	cfg := config.Load()
 	app, err := NewApp(cfg, &sql.DB{})
@@ -3,11 +3,16 @@ package config
 import (
 	"fmt"
 	"log"
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // the deploy image has no zoneinfo
 )
 
-type postgresConfig struct {
+// PostgresConfig holds the database connection.
+type PostgresConfig struct {
 	Host     string
 	Port     int
 	User     string
@@ -18,28 +23,92 @@ type postgresConfig struct {
 // Config holds application configuration values loaded from environment variables.
 type Config struct {
 	Port      int // Port for the HTTP server
-	Postgres  postgresConfig
-	JWTSecret string // Secret key for JWT signing
+	Postgres  PostgresConfig
+	JWTSecret string // Secret key for JWT signing (HS256), optional with JWTKeysDir
+	// JWTKeysDir holds PEM key files to sign and verify JWTs, named after their key ID
+	JWTKeysDir string
+	// JWTSigningKeyID is the key in JWTKeysDir that signs new JWTs
+	JWTSigningKeyID string
+	// ReportLocation defines where a business day starts and ends in reports
+	ReportLocation *time.Location
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For header is used to determine the client IP
+	TrustedProxies []netip.Prefix
+	// PasswordMinLength is the minimum number of characters of a new password
+	PasswordMinLength int
+	// CommonPasswords is how many of the most common passwords are rejected as new password
+	CommonPasswords int
+	// Argon2 holds the cost parameters for password hashes. Stored hashes with weaker parameters are upgraded on login
+	Argon2 argon2Config
+	// MetricsPort is the internal port of the metrics endpoint, which is not published by the reverse proxy
+	MetricsPort int
+	// TracesExporter sends the spans to an OTLP collector ("otlp"), stdout ("console") or nowhere ("none")
+	TracesExporter string
+	// ServiceName identifies the backend in traces
+	ServiceName string
+	// SyncMaxItemAge is how long ago an offline client may have entered the orders and payments it uploads
+	SyncMaxItemAge time.Duration
+}
+
+type argon2Config struct {
+	MemoryKiB   int
+	Iterations  int
+	Parallelism int
 }
 
 // Load reads configuration from environment variables and returns a Config struct.
 // Defaults: PORT=3000 CAPACITY=1000, CONSUMER_URL="http://localhost:4000" DELIVERY_ATTEMPTS=3
 func Load() Config {
 	port := parseEnvInt("PORT", 3000)
-	postgres := postgresConfig{
+	metricsPort := parseEnvInt("METRICS_PORT", 9090)
+	// the names of the OpenTelemetry SDK environment variables
+	tracesExporter := parseEnvString("OTEL_TRACES_EXPORTER", "none")
+	serviceName := parseEnvString("OTEL_SERVICE_NAME", "jotti-backend")
+	postgres := LoadPostgres()
+	// With key files the secret is only needed to verify tokens issued before switching to them
+	jwtKeysDir := os.Getenv("JWT_KEYS_DIR")
+	jwtSigningKeyID := os.Getenv("JWT_SIGNING_KEY_ID")
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtKeysDir == "" {
+		jwtSecret = parseEnvString("JWT_SECRET", "")
+	}
+	reportLocation := parseEnvLocation("REPORT_TIMEZONE", "Europe/Berlin")
+	trustedProxies := parseEnvPrefixes("TRUSTED_PROXIES")
+	passwordMinLength := parseEnvInt("PASSWORD_MIN_LENGTH", 8)
+	commonPasswords := parseEnvInt("COMMON_PASSWORDS", 1000)
+	syncMaxItemAge := time.Duration(parseEnvInt("SYNC_MAX_ITEM_AGE_HOURS", 24)) * time.Hour
+	argon2 := argon2Config{
+		MemoryKiB:   parseEnvInt("ARGON2_MEMORY_KIB", 64*1024),
+		Iterations:  parseEnvInt("ARGON2_ITERATIONS", 2),
+		Parallelism: min(parseEnvInt("ARGON2_PARALLELISM", 2), 255),
+	}
+
+	return Config{
+		Port:              port,
+		MetricsPort:       metricsPort,
+		TracesExporter:    tracesExporter,
+		ServiceName:       serviceName,
+		Postgres:          postgres,
+		JWTSecret:         jwtSecret,
+		JWTKeysDir:        jwtKeysDir,
+		JWTSigningKeyID:   jwtSigningKeyID,
+		ReportLocation:    reportLocation,
+		TrustedProxies:    trustedProxies,
+		PasswordMinLength: passwordMinLength,
+		CommonPasswords:   commonPasswords,
+		Argon2:            argon2,
+		SyncMaxItemAge:    syncMaxItemAge,
+	}
+}
+
+// LoadPostgres reads only the database connection from environment variables, e.g. to run migrations.
+func LoadPostgres() PostgresConfig {
+	return PostgresConfig{
 		Host:     parseEnvString("POSTGRES_HOST", "localhost"),
 		Port:     parseEnvInt("POSTGRES_PORT", 5432),
 		User:     parseEnvString("POSTGRES_USER", "admin"),
 		Password: parseEnvString("POSTGRES_PASSWORD", "admin"),
 		DBName:   parseEnvString("POSTGRES_DBNAME", "jotti"),
 	}
-	jwtSecret := parseEnvString("JWT_SECRET", "")
-
-	return Config{
-		Port:      port,
-		Postgres:  postgres,
-		JWTSecret: jwtSecret,
-	}
 }
 
 // parseEnvString reads an environment variable by name and returns its value, or the provided default if unset.
@@ -54,6 +123,51 @@ func parseEnvString(name, defaultValue string) string {
 	return v
 }
 
+// parseEnvLocation reads an IANA time zone name from an environment variable and loads it.
+// If the zone is unknown, logs an error and returns the provided default zone.
+func parseEnvLocation(name, defaultValue string) *time.Location {
+	v := parseEnvString(name, defaultValue)
+
+	location, err := time.LoadLocation(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid %s value: %v\n", name, err)
+		location, _ = time.LoadLocation(defaultValue)
+	}
+
+	return location
+}
+
+// parseEnvPrefixes reads a comma-separated list of IP addresses and CIDR ranges from an environment variable.
+// Invalid entries are logged and skipped. Returns an empty list if unset.
+func parseEnvPrefixes(name string) []netip.Prefix {
+	prefixes := []netip.Prefix{}
+	for _, v := range strings.Split(os.Getenv(name), ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		if !strings.Contains(v, "/") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid %s value: %v\n", name, err)
+				continue
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid %s value: %v\n", name, err)
+			continue
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes
+}
+
 // parseEnvInt reads an environment variable by name and converts it to int.
 // If conversion fails, logs an error and returns the provided default value.
 func parseEnvInt(name string, defaultValue int) int {
@@ -5,6 +5,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoad_Defaults(t *testing.T) {
@@ -32,6 +33,39 @@ func TestLoad_Defaults(t *testing.T) {
 	if cfg.Postgres.DBName != "jotti" {
 		t.Errorf("expected default Postgres DBName 'jotti', got %s", cfg.Postgres.DBName)
 	}
+	if cfg.ReportLocation.String() != "Europe/Berlin" {
+		t.Errorf("expected default report location 'Europe/Berlin', got %s", cfg.ReportLocation)
+	}
+	if len(cfg.TrustedProxies) != 0 {
+		t.Errorf("expected no trusted proxies by default, got %v", cfg.TrustedProxies)
+	}
+	if cfg.PasswordMinLength != 8 {
+		t.Errorf("expected default password min length 8, got %d", cfg.PasswordMinLength)
+	}
+	if cfg.CommonPasswords != 1000 {
+		t.Errorf("expected default common passwords 1000, got %d", cfg.CommonPasswords)
+	}
+	if cfg.Argon2.MemoryKiB != 64*1024 || cfg.Argon2.Iterations != 2 || cfg.Argon2.Parallelism != 2 {
+		t.Errorf("expected default Argon2 parameters m=65536,t=2,p=2, got %+v", cfg.Argon2)
+	}
+	if cfg.SyncMaxItemAge != 24*time.Hour {
+		t.Errorf("expected default sync max item age 24h, got %s", cfg.SyncMaxItemAge)
+	}
+}
+
+func TestLoad_TrustedProxies(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("JWT_SECRET", "test-secret-key-for-unit-tests")
+	os.Setenv("TRUSTED_PROXIES", "172.16.0.0/12, 10.0.0.5,invalid")
+
+	cfg := Load()
+
+	if len(cfg.TrustedProxies) != 2 {
+		t.Fatalf("expected 2 trusted proxies, got %v", cfg.TrustedProxies)
+	}
+	if cfg.TrustedProxies[0].String() != "172.16.0.0/12" || cfg.TrustedProxies[1].String() != "10.0.0.5/32" {
+		t.Errorf("unexpected trusted proxies: %v", cfg.TrustedProxies)
+	}
 }
 
 func TestLoad_EnvValues(t *testing.T) {
@@ -121,3 +155,18 @@ func TestLoad_NegativeValues(t *testing.T) {
 		t.Errorf("expected fallback port 3000 for negative value, got %d", cfg.Port)
 	}
 }
+
+func TestLoad_JWTKeysDir(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("JWT_KEYS_DIR", "/run/secrets/jwt")
+	os.Setenv("JWT_SIGNING_KEY_ID", "2026-01")
+
+	cfg := Load()
+
+	if cfg.JWTKeysDir != "/run/secrets/jwt" || cfg.JWTSigningKeyID != "2026-01" {
+		t.Errorf("expected JWT key settings, got %q %q", cfg.JWTKeysDir, cfg.JWTSigningKeyID)
+	}
+	if cfg.JWTSecret != "" {
+		t.Errorf("expected no JWT secret, got %q", cfg.JWTSecret)
+	}
+}
@@ -1,9 +1,13 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"io"
+	"net"
+	"strings"
 
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/rs/zerolog/log"
@@ -16,31 +20,125 @@ type ErrorCode string
 const (
 	// UniqueViolation indicates a violation of a unique constraint.
 	ErrorCodeUniqueViolation ErrorCode = "23505"
+	// ForeignKeyViolation indicates a reference to a row that does not exist or a deleted row that is still referenced.
+	ErrorCodeForeignKeyViolation ErrorCode = "23503"
+	// NotNullViolation indicates a missing value in a NOT NULL column.
+	ErrorCodeNotNullViolation ErrorCode = "23502"
+	// CheckViolation indicates a violation of a check constraint.
+	ErrorCodeCheckViolation ErrorCode = "23514"
+	// SerializationFailure indicates a transaction that conflicted with a concurrent transaction.
+	ErrorCodeSerializationFailure ErrorCode = "40001"
+	// DeadlockDetected indicates a transaction that was aborted to resolve a deadlock.
+	ErrorCodeDeadlockDetected ErrorCode = "40P01"
+	// QueryCanceled indicates a statement that was canceled, e.g. by a statement timeout.
+	ErrorCodeQueryCanceled ErrorCode = "57014"
+	// AdminShutdown indicates a connection that was terminated by the server.
+	ErrorCodeAdminShutdown ErrorCode = "57P01"
+	// CannotConnectNow indicates a server that is starting up or shutting down.
+	ErrorCodeCannotConnectNow ErrorCode = "57P03"
 )
 
+// connectionExceptionClass is the class of all error codes of failed connections (08xxx).
+const connectionExceptionClass = "08"
+
 // ErrNotFound is returned when a record is not found.
 var ErrNotFound = errors.New("not found")
 
 // ErrAlreadyExists is returned when a record already exists.
 var ErrAlreadyExists = errors.New("already exists")
 
+// ErrForeignKeyViolation is returned when a record references a record that does not exist,
+// or when a record that is still referenced is deleted.
+var ErrForeignKeyViolation = errors.New("foreign key violation")
+
+// ErrCheckViolation is returned when a record violates a check or not null constraint.
+var ErrCheckViolation = errors.New("check violation")
+
+// ErrSerializationFailure is returned when a transaction conflicted with a concurrent transaction and can be retried.
+var ErrSerializationFailure = errors.New("serialization failure")
+
+// ErrConnection is returned when the connection to the database failed or was lost. The operation can be retried later.
+var ErrConnection = errors.New("database connection failed")
+
+// ErrCanceled is returned when a query was canceled, usually because the request was canceled or timed out.
+var ErrCanceled = errors.New("query canceled")
+
 // ErrDatabase is returned when there is a database error.
 var ErrDatabase = errors.New("database error")
 
+// ConstraintError is a violation of a constraint. It wraps ErrAlreadyExists, ErrForeignKeyViolation or
+// ErrCheckViolation, so it is checked with errors.Is, and tells which constraint was violated.
+type ConstraintError struct {
+	Err        error
+	Constraint string
+	Table      string
+}
+
+func (e *ConstraintError) Error() string {
+	return e.Err.Error() + " (" + e.Constraint + ")"
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
+
+// Constraint returns the name of the violated constraint if err is a ConstraintError, otherwise "".
+func Constraint(err error) string {
+	var constraintErr *ConstraintError
+	if errors.As(err, &constraintErr) {
+		return constraintErr.Constraint
+	}
+	return ""
+}
+
+// IsTemporary reports whether err is a serialization failure or connection error, so the operation can succeed later.
+func IsTemporary(err error) bool {
+	return errors.Is(err, ErrSerializationFailure) || errors.Is(err, ErrConnection)
+}
+
 // Error maps a database error to a more specific error.
 func Error(err error) error {
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
-		switch pgErr.Code {
-		case string(ErrorCodeUniqueViolation):
-			return ErrAlreadyExists
+		switch ErrorCode(pgErr.Code) {
+		case ErrorCodeUniqueViolation:
+			return &ConstraintError{Err: ErrAlreadyExists, Constraint: pgErr.ConstraintName, Table: pgErr.TableName}
+		case ErrorCodeForeignKeyViolation:
+			return &ConstraintError{Err: ErrForeignKeyViolation, Constraint: pgErr.ConstraintName, Table: pgErr.TableName}
+		case ErrorCodeCheckViolation, ErrorCodeNotNullViolation:
+			constraint := pgErr.ConstraintName
+			if constraint == "" {
+				constraint = pgErr.ColumnName
+			}
+			return &ConstraintError{Err: ErrCheckViolation, Constraint: constraint, Table: pgErr.TableName}
+		case ErrorCodeSerializationFailure, ErrorCodeDeadlockDetected:
+			return ErrSerializationFailure
+		case ErrorCodeQueryCanceled:
+			return ErrCanceled
+		case ErrorCodeAdminShutdown, ErrorCodeCannotConnectNow:
+			return ErrConnection
+		}
+		if strings.HasPrefix(pgErr.Code, connectionExceptionClass) {
+			return ErrConnection
 		}
+		return ErrDatabase
 	}
 
 	if errors.Is(err, sql.ErrNoRows) {
 		return ErrNotFound
 	}
 
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrCanceled
+	}
+
+	var netErr net.Error
+	var connectErr *pgconn.ConnectError
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &connectErr) || errors.As(err, &netErr) {
+		return ErrConnection
+	}
+
 	return ErrDatabase
 }
 
@@ -0,0 +1,55 @@
+//go:build unit
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		expected   error
+		constraint string
+	}{
+		{"no rows", sql.ErrNoRows, ErrNotFound, ""},
+		{"unique violation", &pgconn.PgError{Code: "23505", ConstraintName: "tables_name_key"}, ErrAlreadyExists, "tables_name_key"},
+		{"foreign key violation", &pgconn.PgError{Code: "23503", ConstraintName: "users_role_fkey"}, ErrForeignKeyViolation, "users_role_fkey"},
+		{"check violation", &pgconn.PgError{Code: "23514", ConstraintName: "products_price_check"}, ErrCheckViolation, "products_price_check"},
+		{"not null violation", &pgconn.PgError{Code: "23502", ColumnName: "name"}, ErrCheckViolation, "name"},
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, ErrSerializationFailure, ""},
+		{"deadlock", &pgconn.PgError{Code: "40P01"}, ErrSerializationFailure, ""},
+		{"statement timeout", &pgconn.PgError{Code: "57014"}, ErrCanceled, ""},
+		{"server shutdown", &pgconn.PgError{Code: "57P01"}, ErrConnection, ""},
+		{"connection failure", &pgconn.PgError{Code: "08006"}, ErrConnection, ""},
+		{"bad connection", fmt.Errorf("query: %w", driver.ErrBadConn), ErrConnection, ""},
+		{"context canceled", fmt.Errorf("query: %w", context.Canceled), ErrCanceled, ""},
+		{"deadline exceeded", context.DeadlineExceeded, ErrCanceled, ""},
+		{"syntax error", &pgconn.PgError{Code: "42601"}, ErrDatabase, ""},
+		{"other error", errors.New("boom"), ErrDatabase, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Error(tt.err)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+			if constraint := Constraint(err); constraint != tt.constraint {
+				t.Errorf("Expected constraint %q, got %q", tt.constraint, constraint)
+			}
+			temporary := tt.expected == ErrSerializationFailure || tt.expected == ErrConnection
+			if IsTemporary(err) != temporary {
+				t.Errorf("Expected IsTemporary %v for %v", temporary, err)
+			}
+		})
+	}
+}
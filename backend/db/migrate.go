@@ -0,0 +1,334 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles are the SQL migrations "<version>_<name>.up.sql" and "<version>_<name>.down.sql".
+// Each migration runs in a transaction together with its entry in schema_versions, so new files contain no
+// BEGIN/COMMIT. Released files that have them are kept unchanged, see withoutOwnTransaction.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// ownTransaction matches a migration that is wrapped in its own BEGIN and COMMIT, e.g. 01_initial from the time the
+// migrations were applied with golang-migrate.
+var ownTransaction = regexp.MustCompile(`(?is)^\s*BEGIN\s*;(.*)COMMIT\s*;\s*$`)
+
+// migrationLockID is the key of the advisory lock that keeps two processes from migrating at the same time.
+const migrationLockID = 7_413_620_011
+
+// ErrSchemaBehind is returned when the database misses migrations of this binary.
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// ErrDirtySchema is returned when a database migrated with golang-migrate is marked dirty.
+var ErrDirtySchema = errors.New("database schema is dirty")
+
+// Migration is a versioned schema change.
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+// MigrationState is a migration with the time it was applied, nil if it is pending.
+type MigrationState struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrations returns the embedded migrations ordered by version.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		file := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(file, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %s", file)
+		}
+		versionText, name, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(versionText)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version in migration file name %s", file)
+		}
+
+		content, err := migrationFiles.ReadFile("migrations/" + file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", m.Name, name, version)
+		}
+		if direction == "up" {
+			m.up = withoutOwnTransaction(string(content))
+		} else {
+			m.down = withoutOwnTransaction(string(content))
+		}
+	}
+
+	migrations := []Migration{}
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return a.Version - b.Version })
+
+	return migrations, nil
+}
+
+// withoutOwnTransaction removes the BEGIN and COMMIT around a migration. Its statements then run in the transaction
+// of the migration runner; a COMMIT in the file would commit the migration without its entry in schema_versions.
+func withoutOwnTransaction(migration string) string {
+	if match := ownTransaction.FindStringSubmatch(migration); match != nil {
+		return match[1]
+	}
+	return migration
+}
+
+// MigrateUp applies all pending migrations in order and returns them.
+func MigrateUp(ctx context.Context, db *sql.DB) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []Migration{}
+	err = withMigrationLock(ctx, db, func(conn *sql.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if _, ok := versions[m.Version]; ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, m.up, `INSERT INTO schema_versions (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// MigrateDown reverts the latest applied migration and returns it. It returns false if no migration is applied.
+func MigrateDown(ctx context.Context, db *sql.DB) (Migration, bool, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return Migration{}, false, err
+	}
+
+	var reverted Migration
+	found := false
+	err = withMigrationLock(ctx, db, func(conn *sql.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0; i-- {
+			m := migrations[i]
+			if _, ok := versions[m.Version]; !ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, m.down, `DELETE FROM schema_versions WHERE version = $1`, m.Version); err != nil {
+				return fmt.Errorf("reverting migration %d_%s failed: %w", m.Version, m.Name, err)
+			}
+			reverted, found = m, true
+			return nil
+		}
+		return nil
+	})
+
+	return reverted, found, err
+}
+
+// GetMigrationStates returns all embedded migrations with the time they were applied to the database.
+func GetMigrationStates(ctx context.Context, db *sql.DB) ([]MigrationState, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	states := []MigrationState{}
+	err = withMigrationLock(ctx, db, func(conn *sql.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			state := MigrationState{Migration: m}
+			if appliedAt, ok := versions[m.Version]; ok {
+				state.AppliedAt = &appliedAt
+			}
+			states = append(states, state)
+		}
+		return nil
+	})
+
+	return states, err
+}
+
+// CheckSchema returns ErrSchemaBehind if a migration of this binary is not applied to the database.
+func CheckSchema(ctx context.Context, db *sql.DB) error {
+	states, err := GetMigrationStates(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, state := range states {
+		if state.AppliedAt == nil {
+			return fmt.Errorf("%w: migration %d_%s is not applied", ErrSchemaBehind, state.Version, state.Name)
+		}
+	}
+
+	return nil
+}
+
+// withMigrationLock runs fn on a single connection that holds the migration lock.
+func withMigrationLock(ctx context.Context, db *sql.DB, fn func(conn *sql.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer Close(conn, "migration connection")
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return err
+	}
+	defer func() {
+		// the lock is released with the session anyway, so an error only delays the next migration
+		_, _ = conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	}()
+
+	return fn(conn)
+}
+
+// appliedVersions creates schema_versions if needed and returns the applied versions with the time they were applied.
+// Databases migrated with golang-migrate take over its version from schema_migrations.
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	var exists bool
+	if err := conn.QueryRowContext(ctx, `SELECT to_regclass('schema_versions') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		if err := createSchemaVersions(ctx, conn); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_versions`)
+	if err != nil {
+		return nil, err
+	}
+	defer Close(rows, "schema_versions")
+
+	versions := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		versions[version] = appliedAt
+	}
+
+	return versions, rows.Err()
+}
+
+func createSchemaVersions(ctx context.Context, conn *sql.Conn) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `
+		CREATE TABLE schema_versions (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+		COMMENT ON TABLE schema_versions IS 'Applied schema migrations, one row per version'`)
+	if err != nil {
+		return err
+	}
+
+	var legacy bool
+	if err := tx.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&legacy); err != nil {
+		return err
+	}
+
+	if legacy {
+		var version int
+		var dirty bool
+		err := tx.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w: golang-migrate stopped in version %d", ErrDirtySchema, version)
+		}
+
+		migrations, err := Migrations()
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if m.Version > version {
+				break
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT INTO schema_versions (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// runMigration runs the migration and the change of its version entry in one transaction.
+func runMigration(ctx context.Context, conn *sql.Conn, migration, versionQuery string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, migration); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, versionQuery, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,65 @@
+//go:build integration
+
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMigrateDownAndUp(t *testing.T) {
+	db := OpenTestDatabase(t)
+	ctx := context.Background()
+
+	if err := CheckSchema(ctx, db); err != nil {
+		t.Fatalf("Expected migrated schema, got %v", err)
+	}
+
+	reverted, ok, err := MigrateDown(ctx, db)
+	if err != nil || !ok {
+		t.Fatalf("Failed to revert migration: %v", err)
+	}
+	if err := CheckSchema(ctx, db); !errors.Is(err, ErrSchemaBehind) {
+		t.Fatalf("Expected ErrSchemaBehind, got %v", err)
+	}
+
+	applied, err := MigrateUp(ctx, db)
+	if err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != reverted.Version {
+		t.Fatalf("Expected migration %d to be applied again, got %+v", reverted.Version, applied)
+	}
+}
+
+func TestMigrateUp_TakesOverGolangMigrateVersion(t *testing.T) {
+	db := OpenTestDatabase(t)
+	ctx := context.Background()
+
+	// simulate a database migrated by golang-migrate up to the second to last version
+	migrations, _ := Migrations()
+	last := migrations[len(migrations)-1]
+	if _, _, err := MigrateDown(ctx, db); err != nil {
+		t.Fatalf("Failed to revert migration: %v", err)
+	}
+	for _, query := range []string{
+		`DROP TABLE schema_versions`,
+		`CREATE TABLE schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatalf("Failed to prepare legacy schema: %v", err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`, last.Version-1); err != nil {
+		t.Fatalf("Failed to prepare legacy schema: %v", err)
+	}
+
+	applied, err := MigrateUp(ctx, db)
+	if err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != last.Version {
+		t.Fatalf("Expected only migration %d to be applied, got %+v", last.Version, applied)
+	}
+}
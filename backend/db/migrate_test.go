@@ -0,0 +1,47 @@
+//go:build unit
+
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Failed to read migrations: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("Expected embedded migrations")
+	}
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("Expected version %d, got %d_%s", i+1, m.Version, m.Name)
+		}
+		// each migration runs in a transaction of the migration runner
+		for _, content := range []string{m.up, m.down} {
+			if strings.Contains(content, "BEGIN;") || strings.Contains(content, "COMMIT;") {
+				t.Errorf("Expected migration %d_%s without BEGIN/COMMIT", m.Version, m.Name)
+			}
+		}
+	}
+}
+
+func TestWithoutOwnTransaction(t *testing.T) {
+	for _, tc := range []struct {
+		migration string
+		expected  string
+	}{
+		{"BEGIN;\n\nCREATE TABLE a (id INT);\n\nCOMMIT;", "\n\nCREATE TABLE a (id INT);\n\n"},
+		{"begin;\nDROP TABLE a;\ncommit;\n", "\nDROP TABLE a;\n"},
+		{"CREATE TABLE a (id INT);\n", "CREATE TABLE a (id INT);\n"},
+		// only a transaction around the whole migration is removed
+		{"CREATE TABLE a (id INT);\nCOMMIT;", "CREATE TABLE a (id INT);\nCOMMIT;"},
+	} {
+		if actual := withoutOwnTransaction(tc.migration); actual != tc.expected {
+			t.Errorf("Expected %q for %q, got %q", tc.expected, tc.migration, actual)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+ALTER TABLE events DROP COLUMN IF EXISTS period_id;
+ALTER TABLE tables DROP COLUMN IF EXISTS period_id;
+ALTER TABLE products DROP COLUMN IF EXISTS period_id;
+
+DROP TABLE IF EXISTS periods;
+
+DROP TYPE IF EXISTS PeriodStatus;
@@ -0,0 +1,32 @@
+-- Periods (Veranstaltungen), e.g. "Sommerfest 2025". At most one period is open at a time.
+CREATE TYPE PeriodStatus AS ENUM ('open', 'closed');
+
+CREATE TABLE IF NOT EXISTS periods (
+    id INT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name TEXT NOT NULL,
+    status PeriodStatus NOT NULL,
+    opened_at TIMESTAMPTZ NOT NULL,
+    closed_at TIMESTAMPTZ NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_periods_single_open ON periods(status) WHERE status = 'open';
+
+COMMENT ON TABLE periods IS 'Events (Veranstaltungen) that scope products, tables and table events';
+COMMENT ON COLUMN periods.id IS 'Surrogate identity primary key';
+COMMENT ON COLUMN periods.name IS 'Name of the period, e.g. "Sommerfest 2025"';
+COMMENT ON COLUMN periods.status IS 'Period status: open or closed. Only one period can be open';
+COMMENT ON COLUMN periods.opened_at IS 'Opening timestamp (UTC)';
+COMMENT ON COLUMN periods.closed_at IS 'Closing timestamp (UTC); NULL while open';
+
+-- Tag products, tables and events with the period they belong to. NULL means no period (all periods).
+ALTER TABLE products ADD COLUMN IF NOT EXISTS period_id INT NULL REFERENCES periods(id);
+ALTER TABLE tables ADD COLUMN IF NOT EXISTS period_id INT NULL REFERENCES periods(id);
+ALTER TABLE events ADD COLUMN IF NOT EXISTS period_id INT NULL REFERENCES periods(id);
+
+CREATE INDEX IF NOT EXISTS idx_products_period_id ON products(period_id);
+CREATE INDEX IF NOT EXISTS idx_tables_period_id ON tables(period_id);
+CREATE INDEX IF NOT EXISTS idx_events_period_id ON events(period_id);
+
+COMMENT ON COLUMN products.period_id IS 'Period the product belongs to; NULL if available in all periods';
+COMMENT ON COLUMN tables.period_id IS 'Period the table belongs to; NULL if available in all periods';
+COMMENT ON COLUMN events.period_id IS 'Period that was open when the event happened; NULL if none was open';
@@ -0,0 +1,19 @@
+-- Sessions: one per login. Access tokens reference the session, refresh tokens rotate on every use.
+CREATE TABLE IF NOT EXISTS sessions (
+    id UUID PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    refresh_token_hash TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
+COMMENT ON TABLE sessions IS 'Login sessions of users. Revoking a session invalidates its access and refresh tokens';
+COMMENT ON COLUMN sessions.id IS 'Session ID, embedded as "sid" claim in access tokens';
+COMMENT ON COLUMN sessions.user_id IS 'User the session belongs to';
+COMMENT ON COLUMN sessions.refresh_token_hash IS 'SHA-256 hash of the current refresh token; replaced on every refresh';
+COMMENT ON COLUMN sessions.created_at IS 'Login timestamp (UTC)';
+COMMENT ON COLUMN sessions.expires_at IS 'Expiry of the current refresh token (UTC)';
+COMMENT ON COLUMN sessions.revoked_at IS 'Revocation timestamp (UTC) on logout, deactivation or password reset; NULL while active';
@@ -0,0 +1,6 @@
+DELETE FROM events WHERE user_id IS NULL;
+ALTER TABLE events ALTER COLUMN user_id SET NOT NULL;
+
+DROP TABLE IF EXISTS login_attempts;
+
+DROP TYPE IF EXISTS LoginAttemptKind;
@@ -0,0 +1,23 @@
+-- Failed login attempts per username and per client IP for brute-force protection.
+CREATE TYPE LoginAttemptKind AS ENUM ('username', 'ip');
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    kind LoginAttemptKind NOT NULL,
+    value TEXT NOT NULL,
+    failed_attempts INT NOT NULL,
+    last_failed_at TIMESTAMPTZ NOT NULL,
+    blocked_until TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (kind, value)
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_blocked_until ON login_attempts(blocked_until);
+
+COMMENT ON TABLE login_attempts IS 'Failed login attempts per username and client IP; rows are removed on successful login or by an admin';
+COMMENT ON COLUMN login_attempts.kind IS 'What the attempts are counted for: username or ip';
+COMMENT ON COLUMN login_attempts.value IS 'The username (lowercase) or client IP';
+COMMENT ON COLUMN login_attempts.failed_attempts IS 'Number of failed attempts since the counter (re)started';
+COMMENT ON COLUMN login_attempts.last_failed_at IS 'Timestamp of the last failed attempt (UTC)';
+COMMENT ON COLUMN login_attempts.blocked_until IS 'No login attempts are accepted before this timestamp (UTC)';
+
+-- Failed logins of unknown usernames are recorded as events without a user.
+ALTER TABLE events ALTER COLUMN user_id DROP NOT NULL;
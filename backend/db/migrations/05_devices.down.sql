@@ -0,0 +1,8 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS last_active_at;
+ALTER TABLE sessions DROP COLUMN IF EXISTS device_id;
+
+DROP TABLE IF EXISTS devices;
+
+DROP TYPE IF EXISTS DeviceStatus;
+
+ALTER TABLE users DROP COLUMN IF EXISTS pin_hash;
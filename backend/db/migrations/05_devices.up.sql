@@ -0,0 +1,33 @@
+-- Optional PIN for quick login on registered devices.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pin_hash TEXT NULL;
+
+COMMENT ON COLUMN users.pin_hash IS 'Argon2id hash of the 4-6 digit PIN for quick login on registered devices; NULL if not set';
+
+-- Devices: shared tablets registered by an admin on which staff log in with their PIN.
+CREATE TYPE DeviceStatus AS ENUM ('active', 'revoked');
+
+CREATE TABLE IF NOT EXISTS devices (
+    id INT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name TEXT NOT NULL,
+    status DeviceStatus NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NULL
+);
+
+COMMENT ON TABLE devices IS 'Shared devices registered by an admin for PIN login';
+COMMENT ON COLUMN devices.id IS 'Surrogate identity primary key';
+COMMENT ON COLUMN devices.name IS 'Name of the device, e.g. "Tablet Theke"';
+COMMENT ON COLUMN devices.status IS 'Device status: active or revoked';
+COMMENT ON COLUMN devices.token_hash IS 'SHA-256 hash of the device token the device authenticates with';
+COMMENT ON COLUMN devices.created_at IS 'Registration timestamp (UTC)';
+COMMENT ON COLUMN devices.revoked_at IS 'Revocation timestamp (UTC); NULL while active';
+
+-- PIN login sessions are bound to a device and end after inactivity.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS device_id INT NULL REFERENCES devices(id);
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS last_active_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS idx_sessions_device_id ON sessions(device_id);
+
+COMMENT ON COLUMN sessions.device_id IS 'Device of a PIN login; NULL for logins with username and password';
+COMMENT ON COLUMN sessions.last_active_at IS 'Last request of the session (UTC), updated at most once per minute';
@@ -0,0 +1,9 @@
+CREATE TYPE UserRole AS ENUM ('admin', 'service');
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_fkey;
+UPDATE users SET role = 'service' WHERE role NOT IN ('admin', 'service');
+ALTER TABLE users ALTER COLUMN role TYPE UserRole USING role::UserRole;
+
+COMMENT ON COLUMN users.role IS 'Role of the user, determining access rights';
+
+DROP TABLE IF EXISTS roles;
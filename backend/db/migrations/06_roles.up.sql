@@ -0,0 +1,23 @@
+-- Roles: named permission sets managed by admins. The admin role always has all permissions.
+CREATE TABLE IF NOT EXISTS roles (
+    name TEXT PRIMARY KEY,
+    permissions JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+COMMENT ON TABLE roles IS 'Roles as named sets of permissions; every user has one role';
+COMMENT ON COLUMN roles.name IS 'Lowercase role name, e.g. "schichtleitung"; carried in access tokens';
+COMMENT ON COLUMN roles.permissions IS 'JSON array of permission names, e.g. ["tables.view", "tables.serve"]; ignored for the admin role';
+COMMENT ON COLUMN roles.created_at IS 'Creation timestamp (UTC)';
+
+INSERT INTO roles (name, permissions, created_at) VALUES
+  ('admin', '[]', now()),
+  ('service', '["tables.view", "tables.serve", "payments.register"]', now());
+
+-- Users reference their role instead of the fixed UserRole enum.
+ALTER TABLE users ALTER COLUMN role TYPE TEXT USING role::TEXT;
+ALTER TABLE users ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles(name);
+
+DROP TYPE IF EXISTS UserRole;
+
+COMMENT ON COLUMN users.role IS 'Name of the role of the user, determining the permissions';
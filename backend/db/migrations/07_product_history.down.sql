@@ -0,0 +1 @@
+DELETE FROM events WHERE type = 'product.seeded:v1';
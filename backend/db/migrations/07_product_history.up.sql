@@ -0,0 +1,25 @@
+-- Product changes are recorded as product.* events (subject "audit:product:<id>").
+-- Seed the current state of every existing product, so the price history of each product starts now.
+-- Earlier orders keep the names and prices they were placed with.
+INSERT INTO events (user_id, type, subject, data, timestamp, period_id)
+SELECT
+    NULL,
+    'product.seeded:v1',
+    'audit:product:' || p.id,
+    jsonb_build_object(
+        'before', NULL,
+        'after', jsonb_strip_nulls(jsonb_build_object(
+            'id', p.id,
+            'name', p.name,
+            'description', p.description,
+            'netPriceCents', p.net_price_cents,
+            'status', p.status,
+            'category', p.category,
+            'createdAt', to_char(p.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+            'periodId', p.period_id
+        ))
+    ),
+    now(),
+    NULL
+FROM products p
+WHERE NOT EXISTS (SELECT 1 FROM events e WHERE e.subject = 'audit:product:' || p.id);
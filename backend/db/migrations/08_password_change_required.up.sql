@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_change_required BOOLEAN NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN users.password_change_required IS 'Set by an admin; the user has to choose a new password before the next login';
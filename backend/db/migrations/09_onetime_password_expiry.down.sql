@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS onetime_password_failed_attempts;
+ALTER TABLE users DROP COLUMN IF EXISTS onetime_password_expires_at;
@@ -0,0 +1,9 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS onetime_password_expires_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS onetime_password_failed_attempts INTEGER NOT NULL DEFAULT 0;
+
+-- Pending one-time passwords get the full validity from now on
+UPDATE users SET onetime_password_expires_at = now() + INTERVAL '24 hours'
+WHERE onetime_password_hash IS NOT NULL AND onetime_password_hash != '';
+
+COMMENT ON COLUMN users.onetime_password_expires_at IS 'Until when the one-time password can be used; in the past once it expired or was guessed wrong too often';
+COMMENT ON COLUMN users.onetime_password_failed_attempts IS 'Wrong one-time passwords since the last password reset';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS events_payment_id_idx;
+DROP INDEX IF EXISTS events_order_id_idx;
@@ -0,0 +1,8 @@
+-- Orders and payments carry a client-chosen ID, so a retried request can't book them twice
+CREATE UNIQUE INDEX IF NOT EXISTS events_order_id_idx ON events ((data->>'orderId'))
+WHERE type = 'table.order-placed:v1';
+CREATE UNIQUE INDEX IF NOT EXISTS events_payment_id_idx ON events ((data->>'paymentId'))
+WHERE type = 'table.payment-registered:v1';
+
+COMMENT ON INDEX events_order_id_idx IS 'Idempotency of placed orders: each order ID is booked only once';
+COMMENT ON INDEX events_payment_id_idx IS 'Idempotency of registered payments: each payment ID is booked only once';
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS events_table_opened_idx;
@@ -0,0 +1,7 @@
+-- Concurrent openings of a table would write two sessions with the same number. Reopening a session writes its
+-- number again, so the opening of the session is part of the key. Events written before have no opening and are
+-- not checked, NULLs are distinct.
+CREATE UNIQUE INDEX IF NOT EXISTS events_table_opened_idx ON events (subject, (data->>'sessionNumber'), (data->>'opening'))
+WHERE type = 'table.opened:v1';
+
+COMMENT ON INDEX events_table_opened_idx IS 'Each opening of a table session is written only once';
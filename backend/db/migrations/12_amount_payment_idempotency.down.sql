@@ -0,0 +1 @@
+DROP INDEX IF EXISTS events_amount_payment_id_idx;
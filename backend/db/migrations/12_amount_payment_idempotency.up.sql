@@ -0,0 +1,5 @@
+-- Amount payments carry a client-chosen ID too, e.g. when an offline client uploads them again
+CREATE UNIQUE INDEX IF NOT EXISTS events_amount_payment_id_idx ON events ((data->>'paymentId'))
+WHERE type = 'table.amount-payment-registered:v1';
+
+COMMENT ON INDEX events_amount_payment_id_idx IS 'Idempotency of registered amount payments: each payment ID is booked only once';
@@ -0,0 +1,13 @@
+-- Refresh tokens are single use. Rotated tokens are kept, so a reused token can be told apart from an unknown one.
+CREATE TABLE IF NOT EXISTS used_refresh_tokens (
+    refresh_token_hash TEXT PRIMARY KEY,
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    used_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_used_refresh_tokens_session_id ON used_refresh_tokens(session_id);
+
+COMMENT ON TABLE used_refresh_tokens IS 'Refresh tokens replaced by a refresh; presenting one again revokes its session';
+COMMENT ON COLUMN used_refresh_tokens.refresh_token_hash IS 'SHA-256 hash of the replaced refresh token';
+COMMENT ON COLUMN used_refresh_tokens.session_id IS 'Session the refresh token belonged to';
+COMMENT ON COLUMN used_refresh_tokens.used_at IS 'Timestamp of the refresh that replaced the token (UTC)';
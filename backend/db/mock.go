@@ -0,0 +1,14 @@
+package db
+
+import "context"
+
+// NewMockTransactor creates a transactor for unit tests that runs the function without a transaction.
+func NewMockTransactor() mockTransactor {
+	return mockTransactor{}
+}
+
+type mockTransactor struct{}
+
+func (mockTransactor) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
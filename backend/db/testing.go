@@ -1,22 +1,50 @@
+//go:build integration
+
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
 )
 
-func OpenTestDatabase() *sql.DB {
-	db, err := sql.Open("pgx", "host=localhost port=5432 user=admin password=admin dbname=jotti sslmode=disable")
+// testServer is the Postgres server of the integration tests, see test-integration.sh.
+const testServer = "host=localhost port=5432 user=admin password=admin sslmode=disable"
+
+// OpenTestDatabase creates a throwaway database with all migrations applied. It is dropped when the test ends,
+// so tests don't see each other's data and can run in parallel.
+func OpenTestDatabase(t testing.TB) *sql.DB {
+	t.Helper()
+
+	server, err := sql.Open("pgx", testServer+" dbname=jotti")
 	if err != nil {
-		fmt.Printf("Failed to connect to Postgres: %v\n", err)
-		os.Exit(1)
+		t.Fatalf("Failed to connect to Postgres: %v", err)
 	}
 
-	err = db.Ping()
+	name := "jotti_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err := server.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	db, err := sql.Open("pgx", fmt.Sprintf("%s dbname=%s", testServer, name))
 	if err != nil {
-		fmt.Printf("Failed to ping Postgres: %v\n", err)
-		os.Exit(1)
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = db.Close()
+		if _, err := server.Exec("DROP DATABASE IF EXISTS " + name + " WITH (FORCE)"); err != nil {
+			t.Errorf("Failed to drop test database: %v", err)
+		}
+		_ = server.Close()
+	})
+
+	if _, err := MigrateUp(context.Background(), db); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
 	}
 
 	return db
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/nicograef/jotti/backend/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedQuerier starts a span for every query. The span covers running the query, not reading its rows.
+type tracedQuerier struct {
+	q Querier
+}
+
+func (t tracedQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	result, err := t.q.ExecContext(ctx, query, args...)
+	recordQueryError(span, err)
+	return result, err
+}
+
+func (t tracedQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	rows, err := t.q.QueryContext(ctx, query, args...)
+	recordQueryError(span, err)
+	return rows, err
+}
+
+func (t tracedQuerier) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	row := t.q.QueryRowContext(ctx, query, args...)
+	recordQueryError(span, row.Err())
+	return row
+}
+
+// startQuerySpan starts a span named after the operation of the query (e.g. SELECT). The arguments are not recorded.
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation := "QUERY"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+
+	return tracing.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.operation.name", operation),
+			attribute.String("db.query.text", query),
+		),
+	)
+}
+
+func recordQueryError(span trace.Span, err error) {
+	if err == nil || errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, Error(err).Error())
+}
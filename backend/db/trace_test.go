@@ -0,0 +1,51 @@
+//go:build unit
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type failingQuerier struct {
+	Querier
+	err error
+}
+
+func (f failingQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return nil, f.err
+}
+
+func TestTracedQuerier(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	querier := tracedQuerier{failingQuerier{err: errors.New("connection refused")}}
+	query := "\n\t\tinsert INTO tables (name) VALUES ($1)"
+	if _, err := querier.ExecContext(context.Background(), query, "Tisch 1"); err == nil {
+		t.Fatal("Expected error of querier")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name() != "INSERT" {
+		t.Errorf("Expected span INSERT, got %s", spans[0].Name())
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("Expected error status, got %v", spans[0].Status())
+	}
+	for _, attr := range spans[0].Attributes() {
+		if attr.Value.AsString() == "Tisch 1" {
+			t.Error("Expected query arguments not to be recorded")
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/nicograef/jotti/backend/tracing"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Querier runs queries, either directly on the database or within a transaction.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type txKey struct{}
+
+// Conn returns the transaction started by Transactor.InTransaction for the context, otherwise db.
+// Repositories use it for every query, so they take part in a transaction without knowing about it.
+// Every query is traced as a span.
+func Conn(ctx context.Context, db *sql.DB) Querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tracedQuerier{tx}
+	}
+	return tracedQuerier{db}
+}
+
+// TxRunner runs repository calls in one transaction. Commands depend on it instead of Transactor, so unit tests can
+// use NewMockTransactor.
+type TxRunner interface {
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// DefaultTxAttempts is how often a transaction is run before a serialization failure is returned.
+const DefaultTxAttempts = 3
+
+// Transactor runs several repository calls in one transaction (unit of work).
+type Transactor struct {
+	DB *sql.DB
+	// Isolation is the isolation level of the transactions, the default of Postgres (read committed) if zero.
+	Isolation sql.IsolationLevel
+	// MaxAttempts limits the runs of a transaction that fails with a serialization failure, DefaultTxAttempts if zero.
+	MaxAttempts int
+}
+
+// InTransaction runs fn in a transaction that is committed if fn returns nil and rolled back otherwise.
+// Repository calls with the context passed to fn are part of the transaction. If the transaction fails with a
+// serialization failure or deadlock, fn runs again in a new transaction, so fn must not have other side effects.
+// Within a transaction, fn just joins the outer transaction. Errors of the database are mapped by Error.
+func (t Transactor) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	ctx, span := tracing.Start(ctx, "db.transaction", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	attempts := t.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultTxAttempts
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		span.SetAttributes(attribute.Int("db.transaction.attempts", attempt))
+		err = t.run(ctx, fn)
+		if !errors.Is(err, ErrSerializationFailure) {
+			return err
+		}
+
+		zerolog.Ctx(ctx).Warn().Err(err).Int("attempt", attempt).Msg("Transaction failed, retrying")
+		select {
+		case <-ctx.Done():
+			return Error(ctx.Err())
+		case <-time.After(time.Duration(attempt*attempt) * 10 * time.Millisecond):
+		}
+	}
+
+	return err
+}
+
+func (t Transactor) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := t.DB.BeginTx(ctx, &sql.TxOptions{Isolation: t.Isolation})
+	if err != nil {
+		return Error(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Error(err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+//go:build integration
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func countPeriods(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var count int
+	if err := db.QueryRow(`SELECT count(*) FROM periods`).Scan(&count); err != nil {
+		t.Fatalf("Failed to count periods: %v", err)
+	}
+	return count
+}
+
+func insertPeriod(ctx context.Context, db *sql.DB, name string) error {
+	_, err := Conn(ctx, db).ExecContext(ctx, `INSERT INTO periods (name, status, opened_at) VALUES ($1, 'closed', now())`, name)
+	return err
+}
+
+func TestInTransaction_RollsBackOnError(t *testing.T) {
+	db := OpenTestDatabase(t)
+	transactor := Transactor{DB: db}
+	failure := errors.New("failure")
+
+	err := transactor.InTransaction(context.Background(), func(ctx context.Context) error {
+		if err := insertPeriod(ctx, db, "Sommerfest"); err != nil {
+			return err
+		}
+		// joins the outer transaction
+		return transactor.InTransaction(ctx, func(ctx context.Context) error {
+			if err := insertPeriod(ctx, db, "Weinfest"); err != nil {
+				return err
+			}
+			return failure
+		})
+	})
+
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected failure, got %v", err)
+	}
+	if count := countPeriods(t, db); count != 0 {
+		t.Fatalf("Expected no periods after rollback, got %d", count)
+	}
+}
+
+func TestInTransaction_RetriesSerializationFailure(t *testing.T) {
+	db := OpenTestDatabase(t)
+	transactor := Transactor{DB: db, Isolation: sql.LevelSerializable}
+
+	attempts := 0
+	err := transactor.InTransaction(context.Background(), func(ctx context.Context) error {
+		attempts++
+		if err := insertPeriod(ctx, db, fmt.Sprintf("Sommerfest %d", attempts)); err != nil {
+			return err
+		}
+		if attempts == 1 {
+			return fmt.Errorf("conflict: %w", ErrSerializationFailure)
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("Expected 2 attempts, got %d", attempts)
+	}
+	if count := countPeriods(t, db); count != 1 {
+		t.Fatalf("Expected only the period of the second attempt, got %d", count)
+	}
+}
+
+func TestInTransaction_GivesUpAfterMaxAttempts(t *testing.T) {
+	db := OpenTestDatabase(t)
+	transactor := Transactor{DB: db, MaxAttempts: 2}
+
+	attempts := 0
+	err := transactor.InTransaction(context.Background(), func(ctx context.Context) error {
+		attempts++
+		return ErrSerializationFailure
+	})
+
+	if !errors.Is(err, ErrSerializationFailure) || attempts != 2 {
+		t.Fatalf("Expected serialization failure after 2 attempts, got %v after %d", err, attempts)
+	}
+}
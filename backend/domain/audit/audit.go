@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	e "github.com/nicograef/jotti/backend/domain/event"
+)
+
+type EventType string
+
+const (
+	EventTypeUserCreatedV1       EventType = "user.created:v1"
+	EventTypeUserUpdatedV1       EventType = "user.updated:v1"
+	EventTypeUserActivatedV1     EventType = "user.activated:v1"
+	EventTypeUserDeactivatedV1   EventType = "user.deactivated:v1"
+	EventTypeUserPasswordResetV1 EventType = "user.password-reset:v1"
+	// EventTypeUserPasswordChangeRequiredV1 records that an admin requires the user to choose a new password.
+	EventTypeUserPasswordChangeRequiredV1 EventType = "user.password-change-required:v1"
+
+	EventTypeProductCreatedV1     EventType = "product.created:v1"
+	EventTypeProductUpdatedV1     EventType = "product.updated:v1"
+	EventTypeProductActivatedV1   EventType = "product.activated:v1"
+	EventTypeProductDeactivatedV1 EventType = "product.deactivated:v1"
+	// EventTypeProductSeededV1 records the state of a product that existed before product changes were recorded.
+	EventTypeProductSeededV1 EventType = "product.seeded:v1"
+
+	EventTypeTableCreatedV1     EventType = "table.created:v1"
+	EventTypeTableUpdatedV1     EventType = "table.updated:v1"
+	EventTypeTableActivatedV1   EventType = "table.activated:v1"
+	EventTypeTableDeactivatedV1 EventType = "table.deactivated:v1"
+
+	EventTypeRoleCreatedV1 EventType = "role.created:v1"
+	EventTypeRoleUpdatedV1 EventType = "role.updated:v1"
+	EventTypeRoleDeletedV1 EventType = "role.deleted:v1"
+
+	EventTypeDeviceRegisteredV1 EventType = "device.registered:v1"
+	EventTypeDeviceRevokedV1    EventType = "device.revoked:v1"
+)
+
+// EventTypes are all types of audit events.
+var EventTypes = []EventType{
+	EventTypeUserCreatedV1, EventTypeUserUpdatedV1, EventTypeUserActivatedV1, EventTypeUserDeactivatedV1, EventTypeUserPasswordResetV1, EventTypeUserPasswordChangeRequiredV1,
+	EventTypeProductCreatedV1, EventTypeProductUpdatedV1, EventTypeProductActivatedV1, EventTypeProductDeactivatedV1, EventTypeProductSeededV1,
+	EventTypeTableCreatedV1, EventTypeTableUpdatedV1, EventTypeTableActivatedV1, EventTypeTableDeactivatedV1,
+	EventTypeRoleCreatedV1, EventTypeRoleUpdatedV1, EventTypeRoleDeletedV1,
+	EventTypeDeviceRegisteredV1, EventTypeDeviceRevokedV1,
+}
+
+// Entity is the kind of admin-managed entity an audit event is about.
+type Entity string
+
+const (
+	UserEntity    Entity = "user"
+	ProductEntity Entity = "product"
+	TableEntity   Entity = "table"
+	RoleEntity    Entity = "role"
+	DeviceEntity  Entity = "device"
+)
+
+// EventTypesOf returns the types of audit events about the given kind of entity.
+func EventTypesOf(entity Entity) []EventType {
+	types := []EventType{}
+	for _, t := range EventTypes {
+		if strings.HasPrefix(string(t), string(entity)+".") {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// Subject returns the subject of the audit events of an entity, e.g. "audit:product:7".
+// Audit events have their own subjects, so they never show up in the events of a table's sessions ("table:7").
+func Subject(entity Entity, id string) string {
+	return fmt.Sprintf("audit:%s:%s", entity, id)
+}
+
+// Change is the payload of an audit event: the entity before and after the change.
+// Before is null for created entities, After is null for deleted ones.
+type Change struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// NewEvent creates the audit event for a change an admin (actorID) made to an entity.
+// before and after are serialized with their JSON representation, which must not contain secrets like password hashes.
+func NewEvent(actorID int, eventType EventType, entity Entity, id string, before, after any) (e.Event, error) {
+	if !slices.Contains(EventTypes, eventType) {
+		return e.Event{}, fmt.Errorf("unsupported audit event type: %s", eventType)
+	}
+
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return e.Event{}, err
+	}
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return e.Event{}, err
+	}
+
+	return e.New(actorID, string(eventType), Subject(entity, id), Change{Before: beforeJSON, After: afterJSON})
+}
+
+// ParseChange returns the payload of an audit event.
+func ParseChange(event e.Event) (Change, error) {
+	if !slices.Contains(EventTypes, EventType(event.Type)) {
+		return Change{}, fmt.Errorf("unsupported event type: %s", event.Type)
+	}
+
+	change := Change{}
+	if err := json.Unmarshal(event.Data, &change); err != nil {
+		return Change{}, err
+	}
+
+	return change, nil
+}
@@ -0,0 +1,66 @@
+//go:build unit
+
+package audit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nicograef/jotti/backend/domain/user"
+)
+
+func TestNewEvent_Success(t *testing.T) {
+	after := user.User{ID: 7, Name: "Nico", Username: "nico", PasswordHash: "secret-hash", OnetimePasswordHash: "otp-hash", PinHash: "pin-hash"}
+
+	e, err := NewEvent(1, EventTypeUserCreatedV1, UserEntity, "7", nil, after)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if e.UserID != 1 {
+		t.Errorf("expected actor 1 as user ID, got %d", e.UserID)
+	}
+	if e.Subject != "audit:user:7" {
+		t.Errorf("unexpected subject: %s", e.Subject)
+	}
+	if strings.Contains(string(e.Data), "hash") {
+		t.Errorf("expected no hashes in event data, got %s", e.Data)
+	}
+
+	change, err := ParseChange(e)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(change.Before) != "null" {
+		t.Errorf("expected before to be null, got %s", change.Before)
+	}
+	if !strings.Contains(string(change.After), `"username":"nico"`) {
+		t.Errorf("expected after to contain the user, got %s", change.After)
+	}
+}
+
+func TestNewEvent_UnsupportedType(t *testing.T) {
+	_, err := NewEvent(1, "table.opened:v1", TableEntity, "7", nil, nil)
+	if err == nil {
+		t.Fatalf("expected error for non-audit event type")
+	}
+}
+
+func TestNewEvent_InvalidActor(t *testing.T) {
+	_, err := NewEvent(0, EventTypeTableCreatedV1, TableEntity, "7", nil, nil)
+	if err == nil {
+		t.Fatalf("expected error for missing actor")
+	}
+}
+
+func TestEventTypesOf(t *testing.T) {
+	types := EventTypesOf(ProductEntity)
+
+	if len(types) != 5 {
+		t.Fatalf("expected 5 product event types, got %v", types)
+	}
+	for _, eventType := range types {
+		if !strings.HasPrefix(string(eventType), "product.") {
+			t.Errorf("unexpected event type %s", eventType)
+		}
+	}
+}
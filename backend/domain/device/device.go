@@ -0,0 +1,80 @@
+package device
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	z "github.com/Oudwins/zog"
+	"github.com/nicograef/jotti/backend/domain/validation"
+)
+
+// Status represents the status of a device.
+type Status string
+
+const (
+	// ActiveStatus: staff can log in on the device with their PIN.
+	ActiveStatus Status = "active"
+	// RevokedStatus: the device token is no longer accepted.
+	RevokedStatus Status = "revoked"
+)
+
+// Device is a shared tablet or phone registered by an admin. On registered devices staff can log in
+// by selecting their name and entering their PIN. The device authenticates with a token; only its hash is stored.
+type Device struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Status    Status     `json:"status"`
+	TokenHash string     `json:"-"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+var IDSchema = z.Int().GTE(1, z.Message("Invalid device ID"))
+
+var NameSchema = z.String().Trim().Min(3, z.Message("Name too short")).Max(50, z.Message("Name too long")).Required(z.Message("Name required"))
+
+// NewDevice creates a new active device and returns it together with the plain device token.
+// The new Device does not have an ID assigned; it is expected to be set by the persistence layer.
+func NewDevice(name string) (Device, string, error) {
+	if err := validation.Field("name", NameSchema.Validate(&name)); err != nil {
+		return Device{}, "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return Device{}, "", fmt.Errorf("failed to generate device token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	device := Device{
+		Name:      name,
+		Status:    ActiveStatus,
+		TokenHash: HashToken(token),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	return device, token, nil
+}
+
+// Revoke disables the device.
+func (d *Device) Revoke() error {
+	if d.Status == RevokedStatus {
+		return errors.New("device already revoked")
+	}
+
+	revokedAt := time.Now().UTC()
+	d.Status = RevokedStatus
+	d.RevokedAt = &revokedAt
+	return nil
+}
+
+// HashToken returns the hex encoded SHA-256 hash of a device token.
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
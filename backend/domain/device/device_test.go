@@ -0,0 +1,36 @@
+//go:build unit
+
+package device
+
+import "testing"
+
+func TestNewDevice(t *testing.T) {
+	d, token, err := NewDevice("Tablet Theke")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if d.Status != ActiveStatus {
+		t.Errorf("expected status active, got %s", d.Status)
+	}
+	if token == "" || d.TokenHash != HashToken(token) {
+		t.Errorf("expected stored hash of device token, got %+v", d)
+	}
+
+	if _, _, err := NewDevice("T"); err == nil {
+		t.Error("expected error for short name")
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	d, _, _ := NewDevice("Tablet Theke")
+
+	if err := d.Revoke(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if d.Status != RevokedStatus || d.RevokedAt == nil {
+		t.Errorf("expected revoked device, got %+v", d)
+	}
+	if err := d.Revoke(); err == nil {
+		t.Error("expected error revoking a revoked device")
+	}
+}
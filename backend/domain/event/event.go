@@ -14,7 +14,7 @@ import (
 // Identifies the event. Must be unique within the scope of the producer/source.
 type Event struct {
 	ID int `json:"id"`
-	// The ID of the user associated with the event.
+	// The ID of the user associated with the event. 0 for anonymous events (e.g. a failed login of an unknown user).
 	UserID int `json:"userId"`
 	// The type of event related to the source system and subject. E.g. com.library.book.borrowed:v1
 	Type string `json:"type"`
@@ -24,6 +24,23 @@ type Event struct {
 	Subject string `json:"subject"`
 	// The event payload.
 	Data json.RawMessage `json:"data"`
+	// The period (Veranstaltung) that was open when the event happened. Set by the persistence layer if not given.
+	PeriodID *int `json:"periodId,omitempty"`
+}
+
+// Filter selects events. Zero values do not restrict the result.
+type Filter struct {
+	// Types the events must have one of.
+	Types []string
+	// UserID of the actor who triggered the events.
+	UserID int
+	// Subject the events are related to.
+	Subject string
+	// From and To limit the events to a timestamp in [From, To).
+	From time.Time
+	To   time.Time
+	// Limit is the maximum number of events returned.
+	Limit int
 }
 
 // New creates a new Event with the given parameters and automatically sets the ID and Time fields.
@@ -49,12 +66,38 @@ func New(userID int, eventType string, subject string, data any) (Event, error)
 	return event, nil
 }
 
+// NewAnonymous creates a new Event that is not associated with a user, e.g. a failed login of an unknown username.
+// It returns an error if any of the other fields are invalid.
+func NewAnonymous(eventType string, subject string, data any) (Event, error) {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	event := Event{
+		Type:    eventType,
+		Time:    time.Now().UTC(),
+		Subject: subject,
+		Data:    dataJSON,
+	}
+
+	if err := event.validateContent(); err != nil {
+		return Event{}, err
+	}
+
+	return event, nil
+}
+
 // Validate checks the Event fields for validity according to the CNCF Cloudevents specification.
 func (e *Event) Validate() error {
 	if e.UserID <= 0 {
 		return errors.New("user ID must be a positive integer")
 	}
 
+	return e.validateContent()
+}
+
+func (e *Event) validateContent() error {
 	if len(strings.TrimSpace(e.Type)) < 5 {
 		return errors.New("event type must be at least 5 characters long")
 	}
@@ -62,3 +62,18 @@ func TestValidate_Errors(t *testing.T) {
 		})
 	}
 }
+
+func TestNewAnonymous(t *testing.T) {
+	e, err := NewAnonymous("com.example.event:v1", "login:unknown", map[string]any{"k": "v"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if e.UserID != 0 {
+		t.Errorf("expected no user ID, got %d", e.UserID)
+	}
+
+	if _, err := NewAnonymous("com.example.event:v1", "", map[string]any{"k": "v"}); err == nil {
+		t.Error("expected error for empty subject")
+	}
+}
@@ -1,6 +1,8 @@
 package jwt
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -8,18 +10,46 @@ import (
 
 const issuer = "jotti"
 
-func GenerateJWTTokenForUser(userID int, userRole string, secret string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"alg":  jwt.SigningMethodHS256.Alg(),
-		"iss":  issuer,
-		"iat":  jwt.NewNumericDate(time.Now()),
-		"exp":  jwt.NewNumericDate(time.Now().Add(12 * time.Hour)), // 12 hours validity
-		"sub":  userID,
-		"role": userRole,
+// AccessTokenValidity is kept short because access tokens are only checked against the session on use.
+// Clients renew them with their refresh token.
+const AccessTokenValidity = 15 * time.Minute
+
+// Claims are the validated claims of an access token.
+// Permissions are resolved from the role when the token is issued, so role changes apply on the next refresh.
+type Claims struct {
+	UserID      int
+	Role        string
+	Permissions []string
+	SessionID   string
+}
+
+// GenerateJWTTokenForUser returns an access token signed with the signing key of keys.
+func GenerateJWTTokenForUser(userID int, userRole string, permissions []string, sessionID string, keys Keys) (string, error) {
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	signing, ok := keys.keys[keys.signingKeyID]
+	if !ok || signing.signingKey == nil {
+		return "", fmt.Errorf("signing key %q not available", keys.signingKeyID)
+	}
+
+	token := jwt.NewWithClaims(signing.method, jwt.MapClaims{
+		"alg":   signing.method.Alg(),
+		"iss":   issuer,
+		"iat":   jwt.NewNumericDate(time.Now()),
+		"exp":   jwt.NewNumericDate(time.Now().Add(AccessTokenValidity)),
+		"sub":   userID,
+		"role":  userRole,
+		"perms": permissions,
+		"sid":   sessionID,
 	})
 
-	key := []byte(secret)
-	stringToken, err := token.SignedString(key)
+	if keys.signingKeyID != legacyKeyID {
+		token.Header["kid"] = keys.signingKeyID
+	}
+
+	stringToken, err := token.SignedString(signing.signingKey)
 	if err != nil {
 		return "", err
 	}
@@ -27,19 +57,39 @@ func GenerateJWTTokenForUser(userID int, userRole string, secret string) (string
 	return stringToken, nil
 }
 
-func ParseAndValidateJWTToken(tokenString, secret string) (int, string, error) {
+// ParseAndValidateJWTToken verifies the access token with the key of its kid header and returns its claims.
+func ParseAndValidateJWTToken(tokenString string, keys Keys) (Claims, error) {
 	claims := jwt.MapClaims{}
-	keyFunc := func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	}
 
-	_, err := jwt.ParseWithClaims(tokenString, claims, keyFunc, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired(), jwt.WithIssuer(issuer))
+	_, err := jwt.ParseWithClaims(tokenString, claims, keys.verificationKey, jwt.WithValidMethods(validMethods), jwt.WithExpirationRequired(), jwt.WithIssuer(issuer))
 	if err != nil {
-		return 0, "", err
+		return Claims{}, err
 	}
 
-	userID := int(claims["sub"].(float64))
-	userRole := claims["role"].(string)
+	userID, ok := claims["sub"].(float64)
+	if !ok {
+		return Claims{}, errors.New("missing subject claim")
+	}
+	userRole, ok := claims["role"].(string)
+	if !ok {
+		return Claims{}, errors.New("missing role claim")
+	}
+	sessionID, ok := claims["sid"].(string)
+	if !ok || sessionID == "" {
+		return Claims{}, errors.New("missing session claim")
+	}
+	perms, ok := claims["perms"].([]interface{})
+	if !ok {
+		return Claims{}, errors.New("missing permissions claim")
+	}
+	permissions := make([]string, 0, len(perms))
+	for _, p := range perms {
+		permission, ok := p.(string)
+		if !ok {
+			return Claims{}, errors.New("invalid permissions claim")
+		}
+		permissions = append(permissions, permission)
+	}
 
-	return userID, userRole, nil
+	return Claims{UserID: int(userID), Role: userRole, Permissions: permissions, SessionID: sessionID}, nil
 }
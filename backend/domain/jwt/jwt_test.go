@@ -4,12 +4,13 @@ package jwt
 
 import (
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 func TestGenerateJWTTokenForUser(t *testing.T) {
-	token, err := GenerateJWTTokenForUser(1, "admin", "test_secret")
+	token, err := GenerateJWTTokenForUser(1, "admin", []string{"users.manage"}, "session-1", NewSecretKeys("test_secret"))
 	if err != nil {
 		t.Fatalf("Failed to generate JWT token: %v", err)
 	}
@@ -29,23 +30,61 @@ func TestGenerateJWTTokenForUser(t *testing.T) {
 	if claims["role"].(string) != "admin" {
 		t.Errorf("Expected role '%s', got '%v'", "admin", claims["role"])
 	}
+	if claims["sid"].(string) != "session-1" {
+		t.Errorf("Expected session '%s', got '%v'", "session-1", claims["sid"])
+	}
 }
 
 func TestParseAndValidateJWTToken(t *testing.T) {
-	token, err := GenerateJWTTokenForUser(2, "service", "test_secret")
+	token, err := GenerateJWTTokenForUser(2, "service", []string{"tables.view", "tables.serve"}, "session-2", NewSecretKeys("test_secret"))
 	if err != nil {
 		t.Fatalf("Failed to generate JWT token: %v", err)
 	}
 
-	userID, userRole, err := ParseAndValidateJWTToken(token, "test_secret")
+	claims, err := ParseAndValidateJWTToken(token, NewSecretKeys("test_secret"))
 	if err != nil {
 		t.Fatalf("Failed to parse and validate JWT token: %v", err)
 	}
 
-	if userID != 2 {
-		t.Errorf("Expected UserID '%d', got '%d'", 2, userID)
+	if claims.UserID != 2 {
+		t.Errorf("Expected UserID '%d', got '%d'", 2, claims.UserID)
+	}
+	if claims.Role != "service" {
+		t.Errorf("Expected Role '%s', got '%s'", "service", claims.Role)
+	}
+	if claims.SessionID != "session-2" {
+		t.Errorf("Expected SessionID '%s', got '%s'", "session-2", claims.SessionID)
+	}
+	if len(claims.Permissions) != 2 || claims.Permissions[1] != "tables.serve" {
+		t.Errorf("Expected Permissions %v, got %v", []string{"tables.view", "tables.serve"}, claims.Permissions)
+	}
+}
+
+func TestParseAndValidateJWTToken_NoPermissions(t *testing.T) {
+	token, err := GenerateJWTTokenForUser(2, "kueche", nil, "session-2", NewSecretKeys("test_secret"))
+	if err != nil {
+		t.Fatalf("Failed to generate JWT token: %v", err)
+	}
+
+	claims, err := ParseAndValidateJWTToken(token, NewSecretKeys("test_secret"))
+	if err != nil {
+		t.Fatalf("Failed to parse and validate JWT token: %v", err)
+	}
+	if len(claims.Permissions) != 0 {
+		t.Errorf("Expected no permissions, got %v", claims.Permissions)
 	}
-	if userRole != "service" {
-		t.Errorf("Expected Role '%s', got '%s'", "service", userRole)
+}
+
+func TestParseAndValidateJWTToken_MissingSession(t *testing.T) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":  "jotti",
+		"exp":  jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		"sub":  1,
+		"role": "admin",
+	})
+	tokenString, _ := token.SignedString([]byte("test_secret"))
+
+	if _, err := ParseAndValidateJWTToken(tokenString, NewSecretKeys("test_secret")); err == nil {
+		t.Error("Expected error for token without session")
 	}
 }
@@ -0,0 +1,179 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// legacyKeyID identifies the HS256 secret. Tokens signed with it have no kid header.
+const legacyKeyID = ""
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// key is a key to sign or verify access tokens. Keys of retired signing keys only have a verification key.
+type key struct {
+	method          jwt.SigningMethod
+	signingKey      any
+	verificationKey any
+}
+
+// Keys sign new access tokens with one key and verify tokens with any of the known keys, selected by the kid header.
+// To rotate keys, a new signing key is added and the old key is kept for verification until its tokens expired.
+type Keys struct {
+	signingKeyID string
+	keys         map[string]key
+}
+
+// NewSecretKeys signs and verifies access tokens with a shared secret (HS256).
+func NewSecretKeys(secret string) Keys {
+	return Keys{
+		signingKeyID: legacyKeyID,
+		keys:         map[string]key{legacyKeyID: newSecretKey(secret)},
+	}
+}
+
+func newSecretKey(secret string) key {
+	return key{method: jwt.SigningMethodHS256, signingKey: []byte(secret), verificationKey: []byte(secret)}
+}
+
+// LoadKeys reads the keys from the PEM files in dir. The file name without ".pem" is the key ID.
+// Files with a private key (PKCS#8 Ed25519 or ECDSA P-256, or SEC 1 "EC PRIVATE KEY") can sign,
+// files with a public key (PKIX) only verify. New tokens are signed with the key signingKeyID.
+// If secret is not empty, tokens without kid are still verified with it (HS256), e.g. while switching
+// from a shared secret to key files. Without dir, the secret signs all tokens.
+func LoadKeys(dir, signingKeyID, secret string) (Keys, error) {
+	if dir == "" {
+		if secret == "" {
+			return Keys{}, errors.New("neither key directory nor secret configured")
+		}
+		return NewSecretKeys(secret), nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return Keys{}, fmt.Errorf("failed to read key directory: %w", err)
+	}
+
+	keys := Keys{signingKeyID: signingKeyID, keys: map[string]key{}}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".pem" {
+			continue
+		}
+
+		id := strings.TrimSuffix(entry.Name(), ".pem")
+		if id == legacyKeyID {
+			return Keys{}, fmt.Errorf("key file %s has no key ID", entry.Name())
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return Keys{}, fmt.Errorf("failed to read key file %s: %w", entry.Name(), err)
+		}
+
+		k, err := parseKey(data)
+		if err != nil {
+			return Keys{}, fmt.Errorf("invalid key file %s: %w", entry.Name(), err)
+		}
+		keys.keys[id] = k
+	}
+
+	if secret != "" {
+		keys.keys[legacyKeyID] = newSecretKey(secret)
+	}
+
+	signingKey, ok := keys.keys[signingKeyID]
+	if !ok || signingKeyID == legacyKeyID {
+		return Keys{}, fmt.Errorf("signing key %q not found in %s", signingKeyID, dir)
+	}
+	if signingKey.signingKey == nil {
+		return Keys{}, fmt.Errorf("signing key %q has no private key", signingKeyID)
+	}
+
+	return keys, nil
+}
+
+// parseKey reads a private or public Ed25519 or ECDSA P-256 key from PEM.
+func parseKey(data []byte) (key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return key{}, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return key{}, err
+		}
+		signer, ok := privateKey.(crypto.Signer)
+		if !ok {
+			return key{}, errors.New("unsupported private key")
+		}
+		return newKey(privateKey, signer.Public())
+	case "EC PRIVATE KEY":
+		privateKey, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return key{}, err
+		}
+		return newKey(privateKey, &privateKey.PublicKey)
+	case "PUBLIC KEY":
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return key{}, err
+		}
+		return newKey(nil, publicKey)
+	default:
+		return key{}, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// newKey selects the signing method for the public key. privateKey is nil for verification-only keys.
+func newKey(privateKey any, publicKey crypto.PublicKey) (key, error) {
+	switch pk := publicKey.(type) {
+	case ed25519.PublicKey:
+		return key{method: jwt.SigningMethodEdDSA, signingKey: privateKey, verificationKey: pk}, nil
+	case *ecdsa.PublicKey:
+		if pk.Curve != elliptic.P256() {
+			return key{}, errors.New("only ECDSA keys on curve P-256 are supported")
+		}
+		return key{method: jwt.SigningMethodES256, signingKey: privateKey, verificationKey: pk}, nil
+	default:
+		return key{}, errors.New("only Ed25519 and ECDSA P-256 keys are supported")
+	}
+}
+
+// validMethods are the signing methods accepted when parsing tokens. The method also has to match the key.
+var validMethods = []string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodES256.Alg()}
+
+// verificationKey returns the key for the kid header of the token. The signing method of the token has to match
+// the key, so a public key can't be used as HMAC secret.
+func (k Keys) verificationKey(token *jwt.Token) (any, error) {
+	id := legacyKeyID
+	if kid, ok := token.Header["kid"]; ok {
+		id, ok = kid.(string)
+		if !ok || id == legacyKeyID {
+			return nil, ErrUnknownKey
+		}
+	}
+
+	verification, ok := k.keys[id]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != verification.method.Alg() {
+		return nil, fmt.Errorf("signing method %s does not match key", token.Method.Alg())
+	}
+
+	return verification.verificationKey, nil
+}
@@ -0,0 +1,70 @@
+//go:build unit
+
+package login
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordFailure_FreeAttempts(t *testing.T) {
+	now := time.Now()
+	a := NewAttempts(UsernameKind, "Nico")
+
+	for range FreeAttempts {
+		a.RecordFailure(now)
+	}
+
+	if a.IsBlocked(now) {
+		t.Errorf("expected no block after %d failed attempts, got %+v", FreeAttempts, a)
+	}
+	if a.Key() != "username:nico" {
+		t.Errorf("expected key username:nico, got %s", a.Key())
+	}
+}
+
+func TestRecordFailure_ExponentialBackoff(t *testing.T) {
+	now := time.Now()
+	a := NewAttempts(IPKind, "192.0.2.1")
+
+	expected := []time.Duration{0, 0, 0, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}
+	for i, delay := range expected {
+		a.RecordFailure(now)
+		if got := a.RetryAfter(now); got != delay {
+			t.Errorf("attempt %d: expected retry after %s, got %s", i+1, delay, got)
+		}
+	}
+	if a.IsLockedOut(now) {
+		t.Error("expected no lockout before threshold")
+	}
+}
+
+func TestRecordFailure_Lockout(t *testing.T) {
+	now := time.Now()
+	a := NewAttempts(UsernameKind, "nico")
+
+	for range LockoutThreshold {
+		a.RecordFailure(now)
+	}
+
+	if !a.IsLockedOut(now) {
+		t.Fatalf("expected lockout after %d failed attempts, got %+v", LockoutThreshold, a)
+	}
+	if a.IsLockedOut(now.Add(LockoutDuration)) {
+		t.Error("expected lockout to end after lockout duration")
+	}
+}
+
+func TestRecordFailure_ResetWindow(t *testing.T) {
+	now := time.Now()
+	a := NewAttempts(UsernameKind, "nico")
+
+	for range LockoutThreshold {
+		a.RecordFailure(now)
+	}
+	a.RecordFailure(now.Add(ResetWindow + time.Minute))
+
+	if a.FailedAttempts != 1 {
+		t.Errorf("expected counter to start over, got %d failed attempts", a.FailedAttempts)
+	}
+}
@@ -0,0 +1,52 @@
+package login
+
+import (
+	"strings"
+
+	e "github.com/nicograef/jotti/backend/domain/event"
+)
+
+type EventType string
+
+const (
+	// EventTypeLoginFailedV1 is recorded for every rejected login as audit trail.
+	EventTypeLoginFailedV1 EventType = "login.failed:v1"
+)
+
+// FailureReason describes why a login was rejected.
+type FailureReason string
+
+const (
+	UnknownUserReason     FailureReason = "unknown_user"
+	InvalidPasswordReason FailureReason = "invalid_password"
+	InvalidPinReason      FailureReason = "invalid_pin"
+	NoPinReason           FailureReason = "no_pin"
+	UserInactiveReason    FailureReason = "user_inactive"
+	NoPasswordReason      FailureReason = "no_password"
+	BlockedReason         FailureReason = "blocked"
+	// PasswordChangeRequiredReason is used if the password was correct but has to be changed first.
+	PasswordChangeRequiredReason FailureReason = "password_change_required"
+)
+
+type loginFailedV1Data struct {
+	Username string        `json:"username"`
+	ClientIP string        `json:"clientIp"`
+	Reason   FailureReason `json:"reason"`
+}
+
+// NewLoginFailedEvent creates the audit event for a rejected login. The subject is the attempted username.
+// userID is 0 if the username does not belong to a user; the event is anonymous then.
+func NewLoginFailedEvent(userID int, username, clientIP string, reason FailureReason) (e.Event, error) {
+	username = strings.ToLower(username)
+	data := loginFailedV1Data{
+		Username: username,
+		ClientIP: clientIP,
+		Reason:   reason,
+	}
+
+	subject := "login:" + username
+	if userID == 0 {
+		return e.NewAnonymous(string(EventTypeLoginFailedV1), subject, data)
+	}
+	return e.New(userID, string(EventTypeLoginFailedV1), subject, data)
+}
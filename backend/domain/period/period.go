@@ -0,0 +1,82 @@
+package period
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	z "github.com/Oudwins/zog"
+	"github.com/nicograef/jotti/backend/domain/validation"
+)
+
+// Status represents the status of a period.
+type Status string
+
+const (
+	// OpenStatus: the period is running, new products, tables and table events belong to it.
+	OpenStatus Status = "open"
+	// ClosedStatus: the period is over and only part of the history.
+	ClosedStatus Status = "closed"
+)
+
+// Period is an event (Veranstaltung) like "Sommerfest 2025". It scopes products, tables, table events and reports.
+// At most one period is open at a time.
+type Period struct {
+	ID       int        `json:"id"`
+	Name     string     `json:"name"`
+	Status   Status     `json:"status"`
+	OpenedAt time.Time  `json:"openedAt"`
+	ClosedAt *time.Time `json:"closedAt,omitempty"`
+}
+
+var IDSchema = z.Int().GTE(1, z.Message("Invalid period ID"))
+
+var NameSchema = z.String().Trim().Min(3, z.Message("Name too short")).Max(50, z.Message("Name too long"))
+
+var StatusSchema = z.StringLike[Status]().OneOf(
+	[]Status{OpenStatus, ClosedStatus},
+	z.Message("Invalid status"),
+)
+
+var PeriodSchema = z.Struct(z.Shape{
+	"ID":       IDSchema.Required(),
+	"Name":     NameSchema.Required(),
+	"Status":   StatusSchema.Required(),
+	"OpenedAt": z.Time().Required(),
+})
+
+func (p Period) Validate() error {
+	if errsMap := PeriodSchema.Validate(&p); errsMap != nil {
+		issues := z.Issues.SanitizeMapAndCollect(errsMap)
+		return fmt.Errorf("invalid period: %v", issues)
+	}
+	return nil
+}
+
+// NewPeriod creates a new open Period after validating the name.
+// The new Period does not have an ID assigned; it is expected to be set by the persistence layer.
+func NewPeriod(name string) (Period, error) {
+	if err := validation.Field("name", NameSchema.Validate(&name)); err != nil {
+		return Period{}, err
+	}
+
+	period := Period{
+		Name:     name,
+		Status:   OpenStatus,
+		OpenedAt: time.Now().UTC(),
+	}
+
+	return period, nil
+}
+
+// Close ends the period.
+func (p *Period) Close() error {
+	if p.Status == ClosedStatus {
+		return errors.New("period already closed")
+	}
+
+	closedAt := time.Now().UTC()
+	p.Status = ClosedStatus
+	p.ClosedAt = &closedAt
+	return nil
+}
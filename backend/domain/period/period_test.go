@@ -0,0 +1,33 @@
+//go:build unit
+
+package period
+
+import "testing"
+
+func TestNewPeriod(t *testing.T) {
+	p, err := NewPeriod("Sommerfest 2025")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if p.Status != OpenStatus {
+		t.Errorf("expected status open, got %s", p.Status)
+	}
+
+	if _, err := NewPeriod("SF"); err == nil {
+		t.Error("expected error for short name")
+	}
+}
+
+func TestClose(t *testing.T) {
+	p, _ := NewPeriod("Weihnachtsmarkt")
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if p.Status != ClosedStatus || p.ClosedAt == nil {
+		t.Errorf("expected closed period with closing time, got %+v", p)
+	}
+	if err := p.Close(); err == nil {
+		t.Error("expected error closing a closed period")
+	}
+}
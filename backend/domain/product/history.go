@@ -0,0 +1,63 @@
+package product
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/nicograef/jotti/backend/domain/audit"
+	e "github.com/nicograef/jotti/backend/domain/event"
+)
+
+// History is the sequence of states of a product, recorded by its product.* audit events.
+// It answers what a product was called and cost at a given time, e.g. to interpret old orders.
+type History struct {
+	states []historyState
+}
+
+type historyState struct {
+	since   time.Time
+	product Product
+}
+
+// NewHistories builds the histories of all products the given product.* audit events are about, keyed by product ID.
+// Events are applied in the order of their sequence number.
+func NewHistories(events []e.Event) (map[int]History, error) {
+	sorted := slices.Clone(events)
+	slices.SortFunc(sorted, func(a, b e.Event) int { return a.ID - b.ID })
+
+	histories := map[int]History{}
+	for _, event := range sorted {
+		if !slices.Contains(audit.EventTypesOf(audit.ProductEntity), audit.EventType(event.Type)) {
+			return nil, fmt.Errorf("unsupported event type: %s", event.Type)
+		}
+
+		change, err := audit.ParseChange(event)
+		if err != nil {
+			return nil, err
+		}
+
+		product := Product{}
+		if err := json.Unmarshal(change.After, &product); err != nil {
+			return nil, fmt.Errorf("invalid product in event %d: %w", event.ID, err)
+		}
+
+		h := histories[product.ID]
+		h.states = append(h.states, historyState{since: event.Time, product: product})
+		histories[product.ID] = h
+	}
+
+	return histories, nil
+}
+
+// At returns the product as it was at the given time.
+// It returns false if no state of the product was recorded before that time.
+func (h History) At(t time.Time) (Product, bool) {
+	for i := len(h.states) - 1; i >= 0; i-- {
+		if !h.states[i].since.After(t) {
+			return h.states[i].product, true
+		}
+	}
+	return Product{}, false
+}
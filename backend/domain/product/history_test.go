@@ -0,0 +1,62 @@
+//go:build unit
+
+package product
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nicograef/jotti/backend/domain/audit"
+	e "github.com/nicograef/jotti/backend/domain/event"
+)
+
+func newProductEvent(t *testing.T, id int, eventType audit.EventType, at time.Time, before, after any) e.Event {
+	t.Helper()
+	event, err := audit.NewEvent(1, eventType, audit.ProductEntity, "5", before, after)
+	if err != nil {
+		t.Fatalf("expected no error creating event, got %v", err)
+	}
+	event.ID = id
+	event.Time = at
+	return event
+}
+
+func TestHistory_At(t *testing.T) {
+	weizen := Product{ID: 5, Name: "Weizen", NetPriceCents: 400}
+	expensive := Product{ID: 5, Name: "Weizen", NetPriceCents: 450}
+	events := []e.Event{
+		// unordered on purpose, histories follow the sequence number
+		newProductEvent(t, 2, audit.EventTypeProductUpdatedV1, time.Date(2025, 7, 20, 10, 0, 0, 0, time.UTC), weizen, expensive),
+		newProductEvent(t, 1, audit.EventTypeProductSeededV1, time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC), nil, weizen),
+	}
+
+	histories, err := NewHistories(events)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	history, ok := histories[5]
+	if !ok {
+		t.Fatalf("expected history of product 5")
+	}
+
+	if _, ok := history.At(time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)); ok {
+		t.Errorf("expected no state before the first event")
+	}
+	if p, ok := history.At(time.Date(2025, 7, 12, 0, 0, 0, 0, time.UTC)); !ok || p.NetPriceCents != 400 {
+		t.Errorf("expected price 400 on 12 July, got %d (%v)", p.NetPriceCents, ok)
+	}
+	if p, ok := history.At(time.Date(2025, 7, 20, 10, 0, 0, 0, time.UTC)); !ok || p.NetPriceCents != 450 {
+		t.Errorf("expected price 450 from the time of the update, got %d (%v)", p.NetPriceCents, ok)
+	}
+}
+
+func TestNewHistories_UnsupportedType(t *testing.T) {
+	event, err := audit.NewEvent(1, audit.EventTypeTableCreatedV1, audit.TableEntity, "1", nil, map[string]any{"id": 1})
+	if err != nil {
+		t.Fatalf("expected no error creating event, got %v", err)
+	}
+
+	if _, err := NewHistories([]e.Event{event}); err == nil {
+		t.Fatalf("expected error for non-product event")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	z "github.com/Oudwins/zog"
+	"github.com/nicograef/jotti/backend/domain/validation"
 )
 
 // Status represents the status of a product.
@@ -37,6 +38,8 @@ type Product struct {
 	Status        Status    `json:"status"`
 	Category      Category  `json:"category"`
 	CreatedAt     time.Time `json:"createdAt"`
+	// PeriodID is the period (Veranstaltung) the product belongs to. Nil if the product is available in all periods.
+	PeriodID *int `json:"periodId,omitempty"`
 }
 
 // IDSchema defines the schema for a product ID.
@@ -84,20 +87,13 @@ func (p Product) Validate() error {
 // NewProduct creates a new Product instance after validating the input parameters.
 // The new Product does not have an ID assigned; it is expected to be set by the persistence layer.
 func NewProduct(name, description string, netPriceCents int, category Category) (Product, error) {
-	if issue := NameSchema.Validate(&name); issue != nil {
-		return Product{}, fmt.Errorf("invalid name")
-	}
-
-	if issue := DescriptionSchema.Optional().Validate(&description); issue != nil {
-		return Product{}, fmt.Errorf("invalid description")
-	}
-
-	if issue := NetPriceCentsSchema.Validate(&netPriceCents); issue != nil {
-		return Product{}, fmt.Errorf("invalid net price")
-	}
-
-	if issue := CategorySchema.Validate(&category); issue != nil {
-		return Product{}, fmt.Errorf("invalid category")
+	var v validation.Validator
+	v.Check("name", NameSchema.Validate(&name))
+	v.Check("description", DescriptionSchema.Optional().Validate(&description))
+	v.Check("netPriceCents", NetPriceCentsSchema.Validate(&netPriceCents))
+	v.Check("category", CategorySchema.Validate(&category))
+	if err := v.Err(); err != nil {
+		return Product{}, err
 	}
 
 	product := Product{
@@ -121,20 +117,13 @@ func (p *Product) Deactivate() {
 }
 
 func (p *Product) UpdateDetails(name, description string, netPriceCents int, category Category) error {
-	if issue := NameSchema.Validate(&name); issue != nil {
-		return fmt.Errorf("invalid name")
-	}
-
-	if issue := DescriptionSchema.Validate(&description); issue != nil {
-		return fmt.Errorf("invalid description")
-	}
-
-	if issue := NetPriceCentsSchema.Validate(&netPriceCents); issue != nil {
-		return fmt.Errorf("invalid net price")
-	}
-
-	if issue := CategorySchema.Validate(&category); issue != nil {
-		return fmt.Errorf("invalid category")
+	var v validation.Validator
+	v.Check("name", NameSchema.Validate(&name))
+	v.Check("description", DescriptionSchema.Validate(&description))
+	v.Check("netPriceCents", NetPriceCentsSchema.Validate(&netPriceCents))
+	v.Check("category", CategorySchema.Validate(&category))
+	if err := v.Err(); err != nil {
+		return err
 	}
 
 	p.Name = name
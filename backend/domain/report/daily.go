@@ -0,0 +1,25 @@
+package report
+
+import (
+	"time"
+
+	e "github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/product"
+)
+
+// DailyReport summarizes all table events of one business day.
+type DailyReport struct {
+	Date string `json:"date"` // YYYY-MM-DD
+	Summary
+}
+
+// NewDailyReport builds the report of the given day from all table events that happened on that day
+// and the histories of the ordered products.
+func NewDailyReport(day time.Time, events []e.Event, products map[int]product.History) (DailyReport, error) {
+	summary, err := NewSummary(events, products)
+	if err != nil {
+		return DailyReport{}, err
+	}
+
+	return DailyReport{Date: day.Format(time.DateOnly), Summary: summary}, nil
+}
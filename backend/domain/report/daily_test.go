@@ -0,0 +1,130 @@
+//go:build unit
+
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nicograef/jotti/backend/domain/audit"
+	e "github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/product"
+	"github.com/nicograef/jotti/backend/domain/table"
+)
+
+func mustEvent(t *testing.T) func(e.Event, error) e.Event {
+	return func(event e.Event, err error) e.Event {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("expected no error creating event, got %v", err)
+		}
+		return event
+	}
+}
+
+func TestNewDailyReport(t *testing.T) {
+	must := mustEvent(t)
+	events := []e.Event{
+		must(table.NewOrderPlacedEvent(1, 5, "", []table.OrderProduct{
+			{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 3},
+			{ID: 2, Name: "Pommes", NetPriceCents: 350, Quantity: 1},
+		})),
+		must(table.NewPaymentRegisteredEvent(1, 5, "", []table.PaymentProduct{{ID: 2, Name: "Pommes", NetPriceCents: 350, Quantity: 1}})),
+		must(table.NewItemsWrittenOffEvent(1, 5, table.BreakageWriteOff, "", []table.WriteOffProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}}, 0)),
+		must(table.NewItemsWrittenOffEvent(1, 5, table.UnpaidWriteOff, "", []table.WriteOffProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 2}}, 0)),
+	}
+
+	report, err := NewDailyReport(time.Date(2025, 6, 14, 0, 0, 0, 0, time.UTC), events, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if report.Date != "2025-06-14" {
+		t.Errorf("expected date 2025-06-14, got %s", report.Date)
+	}
+	if report.OrderedCents != 1550 || report.PaidCents != 350 {
+		t.Errorf("expected ordered 1550 and paid 350, got %d and %d", report.OrderedCents, report.PaidCents)
+	}
+	if len(report.Products) != 2 || report.Products[0].ID != 1 || report.Products[0].Quantity != 3 {
+		t.Errorf("expected Bier to be the top product with quantity 3, got %+v", report.Products)
+	}
+	if report.WriteOffs.TotalCents != 1200 || len(report.WriteOffs.WriteOffs) != 2 {
+		t.Errorf("expected 2 write-offs totalling 1200, got %+v", report.WriteOffs)
+	}
+	if len(report.WriteOffs.Categories) != 2 || report.WriteOffs.Categories[1].Category != table.UnpaidWriteOff || report.WriteOffs.Categories[1].TotalCents != 800 {
+		t.Errorf("expected unpaid write-offs of 800, got %+v", report.WriteOffs.Categories)
+	}
+}
+
+func TestNewDailyReport_InterpretsOrdersWithProductHistory(t *testing.T) {
+	must := mustEvent(t)
+	weizen := product.Product{ID: 1, Name: "Weizen", NetPriceCents: 400}
+	weissbier := product.Product{ID: 1, Name: "Weißbier", NetPriceCents: 450}
+	seeded := must(audit.NewEvent(1, audit.EventTypeProductSeededV1, audit.ProductEntity, "1", nil, weizen))
+	seeded.ID = 1
+	seeded.Time = time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	updated := must(audit.NewEvent(1, audit.EventTypeProductUpdatedV1, audit.ProductEntity, "1", weizen, weissbier))
+	updated.ID = 2
+	updated.Time = time.Date(2025, 7, 12, 18, 0, 0, 0, time.UTC)
+	histories, err := product.NewHistories([]e.Event{seeded, updated})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	before := must(table.NewOrderPlacedEvent(1, 5, "", []table.OrderProduct{{ID: 1, Name: "Weizen", NetPriceCents: 400, Quantity: 2}}))
+	before.Time = time.Date(2025, 7, 12, 17, 0, 0, 0, time.UTC)
+	// a device with an outdated product list still ordered with the old price
+	after := must(table.NewOrderPlacedEvent(1, 5, "", []table.OrderProduct{{ID: 1, Name: "Weizen", NetPriceCents: 400, Quantity: 1}}))
+	after.Time = time.Date(2025, 7, 12, 19, 0, 0, 0, time.UTC)
+	// orders without recorded product states keep their own name
+	unknown := must(table.NewOrderPlacedEvent(1, 5, "", []table.OrderProduct{{ID: 2, Name: "Pommes", NetPriceCents: 350, Quantity: 1}}))
+	unknown.Time = time.Date(2025, 7, 12, 19, 0, 0, 0, time.UTC)
+
+	report, err := NewDailyReport(time.Date(2025, 7, 12, 0, 0, 0, 0, time.UTC), []e.Event{before, after, unknown}, histories)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if report.OrderedCents != 1550 {
+		t.Errorf("expected ordered 1550 at the charged prices, got %d", report.OrderedCents)
+	}
+	if len(report.Products) != 3 {
+		t.Fatalf("expected 3 product sales, got %+v", report.Products)
+	}
+	if p := report.Products[0]; p.Name != "Weizen" || p.Quantity != 2 || p.ListPriceCents == nil || *p.ListPriceCents != 400 {
+		t.Errorf("expected 2 Weizen at list price 400, got %+v", p)
+	}
+	if p := report.Products[1]; p.Name != "Weißbier" || p.NetPriceCents != 400 || p.ListPriceCents == nil || *p.ListPriceCents != 450 {
+		t.Errorf("expected Weißbier charged 400 at list price 450, got %+v", p)
+	}
+	if p := report.Products[2]; p.Name != "Pommes" || p.ListPriceCents != nil {
+		t.Errorf("expected Pommes without list price, got %+v", p)
+	}
+}
+
+func TestNewDailyReport_Refunds(t *testing.T) {
+	must := mustEvent(t)
+	payment := must(table.NewPaymentRegisteredEvent(1, 5, "", []table.PaymentProduct{{ID: 2, Name: "Pommes", NetPriceCents: 350, Quantity: 2}}))
+	payments, err := table.GetPaymentsFromEvents([]e.Event{payment})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	events := []e.Event{
+		payment,
+		must(table.NewAmountPaymentRegisteredEvent(1, 5, "", 500)),
+		must(table.NewPaymentReversedEvent(1, 5, 1, payments[0], "Falscher Tisch")),
+	}
+
+	report, err := NewDailyReport(time.Date(2025, 6, 14, 0, 0, 0, 0, time.UTC), events, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if report.PaidCents != 1200 || report.RefundedCents != 700 || report.NetPaidCents != 500 {
+		t.Errorf("expected paid 1200, refunded 700 and net 500, got %d, %d and %d", report.PaidCents, report.RefundedCents, report.NetPaidCents)
+	}
+	if len(report.Refunds) != 1 || report.Refunds[0].PaymentID != payments[0].ID {
+		t.Errorf("expected refund of payment %s, got %+v", payments[0].ID, report.Refunds)
+	}
+}
@@ -0,0 +1,24 @@
+package report
+
+import (
+	e "github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/period"
+	"github.com/nicograef/jotti/backend/domain/product"
+)
+
+// PeriodReport summarizes all table events of one period (Veranstaltung).
+type PeriodReport struct {
+	Period period.Period `json:"period"`
+	Summary
+}
+
+// NewPeriodReport builds the report of the given period from all table events that happened during the period
+// and the histories of the ordered products.
+func NewPeriodReport(p period.Period, events []e.Event, products map[int]product.History) (PeriodReport, error) {
+	summary, err := NewSummary(events, products)
+	if err != nil {
+		return PeriodReport{}, err
+	}
+
+	return PeriodReport{Period: p, Summary: summary}, nil
+}
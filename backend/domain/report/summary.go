@@ -0,0 +1,156 @@
+package report
+
+import (
+	"slices"
+
+	e "github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/product"
+	"github.com/nicograef/jotti/backend/domain/table"
+)
+
+// ProductSales sums up how often a product was ordered at a given price.
+type ProductSales struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// NetPriceCents is the price the product was ordered (and charged) with.
+	NetPriceCents int `json:"netPriceCents"`
+	// ListPriceCents is the price of the product according to the price history at the time of the orders.
+	// Nil if no price was recorded for the product at that time.
+	ListPriceCents *int `json:"listPriceCents"`
+	Quantity       int  `json:"quantity"`
+	TotalCents     int  `json:"totalCents"`
+}
+
+// WriteOffCategoryTotal sums up all write-offs of one category.
+type WriteOffCategoryTotal struct {
+	Category   table.WriteOffCategory `json:"category"`
+	Count      int                    `json:"count"`
+	TotalCents int                    `json:"totalCents"`
+}
+
+// WriteOffSection lists all write-offs of a day, since they are neither revenue nor open balance.
+type WriteOffSection struct {
+	TotalCents int                     `json:"totalCents"`
+	Categories []WriteOffCategoryTotal `json:"categories"`
+	WriteOffs  []table.WriteOff        `json:"writeOffs"`
+}
+
+// Summary sums up orders, payments, refunds and write-offs of a set of table events.
+// Payments and refunds are booked when they happened, so a payment reversed later
+// counts as paid in its own time range and as refunded in the time range of the reversal.
+type Summary struct {
+	OrderedCents  int                     `json:"orderedCents"`
+	PaidCents     int                     `json:"paidCents"`
+	RefundedCents int                     `json:"refundedCents"`
+	NetPaidCents  int                     `json:"netPaidCents"` // PaidCents minus RefundedCents
+	Products      []ProductSales          `json:"products"`
+	Refunds       []table.PaymentReversal `json:"refunds"`
+	WriteOffs     WriteOffSection         `json:"writeOffs"`
+}
+
+// NewSummary builds the summary of the given table events.
+// Ordered products are interpreted with the product histories: names and list prices are the ones at the time of the order.
+func NewSummary(events []e.Event, products map[int]product.History) (Summary, error) {
+	orders, err := table.GetOrdersFromEvents(events)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	payments, err := table.GetPaymentsFromEvents(events)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	writeOffs, err := table.GetWriteOffsFromEvents(events)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	reversals, err := table.GetPaymentReversalsFromEvents(events)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	report := Summary{
+		Products: []ProductSales{},
+		Refunds:  reversals,
+		WriteOffs: WriteOffSection{
+			Categories: []WriteOffCategoryTotal{},
+			WriteOffs:  writeOffs,
+		},
+	}
+
+	for _, order := range orders {
+		report.OrderedCents += order.TotalNetPriceCents
+		for _, orderProduct := range order.Products {
+			report.Products = addProductSales(report.Products, orderProduct, products[orderProduct.ID], order)
+		}
+	}
+
+	for _, payment := range payments {
+		report.PaidCents += payment.TotalPaymentCents
+	}
+
+	for _, reversal := range reversals {
+		report.RefundedCents += reversal.AmountCents
+	}
+	report.NetPaidCents = report.PaidCents - report.RefundedCents
+
+	for _, writeOff := range writeOffs {
+		report.WriteOffs.TotalCents += writeOff.TotalWriteOffCents
+		report.WriteOffs.Categories = addWriteOff(report.WriteOffs.Categories, writeOff)
+	}
+
+	slices.SortFunc(report.Products, func(a, b ProductSales) int { return b.TotalCents - a.TotalCents })
+
+	return report, nil
+}
+
+func addProductSales(sales []ProductSales, ordered table.OrderProduct, history product.History, order table.Order) []ProductSales {
+	name := ordered.Name
+	var listPriceCents *int
+	if p, ok := history.At(order.PlacedAt); ok {
+		name = p.Name
+		listPriceCents = &p.NetPriceCents
+	}
+
+	for i := range sales {
+		if sales[i].ID == ordered.ID && sales[i].NetPriceCents == ordered.NetPriceCents && equalPrice(sales[i].ListPriceCents, listPriceCents) {
+			sales[i].Quantity += ordered.Quantity
+			sales[i].TotalCents += ordered.NetPriceCents * ordered.Quantity
+			return sales
+		}
+	}
+
+	return append(sales, ProductSales{
+		ID:             ordered.ID,
+		Name:           name,
+		NetPriceCents:  ordered.NetPriceCents,
+		ListPriceCents: listPriceCents,
+		Quantity:       ordered.Quantity,
+		TotalCents:     ordered.NetPriceCents * ordered.Quantity,
+	})
+}
+
+func equalPrice(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func addWriteOff(totals []WriteOffCategoryTotal, writeOff table.WriteOff) []WriteOffCategoryTotal {
+	for i := range totals {
+		if totals[i].Category == writeOff.Category {
+			totals[i].Count++
+			totals[i].TotalCents += writeOff.TotalWriteOffCents
+			return totals
+		}
+	}
+
+	return append(totals, WriteOffCategoryTotal{
+		Category:   writeOff.Category,
+		Count:      1,
+		TotalCents: writeOff.TotalWriteOffCents,
+	})
+}
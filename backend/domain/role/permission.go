@@ -0,0 +1,62 @@
+package role
+
+import (
+	z "github.com/Oudwins/zog"
+)
+
+// Permission allows a user to use a group of endpoints. Every endpoint requires exactly one permission.
+type Permission string
+
+const (
+	// ManageUsers: create, update, activate and deactivate users, reset passwords and clear login lockouts.
+	ManageUsers Permission = "users.manage"
+	// ManageRoles: create, update and delete roles.
+	ManageRoles Permission = "roles.manage"
+	// ManageDevices: register and revoke devices for PIN login.
+	ManageDevices Permission = "devices.manage"
+	// ManageProducts: create, update, activate and deactivate products.
+	ManageProducts Permission = "products.manage"
+	// ManageTables: create, update, activate and deactivate tables.
+	ManageTables Permission = "tables.manage"
+	// ManagePeriods: open and close periods.
+	ManagePeriods Permission = "periods.manage"
+	// ViewReports: see daily and period reports.
+	ViewReports Permission = "reports.view"
+	// ViewAudit: browse the audit log of changes made by admins.
+	ViewAudit Permission = "audit.view"
+	// ViewTables: see tables with their orders, payments and balances.
+	ViewTables Permission = "tables.view"
+	// ServeTables: open, reopen and close tables and place orders.
+	ServeTables Permission = "tables.serve"
+	// RegisterPayments: register payments and reverse own recent payments.
+	RegisterPayments Permission = "payments.register"
+	// ReverseAnyPayment: reverse payments of other users and after the reversal window.
+	ReverseAnyPayment Permission = "payments.reverse_any"
+	// WriteOffItems: write off unpaid items of a table, e.g. to cancel orders or grant discounts.
+	WriteOffItems Permission = "items.write_off"
+	// ForceCloseTables: close tables with unpaid items.
+	ForceCloseTables Permission = "tables.force_close"
+)
+
+// AllPermissions lists every permission in the order they are shown to admins.
+var AllPermissions = []Permission{
+	ManageUsers,
+	ManageRoles,
+	ManageDevices,
+	ManageProducts,
+	ManageTables,
+	ManagePeriods,
+	ViewReports,
+	ViewAudit,
+	ViewTables,
+	ServeTables,
+	RegisterPayments,
+	ReverseAnyPayment,
+	WriteOffItems,
+	ForceCloseTables,
+}
+
+var PermissionSchema = z.StringLike[Permission]().OneOf(
+	AllPermissions,
+	z.Message("Invalid permission"),
+)
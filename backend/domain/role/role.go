@@ -0,0 +1,117 @@
+package role
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	z "github.com/Oudwins/zog"
+	"github.com/nicograef/jotti/backend/domain/validation"
+)
+
+const (
+	// AdminName is the built-in role that always has all permissions. It can't be changed or deleted,
+	// so admins can't lock themselves out.
+	AdminName = "admin"
+	// ServiceName is the built-in role for service staff.
+	ServiceName = "service"
+)
+
+// Role is a named set of permissions managed by admins, e.g. "schichtleitung" or "kueche".
+// Every user has exactly one role.
+type Role struct {
+	Name        string       `json:"name"`
+	Permissions []Permission `json:"permissions"`
+	CreatedAt   time.Time    `json:"createdAt"`
+}
+
+// NamePattern is the format of role names. Role names are stored with the user and carried in the access token.
+var NamePattern = regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+
+var NameSchema = z.String().Trim().Match(NamePattern, z.Message("Only lowercase alphanumerical role names with 3 to 30 characters allowed"))
+
+var ErrProtected = errors.New("role is protected")
+
+// NewRole creates a new role with the given permissions.
+func NewRole(name string, permissions []Permission) (Role, error) {
+	if err := validation.Field("name", NameSchema.Validate(&name)); err != nil {
+		return Role{}, err
+	}
+
+	permissions, err := normalizePermissions(permissions)
+	if err != nil {
+		return Role{}, err
+	}
+
+	role := Role{
+		Name:        name,
+		Permissions: permissions,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	return role, nil
+}
+
+// IsProtected reports whether the role is built in and can't be changed or deleted.
+func (r Role) IsProtected() bool {
+	return r.Name == AdminName
+}
+
+// SetPermissions replaces the permissions of the role.
+func (r *Role) SetPermissions(permissions []Permission) error {
+	if r.IsProtected() {
+		return ErrProtected
+	}
+
+	permissions, err := normalizePermissions(permissions)
+	if err != nil {
+		return err
+	}
+
+	r.Permissions = permissions
+	return nil
+}
+
+// Has reports whether the role grants the permission. The admin role has all permissions.
+func (r Role) Has(p Permission) bool {
+	return r.IsProtected() || slices.Contains(r.Permissions, p)
+}
+
+// EffectivePermissions returns the permissions the role grants, i.e. all permissions for the admin role.
+func (r Role) EffectivePermissions() []Permission {
+	if r.IsProtected() {
+		return slices.Clone(AllPermissions)
+	}
+	return slices.Clone(r.Permissions)
+}
+
+// GrantsOnly reports whether the role grants no permission beyond the given ones, e.g. those of the user who assigns it.
+func (r Role) GrantsOnly(permissions []Permission) bool {
+	for _, p := range r.EffectivePermissions() {
+		if !slices.Contains(permissions, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizePermissions validates the permissions and returns them without duplicates in the order of AllPermissions.
+func normalizePermissions(permissions []Permission) ([]Permission, error) {
+	var v validation.Validator
+	for i, p := range permissions {
+		v.Check("permissions["+strconv.Itoa(i)+"]", PermissionSchema.Validate(&p))
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	normalized := []Permission{}
+	for _, p := range AllPermissions {
+		if slices.Contains(permissions, p) {
+			normalized = append(normalized, p)
+		}
+	}
+	return normalized, nil
+}
@@ -0,0 +1,60 @@
+//go:build unit
+
+package role
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewRole(t *testing.T) {
+	r, err := NewRole("schichtleitung", []Permission{WriteOffItems, ViewTables, WriteOffItems})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []Permission{ViewTables, WriteOffItems}
+	if !slices.Equal(r.Permissions, expected) {
+		t.Errorf("expected permissions %v, got %v", expected, r.Permissions)
+	}
+	if !r.Has(WriteOffItems) || r.Has(ManageUsers) {
+		t.Errorf("unexpected permissions %v", r.Permissions)
+	}
+}
+
+func TestNewRole_Invalid(t *testing.T) {
+	if _, err := NewRole("Küche", nil); err == nil {
+		t.Error("expected error for invalid name")
+	}
+	if _, err := NewRole("kueche", []Permission{"orders.cook"}); err == nil {
+		t.Error("expected error for unknown permission")
+	}
+}
+
+func TestAdminRole(t *testing.T) {
+	r := Role{Name: AdminName}
+
+	if !r.Has(ManageRoles) {
+		t.Error("expected admin role to have all permissions")
+	}
+	if len(r.EffectivePermissions()) != len(AllPermissions) {
+		t.Errorf("expected all permissions, got %v", r.EffectivePermissions())
+	}
+	if err := r.SetPermissions([]Permission{ViewTables}); err != ErrProtected {
+		t.Errorf("expected ErrProtected, got %v", err)
+	}
+}
+
+func TestGrantsOnly(t *testing.T) {
+	r := Role{Name: "schichtleitung", Permissions: []Permission{ViewTables, ReverseAnyPayment}}
+
+	if !r.GrantsOnly([]Permission{ViewTables, ReverseAnyPayment, ManageUsers}) {
+		t.Error("expected role within the given permissions")
+	}
+	if r.GrantsOnly([]Permission{ViewTables, ManageUsers}) {
+		t.Error("expected role to grant more than the given permissions")
+	}
+	if (Role{Name: AdminName}).GrantsOnly([]Permission{ManageUsers}) {
+		t.Error("expected admin role to grant all permissions")
+	}
+}
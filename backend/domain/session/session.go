@@ -0,0 +1,127 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshTokenValidity is how long a refresh token can be used before the user has to log in again.
+// Every refresh rotates the token and extends the session by this duration.
+const RefreshTokenValidity = 7 * 24 * time.Hour
+
+// DeviceIdleTimeout is after how much inactivity a PIN login on a shared device is logged out.
+const DeviceIdleTimeout = 10 * time.Minute
+
+// touchInterval limits how often the last activity of a session is written.
+const touchInterval = time.Minute
+
+// Session is a login of a user on one client. Access tokens carry the session ID, so revoking the
+// session invalidates all access tokens issued for it. Only the hash of the refresh token is stored.
+// Sessions of PIN logins are bound to a device and end after DeviceIdleTimeout without activity.
+type Session struct {
+	ID               string     `json:"id"`
+	UserID           int        `json:"userId"`
+	DeviceID         *int       `json:"deviceId,omitempty"`
+	RefreshTokenHash string     `json:"-"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	ExpiresAt        time.Time  `json:"expiresAt"`
+	LastActiveAt     time.Time  `json:"lastActiveAt"`
+	RevokedAt        *time.Time `json:"revokedAt,omitempty"`
+}
+
+var ErrSessionRevoked = errors.New("session revoked")
+
+// NewSession creates a new session for the given user and returns it together with the plain refresh token.
+func NewSession(userID int) (Session, string, error) {
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return Session{}, "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	now := time.Now().UTC()
+	session := Session{
+		ID:               uuid.NewString(),
+		UserID:           userID,
+		RefreshTokenHash: HashRefreshToken(refreshToken),
+		CreatedAt:        now,
+		ExpiresAt:        now.Add(RefreshTokenValidity),
+		LastActiveAt:     now,
+	}
+
+	return session, refreshToken, nil
+}
+
+// NewDeviceSession creates a new session for a PIN login on the given device.
+func NewDeviceSession(userID, deviceID int) (Session, string, error) {
+	session, refreshToken, err := NewSession(userID)
+	if err != nil {
+		return Session{}, "", err
+	}
+
+	session.DeviceID = &deviceID
+	return session, refreshToken, nil
+}
+
+// IsActive reports whether the session is neither revoked, expired nor idle at the given time.
+func (s Session) IsActive(now time.Time) bool {
+	if s.DeviceID != nil && now.Sub(s.LastActiveAt) > DeviceIdleTimeout {
+		return false
+	}
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// NeedsTouch reports whether the last activity is old enough to be updated.
+func (s Session) NeedsTouch(now time.Time) bool {
+	return now.Sub(s.LastActiveAt) > touchInterval
+}
+
+// Rotate replaces the refresh token of an active session and returns the new plain refresh token.
+// The previous refresh token can no longer be used.
+func (s *Session) Rotate() (string, error) {
+	now := time.Now().UTC()
+	if !s.IsActive(now) {
+		return "", ErrSessionRevoked
+	}
+
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	s.RefreshTokenHash = HashRefreshToken(refreshToken)
+	s.ExpiresAt = now.Add(RefreshTokenValidity)
+
+	return refreshToken, nil
+}
+
+// Revoke ends the session. Revoking an already revoked session keeps the original revocation time.
+func (s *Session) Revoke() {
+	if s.RevokedAt != nil {
+		return
+	}
+
+	revokedAt := time.Now().UTC()
+	s.RevokedAt = &revokedAt
+}
+
+// HashRefreshToken returns the hex encoded SHA-256 hash of a refresh token.
+// Refresh tokens are long random values, so a fast hash is sufficient.
+func HashRefreshToken(refreshToken string) string {
+	hash := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(hash[:])
+}
+
+func generateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
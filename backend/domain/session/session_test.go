@@ -0,0 +1,79 @@
+//go:build unit
+
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewSession(t *testing.T) {
+	s, refreshToken, err := NewSession(1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if s.ID == "" || refreshToken == "" {
+		t.Fatalf("expected session ID and refresh token, got %+v", s)
+	}
+	if s.RefreshTokenHash != HashRefreshToken(refreshToken) {
+		t.Error("expected stored hash to match refresh token")
+	}
+	if s.RefreshTokenHash == refreshToken {
+		t.Error("expected refresh token not to be stored in plain text")
+	}
+	if !s.IsActive(time.Now()) {
+		t.Error("expected new session to be active")
+	}
+}
+
+func TestRotate(t *testing.T) {
+	s, oldToken, _ := NewSession(1)
+
+	newToken, err := s.Rotate()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if newToken == oldToken {
+		t.Error("expected a new refresh token")
+	}
+	if s.RefreshTokenHash != HashRefreshToken(newToken) {
+		t.Error("expected hash of the new refresh token")
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	s, _, _ := NewSession(1)
+
+	s.Revoke()
+	if s.IsActive(time.Now()) {
+		t.Error("expected revoked session to be inactive")
+	}
+	if _, err := s.Rotate(); err != ErrSessionRevoked {
+		t.Errorf("expected ErrSessionRevoked, got %v", err)
+	}
+}
+
+func TestIsActive_Expired(t *testing.T) {
+	s, _, _ := NewSession(1)
+
+	if s.IsActive(time.Now().Add(RefreshTokenValidity + time.Minute)) {
+		t.Error("expected expired session to be inactive")
+	}
+}
+
+func TestIsActive_DeviceIdle(t *testing.T) {
+	s, _, _ := NewDeviceSession(1, 2)
+	now := time.Now()
+
+	if !s.IsActive(now.Add(DeviceIdleTimeout - time.Minute)) {
+		t.Error("expected device session to be active before idle timeout")
+	}
+	if s.IsActive(now.Add(DeviceIdleTimeout + time.Minute)) {
+		t.Error("expected device session to end after idle timeout")
+	}
+
+	normal, _, _ := NewSession(1)
+	if !normal.IsActive(now.Add(DeviceIdleTimeout + time.Minute)) {
+		t.Error("expected session without device to have no idle timeout")
+	}
+}
@@ -0,0 +1,82 @@
+package table
+
+import (
+	"fmt"
+	"strconv"
+
+	z "github.com/Oudwins/zog"
+	"github.com/google/uuid"
+	e "github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/validation"
+)
+
+// amountPaymentRegisteredV1Data describes a partial payment of a fixed amount that does not reference specific products.
+type amountPaymentRegisteredV1Data struct {
+	PaymentID   string `json:"paymentId"` // UUID string
+	AmountCents int    `json:"amountCents"`
+}
+
+// AmountCentsSchema defines the schema for the amount of an amount-based payment.
+var AmountCentsSchema = z.Int().GTE(1, z.Message("Amount must be at least 1 cent")).LTE(9999999, z.Message("Amount too high"))
+
+var amountPaymentRegisteredV1DataSchema = z.Struct(z.Shape{
+	"PaymentID":   z.String().UUID().Required(),
+	"AmountCents": AmountCentsSchema.Required(),
+})
+
+// NewAmountPaymentRegisteredEvent creates an event that registers a payment of an amount. Like payments of products,
+// the payment ID may be chosen by the client; a new one is generated if it's empty.
+func NewAmountPaymentRegisteredEvent(userID, tableID int, paymentID string, amountCents int) (e.Event, error) {
+	if paymentID == "" {
+		paymentID = uuid.New().String()
+	}
+
+	data := amountPaymentRegisteredV1Data{
+		PaymentID:   paymentID,
+		AmountCents: amountCents,
+	}
+
+	if err := validation.Struct(&data, amountPaymentRegisteredV1DataSchema.Validate(&data)); err != nil {
+		return e.Event{}, fmt.Errorf("amount payment registered data validation failed: %w", err)
+	}
+
+	event, err := e.New(userID, string(EventTypeAmountPaymentRegisteredV1), "table:"+strconv.Itoa(tableID), data)
+	if err != nil {
+		return e.Event{}, err
+	}
+
+	return event, nil
+}
+
+func buildAmountPaymentFromEvent(event e.Event) (Payment, error) {
+	if event.Type != string(EventTypeAmountPaymentRegisteredV1) {
+		return Payment{}, fmt.Errorf("unsupported event type: %s", event.Type)
+	}
+
+	tableID, err := strconv.Atoi(event.Subject[len("table:"):])
+	if err != nil {
+		return Payment{}, fmt.Errorf("invalid table ID in event subject: %v", err)
+	}
+
+	data := amountPaymentRegisteredV1Data{}
+	err = e.ParseData(event, &data, amountPaymentRegisteredV1DataSchema)
+	if err != nil {
+		return Payment{}, err
+	}
+
+	payment := Payment{
+		ID:                data.PaymentID,
+		UserID:            event.UserID,
+		TableID:           tableID,
+		Products:          []PaymentProduct{},
+		TotalPaymentCents: data.AmountCents,
+		RegisteredAt:      event.Time,
+	}
+
+	if err := amountPaymentSchema.Validate(&payment); err != nil {
+		issues := z.Issues.SanitizeMapAndCollect(err)
+		return Payment{}, fmt.Errorf("amount payment validation failed: %v", issues)
+	}
+
+	return payment, nil
+}
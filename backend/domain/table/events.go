@@ -1,17 +1,43 @@
 package table
 
-import e "github.com/nicograef/jotti/backend/domain/event"
+import (
+	"time"
+
+	e "github.com/nicograef/jotti/backend/domain/event"
+)
 
 type EventType string
 
 const (
 	EventTypeOrderPlacedV1       EventType = "table.order-placed:v1"
 	EventTypePaymentRegisteredV1 EventType = "table.payment-registered:v1"
+	// EventTypeAmountPaymentRegisteredV1 is a partial payment of a fixed amount without product references.
+	EventTypeAmountPaymentRegisteredV1 EventType = "table.amount-payment-registered:v1"
+	// EventTypeTableOpenedV1 starts (or reopens) a guest visit at a table.
+	EventTypeTableOpenedV1 EventType = "table.opened:v1"
+	// EventTypeTableClosedV1 ends a guest visit at a table.
+	EventTypeTableClosedV1 EventType = "table.closed:v1"
+	// EventTypeItemsWrittenOffV1 removes open items from a table without a payment.
+	EventTypeItemsWrittenOffV1 EventType = "table.items-written-off:v1"
+	// EventTypePaymentReversedV1 refunds a registered payment, its products are unpaid again.
+	EventTypePaymentReversedV1 EventType = "table.payment-reversed:v1"
 )
 
+// EventTypes are all types of table events.
+var EventTypes = []EventType{
+	EventTypeOrderPlacedV1, EventTypePaymentRegisteredV1, EventTypeAmountPaymentRegisteredV1,
+	EventTypeTableOpenedV1, EventTypeTableClosedV1, EventTypeItemsWrittenOffV1, EventTypePaymentReversedV1,
+}
+
+// GetBalanceFromEvents returns the open amount of the given events. Reversed payments are ignored.
 func GetBalanceFromEvents(events []e.Event) (int, error) {
 	balanceCents := 0
 
+	reversed, err := getReversedPaymentsFromEvents(events)
+	if err != nil {
+		return 0, err
+	}
+
 	for _, event := range events {
 		if event.Type == string(EventTypeOrderPlacedV1) {
 			order, err := buildOrderFromEvent(event)
@@ -24,7 +50,23 @@ func GetBalanceFromEvents(events []e.Event) (int, error) {
 			if err != nil {
 				return 0, err
 			}
-			balanceCents -= payment.TotalPaymentCents
+			if _, ok := reversed[payment.ID]; !ok {
+				balanceCents -= payment.TotalPaymentCents
+			}
+		} else if event.Type == string(EventTypeAmountPaymentRegisteredV1) {
+			payment, err := buildAmountPaymentFromEvent(event)
+			if err != nil {
+				return 0, err
+			}
+			if _, ok := reversed[payment.ID]; !ok {
+				balanceCents -= payment.TotalPaymentCents
+			}
+		} else if event.Type == string(EventTypeItemsWrittenOffV1) {
+			writeOff, err := buildWriteOffFromEvent(event)
+			if err != nil {
+				return 0, err
+			}
+			balanceCents -= writeOff.TotalWriteOffCents
 		}
 	}
 
@@ -47,24 +89,100 @@ func GetOrdersFromEvents(events []e.Event) ([]Order, error) {
 	return orders, nil
 }
 
+// GetPaymentsFromEvents returns all payments of the given events. Reversed payments are included and marked with ReversedAt.
 func GetPaymentsFromEvents(events []e.Event) ([]Payment, error) {
 	payments := []Payment{}
 
+	reversed, err := getReversedPaymentsFromEvents(events)
+	if err != nil {
+		return []Payment{}, err
+	}
+
 	for _, event := range events {
+		var payment Payment
+		var err error
+
 		if event.Type == string(EventTypePaymentRegisteredV1) {
-			payment, err := buildPaymentFromEvent(event)
+			payment, err = buildPaymentFromEvent(event)
+		} else if event.Type == string(EventTypeAmountPaymentRegisteredV1) {
+			payment, err = buildAmountPaymentFromEvent(event)
+		} else {
+			continue
+		}
+		if err != nil {
+			return []Payment{}, err
+		}
+
+		if reversedAt, ok := reversed[payment.ID]; ok {
+			payment.ReversedAt = &reversedAt
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, nil
+}
+
+// GetPaymentReversalsFromEvents returns all payment reversals of the given events.
+func GetPaymentReversalsFromEvents(events []e.Event) ([]PaymentReversal, error) {
+	reversals := []PaymentReversal{}
+
+	for _, event := range events {
+		if event.Type == string(EventTypePaymentReversedV1) {
+			reversal, err := buildPaymentReversalFromEvent(event)
 			if err != nil {
-				return []Payment{}, err
+				return []PaymentReversal{}, err
 			}
-			payments = append(payments, payment)
+			reversals = append(reversals, reversal)
 		}
 	}
 
-	return payments, nil
+	return reversals, nil
+}
+
+// getReversedPaymentsFromEvents maps the IDs of reversed payments to the time they were reversed.
+func getReversedPaymentsFromEvents(events []e.Event) (map[string]time.Time, error) {
+	reversals, err := GetPaymentReversalsFromEvents(events)
+	if err != nil {
+		return nil, err
+	}
+
+	reversed := make(map[string]time.Time, len(reversals))
+	for _, reversal := range reversals {
+		reversed[reversal.PaymentID] = reversal.ReversedAt
+	}
+
+	return reversed, nil
+}
+
+// GetWriteOffsFromEvents returns all write-offs of the given events.
+func GetWriteOffsFromEvents(events []e.Event) ([]WriteOff, error) {
+	writeOffs := []WriteOff{}
+
+	for _, event := range events {
+		if event.Type == string(EventTypeItemsWrittenOffV1) {
+			writeOff, err := buildWriteOffFromEvent(event)
+			if err != nil {
+				return []WriteOff{}, err
+			}
+			writeOffs = append(writeOffs, writeOff)
+		}
+	}
+
+	return writeOffs, nil
 }
 
+// GetUnpaidProductsFromEvents returns the ordered products that have not been paid yet.
+// Amount payments do not reference products. Their sum is used as a credit that settles whole
+// product units in order of appearance once all product payments have been deducted.
+// Products of reversed payments are unpaid again.
 func GetUnpaidProductsFromEvents(events []e.Event) ([]OrderProduct, error) {
 	unpaidProducts := []OrderProduct{}
+	creditCents := 0
+
+	reversed, err := getReversedPaymentsFromEvents(events)
+	if err != nil {
+		return []OrderProduct{}, err
+	}
 
 	for _, event := range events {
 		if event.Type == string(EventTypeOrderPlacedV1) {
@@ -92,24 +210,93 @@ func GetUnpaidProductsFromEvents(events []e.Event) ([]OrderProduct, error) {
 			if err != nil {
 				return []OrderProduct{}, err
 			}
+			if _, ok := reversed[payment.ID]; ok {
+				continue
+			}
 
 			// reduce quantities of paid products from unpaidProducts
 			for _, paidProduct := range payment.Products {
-				for i := 0; i < len(unpaidProducts); i++ {
-					if unpaidProducts[i].ID == paidProduct.ID && unpaidProducts[i].NetPriceCents == paidProduct.NetPriceCents {
-						if unpaidProducts[i].Quantity > paidProduct.Quantity {
-							unpaidProducts[i].Quantity -= paidProduct.Quantity
-						} else {
-							// remove product from unpaidProducts if fully paid
-							unpaidProducts = append(unpaidProducts[:i], unpaidProducts[i+1:]...)
-							i-- // adjust index after removal
-						}
-						break
-					}
-				}
+				unpaidProducts = reduceUnpaidProduct(unpaidProducts, paidProduct.ID, paidProduct.NetPriceCents, paidProduct.Quantity)
 			}
+		} else if event.Type == string(EventTypeAmountPaymentRegisteredV1) {
+			payment, err := buildAmountPaymentFromEvent(event)
+			if err != nil {
+				return []OrderProduct{}, err
+			}
+			if _, ok := reversed[payment.ID]; ok {
+				continue
+			}
+			creditCents += payment.TotalPaymentCents
+		} else if event.Type == string(EventTypeItemsWrittenOffV1) {
+			writeOff, err := buildWriteOffFromEvent(event)
+			if err != nil {
+				return []OrderProduct{}, err
+			}
+
+			// written off products are no longer open and the credit recorded against them is used up
+			for _, writtenOffProduct := range writeOff.Products {
+				unpaidProducts = reduceUnpaidProduct(unpaidProducts, writtenOffProduct.ID, writtenOffProduct.NetPriceCents, writtenOffProduct.Quantity)
+			}
+			creditCents -= writeOff.CreditCents
+		}
+	}
+
+	return settleProductsWithCredit(unpaidProducts, creditCents), nil
+}
+
+// reduceUnpaidProduct reduces the quantity of the matching unpaid product and removes it once nothing is left.
+func reduceUnpaidProduct(unpaidProducts []OrderProduct, id, netPriceCents, quantity int) []OrderProduct {
+	for i := 0; i < len(unpaidProducts); i++ {
+		if unpaidProducts[i].ID == id && unpaidProducts[i].NetPriceCents == netPriceCents {
+			if unpaidProducts[i].Quantity > quantity {
+				unpaidProducts[i].Quantity -= quantity
+			} else {
+				// remove product from unpaidProducts if fully settled
+				unpaidProducts = append(unpaidProducts[:i], unpaidProducts[i+1:]...)
+			}
+			break
+		}
+	}
+
+	return unpaidProducts
+}
+
+// AreProductsUnpaid checks whether the given quantities of products are still unpaid.
+func AreProductsUnpaid(unpaidProducts []OrderProduct, products []WriteOffProduct) bool {
+	remaining := append([]OrderProduct{}, unpaidProducts...)
+
+	for _, product := range products {
+		found := false
+		for _, unpaidProduct := range remaining {
+			if unpaidProduct.ID == product.ID && unpaidProduct.NetPriceCents == product.NetPriceCents && unpaidProduct.Quantity >= product.Quantity {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+		remaining = reduceUnpaidProduct(remaining, product.ID, product.NetPriceCents, product.Quantity)
+	}
+
+	return true
+}
+
+// settleProductsWithCredit removes as many whole product units as the credit covers, starting with the first product.
+// A remaining credit that does not cover a whole unit is only reflected in the balance.
+func settleProductsWithCredit(products []OrderProduct, creditCents int) []OrderProduct {
+	unpaidProducts := []OrderProduct{}
+
+	for _, product := range products {
+		if creditCents > 0 && product.NetPriceCents > 0 {
+			settledQuantity := min(product.Quantity, creditCents/product.NetPriceCents)
+			product.Quantity -= settledQuantity
+			creditCents -= settledQuantity * product.NetPriceCents
+		}
+		if product.Quantity > 0 {
+			unpaidProducts = append(unpaidProducts, product)
 		}
 	}
 
-	return unpaidProducts, nil
+	return unpaidProducts
 }
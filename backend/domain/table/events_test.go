@@ -0,0 +1,219 @@
+//go:build unit
+
+package table
+
+import (
+	"testing"
+	"time"
+
+	e "github.com/nicograef/jotti/backend/domain/event"
+)
+
+// mustEvent returns a helper that fails the test if an event could not be created.
+func mustEvent(t *testing.T) func(e.Event, error) e.Event {
+	return func(event e.Event, err error) e.Event {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("expected no error creating event, got %v", err)
+		}
+		return event
+	}
+}
+
+func TestGetBalanceFromEvents_AmountPayment(t *testing.T) {
+	must := mustEvent(t)
+	events := []e.Event{
+		must(NewOrderPlacedEvent(1, 5, "", []OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 3}})),
+		must(NewAmountPaymentRegisteredEvent(1, 5, "", 500)),
+	}
+
+	balance, err := GetBalanceFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if balance != 700 {
+		t.Errorf("expected balance 700, got %d", balance)
+	}
+}
+
+func TestGetPaymentsFromEvents_AmountPayment(t *testing.T) {
+	must := mustEvent(t)
+	events := []e.Event{
+		must(NewAmountPaymentRegisteredEvent(1, 5, "", 500)),
+	}
+
+	payments, err := GetPaymentsFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(payments) != 1 {
+		t.Fatalf("expected 1 payment, got %d", len(payments))
+	}
+	if payments[0].TotalPaymentCents != 500 || len(payments[0].Products) != 0 {
+		t.Errorf("expected amount payment of 500 without products, got %+v", payments[0])
+	}
+}
+
+func TestGetUnpaidProductsFromEvents_AmountPaymentSettlesWholeUnits(t *testing.T) {
+	must := mustEvent(t)
+	events := []e.Event{
+		must(NewOrderPlacedEvent(1, 5, "", []OrderProduct{
+			{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 2},
+			{ID: 2, Name: "Pommes", NetPriceCents: 350, Quantity: 1},
+		})),
+		must(NewPaymentRegisteredEvent(1, 5, "", []PaymentProduct{{ID: 2, Name: "Pommes", NetPriceCents: 350, Quantity: 1}})),
+		must(NewAmountPaymentRegisteredEvent(1, 5, "", 500)),
+	}
+
+	unpaid, err := GetUnpaidProductsFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(unpaid) != 1 {
+		t.Fatalf("expected 1 unpaid product, got %d", len(unpaid))
+	}
+	if unpaid[0].ID != 1 || unpaid[0].Quantity != 1 {
+		t.Errorf("expected 1 unpaid Bier, got %+v", unpaid[0])
+	}
+}
+
+func TestItemsWrittenOff_Projections(t *testing.T) {
+	must := mustEvent(t)
+	events := []e.Event{
+		must(NewOrderPlacedEvent(1, 5, "", []OrderProduct{
+			{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 2},
+			{ID: 2, Name: "Pommes", NetPriceCents: 350, Quantity: 1},
+		})),
+		must(NewItemsWrittenOffEvent(1, 5, BreakageWriteOff, "Glas umgefallen", []WriteOffProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}}, 0)),
+	}
+
+	balance, err := GetBalanceFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if balance != 750 {
+		t.Errorf("expected balance 750, got %d", balance)
+	}
+
+	unpaid, err := GetUnpaidProductsFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(unpaid) != 2 || unpaid[0].Quantity != 1 {
+		t.Errorf("expected 1 unpaid Bier and 1 Pommes, got %+v", unpaid)
+	}
+
+	payments, err := GetPaymentsFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(payments) != 0 {
+		t.Errorf("expected write-off not to count as payment, got %+v", payments)
+	}
+
+	writeOffs, err := GetWriteOffsFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(writeOffs) != 1 || writeOffs[0].Category != BreakageWriteOff || writeOffs[0].TotalWriteOffCents != 400 {
+		t.Errorf("expected breakage write-off of 400, got %+v", writeOffs)
+	}
+}
+
+func TestNewItemsWrittenOffEvent_Invalid(t *testing.T) {
+	if _, err := NewItemsWrittenOffEvent(1, 5, "lost", "", []WriteOffProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}}, 0); err == nil {
+		t.Error("expected error for invalid category")
+	}
+	if _, err := NewItemsWrittenOffEvent(1, 5, UnpaidWriteOff, "", []WriteOffProduct{}, 0); err == nil {
+		t.Error("expected error for missing products")
+	}
+	if _, err := NewItemsWrittenOffEvent(1, 5, UnpaidWriteOff, "", []WriteOffProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}}, 500); err == nil {
+		t.Error("expected error for credit exceeding the products")
+	}
+}
+
+func TestPaymentReversed_Projections(t *testing.T) {
+	must := mustEvent(t)
+	order := must(NewOrderPlacedEvent(1, 5, "", []OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 2}}))
+	payment := must(NewPaymentRegisteredEvent(1, 5, "", []PaymentProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 2}}))
+	payments, err := GetPaymentsFromEvents([]e.Event{payment})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	events := []e.Event{order, payment, must(NewPaymentReversedEvent(1, 5, 1, payments[0], "Falscher Tisch"))}
+
+	balance, err := GetBalanceFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if balance != 800 {
+		t.Errorf("expected balance 800, got %d", balance)
+	}
+
+	unpaid, err := GetUnpaidProductsFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(unpaid) != 1 || unpaid[0].Quantity != 2 {
+		t.Errorf("expected 2 unpaid Bier, got %+v", unpaid)
+	}
+
+	payments, err = GetPaymentsFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(payments) != 1 || payments[0].ReversedAt == nil {
+		t.Errorf("expected reversed payment to be marked, got %+v", payments)
+	}
+}
+
+func TestCanReversePayment(t *testing.T) {
+	now := time.Now()
+	payment := Payment{UserID: 2, RegisteredAt: now.Add(-5 * time.Minute)}
+
+	if !CanReversePayment(payment, 2, false, now) {
+		t.Error("expected original user to reverse within window")
+	}
+	if CanReversePayment(payment, 3, false, now) {
+		t.Error("expected other user not to reverse payment")
+	}
+	if CanReversePayment(payment, 2, false, now.Add(ReversalWindow)) {
+		t.Error("expected original user not to reverse after window")
+	}
+	if !CanReversePayment(payment, 3, true, now.Add(time.Hour)) {
+		t.Error("expected admin to reverse payment at any time")
+	}
+}
+
+func TestSplitAmount(t *testing.T) {
+	shares, err := SplitAmount(1000, 3)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []int{334, 333, 333}
+	for i, share := range shares {
+		if share != expected[i] {
+			t.Errorf("expected share %d to be %d, got %d", i, expected[i], share)
+		}
+	}
+}
+
+func TestSplitAmount_NegativeAmount(t *testing.T) {
+	shares, err := SplitAmount(-200, 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if shares[0] != 0 || shares[1] != 0 {
+		t.Errorf("expected zero shares, got %v", shares)
+	}
+}
+
+func TestSplitAmount_InvalidParts(t *testing.T) {
+	if _, err := SplitAmount(1000, 0); err != ErrInvalidSplitParts {
+		t.Errorf("expected ErrInvalidSplitParts, got %v", err)
+	}
+	if _, err := SplitAmount(1000, MaxSplitParts+1); err != ErrInvalidSplitParts {
+		t.Errorf("expected ErrInvalidSplitParts, got %v", err)
+	}
+}
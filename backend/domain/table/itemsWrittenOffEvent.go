@@ -0,0 +1,92 @@
+package table
+
+import (
+	"fmt"
+	"strconv"
+
+	z "github.com/Oudwins/zog"
+	"github.com/google/uuid"
+	e "github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/validation"
+)
+
+type itemsWrittenOffV1Data struct {
+	WriteOffID string            `json:"writeOffId"` // UUID string
+	Category   WriteOffCategory  `json:"category"`
+	Note       string            `json:"note,omitempty"`
+	Products   []WriteOffProduct `json:"products"`
+	// CreditCents is the part of the products already settled by amount payments. Events without it have no credit.
+	CreditCents int `json:"creditCents,omitempty"`
+}
+
+var itemsWrittenOffV1DataSchema = z.Struct(z.Shape{
+	"WriteOffID":  z.String().UUID().Required(),
+	"Category":    WriteOffCategorySchema.Required(),
+	"Note":        WriteOffNoteSchema,
+	"Products":    z.Slice(writeOffProductSchema).Min(1).Required(),
+	"CreditCents": z.Int().GTE(0, z.Message("Credit must not be negative")),
+})
+
+// NewItemsWrittenOffEvent creates the event for a write-off. The credit of amount payments that only partly settled
+// the products is not written off, it must not exceed the total of the products.
+func NewItemsWrittenOffEvent(userID, tableID int, category WriteOffCategory, note string, products []WriteOffProduct, creditCents int) (e.Event, error) {
+	data := itemsWrittenOffV1Data{
+		WriteOffID:  uuid.New().String(),
+		Category:    category,
+		Note:        note,
+		Products:    products,
+		CreditCents: creditCents,
+	}
+
+	if err := validation.Struct(&data, itemsWrittenOffV1DataSchema.Validate(&data)); err != nil {
+		return e.Event{}, fmt.Errorf("items written off data validation failed: %w", err)
+	}
+	if creditCents > GetWriteOffProductsTotal(products) {
+		return e.Event{}, fmt.Errorf("items written off data validation failed: credit exceeds the total of the products")
+	}
+
+	event, err := e.New(userID, string(EventTypeItemsWrittenOffV1), "table:"+strconv.Itoa(tableID), data)
+	if err != nil {
+		return e.Event{}, err
+	}
+
+	return event, nil
+}
+
+func buildWriteOffFromEvent(event e.Event) (WriteOff, error) {
+	if event.Type != string(EventTypeItemsWrittenOffV1) {
+		return WriteOff{}, fmt.Errorf("unsupported event type: %s", event.Type)
+	}
+
+	tableID, err := strconv.Atoi(event.Subject[len("table:"):])
+	if err != nil {
+		return WriteOff{}, fmt.Errorf("invalid table ID in event subject: %v", err)
+	}
+
+	data := itemsWrittenOffV1Data{}
+	err = e.ParseData(event, &data, itemsWrittenOffV1DataSchema)
+	if err != nil {
+		return WriteOff{}, err
+	}
+
+	totalWriteOffCents := GetWriteOffProductsTotal(data.Products) - data.CreditCents
+
+	writeOff := WriteOff{
+		ID:                 data.WriteOffID,
+		UserID:             event.UserID,
+		TableID:            tableID,
+		Category:           data.Category,
+		Note:               data.Note,
+		Products:           data.Products,
+		CreditCents:        data.CreditCents,
+		TotalWriteOffCents: totalWriteOffCents,
+		WrittenOffAt:       event.Time,
+	}
+
+	if err := writeOffSchema.Validate(&writeOff); err != nil {
+		issues := z.Issues.SanitizeMapAndCollect(err)
+		return WriteOff{}, fmt.Errorf("write-off validation failed: %v", issues)
+	}
+
+	return writeOff, nil
+}
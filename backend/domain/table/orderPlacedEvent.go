@@ -7,6 +7,7 @@ import (
 	z "github.com/Oudwins/zog"
 	"github.com/google/uuid"
 	e "github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/validation"
 )
 
 type orderPlacedV1Data struct {
@@ -19,15 +20,20 @@ var orderPlacedV1DataSchema = z.Struct(z.Shape{
 	"Products": z.Slice(orderProductSchema).Min(1).Required(),
 })
 
-func NewOrderPlacedEvent(userID, tableID int, products []OrderProduct) (e.Event, error) {
+// NewOrderPlacedEvent creates the event for a new order. The order ID is generated unless the client chose one,
+// so a client can retry the request without booking the order twice.
+func NewOrderPlacedEvent(userID, tableID int, orderID string, products []OrderProduct) (e.Event, error) {
+	if orderID == "" {
+		orderID = uuid.New().String()
+	}
+
 	data := orderPlacedV1Data{
-		OrderID:  uuid.New().String(),
+		OrderID:  orderID,
 		Products: products,
 	}
 
-	if err := orderPlacedV1DataSchema.Validate(&data); err != nil {
-		issues := z.Issues.SanitizeMapAndCollect(err)
-		return e.Event{}, fmt.Errorf("order placed data validation failed: %v", issues)
+	if err := validation.Struct(&data, orderPlacedV1DataSchema.Validate(&data)); err != nil {
+		return e.Event{}, fmt.Errorf("order placed data validation failed: %w", err)
 	}
 
 	event, err := e.New(userID, string(EventTypeOrderPlacedV1), "table:"+strconv.Itoa(tableID), data)
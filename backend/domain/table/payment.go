@@ -28,6 +28,8 @@ type Payment struct {
 	Products          []PaymentProduct `json:"products"`
 	TotalPaymentCents int              `json:"totalPaymentCents"`
 	RegisteredAt      time.Time        `json:"registeredAt"`
+	// ReversedAt is set once the payment was reversed. Reversed payments do not count towards the balance.
+	ReversedAt *time.Time `json:"reversedAt,omitempty"`
 }
 
 var paymentSchema = z.Struct(z.Shape{
@@ -38,3 +40,12 @@ var paymentSchema = z.Struct(z.Shape{
 	"TotalPaymentCents": z.Int().GTE(0).Required(),
 	"RegisteredAt":      z.Time().Required(),
 })
+
+// amountPaymentSchema validates payments of a fixed amount, which do not reference any products.
+var amountPaymentSchema = z.Struct(z.Shape{
+	"ID":                z.String().UUID().Required(),
+	"UserID":            z.Int().GTE(1).Required(),
+	"TableID":           z.Int().GTE(1).Required(),
+	"TotalPaymentCents": z.Int().GTE(1).Required(),
+	"RegisteredAt":      z.Time().Required(),
+})
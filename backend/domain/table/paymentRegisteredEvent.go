@@ -7,6 +7,7 @@ import (
 	z "github.com/Oudwins/zog"
 	"github.com/google/uuid"
 	e "github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/validation"
 )
 
 type paymentRegisteredV1Data struct {
@@ -19,15 +20,20 @@ var paymentRegisteredV1DataSchema = z.Struct(z.Shape{
 	"Products":  z.Slice(paymentProductSchema).Min(1).Required(),
 })
 
-func NewPaymentRegisteredEvent(userID, tableID int, products []PaymentProduct) (e.Event, error) {
+// NewPaymentRegisteredEvent creates the event for a new payment. The payment ID is generated unless the client chose one,
+// so a client can retry the request without booking the payment twice.
+func NewPaymentRegisteredEvent(userID, tableID int, paymentID string, products []PaymentProduct) (e.Event, error) {
+	if paymentID == "" {
+		paymentID = uuid.New().String()
+	}
+
 	data := paymentRegisteredV1Data{
-		PaymentID: uuid.New().String(),
+		PaymentID: paymentID,
 		Products:  products,
 	}
 
-	if err := paymentRegisteredV1DataSchema.Validate(&data); err != nil {
-		issues := z.Issues.SanitizeMapAndCollect(err)
-		return e.Event{}, fmt.Errorf("payment registered data validation failed: %v", issues)
+	if err := validation.Struct(&data, paymentRegisteredV1DataSchema.Validate(&data)); err != nil {
+		return e.Event{}, fmt.Errorf("payment registered data validation failed: %w", err)
 	}
 
 	event, err := e.New(userID, string(EventTypePaymentRegisteredV1), "table:"+strconv.Itoa(tableID), data)
@@ -0,0 +1,117 @@
+package table
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	z "github.com/Oudwins/zog"
+	"github.com/google/uuid"
+	e "github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/validation"
+)
+
+// ReversalWindow is the time in which the user who registered a payment may reverse it without an admin.
+const ReversalWindow = 10 * time.Minute
+
+// ReversalReasonSchema defines the schema for the reason given when a payment is reversed.
+var ReversalReasonSchema = z.String().Trim().Min(3, z.Message("Reversal reason too short")).Max(250, z.Message("Reversal reason too long")).Required(z.Message("Reversal reason required"))
+
+// PaymentReversal refunds a registered payment, e.g. because it was booked on the wrong table.
+// The products of the reversed payment are unpaid again.
+type PaymentReversal struct {
+	ID          string    `json:"id"`
+	PaymentID   string    `json:"paymentId"`
+	UserID      int       `json:"userId"`
+	TableID     int       `json:"tableId"`
+	AmountCents int       `json:"amountCents"`
+	Reason      string    `json:"reason"`
+	ReversedAt  time.Time `json:"reversedAt"`
+}
+
+type paymentReversedV1Data struct {
+	ReversalID  string `json:"reversalId"` // UUID string
+	PaymentID   string `json:"paymentId"`  // UUID string of the reversed payment
+	AmountCents int    `json:"amountCents"`
+	Reason      string `json:"reason"`
+	// SessionNumber is the session of the reversed payment, which may be closed already. Older reversals don't
+	// have it and belong to the session they were written in.
+	SessionNumber int `json:"sessionNumber,omitempty"`
+}
+
+var paymentReversedV1DataSchema = z.Struct(z.Shape{
+	"ReversalID":    z.String().UUID().Required(),
+	"PaymentID":     z.String().UUID().Required(),
+	"AmountCents":   z.Int().GTE(0).Required(),
+	"Reason":        ReversalReasonSchema,
+	"SessionNumber": SessionNumberSchema,
+})
+
+// NewPaymentReversedEvent creates an event that reverses a payment of the given session of a table.
+func NewPaymentReversedEvent(userID, tableID, sessionNumber int, payment Payment, reason string) (e.Event, error) {
+	data := paymentReversedV1Data{
+		ReversalID:    uuid.New().String(),
+		PaymentID:     payment.ID,
+		AmountCents:   payment.TotalPaymentCents,
+		Reason:        reason,
+		SessionNumber: sessionNumber,
+	}
+
+	if err := validation.Struct(&data, paymentReversedV1DataSchema.Validate(&data)); err != nil {
+		return e.Event{}, fmt.Errorf("payment reversed data validation failed: %w", err)
+	}
+
+	event, err := e.New(userID, string(EventTypePaymentReversedV1), "table:"+strconv.Itoa(tableID), data)
+	if err != nil {
+		return e.Event{}, err
+	}
+
+	return event, nil
+}
+
+// CanReversePayment checks whether a user may reverse a payment. Users allowed to reverse any payment always may,
+// other users only their own payments within the ReversalWindow.
+func CanReversePayment(payment Payment, userID int, reverseAny bool, now time.Time) bool {
+	if reverseAny {
+		return true
+	}
+
+	return payment.UserID == userID && now.Sub(payment.RegisteredAt) <= ReversalWindow
+}
+
+// paymentReversalSessionNumber returns the session of the payment reversed by the event, or 0 for older reversals.
+func paymentReversalSessionNumber(event e.Event) (int, error) {
+	data := paymentReversedV1Data{}
+	if err := e.ParseData(event, &data, paymentReversedV1DataSchema); err != nil {
+		return 0, err
+	}
+
+	return data.SessionNumber, nil
+}
+
+func buildPaymentReversalFromEvent(event e.Event) (PaymentReversal, error) {
+	if event.Type != string(EventTypePaymentReversedV1) {
+		return PaymentReversal{}, fmt.Errorf("unsupported event type: %s", event.Type)
+	}
+
+	tableID, err := strconv.Atoi(event.Subject[len("table:"):])
+	if err != nil {
+		return PaymentReversal{}, fmt.Errorf("invalid table ID in event subject: %v", err)
+	}
+
+	data := paymentReversedV1Data{}
+	err = e.ParseData(event, &data, paymentReversedV1DataSchema)
+	if err != nil {
+		return PaymentReversal{}, err
+	}
+
+	return PaymentReversal{
+		ID:          data.ReversalID,
+		PaymentID:   data.PaymentID,
+		UserID:      event.UserID,
+		TableID:     tableID,
+		AmountCents: data.AmountCents,
+		Reason:      data.Reason,
+		ReversedAt:  event.Time,
+	}, nil
+}
@@ -0,0 +1,184 @@
+package table
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	z "github.com/Oudwins/zog"
+	e "github.com/nicograef/jotti/backend/domain/event"
+)
+
+// SessionStatus represents the status of a table session.
+type SessionStatus string
+
+const (
+	// SessionOpenStatus: guests are seated, orders and payments can be booked.
+	SessionOpenStatus SessionStatus = "open"
+	// SessionClosedStatus: the visit is over, the session is only part of the history.
+	SessionClosedStatus SessionStatus = "closed"
+)
+
+// Session is a single guest visit at a table. It scopes all orders and payments booked in between
+// opening and closing the table. Sessions are numbered per table, starting at 1.
+type Session struct {
+	Number         int           `json:"number"`
+	TableID        int           `json:"tableId"`
+	Status         SessionStatus `json:"status"`
+	BalanceCents   int           `json:"balanceCents"`
+	WriteOffReason string        `json:"writeOffReason,omitempty"`
+	OpenedAt       time.Time     `json:"openedAt"`
+	ClosedAt       *time.Time    `json:"closedAt,omitempty"`
+	// Events contains all events of the session in order, including the opened and closed events.
+	Events []e.Event `json:"-"`
+}
+
+// SessionNumberSchema defines the schema for the number of a table session.
+var SessionNumberSchema = z.Int().GTE(1, z.Message("Invalid session number"))
+
+// WriteOffReasonSchema defines the schema for the reason given when an open balance is written off.
+var WriteOffReasonSchema = z.String().Trim().Min(3, z.Message("Write-off reason too short")).Max(250, z.Message("Write-off reason too long")).Required(z.Message("Write-off reason required"))
+
+// ErrWriteOffReasonRequired is returned when a session with an open balance is closed without a valid write-off reason.
+var ErrWriteOffReasonRequired = errors.New("write-off reason required")
+
+// GetSessionsFromEvents groups the events of a table into sessions.
+// Events that are not preceded by a table opened event (e.g. events booked before sessions were introduced)
+// implicitly start a new session. Payment reversals belong to the session of the reversed payment.
+func GetSessionsFromEvents(events []e.Event) ([]Session, error) {
+	sessions := []Session{}
+	open := false
+
+	for _, event := range events {
+		last := len(sessions) - 1
+
+		switch event.Type {
+		case string(EventTypeTableOpenedV1):
+			data, err := parseTableOpenedEvent(event)
+			if err != nil {
+				return []Session{}, err
+			}
+
+			if open {
+				return []Session{}, fmt.Errorf("session %d opened while session %d is still open", data.SessionNumber, sessions[last].Number)
+			}
+
+			if data.SessionNumber == len(sessions) && last >= 0 {
+				// reopen the last closed session
+				sessions[last].Status = SessionOpenStatus
+				sessions[last].ClosedAt = nil
+				sessions[last].WriteOffReason = ""
+			} else if data.SessionNumber == len(sessions)+1 {
+				session, err := newSession(data.SessionNumber, event)
+				if err != nil {
+					return []Session{}, err
+				}
+				sessions = append(sessions, session)
+				last++
+			} else {
+				return []Session{}, fmt.Errorf("unexpected session number %d", data.SessionNumber)
+			}
+
+			sessions[last].Events = append(sessions[last].Events, event)
+			open = true
+
+		case string(EventTypeTableClosedV1):
+			data, err := parseTableClosedEvent(event)
+			if err != nil {
+				return []Session{}, err
+			}
+
+			if !open || data.SessionNumber != sessions[last].Number {
+				return []Session{}, fmt.Errorf("session %d closed but it is not open", data.SessionNumber)
+			}
+
+			closedAt := event.Time
+			sessions[last].Status = SessionClosedStatus
+			sessions[last].ClosedAt = &closedAt
+			sessions[last].WriteOffReason = data.WriteOffReason
+			sessions[last].Events = append(sessions[last].Events, event)
+			open = false
+
+		case string(EventTypePaymentReversedV1):
+			number, err := paymentReversalSessionNumber(event)
+			if err != nil {
+				return []Session{}, err
+			}
+
+			if number > len(sessions) {
+				return []Session{}, fmt.Errorf("payment of session %d reversed but the session does not exist", number)
+			}
+
+			if number > 0 && !(open && number == sessions[last].Number) {
+				// the reversed payment belongs to a closed session
+				sessions[number-1].Events = append(sessions[number-1].Events, event)
+				continue
+			}
+			fallthrough
+
+		default:
+			if !open {
+				session, err := newSession(len(sessions)+1, event)
+				if err != nil {
+					return []Session{}, err
+				}
+				sessions = append(sessions, session)
+				last++
+				open = true
+			}
+
+			sessions[last].Events = append(sessions[last].Events, event)
+		}
+	}
+
+	for i := range sessions {
+		balanceCents, err := GetBalanceFromEvents(sessions[i].Events)
+		if err != nil {
+			return []Session{}, err
+		}
+		sessions[i].BalanceCents = balanceCents
+	}
+
+	return sessions, nil
+}
+
+// Openings counts how often the session was opened, 0 if it was started implicitly by an order.
+func (s Session) Openings() int {
+	openings := 0
+	for _, event := range s.Events {
+		if event.Type == string(EventTypeTableOpenedV1) {
+			openings++
+		}
+	}
+	return openings
+}
+
+// GetCurrentSession returns the open session of a table, if there is one.
+func GetCurrentSession(sessions []Session) (Session, bool) {
+	if len(sessions) == 0 {
+		return Session{}, false
+	}
+
+	last := sessions[len(sessions)-1]
+	if last.Status != SessionOpenStatus {
+		return Session{}, false
+	}
+
+	return last, true
+}
+
+func newSession(number int, event e.Event) (Session, error) {
+	tableID, err := strconv.Atoi(event.Subject[len("table:"):])
+	if err != nil {
+		return Session{}, fmt.Errorf("invalid table ID in event subject: %v", err)
+	}
+
+	return Session{
+		Number:   number,
+		TableID:  tableID,
+		Status:   SessionOpenStatus,
+		OpenedAt: event.Time,
+		Events:   []e.Event{},
+	}, nil
+}
@@ -0,0 +1,143 @@
+//go:build unit
+
+package table
+
+import (
+	"testing"
+
+	e "github.com/nicograef/jotti/backend/domain/event"
+)
+
+func TestGetSessionsFromEvents(t *testing.T) {
+	must := mustEvent(t)
+	events := []e.Event{
+		// legacy order without a table opened event
+		must(NewOrderPlacedEvent(1, 5, "", []OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}})),
+		must(NewPaymentRegisteredEvent(1, 5, "", []PaymentProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}})),
+		must(NewTableClosedEvent(1, 5, 1, 0, "")),
+		must(NewTableOpenedEvent(1, 5, 2, 1)),
+		must(NewOrderPlacedEvent(1, 5, "", []OrderProduct{{ID: 2, Name: "Pommes", NetPriceCents: 350, Quantity: 2}})),
+	}
+
+	sessions, err := GetSessionsFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(sessions))
+	}
+	if sessions[0].Status != SessionClosedStatus || sessions[0].BalanceCents != 0 || sessions[0].ClosedAt == nil {
+		t.Errorf("expected first session to be closed without balance, got %+v", sessions[0])
+	}
+	if sessions[1].Status != SessionOpenStatus || sessions[1].BalanceCents != 700 {
+		t.Errorf("expected second session to be open with balance 700, got %+v", sessions[1])
+	}
+
+	current, ok := GetCurrentSession(sessions)
+	if !ok || current.Number != 2 {
+		t.Errorf("expected current session 2, got %+v", current)
+	}
+}
+
+func TestGetSessionsFromEvents_Reopen(t *testing.T) {
+	must := mustEvent(t)
+	events := []e.Event{
+		must(NewTableOpenedEvent(1, 5, 1, 1)),
+		must(NewTableClosedEvent(1, 5, 1, 0, "")),
+		must(NewTableOpenedEvent(1, 5, 1, 2)),
+	}
+
+	sessions, err := GetSessionsFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].Status != SessionOpenStatus || sessions[0].ClosedAt != nil {
+		t.Errorf("expected reopened session, got %+v", sessions)
+	}
+	if openings := sessions[0].Openings(); openings != 2 {
+		t.Errorf("expected 2 openings, got %d", openings)
+	}
+}
+
+func TestGetSessionsFromEvents_OpenedWithoutOpening(t *testing.T) {
+	// table opened events written before the opening was introduced
+	opened := mustEvent(t)(NewTableOpenedEvent(1, 5, 1, 1))
+	opened.Data = []byte(`{"sessionNumber":1}`)
+
+	sessions, err := GetSessionsFromEvents([]e.Event{opened})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].Openings() != 1 {
+		t.Errorf("expected one session opened once, got %+v", sessions)
+	}
+}
+
+func TestGetSessionsFromEvents_ReversalOfClosedSession(t *testing.T) {
+	must := mustEvent(t)
+	payment := must(NewPaymentRegisteredEvent(1, 5, "", []PaymentProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}}))
+	payments, err := GetPaymentsFromEvents([]e.Event{payment})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	events := []e.Event{
+		must(NewTableOpenedEvent(1, 5, 1, 1)),
+		must(NewOrderPlacedEvent(1, 5, "", []OrderProduct{{ID: 1, Name: "Bier", NetPriceCents: 400, Quantity: 1}})),
+		payment,
+		must(NewTableClosedEvent(1, 5, 1, 0, "")),
+		must(NewPaymentReversedEvent(1, 5, 1, payments[0], "Falscher Tisch")),
+	}
+
+	sessions, err := GetSessionsFromEvents(events)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("expected the reversal to belong to the closed session, got %d sessions", len(sessions))
+	}
+	if sessions[0].Status != SessionClosedStatus || sessions[0].BalanceCents != 400 {
+		t.Errorf("expected closed session with balance 400 after reversal, got %+v", sessions[0])
+	}
+
+	events = append(events, must(NewPaymentReversedEvent(1, 5, 2, payments[0], "Falscher Tisch")))
+	if _, err := GetSessionsFromEvents(events); err == nil {
+		t.Fatal("expected error for reversal of unknown session, got nil")
+	}
+}
+
+func TestGetSessionsFromEvents_DoubleOpen(t *testing.T) {
+	must := mustEvent(t)
+	events := []e.Event{
+		must(NewTableOpenedEvent(1, 5, 1, 1)),
+		must(NewTableOpenedEvent(1, 5, 2, 1)),
+	}
+
+	if _, err := GetSessionsFromEvents(events); err == nil {
+		t.Fatal("expected error for session opened twice, got nil")
+	}
+}
+
+func TestGetCurrentSession_Closed(t *testing.T) {
+	must := mustEvent(t)
+	sessions, err := GetSessionsFromEvents([]e.Event{
+		must(NewTableOpenedEvent(1, 5, 1, 1)),
+		must(NewTableClosedEvent(1, 5, 1, 0, "")),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, ok := GetCurrentSession(sessions); ok {
+		t.Error("expected no current session for closed table")
+	}
+}
+
+func TestNewTableClosedEvent_OpenBalanceRequiresReason(t *testing.T) {
+	if _, err := NewTableClosedEvent(1, 5, 1, 700, ""); err != ErrWriteOffReasonRequired {
+		t.Errorf("expected ErrWriteOffReasonRequired, got %v", err)
+	}
+
+	if _, err := NewTableClosedEvent(1, 5, 1, 700, "Gäste gegangen"); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package table
+
+import "errors"
+
+// MaxSplitParts is the maximum number of payers a balance can be split across.
+const MaxSplitParts = 100
+
+// ErrInvalidSplitParts is returned when a balance should be split across an invalid number of payers.
+var ErrInvalidSplitParts = errors.New("invalid number of split parts")
+
+// SplitAmount divides the given amount into the given number of even shares.
+// Remainder cents are assigned one by one to the first shares, so the result is deterministic
+// and the shares always add up to the amount. Amounts below zero are split as zero.
+func SplitAmount(amountCents, parts int) ([]int, error) {
+	if parts < 1 || parts > MaxSplitParts {
+		return nil, ErrInvalidSplitParts
+	}
+
+	amountCents = max(amountCents, 0)
+	shareCents := amountCents / parts
+	remainderCents := amountCents % parts
+
+	shares := make([]int, parts)
+	for i := range shares {
+		shares[i] = shareCents
+		if i < remainderCents {
+			shares[i]++
+		}
+	}
+
+	return shares, nil
+}
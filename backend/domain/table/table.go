@@ -1,11 +1,11 @@
 package table
 
 import (
-	"errors"
 	"fmt"
 	"time"
 
 	z "github.com/Oudwins/zog"
+	"github.com/nicograef/jotti/backend/domain/validation"
 )
 
 type Status string
@@ -22,6 +22,8 @@ type Table struct {
 	Name      string    `json:"name"`
 	Status    Status    `json:"status"`
 	CreatedAt time.Time `json:"createdAt"`
+	// PeriodID is the period (Veranstaltung) the table belongs to. Nil if the table is available in all periods.
+	PeriodID *int `json:"periodId,omitempty"`
 }
 
 var IDSchema = z.Int().GTE(1, z.Message("Invalid table ID"))
@@ -51,8 +53,8 @@ func (p Table) Validate() error {
 // NewTable creates a new Table instance after validating the input parameters.
 // The new Table does not have an ID assigned; it is expected to be set by the persistence layer.
 func NewTable(name string) (Table, error) {
-	if issue := NameSchema.Validate(&name); issue != nil {
-		return Table{}, errors.New("invalid name")
+	if err := validation.Field("name", NameSchema.Validate(&name)); err != nil {
+		return Table{}, err
 	}
 
 	table := Table{
@@ -73,8 +75,8 @@ func (p *Table) Deactivate() {
 }
 
 func (p *Table) Rename(newName string) error {
-	if issue := NameSchema.Validate(&newName); issue != nil {
-		return errors.New("invalid name")
+	if err := validation.Field("name", NameSchema.Validate(&newName)); err != nil {
+		return err
 	}
 	p.Name = newName
 	return nil
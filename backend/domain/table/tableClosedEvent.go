@@ -0,0 +1,64 @@
+package table
+
+import (
+	"fmt"
+	"strconv"
+
+	z "github.com/Oudwins/zog"
+	e "github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/domain/validation"
+)
+
+type tableClosedV1Data struct {
+	SessionNumber int `json:"sessionNumber"`
+	// OpenBalanceCents is the balance when the session was closed. A forced close writes off the unpaid products
+	// with an items written off event before, so the report counts them.
+	OpenBalanceCents int    `json:"openBalanceCents"`
+	WriteOffReason   string `json:"writeOffReason,omitempty"`
+}
+
+var tableClosedV1DataSchema = z.Struct(z.Shape{
+	"SessionNumber":    SessionNumberSchema.Required(),
+	"OpenBalanceCents": z.Int(),
+	"WriteOffReason":   z.String().Trim().Max(250, z.Message("Write-off reason too long")),
+})
+
+// NewTableClosedEvent creates an event that closes the given session of a table.
+// A session with an open balance can only be closed with a write-off reason.
+func NewTableClosedEvent(userID, tableID, sessionNumber, openBalanceCents int, writeOffReason string) (e.Event, error) {
+	if openBalanceCents != 0 {
+		if issue := WriteOffReasonSchema.Validate(&writeOffReason); issue != nil {
+			return e.Event{}, ErrWriteOffReasonRequired
+		}
+	}
+
+	data := tableClosedV1Data{
+		SessionNumber:    sessionNumber,
+		OpenBalanceCents: openBalanceCents,
+		WriteOffReason:   writeOffReason,
+	}
+
+	if err := validation.Struct(&data, tableClosedV1DataSchema.Validate(&data)); err != nil {
+		return e.Event{}, fmt.Errorf("table closed data validation failed: %w", err)
+	}
+
+	event, err := e.New(userID, string(EventTypeTableClosedV1), "table:"+strconv.Itoa(tableID), data)
+	if err != nil {
+		return e.Event{}, err
+	}
+
+	return event, nil
+}
+
+func parseTableClosedEvent(event e.Event) (tableClosedV1Data, error) {
+	if event.Type != string(EventTypeTableClosedV1) {
+		return tableClosedV1Data{}, fmt.Errorf("unsupported event type: %s", event.Type)
+	}
+
+	data := tableClosedV1Data{}
+	if err := e.ParseData(event, &data, tableClosedV1DataSchema); err != nil {
+		return tableClosedV1Data{}, err
+	}
+
+	return data, nil
+}
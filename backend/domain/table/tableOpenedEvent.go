@@ -0,0 +1,54 @@
+package table
+
+import (
+	"fmt"
+	"strconv"
+
+	z "github.com/Oudwins/zog"
+	e "github.com/nicograef/jotti/backend/domain/event"
+)
+
+type tableOpenedV1Data struct {
+	SessionNumber int `json:"sessionNumber"`
+	// Opening counts the openings of the session: 1 when it starts, 2 when it's reopened the first time and so on.
+	// Together with the session number it's unique per table, so concurrent openings can't both be written.
+	// Events written before it was introduced don't have it.
+	Opening int `json:"opening"`
+}
+
+var tableOpenedV1DataSchema = z.Struct(z.Shape{
+	"SessionNumber": SessionNumberSchema.Required(),
+	"Opening":       z.Int().GTE(1, z.Message("Invalid opening")),
+})
+
+// NewTableOpenedEvent creates an event that opens a session of a table.
+// Using the number of the last closed session reopens that session instead of starting a new one, the opening is
+// then one more than the openings of the session so far (see Session.Openings).
+func NewTableOpenedEvent(userID, tableID, sessionNumber, opening int) (e.Event, error) {
+	data := tableOpenedV1Data{SessionNumber: sessionNumber, Opening: opening}
+
+	if err := tableOpenedV1DataSchema.Validate(&data); err != nil {
+		issues := z.Issues.SanitizeMapAndCollect(err)
+		return e.Event{}, fmt.Errorf("table opened data validation failed: %v", issues)
+	}
+
+	event, err := e.New(userID, string(EventTypeTableOpenedV1), "table:"+strconv.Itoa(tableID), data)
+	if err != nil {
+		return e.Event{}, err
+	}
+
+	return event, nil
+}
+
+func parseTableOpenedEvent(event e.Event) (tableOpenedV1Data, error) {
+	if event.Type != string(EventTypeTableOpenedV1) {
+		return tableOpenedV1Data{}, fmt.Errorf("unsupported event type: %s", event.Type)
+	}
+
+	data := tableOpenedV1Data{}
+	if err := e.ParseData(event, &data, tableOpenedV1DataSchema); err != nil {
+		return tableOpenedV1Data{}, err
+	}
+
+	return data, nil
+}
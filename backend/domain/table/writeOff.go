@@ -0,0 +1,80 @@
+package table
+
+import (
+	"time"
+
+	z "github.com/Oudwins/zog"
+	"github.com/nicograef/jotti/backend/domain/product"
+)
+
+// WriteOffCategory represents the reason category of a write-off.
+type WriteOffCategory string
+
+const (
+	// UnpaidWriteOff: guests left without paying (Zechprellerei).
+	UnpaidWriteOff WriteOffCategory = "unpaid"
+	// BreakageWriteOff: items were spilled, dropped or broken.
+	BreakageWriteOff WriteOffCategory = "breakage"
+	// StaffWriteOff: items were consumed by staff.
+	StaffWriteOff WriteOffCategory = "staff"
+	// MarketingWriteOff: items were given away for free, e.g. to sponsors.
+	MarketingWriteOff WriteOffCategory = "marketing"
+)
+
+// WriteOffCategorySchema defines the schema for the reason category of a write-off.
+var WriteOffCategorySchema = z.StringLike[WriteOffCategory]().OneOf(
+	[]WriteOffCategory{UnpaidWriteOff, BreakageWriteOff, StaffWriteOff, MarketingWriteOff},
+	z.Message("Invalid write-off category"),
+)
+
+// WriteOffNoteSchema defines the schema for an optional note describing a write-off.
+var WriteOffNoteSchema = z.String().Trim().Max(250, z.Message("Note too long"))
+
+type WriteOffProduct struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	NetPriceCents int    `json:"netPriceCents"`
+	Quantity      int    `json:"quantity"`
+}
+
+var writeOffProductSchema = z.Struct(z.Shape{
+	"ID":            product.IDSchema.Required(),
+	"Name":          product.NameSchema.Required(),
+	"NetPriceCents": product.NetPriceCentsSchema.Required(),
+	"Quantity":      z.Int().GTE(1, z.Message("Quantity must be at least 1")).Required(),
+})
+
+// WriteOff removes open items from a table without a payment, e.g. because guests left without paying.
+type WriteOff struct {
+	ID       string            `json:"id"`
+	UserID   int               `json:"userId"`
+	TableID  int               `json:"tableId"`
+	Category WriteOffCategory  `json:"category"`
+	Note     string            `json:"note,omitempty"`
+	Products []WriteOffProduct `json:"products"`
+	// CreditCents is the part of the products already settled by amount payments and not written off.
+	CreditCents        int       `json:"creditCents,omitempty"`
+	TotalWriteOffCents int       `json:"totalWriteOffCents"`
+	WrittenOffAt       time.Time `json:"writtenOffAt"`
+}
+
+var writeOffSchema = z.Struct(z.Shape{
+	"ID":                 z.String().UUID().Required(),
+	"UserID":             z.Int().GTE(1).Required(),
+	"TableID":            z.Int().GTE(1).Required(),
+	"Category":           WriteOffCategorySchema.Required(),
+	"Note":               WriteOffNoteSchema,
+	"Products":           z.Slice(writeOffProductSchema).Min(1).Required(),
+	"CreditCents":        z.Int().GTE(0),
+	"TotalWriteOffCents": z.Int().GTE(0).Required(),
+	"WrittenOffAt":       z.Time().Required(),
+})
+
+// GetWriteOffProductsTotal returns the total price of the given products.
+func GetWriteOffProductsTotal(products []WriteOffProduct) int {
+	totalCents := 0
+	for _, product := range products {
+		totalCents += product.NetPriceCents * product.Quantity
+	}
+	return totalCents
+}
@@ -11,8 +11,6 @@ import (
 	"golang.org/x/crypto/argon2"
 )
 
-// var PasswordSchema = z.String().Trim().Min(6, z.Message("Password too short")).Max(20, z.Message("Password too long"))
-
 // var OnetimePasswordSchema = z.String().Trim().Len(6, z.Message("Onetime password must be 6 digits")).Match(
 // 	regexp.MustCompile(`^[0-9]{6}$`),
 // 	z.Message("Onetime password must be 6 digits"),
@@ -40,12 +38,25 @@ func generateCryptographicSalt(saltSize uint32) ([]byte, error) {
 	return salt, nil
 }
 
-func createArgon2idHash(password string) (string, error) {
+// HashParams are the Argon2id cost parameters for new password hashes.
+type HashParams struct {
+	MemoryCost uint32 // in KiB
+	TimeCost   uint32 // number of passes over the memory
+	Threads    uint8
+}
+
+// DefaultHashParams follow the OWASP recommendation for Argon2id.
+var DefaultHashParams = HashParams{MemoryCost: 64 * 1024, TimeCost: 2, Threads: 2}
+
+// hashKeyLength is the length of new hashes in bytes.
+const hashKeyLength = 32
+
+func createArgon2idHash(password string, params HashParams) (string, error) {
 	config := &argon2Configuration{
-		TimeCost:   2,
-		MemoryCost: 64 * 1024,
-		Threads:    2,
-		KeyLength:  32,
+		TimeCost:   params.TimeCost,
+		MemoryCost: params.MemoryCost,
+		Threads:    params.Threads,
+		KeyLength:  hashKeyLength,
 	}
 
 	salt, err := generateCryptographicSalt(16)
@@ -121,6 +132,17 @@ func parseArgon2Hash(encodedHash string) (*argon2Configuration, error) {
 	return config, nil
 }
 
+// needsRehash reports whether the hash was created with weaker parameters than the given ones.
+// A hash with more threads but otherwise equal costs is not weaker, so it is kept.
+func needsRehash(encodedHash string, params HashParams) bool {
+	config, err := parseArgon2Hash(encodedHash)
+	if err != nil {
+		return false
+	}
+
+	return config.MemoryCost < params.MemoryCost || config.TimeCost < params.TimeCost || config.KeyLength < hashKeyLength
+}
+
 func verifyPassword(correctPasswordHash, userProvidedPassword string) error {
 	config, err := parseArgon2Hash(correctPasswordHash)
 	if err != nil {
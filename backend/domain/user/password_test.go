@@ -4,7 +4,11 @@ package user
 
 import (
 	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/nicograef/jotti/backend/domain/role"
 )
 
 func TestGenerateOnetimePassword(t *testing.T) {
@@ -19,3 +23,113 @@ func TestGenerateOnetimePassword(t *testing.T) {
 		t.Fatalf("Expected numeric password, got %s", password)
 	}
 }
+
+// seededAdminHash is the password hash of the admin in 01_initial.up.sql.
+const seededAdminHash = "$argon2id$v=19$m=64,t=2,p=2$ekV4Uzg2cUhVTTBUaTJJVw$4Sfsc6eRVIWXSzgNoWaybDBws3c830yC6IMcdUDG1ns"
+
+func TestNeedsRehash(t *testing.T) {
+	if !needsRehash(seededAdminHash, DefaultHashParams) {
+		t.Fatal("expected seeded admin hash to need a rehash")
+	}
+	if needsRehash(seededAdminHash, HashParams{MemoryCost: 64, TimeCost: 2, Threads: 2}) {
+		t.Fatal("expected hash with equal parameters to be kept")
+	}
+	if needsRehash(seededAdminHash, HashParams{MemoryCost: 64, TimeCost: 2, Threads: 1}) {
+		t.Fatal("expected hash with more threads to be kept")
+	}
+	if needsRehash("invalidhashformat", DefaultHashParams) {
+		t.Fatal("expected invalid hash not to be rehashed")
+	}
+}
+
+func TestGenerateJWTToken_RehashesWeakerHash(t *testing.T) {
+	seededParams := HashParams{MemoryCost: 64, TimeCost: 2, Threads: 2}
+	weakHash, _ := createArgon2idHash("adminpassword", seededParams)
+	if !strings.HasPrefix(weakHash, "$argon2id$v=19$m=64,t=2,p=2$") || len(weakHash) != len(seededAdminHash) {
+		t.Fatalf("expected hash in the format of the seeded admin, got %s", weakHash)
+	}
+
+	u := User{ID: 1, Username: "admin", Role: AdminRole, Status: ActiveStatus, PasswordHash: weakHash}
+	params := HashParams{MemoryCost: 128, TimeCost: 3, Threads: 1}
+
+	if _, err := u.GenerateJWTToken("wrongpassword", role.Role{Name: role.AdminName}, "session-1", testKeys, params); err != ErrInvalidPassword {
+		t.Fatalf("expected invalid password error, got %v", err)
+	}
+	if u.PasswordHash != weakHash {
+		t.Fatal("expected hash to be kept after failed login")
+	}
+
+	if _, err := u.GenerateJWTToken("adminpassword", role.Role{Name: role.AdminName}, "session-1", testKeys, params); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(u.PasswordHash, "$argon2id$v=19$m=128,t=3,p=1$") {
+		t.Fatalf("expected hash with new parameters, got %s", u.PasswordHash)
+	}
+
+	rehashed := u.PasswordHash
+	if _, err := u.GenerateJWTToken("adminpassword", role.Role{Name: role.AdminName}, "session-1", testKeys, params); err != nil {
+		t.Fatalf("expected login with rehashed password, got %v", err)
+	}
+	if u.PasswordHash != rehashed {
+		t.Fatal("expected current hash not to be rehashed again")
+	}
+}
+
+func TestGenerateJWTToken_PasswordChangeRequiredKeepsHash(t *testing.T) {
+	weakHash, _ := createArgon2idHash("adminpassword", HashParams{MemoryCost: 64, TimeCost: 2, Threads: 2})
+	u := User{ID: 1, Username: "admin", Role: AdminRole, Status: ActiveStatus, PasswordHash: weakHash, PasswordChangeRequired: true}
+
+	_, err := u.GenerateJWTToken("adminpassword", role.Role{Name: role.AdminName}, "session-1", testKeys, HashParams{MemoryCost: 128, TimeCost: 3, Threads: 1})
+	if err != ErrPasswordChangeRequired {
+		t.Fatalf("expected password change required error, got %v", err)
+	}
+	if u.PasswordHash != weakHash {
+		t.Fatal("expected hash not to be rehashed when the password has to be changed")
+	}
+}
+
+func TestSetPassword_OnetimePasswordExpires(t *testing.T) {
+	u, onetimePassword, _ := NewUser("Test User", "testuser", ServiceRole)
+	if !u.HasPendingOnetimePassword(time.Now()) {
+		t.Fatal("expected new user to have a pending one-time password")
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	u.OnetimePasswordExpiresAt = &expired
+
+	if err := u.SetPassword(onetimePassword, "Kellner-Tisch-42", DefaultPasswordPolicy); err != ErrOnetimePasswordExpired {
+		t.Fatalf("expected one-time password expired error, got %v", err)
+	}
+}
+
+func TestSetPassword_OnetimePasswordAttemptLimit(t *testing.T) {
+	u, onetimePassword, _ := NewUser("Test User", "testuser", ServiceRole)
+	wrong := "000000"
+	if onetimePassword == wrong {
+		wrong = "111111"
+	}
+
+	if err := u.SetPassword(wrong, "Kellner-Tisch-42", DefaultPasswordPolicy); err != ErrInvalidPassword {
+		t.Fatalf("expected invalid password error, got %v", err)
+	}
+
+	// the attempts are counted by the repository before each attempt
+	u.OnetimePasswordFailedAttempts = MaxOnetimePasswordAttempts
+	if u.HasPendingOnetimePassword(time.Now()) {
+		t.Fatal("expected locked one-time password not to be pending")
+	}
+	if err := u.SetPassword(onetimePassword, "Kellner-Tisch-42", DefaultPasswordPolicy); err != ErrOnetimePasswordExpired {
+		t.Fatalf("expected one-time password to expire after %d wrong attempts, got %v", MaxOnetimePasswordAttempts, err)
+	}
+
+	onetimePassword, _ = u.ResetPassword()
+	if u.OnetimePasswordFailedAttempts != 0 {
+		t.Fatalf("expected reset to clear failed attempts, got %d", u.OnetimePasswordFailedAttempts)
+	}
+	if err := u.SetPassword(onetimePassword, "Kellner-Tisch-42", DefaultPasswordPolicy); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if u.OnetimePasswordExpiresAt != nil || u.HasPendingOnetimePassword(time.Now()) {
+		t.Fatalf("expected no pending one-time password, got %v", u.OnetimePasswordExpiresAt)
+	}
+}
@@ -0,0 +1,74 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	z "github.com/Oudwins/zog"
+	"github.com/nicograef/jotti/backend/domain/jwt"
+	"github.com/nicograef/jotti/backend/domain/role"
+)
+
+// PinSchema defines the optional PIN for quick login on registered devices.
+var PinSchema = z.String().Match(
+	regexp.MustCompile(`^[0-9]{4,6}$`),
+	z.Message("PIN must be 4 to 6 digits"),
+).Required(z.Message("PIN required"))
+
+var ErrInvalidPin = errors.New("invalid pin")
+
+var ErrNoPin = errors.New("no pin set")
+
+// SetPin sets the PIN for quick login after verifying the password of the user. The PIN is hashed with the
+// configured Argon2id parameters (params).
+func (u *User) SetPin(password, pin string, params HashParams) error {
+	if u.PasswordHash == "" {
+		return ErrNoPassword
+	}
+
+	if err := verifyPassword(u.PasswordHash, password); err != nil {
+		return err
+	}
+
+	if issue := PinSchema.Validate(&pin); issue != nil {
+		return ErrInvalidPin
+	}
+
+	pinHash, err := createArgon2idHash(pin, params)
+	if err != nil {
+		return fmt.Errorf("failed to hash pin: %w", err)
+	}
+
+	u.PinHash = pinHash
+	return nil
+}
+
+// RemovePin disables quick login for the user.
+func (u *User) RemovePin() {
+	u.PinHash = ""
+}
+
+// GenerateJWTTokenWithPin verifies the PIN and returns an access token bound to the given session.
+func (u *User) GenerateJWTTokenWithPin(pin string, r role.Role, sessionID string, keys jwt.Keys) (string, error) {
+	if u.Status != ActiveStatus {
+		return "", ErrNotActive
+	}
+
+	if u.PinHash == "" {
+		return "", ErrNoPin
+	}
+
+	if err := verifyPassword(u.PinHash, pin); err != nil {
+		if errors.Is(err, ErrInvalidPassword) {
+			return "", ErrInvalidPin
+		}
+		return "", err
+	}
+
+	if u.PasswordChangeRequired {
+		return "", ErrPasswordChangeRequired
+	}
+
+	return u.GenerateJWTTokenForSession(r, sessionID, keys)
+}
@@ -0,0 +1,69 @@
+//go:build unit
+
+package user
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nicograef/jotti/backend/domain/jwt"
+	"github.com/nicograef/jotti/backend/domain/role"
+)
+
+const testPasswordHash = "$argon2id$v=19$m=64,t=2,p=4$QzFPUlMxVUd2Wm51a09BNA$WC7jqeO84JjhcPYJKIN6Ep71DLRc0wog7vjIwYq+EEk" // "testpassword"
+
+var testKeys = jwt.NewSecretKeys("secret")
+
+func TestSetPin(t *testing.T) {
+	u := User{ID: 1, Role: ServiceRole, Status: ActiveStatus, PasswordHash: testPasswordHash}
+
+	if err := u.SetPin("wrongpassword", "1234", DefaultHashParams); err != ErrInvalidPassword {
+		t.Fatalf("expected invalid password error, got %v", err)
+	}
+	for _, pin := range []string{"123", "1234567", "12a4", ""} {
+		if err := u.SetPin("testpassword", pin, DefaultHashParams); err != ErrInvalidPin {
+			t.Errorf("expected invalid pin error for %q, got %v", pin, err)
+		}
+	}
+	if err := u.SetPin("testpassword", "4711", DefaultHashParams); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if u.PinHash == "" || u.PinHash == "4711" {
+		t.Fatalf("expected hashed pin, got %q", u.PinHash)
+	}
+
+	params := HashParams{MemoryCost: 128, TimeCost: 3, Threads: 1}
+	if err := u.SetPin("testpassword", "4711", params); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(u.PinHash, "$argon2id$v=19$m=128,t=3,p=1$") {
+		t.Fatalf("expected pin hash with the given parameters, got %s", u.PinHash)
+	}
+}
+
+func TestGenerateJWTTokenWithPin(t *testing.T) {
+	u := User{ID: 1, Role: ServiceRole, Status: ActiveStatus, PasswordHash: testPasswordHash}
+
+	if _, err := u.GenerateJWTTokenWithPin("4711", role.Role{Name: role.ServiceName}, "session-1", testKeys); err != ErrNoPin {
+		t.Fatalf("expected no pin error, got %v", err)
+	}
+
+	_ = u.SetPin("testpassword", "4711", DefaultHashParams)
+	if _, err := u.GenerateJWTTokenWithPin("0000", role.Role{Name: role.ServiceName}, "session-1", testKeys); err != ErrInvalidPin {
+		t.Fatalf("expected invalid pin error, got %v", err)
+	}
+
+	token, err := u.GenerateJWTTokenWithPin("4711", role.Role{Name: role.ServiceName}, "session-1", testKeys)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	claims, err := jwt.ParseAndValidateJWTToken(token, testKeys)
+	if err != nil || claims.UserID != 1 || claims.SessionID != "session-1" {
+		t.Fatalf("expected token for user 1 and session-1, got %+v (%v)", claims, err)
+	}
+
+	u.Deactivate()
+	if _, err := u.GenerateJWTTokenWithPin("4711", role.Role{Name: role.ServiceName}, "session-1", testKeys); err != ErrNotActive {
+		t.Fatalf("expected not active error, got %v", err)
+	}
+}
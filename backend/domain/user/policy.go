@@ -0,0 +1,72 @@
+package user
+
+import (
+	_ "embed"
+	"errors"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// commonPasswords are well-known passwords, the most common first.
+var commonPasswords = strings.Fields(commonPasswordsFile)
+
+// MaxPasswordLength limits the work of hashing a password.
+const MaxPasswordLength = 128
+
+// PasswordPolicy defines which new passwords are accepted and how they are hashed.
+type PasswordPolicy struct {
+	// MinLength is the minimum number of characters. Empty passwords are never accepted.
+	MinLength int
+	// CommonPasswords is how many of the most common passwords are rejected. 0 disables the check.
+	CommonPasswords int
+	// Hashing are the Argon2id parameters for new password hashes. Zero means DefaultHashParams.
+	Hashing HashParams
+}
+
+// DefaultPasswordPolicy is used if nothing else is configured.
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 8, CommonPasswords: len(commonPasswords), Hashing: DefaultHashParams}
+
+var ErrPasswordTooShort = errors.New("password too short")
+
+var ErrPasswordTooLong = errors.New("password too long")
+
+var ErrPasswordContainsUsername = errors.New("password contains username")
+
+var ErrPasswordTooCommon = errors.New("password too common")
+
+var ErrPasswordUnchanged = errors.New("password unchanged")
+
+// Check returns the rule the new password of the given user violates, if any.
+func (p PasswordPolicy) Check(username, password string) error {
+	length := utf8.RuneCountInString(password)
+	if length < max(p.MinLength, 1) {
+		return ErrPasswordTooShort
+	}
+	if length > MaxPasswordLength {
+		return ErrPasswordTooLong
+	}
+
+	lowered := strings.ToLower(password)
+	if username != "" && strings.Contains(lowered, strings.ToLower(username)) {
+		return ErrPasswordContainsUsername
+	}
+
+	n := min(p.CommonPasswords, len(commonPasswords))
+	if slices.Contains(commonPasswords[:max(n, 0)], lowered) {
+		return ErrPasswordTooCommon
+	}
+
+	return nil
+}
+
+// HashParams returns the Argon2id parameters for new password hashes.
+func (p PasswordPolicy) HashParams() HashParams {
+	if p.Hashing == (HashParams{}) {
+		return DefaultHashParams
+	}
+	return p.Hashing
+}
@@ -0,0 +1,89 @@
+//go:build unit
+
+package user
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nicograef/jotti/backend/domain/role"
+)
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 8, CommonPasswords: 100}
+
+	tests := []struct {
+		password string
+		want     error
+	}{
+		{"", ErrPasswordTooShort},
+		{"kurz", ErrPasswordTooShort},
+		{strings.Repeat("a", MaxPasswordLength+1), ErrPasswordTooLong},
+		{"meinMaxMustermann1", ErrPasswordContainsUsername},
+		{"Password", ErrPasswordTooCommon},
+		{"Kellner-Tisch-42", nil},
+	}
+
+	for _, tt := range tests {
+		if err := policy.Check("maxmustermann", tt.password); err != tt.want {
+			t.Errorf("expected %v for %q, got %v", tt.want, tt.password, err)
+		}
+	}
+}
+
+func TestPasswordPolicy_CommonPasswordsLimit(t *testing.T) {
+	if err := (PasswordPolicy{MinLength: 1, CommonPasswords: 1}).Check("user", commonPasswords[1]); err != nil {
+		t.Errorf("expected only the most common password to be rejected, got %v", err)
+	}
+	if err := (PasswordPolicy{MinLength: 1}).Check("user", commonPasswords[0]); err != nil {
+		t.Errorf("expected no common password check, got %v", err)
+	}
+}
+
+func TestSetPassword_AppliesPolicy(t *testing.T) {
+	u := User{ID: 1, Username: "testuser", Status: ActiveStatus, PasswordChangeRequired: true}
+	onetimePassword, _ := u.ResetPassword()
+
+	if err := u.SetPassword(onetimePassword, "123456", DefaultPasswordPolicy); err != ErrPasswordTooShort {
+		t.Fatalf("expected password too short error, got %v", err)
+	}
+	if err := u.SetPassword(onetimePassword, "Kellner-Tisch-42", DefaultPasswordPolicy); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if u.OnetimePasswordHash != "" || u.PasswordChangeRequired {
+		t.Fatalf("expected one-time password and required change to be cleared, got %+v", u)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	u := User{ID: 1, Username: "testuser", Role: ServiceRole, Status: ActiveStatus, PasswordHash: testPasswordHash}
+
+	if err := u.RequirePasswordChange(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := u.GenerateJWTToken("testpassword", role.Role{Name: role.ServiceName}, "session-1", testKeys, DefaultHashParams); err != ErrPasswordChangeRequired {
+		t.Fatalf("expected password change required error, got %v", err)
+	}
+
+	if err := u.ChangePassword("wrongpassword", "Kellner-Tisch-42", DefaultPasswordPolicy); err != ErrInvalidPassword {
+		t.Fatalf("expected invalid password error, got %v", err)
+	}
+	if err := u.ChangePassword("testpassword", "testpassword", DefaultPasswordPolicy); err != ErrPasswordUnchanged {
+		t.Fatalf("expected password unchanged error, got %v", err)
+	}
+	if err := u.ChangePassword("testpassword", "Kellner-Tisch-42", DefaultPasswordPolicy); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err := u.GenerateJWTToken("Kellner-Tisch-42", role.Role{Name: role.ServiceName}, "session-1", testKeys, DefaultHashParams); err != nil {
+		t.Fatalf("expected login with new password, got %v", err)
+	}
+}
+
+func TestRequirePasswordChange_NoPassword(t *testing.T) {
+	u := User{ID: 1, Username: "testuser", Status: ActiveStatus}
+
+	if err := u.RequirePasswordChange(); err != ErrNoPassword {
+		t.Fatalf("expected no password error, got %v", err)
+	}
+}
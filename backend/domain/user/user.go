@@ -8,15 +8,18 @@ import (
 
 	z "github.com/Oudwins/zog"
 	"github.com/nicograef/jotti/backend/domain/jwt"
+	"github.com/nicograef/jotti/backend/domain/role"
+	"github.com/nicograef/jotti/backend/domain/validation"
 )
 
+// Role is the name of the role of a user. Roles and their permissions are managed by admins, see role.Role.
 type Role string
 
 const (
-	// AdminRole: can do everything.
-	AdminRole Role = "admin"
-	// ServiceRole: can only see active tables and products and .
-	ServiceRole Role = "service"
+	// AdminRole: built-in role that can do everything.
+	AdminRole Role = role.AdminName
+	// ServiceRole: built-in role for service staff.
+	ServiceRole Role = role.ServiceName
 )
 
 type Status string
@@ -29,14 +32,22 @@ const (
 )
 
 type User struct {
-	ID                  int       `json:"id"`
-	Name                string    `json:"name"`
-	Username            string    `json:"username"`
-	Role                Role      `json:"role"`
-	Status              Status    `json:"status"`
-	PasswordHash        string    `json:"-"`
-	OnetimePasswordHash string    `json:"-"`
-	CreatedAt           time.Time `json:"createdAt"`
+	ID                  int    `json:"id"`
+	Name                string `json:"name"`
+	Username            string `json:"username"`
+	Role                Role   `json:"role"`
+	Status              Status `json:"status"`
+	PasswordHash        string `json:"-"`
+	OnetimePasswordHash string `json:"-"`
+	PinHash             string `json:"-"`
+	// OnetimePasswordExpiresAt is set while the user has a one-time password. Expired or used up
+	// one-time passwords keep their expiry in the past until the next reset.
+	OnetimePasswordExpiresAt *time.Time `json:"onetimePasswordExpiresAt"`
+	// OnetimePasswordFailedAttempts counts wrong one-time passwords since the last reset.
+	OnetimePasswordFailedAttempts int `json:"-"`
+	// PasswordChangeRequired is set by an admin; the user has to choose a new password before logging in again.
+	PasswordChangeRequired bool      `json:"passwordChangeRequired"`
+	CreatedAt              time.Time `json:"createdAt"`
 }
 
 var IDSchema = z.Int().GTE(1, z.Message("Invalid user ID"))
@@ -48,8 +59,9 @@ var UsernameSchema = z.String().Trim().Min(3, z.Message("Username too short")).M
 	z.Message("Only lowercase alphanumerical usernames allowed"),
 )
 
-var RoleSchema = z.StringLike[Role]().OneOf(
-	[]Role{AdminRole, ServiceRole},
+// RoleSchema only checks the format of the role name; whether the role exists is checked by the application.
+var RoleSchema = z.StringLike[Role]().Match(
+	role.NamePattern,
 	z.Message("Invalid role"),
 )
 
@@ -66,11 +78,25 @@ var UserSchema = z.Struct(z.Shape{
 	"Status":              StatusSchema.Required(),
 	"PasswordHash":        z.String(),
 	"OnetimePasswordHash": z.String(),
+	"PinHash":             z.String(),
 	"CreatedAt":           z.Time().Required(),
 })
 
 var ErrNotActive = fmt.Errorf("user is not active")
 
+// ErrOnetimePasswordExpired is returned if the one-time password is expired or was guessed wrong too often.
+var ErrOnetimePasswordExpired = fmt.Errorf("one-time password expired")
+
+// OnetimePasswordValidity is how long a one-time password can be used to set a password.
+const OnetimePasswordValidity = 24 * time.Hour
+
+// MaxOnetimePasswordAttempts is how many wrong one-time passwords are accepted before it is locked.
+// One-time passwords have only 10^6 combinations.
+const MaxOnetimePasswordAttempts = 5
+
+// ErrPasswordChangeRequired is returned on login if an admin requires the user to choose a new password first.
+var ErrPasswordChangeRequired = fmt.Errorf("password change required")
+
 func (u User) Validate() error {
 	if errsMap := UserSchema.Validate(&u); errsMap != nil {
 		issues := z.Issues.SanitizeMapAndCollect(errsMap)
@@ -80,16 +106,12 @@ func (u User) Validate() error {
 }
 
 func NewUser(name, username string, role Role) (User, string, error) {
-	if issue := NameSchema.Validate(&name); issue != nil {
-		return User{}, "", fmt.Errorf("invalid name")
-	}
-
-	if issue := UsernameSchema.Validate(&username); issue != nil {
-		return User{}, "", fmt.Errorf("invalid username")
-	}
-
-	if issue := RoleSchema.Validate(&role); issue != nil {
-		return User{}, "", fmt.Errorf("invalid role")
+	var v validation.Validator
+	v.Check("name", NameSchema.Validate(&name))
+	v.Check("username", UsernameSchema.Validate(&username))
+	v.Check("role", RoleSchema.Validate(&role))
+	if err := v.Err(); err != nil {
+		return User{}, "", err
 	}
 
 	onetimePassword, err := generateOnetimePassword()
@@ -97,19 +119,22 @@ func NewUser(name, username string, role Role) (User, string, error) {
 		return User{}, "", fmt.Errorf("failed to generate one-time password: %w", err)
 	}
 
-	onetimePasswordHash, err := createArgon2idHash(onetimePassword)
+	onetimePasswordHash, err := createArgon2idHash(onetimePassword, DefaultHashParams)
 	if err != nil {
 		return User{}, "", fmt.Errorf("failed to hash one-time password: %w", err)
 	}
 
+	now := time.Now().UTC()
+	expiresAt := now.Add(OnetimePasswordValidity)
 	user := User{
-		Name:                name,
-		Username:            strings.ToLower(username),
-		Role:                role,
-		Status:              InactiveStatus,
-		PasswordHash:        "",
-		OnetimePasswordHash: onetimePasswordHash,
-		CreatedAt:           time.Now().UTC(),
+		Name:                     name,
+		Username:                 strings.ToLower(username),
+		Role:                     role,
+		Status:                   InactiveStatus,
+		PasswordHash:             "",
+		OnetimePasswordHash:      onetimePasswordHash,
+		OnetimePasswordExpiresAt: &expiresAt,
+		CreatedAt:                now,
 	}
 
 	return user, onetimePassword, nil
@@ -124,16 +149,12 @@ func (u *User) Deactivate() {
 }
 
 func (u *User) UpdateDetails(name, username string, role Role) error {
-	if issue := NameSchema.Validate(&name); issue != nil {
-		return fmt.Errorf("invalid name")
-	}
-
-	if issue := UsernameSchema.Validate(&username); issue != nil {
-		return fmt.Errorf("invalid username")
-	}
-
-	if issue := RoleSchema.Validate(&role); issue != nil {
-		return fmt.Errorf("invalid role")
+	var v validation.Validator
+	v.Check("name", NameSchema.Validate(&name))
+	v.Check("username", UsernameSchema.Validate(&username))
+	v.Check("role", RoleSchema.Validate(&role))
+	if err := v.Err(); err != nil {
+		return err
 	}
 
 	u.Name = name
@@ -149,38 +170,111 @@ func (u *User) ResetPassword() (string, error) {
 		return "", fmt.Errorf("failed to generate one-time password: %w", err)
 	}
 
-	onetimePasswordHash, err := createArgon2idHash(onetimePassword)
+	onetimePasswordHash, err := createArgon2idHash(onetimePassword, DefaultHashParams)
 	if err != nil {
 		return "", fmt.Errorf("failed to hash one-time password: %w", err)
 	}
 
+	expiresAt := time.Now().UTC().Add(OnetimePasswordValidity)
 	u.OnetimePasswordHash = onetimePasswordHash
+	u.OnetimePasswordExpiresAt = &expiresAt
+	u.OnetimePasswordFailedAttempts = 0
 	u.PasswordHash = ""
+	u.PinHash = ""
 
 	return onetimePassword, nil
 }
 
-func (u *User) SetPassword(onetimePassword, newPassword string) error {
+// SetPassword sets the first password of the user (or the first after a reset) with the one-time password.
+// The new password has to satisfy the policy. Callers count every attempt atomically in the database before,
+// so parallel guesses can't exceed MaxOnetimePasswordAttempts; a successful attempt resets the count.
+func (u *User) SetPassword(onetimePassword, newPassword string, policy PasswordPolicy) error {
 	if u.OnetimePasswordHash == "" {
 		return ErrNoPassword
 	}
 
+	now := time.Now().UTC()
+	if !u.HasPendingOnetimePassword(now) {
+		return ErrOnetimePasswordExpired
+	}
+
 	if err := verifyPassword(u.OnetimePasswordHash, onetimePassword); err != nil {
 		return err
 	}
 
-	passwordHash, err := createArgon2idHash(newPassword)
+	if err := policy.Check(u.Username, newPassword); err != nil {
+		return err
+	}
+
+	passwordHash, err := createArgon2idHash(newPassword, policy.HashParams())
 	if err != nil {
 		return fmt.Errorf("failed to hash new password: %w", err)
 	}
 
 	u.PasswordHash = passwordHash
 	u.OnetimePasswordHash = ""
+	u.OnetimePasswordExpiresAt = nil
+	u.OnetimePasswordFailedAttempts = 0
+	u.PasswordChangeRequired = false
 
 	return nil
 }
 
-func (u *User) GenerateJWTToken(password, secret string) (string, error) {
+// HasPendingOnetimePassword reports whether the user has a one-time password that can still be used,
+// i.e. it is neither expired nor locked by MaxOnetimePasswordAttempts wrong attempts.
+func (u User) HasPendingOnetimePassword(now time.Time) bool {
+	return u.OnetimePasswordHash != "" && u.OnetimePasswordExpiresAt != nil && now.Before(*u.OnetimePasswordExpiresAt) &&
+		u.OnetimePasswordFailedAttempts < MaxOnetimePasswordAttempts
+}
+
+// ChangePassword replaces the password of the user after verifying the current one.
+// The new password has to satisfy the policy and differ from the current password.
+func (u *User) ChangePassword(currentPassword, newPassword string, policy PasswordPolicy) error {
+	if u.Status != ActiveStatus {
+		return ErrNotActive
+	}
+
+	if u.PasswordHash == "" {
+		return ErrNoPassword
+	}
+
+	if err := verifyPassword(u.PasswordHash, currentPassword); err != nil {
+		return err
+	}
+
+	if newPassword == currentPassword {
+		return ErrPasswordUnchanged
+	}
+
+	if err := policy.Check(u.Username, newPassword); err != nil {
+		return err
+	}
+
+	passwordHash, err := createArgon2idHash(newPassword, policy.HashParams())
+	if err != nil {
+		return fmt.Errorf("failed to hash new password: %w", err)
+	}
+
+	u.PasswordHash = passwordHash
+	u.PasswordChangeRequired = false
+
+	return nil
+}
+
+// RequirePasswordChange makes the user choose a new password before they can log in again.
+func (u *User) RequirePasswordChange() error {
+	if u.PasswordHash == "" {
+		return ErrNoPassword
+	}
+
+	u.PasswordChangeRequired = true
+	return nil
+}
+
+// GenerateJWTToken verifies the password and returns an access token bound to the given session.
+// If the password hash was created with weaker parameters than the given ones, the password is rehashed;
+// callers should persist the user if PasswordHash changed.
+func (u *User) GenerateJWTToken(password string, r role.Role, sessionID string, keys jwt.Keys, params HashParams) (string, error) {
 	if u.Status != ActiveStatus {
 		return "", ErrNotActive
 	}
@@ -193,5 +287,33 @@ func (u *User) GenerateJWTToken(password, secret string) (string, error) {
 		return "", err
 	}
 
-	return jwt.GenerateJWTTokenForUser(u.ID, string(u.Role), secret)
+	// checked before the rehash, the user is not saved on this error and the new password gets a new hash anyway
+	if u.PasswordChangeRequired {
+		return "", ErrPasswordChangeRequired
+	}
+
+	if needsRehash(u.PasswordHash, params) {
+		passwordHash, err := createArgon2idHash(password, params)
+		if err != nil {
+			return "", fmt.Errorf("failed to rehash password: %w", err)
+		}
+		u.PasswordHash = passwordHash
+	}
+
+	return u.GenerateJWTTokenForSession(r, sessionID, keys)
+}
+
+// GenerateJWTTokenForSession returns an access token for an existing session without verifying credentials,
+// e.g. on refresh. The token carries the permissions of the given role, which has to be the role of the user.
+func (u *User) GenerateJWTTokenForSession(r role.Role, sessionID string, keys jwt.Keys) (string, error) {
+	if r.Name != string(u.Role) {
+		return "", fmt.Errorf("role %s does not match role of user %s", r.Name, u.Role)
+	}
+
+	permissions := []string{}
+	for _, p := range r.EffectivePermissions() {
+		permissions = append(permissions, string(p))
+	}
+
+	return jwt.GenerateJWTTokenForUser(u.ID, string(u.Role), permissions, sessionID, keys)
 }
@@ -0,0 +1,137 @@
+// Package validation turns zog issues into errors that name the invalid fields, so clients can show each problem
+// next to its input.
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+
+	z "github.com/Oudwins/zog"
+	"github.com/Oudwins/zog/zconst"
+)
+
+// FieldError is a problem with one input field. Field is the JSON path of the field, e.g. "products[0].quantity".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is returned for invalid input and lists the problems of all invalid fields.
+type Error struct {
+	Fields []FieldError
+}
+
+func (e *Error) Error() string {
+	problems := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		problems[i] = f.Field + ": " + f.Message
+	}
+	return "invalid " + strings.Join(problems, ", ")
+}
+
+// New returns an error for a single invalid field, for checks that are not expressed with a zog schema.
+func New(field, message string) error {
+	return &Error{Fields: []FieldError{{Field: field, Message: message}}}
+}
+
+// Field returns an *Error for the issues of a single field, nil if there are none.
+func Field(field string, issues z.ZogIssueList) error {
+	var v Validator
+	v.Check(field, issues)
+	return v.Err()
+}
+
+// Fields returns the invalid fields of err, nil if err is not (and doesn't wrap) an *Error.
+func Fields(err error) []FieldError {
+	var validationErr *Error
+	if errors.As(err, &validationErr) {
+		return validationErr.Fields
+	}
+	return nil
+}
+
+// Validator collects the issues of fields that are validated one by one.
+type Validator struct {
+	fields []FieldError
+}
+
+// Check adds the issues of the field, if any. The issues are returned to the pool of zog.
+func (v *Validator) Check(field string, issues z.ZogIssueList) {
+	for _, issue := range issues {
+		v.fields = append(v.fields, FieldError{Field: field, Message: issue.Message})
+	}
+	z.Issues.CollectList(issues)
+}
+
+// Err returns an *Error with all issues, nil if every checked field is valid.
+func (v *Validator) Err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &Error{Fields: v.fields}
+}
+
+// Struct returns an *Error for the issues of validating the struct that v points to, nil if there are none.
+// The Go field names in the issue paths are replaced with the names of their JSON tags. The issues are returned to
+// the pool of zog.
+func Struct(v any, issues z.ZogIssueMap) error {
+	defer z.Issues.CollectMap(issues)
+
+	paths := make([]string, 0, len(issues))
+	for path := range issues {
+		if path != zconst.ISSUE_KEY_FIRST && len(issues[path]) > 0 {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	slices.Sort(paths)
+
+	fields := []FieldError{}
+	for _, path := range paths {
+		field := jsonPath(reflect.TypeOf(v), path)
+		for _, issue := range issues[path] {
+			fields = append(fields, FieldError{Field: field, Message: issue.Message})
+		}
+	}
+	return &Error{Fields: fields}
+}
+
+// jsonPath translates a zog path like "Products[0].NetPriceCents" of type t into "products[0].netPriceCents".
+// Segments that can't be resolved are kept as they are.
+func jsonPath(t reflect.Type, path string) string {
+	segments := strings.Split(path, ".")
+	for i, segment := range segments {
+		name, index, _ := strings.Cut(segment, "[")
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			t = nil
+			continue
+		}
+
+		field, ok := t.FieldByName(name)
+		if !ok {
+			t = nil
+			continue
+		}
+		if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" && tag != "-" {
+			name = tag
+		}
+
+		t = field.Type
+		if index != "" {
+			segments[i] = name + "[" + index
+			if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+				t = t.Elem()
+			}
+		} else {
+			segments[i] = name
+		}
+	}
+	return strings.Join(segments, ".")
+}
@@ -0,0 +1,90 @@
+//go:build unit
+
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	z "github.com/Oudwins/zog"
+)
+
+type item struct {
+	NetPriceCents int `json:"netPriceCents"`
+	Quantity      int `json:"quantity"`
+}
+
+type order struct {
+	OrderID string `json:"orderId"`
+	Items   []item `json:"products"`
+	Note    string
+}
+
+var orderSchema = z.Struct(z.Shape{
+	"OrderID": z.String().UUID(z.Message("Invalid order ID")).Required(),
+	"Items": z.Slice(z.Struct(z.Shape{
+		"NetPriceCents": z.Int().GTE(0, z.Message("Net price must be non-negative")),
+		"Quantity":      z.Int().GTE(1, z.Message("Quantity must be at least 1")),
+	})).Min(1),
+	"Note": z.String().Max(3, z.Message("Note too long")),
+})
+
+func TestValidator(t *testing.T) {
+	name, username := "Al", "ok"
+	nameSchema := z.String().Min(3, z.Message("Name too short"))
+
+	var v Validator
+	v.Check("name", nameSchema.Validate(&name))
+	v.Check("username", z.String().Min(1).Validate(&username))
+
+	err := v.Err()
+	expected := []FieldError{{Field: "name", Message: "Name too short"}}
+	if !reflect.DeepEqual(Fields(err), expected) {
+		t.Errorf("expected fields %v, got %v", expected, Fields(err))
+	}
+	if err.Error() != "invalid name: Name too short" {
+		t.Errorf("unexpected error message %q", err.Error())
+	}
+
+	var valid Validator
+	valid.Check("username", z.String().Min(1).Validate(&username))
+	if err := valid.Err(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestStruct(t *testing.T) {
+	o := order{
+		OrderID: "f47ac10b-58cc-4372-a567-0e02b2c3d479",
+		Items:   []item{{NetPriceCents: 100, Quantity: 1}, {NetPriceCents: 100, Quantity: -1}},
+		Note:    "Too long",
+	}
+
+	err := Struct(&o, orderSchema.Validate(&o))
+	expected := []FieldError{
+		{Field: "products[1].quantity", Message: "Quantity must be at least 1"},
+		{Field: "Note", Message: "Note too long"},
+	}
+	if !reflect.DeepEqual(Fields(err), expected) {
+		t.Errorf("expected fields %v, got %v", expected, Fields(err))
+	}
+
+	o = order{OrderID: "f47ac10b-58cc-4372-a567-0e02b2c3d479", Items: []item{{Quantity: 1}}}
+	if err := Struct(&o, orderSchema.Validate(&o)); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestFields(t *testing.T) {
+	err := fmt.Errorf("%w: %w", errors.New("invalid product data"), New("name", "Name too short"))
+	expected := []FieldError{{Field: "name", Message: "Name too short"}}
+	if !reflect.DeepEqual(Fields(err), expected) {
+		t.Errorf("expected fields %v, got %v", expected, Fields(err))
+	}
+
+	if fields := Fields(errors.New("database error")); fields != nil {
+		t.Errorf("expected no fields, got %v", fields)
+	}
+}
@@ -16,38 +16,44 @@ import (
 
 	"github.com/nicograef/jotti/backend/app"
 	"github.com/nicograef/jotti/backend/config"
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/tracing"
 )
 
 func main() {
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout})
 
-	cfg := config.Load()
-
-	psqlconn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", cfg.Postgres.Host, cfg.Postgres.Port, cfg.Postgres.User, cfg.Postgres.Password, cfg.Postgres.DBName)
+	// "jotti migrate up|down|status" manages the schema instead of starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		database := openDatabase(config.LoadPostgres())
+		defer closeDatabase(database)
 
-	db, err := sql.Open("pgx", psqlconn)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to connect to Postgres")
+		if err := migrate(context.Background(), database, os.Args[2:]); err != nil {
+			log.Fatal().Err(err).Msg("Migration failed")
+		}
+		return
 	}
 
-	db.SetConnMaxLifetime(5 * time.Minute)
-	db.SetMaxOpenConns(50)
-	db.SetMaxIdleConns(10)
+	cfg := config.Load()
 
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracesExporter, cfg.ServiceName)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to set up tracing")
+	}
 	defer func() {
-		if err := db.Close(); err != nil {
-			log.Error().Err(err).Msg("Failed to close database connection")
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Error().Err(err).Msg("Failed to flush traces")
 		}
 	}()
 
-	err = db.Ping()
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to ping Postgres")
-	}
+	database := openDatabase(cfg.Postgres)
+	defer closeDatabase(database)
 
-	log.Info().Msg("Connected to database")
+	if err := db.CheckSchema(context.Background(), database); err != nil {
+		log.Fatal().Err(err).Msg("Database schema is not up to date, run \"jotti migrate up\"")
+	}
 
-	app, err := app.NewApp(cfg, db)
+	app, err := app.NewApp(cfg, database)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to create app")
 	}
@@ -69,3 +75,76 @@ func main() {
 		log.Fatal().Err(err).Msg("Application error")
 	}
 }
+
+func openDatabase(pg config.PostgresConfig) *sql.DB {
+	psqlconn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", pg.Host, pg.Port, pg.User, pg.Password, pg.DBName)
+
+	database, err := sql.Open("pgx", psqlconn)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to Postgres")
+	}
+
+	database.SetConnMaxLifetime(5 * time.Minute)
+	database.SetMaxOpenConns(50)
+	database.SetMaxIdleConns(10)
+
+	err = database.Ping()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to ping Postgres")
+	}
+
+	log.Info().Msg("Connected to database")
+	return database
+}
+
+func closeDatabase(database *sql.DB) {
+	if err := database.Close(); err != nil {
+		log.Error().Err(err).Msg("Failed to close database connection")
+	}
+}
+
+// migrate runs the migrate subcommand: "up" applies all pending migrations, "down" reverts the latest migration
+// and "status" lists all migrations with the time they were applied.
+func migrate(ctx context.Context, database *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: jotti migrate up|down|status")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := db.MigrateUp(ctx, database)
+		for _, m := range applied {
+			log.Info().Int("version", m.Version).Str("name", m.Name).Msg("Migration applied")
+		}
+		if err != nil {
+			return err
+		}
+		log.Info().Int("applied", len(applied)).Msg("Schema is up to date")
+	case "down":
+		reverted, ok, err := db.MigrateDown(ctx, database)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			log.Info().Msg("No migration applied")
+			return nil
+		}
+		log.Info().Int("version", reverted.Version).Str("name", reverted.Name).Msg("Migration reverted")
+	case "status":
+		states, err := db.GetMigrationStates(ctx, database)
+		if err != nil {
+			return err
+		}
+		for _, state := range states {
+			status := "pending"
+			if state.AppliedAt != nil {
+				status = "applied " + state.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%02d_%s\t%s\n", state.Version, state.Name, status)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q, use up, down or status", args[0])
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+)
+
+var (
+	// HTTPRequests counts the handled requests by route and status code.
+	HTTPRequests = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Name: "jotti_http_requests_total",
+		Help: "HTTP requests by route and status code.",
+	}, []string{"route", "status"})
+	// HTTPRequestDuration observes the time to handle a request by route.
+	HTTPRequestDuration = promauto.With(Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "jotti_http_request_duration_seconds",
+		Help:    "Duration of HTTP requests by route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route"})
+	// EventsAppended counts the events written to the event store by type, including events of transactions that
+	// were rolled back afterwards.
+	EventsAppended = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Name: "jotti_events_appended_total",
+		Help: "Events appended to the event store by type.",
+	}, []string{"type"})
+)
+
+var (
+	dbMaxOpenConnections = prometheus.NewDesc("jotti_db_max_open_connections", "Maximum number of open connections to the database.", nil, nil)
+	dbOpenConnections    = prometheus.NewDesc("jotti_db_open_connections", "Established connections to the database, in use and idle.", nil, nil)
+	dbInUseConnections   = prometheus.NewDesc("jotti_db_in_use_connections", "Connections to the database that are in use.", nil, nil)
+	dbIdleConnections    = prometheus.NewDesc("jotti_db_idle_connections", "Idle connections to the database.", nil, nil)
+	dbWaitCount          = prometheus.NewDesc("jotti_db_wait_count_total", "Queries that waited for a free connection.", nil, nil)
+	dbWaitDuration       = prometheus.NewDesc("jotti_db_wait_duration_seconds_total", "Time queries waited for a free connection.", nil, nil)
+)
+
+// DBStats returns a collector for the connection pool statistics of db.
+func DBStats(db *sql.DB) prometheus.Collector {
+	return dbStatsCollector{db}
+}
+
+type dbStatsCollector struct{ db *sql.DB }
+
+func (c dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dbMaxOpenConnections
+	ch <- dbOpenConnections
+	ch <- dbInUseConnections
+	ch <- dbIdleConnections
+	ch <- dbWaitCount
+	ch <- dbWaitDuration
+}
+
+func (c dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.Stats()
+	ch <- prometheus.MustNewConstMetric(dbMaxOpenConnections, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(dbOpenConnections, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(dbInUseConnections, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(dbIdleConnections, prometheus.GaugeValue, float64(stats.Idle))
+	// maintained by database/sql, the collector only reports them
+	ch <- prometheus.MustNewConstMetric(dbWaitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(dbWaitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+}
+
+var (
+	openTables       = prometheus.NewDesc("jotti_open_tables", "Tables with an open session.", nil, nil)
+	openBalanceCents = prometheus.NewDesc("jotti_open_balance_cents", "Unpaid balance of all open tables in cents.", nil, nil)
+)
+
+// OpenTablesFunc reads the number of tables with an open session and the sum of their unpaid balances in cents.
+type OpenTablesFunc func(ctx context.Context) (count, balanceCents int, err error)
+
+// OpenTables returns a collector for the open tables and their balance. If they can't be read, the scrape has no
+// open tables metrics.
+func OpenTables(read OpenTablesFunc) prometheus.Collector {
+	return openTablesCollector{read}
+}
+
+type openTablesCollector struct{ read OpenTablesFunc }
+
+func (c openTablesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- openTables
+	ch <- openBalanceCents
+}
+
+func (c openTablesCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx := collectContext()
+
+	count, balanceCents, err := c.read(ctx)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("Failed to collect open tables metrics")
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(openTables, prometheus.GaugeValue, float64(count))
+	ch <- prometheus.MustNewConstMetric(openBalanceCents, prometheus.GaugeValue, float64(balanceCents))
+}
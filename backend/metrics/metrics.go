@@ -0,0 +1,86 @@
+// Package metrics collects counters, gauges and histograms with the Prometheus client and serves them in the
+// Prometheus text format.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Registry holds the metrics of the backend that are updated while handling requests.
+var Registry = prometheus.NewRegistry()
+
+// Cached collects at most once per ttl. In between, scrapes get the metrics of the last run, so expensive
+// collectors don't query the database on every scrape.
+func Cached(ttl time.Duration, c prometheus.Collector) prometheus.Collector {
+	return &cachedCollector{collector: c, ttl: ttl}
+}
+
+type cachedCollector struct {
+	collector prometheus.Collector
+	ttl       time.Duration
+
+	mu          sync.Mutex
+	collectedAt time.Time
+	metrics     []prometheus.Metric
+}
+
+func (c *cachedCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.collector.Describe(ch)
+}
+
+func (c *cachedCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if c.collectedAt.IsZero() || now.Sub(c.collectedAt) >= c.ttl {
+		collected := make(chan prometheus.Metric)
+		go func() {
+			c.collector.Collect(collected)
+			close(collected)
+		}()
+
+		c.metrics = c.metrics[:0]
+		for m := range collected {
+			c.metrics = append(c.metrics, m)
+		}
+		c.collectedAt = now
+	}
+
+	for _, m := range c.metrics {
+		ch <- m
+	}
+}
+
+// collectContext is the context of collectors that read from the database. Metrics are scraped every few seconds,
+// so only warnings and errors of the collectors are logged.
+func collectContext() context.Context {
+	logger := log.Logger.Level(zerolog.WarnLevel)
+	return logger.WithContext(context.Background())
+}
+
+// Handler serves the metrics of Registry and of the collectors, which collect on every request.
+func Handler(collectors ...prometheus.Collector) http.Handler {
+	r := prometheus.NewRegistry()
+	r.MustRegister(collectors...)
+
+	return promhttp.HandlerFor(prometheus.Gatherers{Registry, r}, promhttp.HandlerOpts{
+		ErrorLog: promhttpLogger{},
+	})
+}
+
+// promhttpLogger logs errors of gathering the metrics.
+type promhttpLogger struct{}
+
+func (promhttpLogger) Println(v ...any) {
+	log.Error().Msg("Failed to serve metrics: " + fmt.Sprint(v...))
+}
@@ -0,0 +1,94 @@
+//go:build unit
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestHandler(t *testing.T) {
+	EventsAppended.WithLabelValues("jotti.table.opened:v1").Inc()
+	open := OpenTables(func(ctx context.Context) (int, int, error) { return 7, 1250, nil })
+
+	rec := httptest.NewRecorder()
+	Handler(open).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Expected text exposition format, got %s", rec.Header().Get("Content-Type"))
+	}
+	for _, expected := range []string{
+		`jotti_events_appended_total{type="jotti.table.opened:v1"} 1`,
+		"jotti_open_tables 7",
+		"jotti_open_balance_cents 1250",
+	} {
+		if !strings.Contains(rec.Body.String(), expected+"\n") {
+			t.Errorf("Expected %s in metrics, got\n%s", expected, rec.Body.String())
+		}
+	}
+}
+
+func TestOpenTables_Error(t *testing.T) {
+	open := OpenTables(func(ctx context.Context) (int, int, error) { return 0, 0, errors.New("connection refused") })
+
+	rec := httptest.NewRecorder()
+	Handler(open).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "jotti_open_tables") {
+		t.Errorf("Expected no open tables metrics, got\n%s", rec.Body.String())
+	}
+}
+
+func TestCached(t *testing.T) {
+	runs := 0
+	read := func(ctx context.Context) (int, int, error) {
+		runs++
+		return runs, 0, nil
+	}
+
+	collect := Cached(time.Hour, OpenTables(read))
+	if n := collectCount(collect); n != 2 {
+		t.Fatalf("Expected 2 metrics, got %d", n)
+	}
+	if n := collectCount(collect); n != 2 {
+		t.Fatalf("Expected the cached 2 metrics, got %d", n)
+	}
+	if runs != 1 {
+		t.Fatalf("Expected one run within the TTL, got %d", runs)
+	}
+
+	runs = 0
+	collect = Cached(0, OpenTables(read))
+	collectCount(collect)
+	collectCount(collect)
+	if runs != 2 {
+		t.Fatalf("Expected a run per scrape after the TTL, got %d", runs)
+	}
+}
+
+func collectCount(c prometheus.Collector) int {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(ch)
+		close(ch)
+	}()
+
+	n := 0
+	for range ch {
+		n++
+	}
+	return n
+}
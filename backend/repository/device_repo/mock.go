@@ -0,0 +1,68 @@
+package device_repo
+
+import (
+	"context"
+	"slices"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/device"
+)
+
+// NewMock creates a new mock repository with the given devices and error.
+func NewMock(devices []device.Device, err error) *mockRepo {
+	deviceMap := make(map[int]device.Device)
+	for _, d := range devices {
+		deviceMap[d.ID] = d
+	}
+
+	return &mockRepo{
+		devices: deviceMap,
+		err:     err,
+	}
+}
+
+type mockRepo struct {
+	devices map[int]device.Device
+	err     error
+}
+
+func (m mockRepo) GetDevice(ctx context.Context, id int) (device.Device, error) {
+	d, ok := m.devices[id]
+	if !ok {
+		return device.Device{}, db.ErrNotFound
+	}
+	return d, m.err
+}
+
+func (m mockRepo) GetDeviceByTokenHash(ctx context.Context, hash string) (device.Device, error) {
+	for _, d := range m.devices {
+		if d.TokenHash == hash {
+			return d, m.err
+		}
+	}
+	return device.Device{}, db.ErrNotFound
+}
+
+func (m mockRepo) GetAllDevices(ctx context.Context) ([]device.Device, error) {
+	result := []device.Device{}
+	for _, d := range m.devices {
+		result = append(result, d)
+	}
+	slices.SortFunc(result, func(a, b device.Device) int { return a.ID - b.ID })
+	return result, m.err
+}
+
+func (m mockRepo) CreateDevice(ctx context.Context, d device.Device) (int, error) {
+	newID := len(m.devices) + 1
+	d.ID = newID
+	m.devices[newID] = d
+	return newID, m.err
+}
+
+func (m mockRepo) UpdateDevice(ctx context.Context, d device.Device) error {
+	if _, ok := m.devices[d.ID]; !ok {
+		return db.ErrNotFound
+	}
+	m.devices[d.ID] = d
+	return m.err
+}
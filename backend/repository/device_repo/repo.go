@@ -0,0 +1,73 @@
+package device_repo
+
+import (
+	"context"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/device"
+)
+
+func (r Repository) GetDevice(ctx context.Context, id int) (device.Device, error) {
+	var d dbdevice
+	err := db.Conn(ctx, r.DB).QueryRowContext(ctx, "SELECT id, name, status, token_hash, created_at, revoked_at FROM devices WHERE id = $1", id).
+		Scan(&d.ID, &d.Name, &d.Status, &d.TokenHash, &d.CreatedAt, &d.RevokedAt)
+	if err != nil {
+		return device.Device{}, db.Error(err)
+	}
+
+	return d.toDomain(), nil
+}
+
+// GetDeviceByTokenHash returns the device with the given token hash, regardless of its status.
+func (r Repository) GetDeviceByTokenHash(ctx context.Context, hash string) (device.Device, error) {
+	var d dbdevice
+	err := db.Conn(ctx, r.DB).QueryRowContext(ctx, "SELECT id, name, status, token_hash, created_at, revoked_at FROM devices WHERE token_hash = $1", hash).
+		Scan(&d.ID, &d.Name, &d.Status, &d.TokenHash, &d.CreatedAt, &d.RevokedAt)
+	if err != nil {
+		return device.Device{}, db.Error(err)
+	}
+
+	return d.toDomain(), nil
+}
+
+func (r Repository) GetAllDevices(ctx context.Context) ([]device.Device, error) {
+	rows, err := db.Conn(ctx, r.DB).QueryContext(ctx, "SELECT id, name, status, token_hash, created_at, revoked_at FROM devices ORDER BY id ASC")
+	if err != nil {
+		return nil, db.Error(err)
+	}
+	defer db.Close(rows, "devices")
+
+	devices := []device.Device{}
+	for rows.Next() {
+		var d dbdevice
+		if err := rows.Scan(&d.ID, &d.Name, &d.Status, &d.TokenHash, &d.CreatedAt, &d.RevokedAt); err != nil {
+			return nil, db.Error(err)
+		}
+		devices = append(devices, d.toDomain())
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, db.Error(err)
+	}
+
+	return devices, nil
+}
+
+func (r Repository) CreateDevice(ctx context.Context, d device.Device) (int, error) {
+	var id int
+	err := db.Conn(ctx, r.DB).QueryRowContext(ctx, "INSERT INTO devices (name, status, token_hash, created_at, revoked_at) VALUES ($1, $2, $3, $4, $5) RETURNING id", d.Name, d.Status, d.TokenHash, d.CreatedAt, d.RevokedAt).Scan(&id)
+	if err != nil {
+		return 0, db.Error(err)
+	}
+
+	return id, nil
+}
+
+func (r Repository) UpdateDevice(ctx context.Context, d device.Device) error {
+	result, err := db.Conn(ctx, r.DB).ExecContext(ctx, "UPDATE devices SET name = $1, status = $2, revoked_at = $3 WHERE id = $4", d.Name, d.Status, d.RevokedAt, d.ID)
+	if err != nil {
+		return db.Error(err)
+	}
+
+	return db.ResultError(result)
+}
@@ -0,0 +1,76 @@
+//go:build integration
+
+package device_repo
+
+import (
+	"context"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	dbpkg "github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/device"
+)
+
+func setup(t *testing.T) (Repository, func(t *testing.T)) {
+	db := dbpkg.OpenTestDatabase(t)
+
+	clean := func(t *testing.T) {
+		for _, stmt := range []string{"UPDATE sessions SET device_id = NULL", "DELETE FROM devices"} {
+			if _, err := db.Exec(stmt); err != nil {
+				t.Fatalf("Failed to clean devices: %v", err)
+			}
+		}
+	}
+	clean(t)
+
+	return Repository{DB: db}, func(t *testing.T) {
+		clean(t)
+		db.Close()
+	}
+}
+
+func TestCreateDevice_GetByTokenHash(t *testing.T) {
+	repo, teardown := setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+	d, token, _ := device.NewDevice("Tablet Theke")
+	id, err := repo.CreateDevice(ctx, d)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got, err := repo.GetDeviceByTokenHash(ctx, device.HashToken(token))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got.ID != id || got.Name != "Tablet Theke" || got.Status != device.ActiveStatus {
+		t.Fatalf("unexpected device: %+v", got)
+	}
+
+	if _, err := repo.GetDeviceByTokenHash(ctx, device.HashToken("unknown")); err != dbpkg.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestUpdateDevice_Revoke(t *testing.T) {
+	repo, teardown := setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+	d, _, _ := device.NewDevice("Tablet Garten")
+	d.ID, _ = repo.CreateDevice(ctx, d)
+
+	_ = d.Revoke()
+	if err := repo.UpdateDevice(ctx, d); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	devices, err := repo.GetAllDevices(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(devices) != 1 || devices[0].Status != device.RevokedStatus || devices[0].RevokedAt == nil {
+		t.Fatalf("expected 1 revoked device, got %+v", devices)
+	}
+}
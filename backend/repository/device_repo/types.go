@@ -0,0 +1,38 @@
+package device_repo
+
+import (
+	"database/sql"
+
+	"github.com/nicograef/jotti/backend/domain/device"
+)
+
+// Repository implements device persistence layer using a SQL database.
+type Repository struct {
+	DB *sql.DB
+}
+
+type dbdevice struct {
+	ID        int          `db:"id"`
+	Name      string       `db:"name"`
+	Status    string       `db:"status"`
+	TokenHash string       `db:"token_hash"`
+	CreatedAt sql.NullTime `db:"created_at"`
+	RevokedAt sql.NullTime `db:"revoked_at"`
+}
+
+func (dd *dbdevice) toDomain() device.Device {
+	d := device.Device{
+		ID:        dd.ID,
+		Name:      dd.Name,
+		Status:    device.Status(dd.Status),
+		TokenHash: dd.TokenHash,
+		CreatedAt: dd.CreatedAt.Time,
+	}
+
+	if dd.RevokedAt.Valid {
+		revokedAt := dd.RevokedAt.Time
+		d.RevokedAt = &revokedAt
+	}
+
+	return d
+}
@@ -2,6 +2,8 @@ package event_repo
 
 import (
 	"context"
+	"slices"
+	"time"
 
 	"github.com/nicograef/jotti/backend/domain/event"
 )
@@ -39,10 +41,76 @@ func (m mockRepo) ReadEvent(ctx context.Context, eventID int) (event.Event, erro
 	return e, m.err
 }
 
-func (m mockRepo) ReadEventsBySubject(ctx context.Context, t event.Event) ([]event.Event, error) {
+func (m mockRepo) ReadEventsBySubject(ctx context.Context, subject string) ([]event.Event, error) {
 	events := []event.Event{}
-	for _, u := range m.events {
-		events = append(events, u)
+	for _, e := range m.events {
+		if e.Subject == subject {
+			events = append(events, e)
+		}
+	}
+	slices.SortFunc(events, func(a, b event.Event) int { return a.ID - b.ID })
+	return events, m.err
+}
+
+func (m mockRepo) ReadEventsInTimeRange(ctx context.Context, from, to time.Time) ([]event.Event, error) {
+	events := []event.Event{}
+	for _, e := range m.events {
+		if !e.Time.Before(from) && e.Time.Before(to) {
+			events = append(events, e)
+		}
+	}
+	slices.SortFunc(events, func(a, b event.Event) int { return a.ID - b.ID })
+	return events, m.err
+}
+
+func (m mockRepo) ReadEventsByPeriod(ctx context.Context, periodID int) ([]event.Event, error) {
+	events := []event.Event{}
+	for _, e := range m.events {
+		if e.PeriodID != nil && *e.PeriodID == periodID {
+			events = append(events, e)
+		}
+	}
+	slices.SortFunc(events, func(a, b event.Event) int { return a.ID - b.ID })
+	return events, m.err
+}
+
+func (m mockRepo) ReadEventsAfter(ctx context.Context, afterID int, types []string, limit int) ([]event.Event, error) {
+	events := []event.Event{}
+	for _, e := range m.events {
+		if e.ID > afterID && slices.Contains(types, e.Type) {
+			events = append(events, e)
+		}
+	}
+	slices.SortFunc(events, func(a, b event.Event) int { return a.ID - b.ID })
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, m.err
+}
+
+func (m mockRepo) ReadEvents(ctx context.Context, f event.Filter) ([]event.Event, error) {
+	events := []event.Event{}
+	for _, e := range m.events {
+		if len(f.Types) > 0 && !slices.Contains(f.Types, e.Type) {
+			continue
+		}
+		if f.UserID != 0 && e.UserID != f.UserID {
+			continue
+		}
+		if f.Subject != "" && e.Subject != f.Subject {
+			continue
+		}
+		if !f.From.IsZero() && e.Time.Before(f.From) {
+			continue
+		}
+		if !f.To.IsZero() && !e.Time.Before(f.To) {
+			continue
+		}
+		events = append(events, e)
+	}
+	slices.SortFunc(events, func(a, b event.Event) int { return b.ID - a.ID })
+	if f.Limit > 0 && len(events) > f.Limit {
+		events = events[:f.Limit]
 	}
 	return events, m.err
 }
@@ -3,65 +3,142 @@ package event_repo
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/nicograef/jotti/backend/db"
 	"github.com/nicograef/jotti/backend/domain/event"
+	"github.com/nicograef/jotti/backend/metrics"
 )
 
-type Repository struct {
-	DB *sql.DB
-}
-
 // WriteEvent stores a new event in the database.
+// Events without a period are tagged with the currently open period (if any).
+// Anonymous events (user ID 0) are stored without a user.
 func (r Repository) WriteEvent(ctx context.Context, e event.Event) (int, error) {
 	var id int
-	err := r.DB.QueryRowContext(ctx,
-		`INSERT INTO events (user_id, type, subject, data, timestamp)
-		 VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+	err := db.Conn(ctx, r.DB).QueryRowContext(ctx,
+		`INSERT INTO events (user_id, type, subject, data, timestamp, period_id)
+		 VALUES (NULLIF($1, 0), $2, $3, $4, $5, COALESCE($6, (SELECT id FROM periods WHERE status = 'open'))) RETURNING id`,
 		e.UserID,
 		e.Type,
 		e.Subject,
 		e.Data,
 		e.Time,
+		e.PeriodID,
 	).Scan(&id)
 
 	if err != nil {
 		return 0, db.Error(err)
 	}
 
+	metrics.EventsAppended.WithLabelValues(e.Type).Inc()
 	return id, nil
 }
 
 func (r Repository) ReadEvent(ctx context.Context, eventID int) (event.Event, error) {
-	row := r.DB.QueryRowContext(ctx,
-		`SELECT id, user_id, type, subject, data, timestamp	FROM events	WHERE id = $1`,
+	row := db.Conn(ctx, r.DB).QueryRowContext(ctx,
+		`SELECT id, user_id, type, subject, data, timestamp, period_id FROM events WHERE id = $1`,
 		eventID,
 	)
 
-	var e event.Event
-	if err := row.Scan(&e.ID, &e.UserID, &e.Type, &e.Subject, &e.Data, &e.Time); err != nil {
-		return e, db.Error(err)
+	var e dbevent
+	if err := row.Scan(&e.ID, &e.UserID, &e.Type, &e.Subject, &e.Data, &e.Time, &e.PeriodID); err != nil {
+		return event.Event{}, db.Error(err)
 	}
 
-	return e, nil
+	return e.toDomain(), nil
 }
 
 // ReadEventsBySubject retrieves all events of the given subject.
 // Events are ordered by their sequence number ascending (first element in slice is first event).
 func (r Repository) ReadEventsBySubject(ctx context.Context, subject string) ([]event.Event, error) {
-	rows, err := r.DB.QueryContext(ctx, `SELECT id, user_id, type, subject, data, timestamp FROM events WHERE subject = $1 ORDER BY id ASC`, subject)
+	rows, err := db.Conn(ctx, r.DB).QueryContext(ctx, `SELECT id, user_id, type, subject, data, timestamp, period_id FROM events WHERE subject = $1 ORDER BY id ASC`, subject)
+	if err != nil {
+		return nil, db.Error(err)
+	}
+	defer db.Close(rows, "events")
+
+	return scanEvents(rows)
+}
+
+// ReadEventsInTimeRange retrieves all events with a timestamp in [from, to).
+// Events are ordered by their sequence number ascending (first element in slice is first event).
+func (r Repository) ReadEventsInTimeRange(ctx context.Context, from, to time.Time) ([]event.Event, error) {
+	rows, err := db.Conn(ctx, r.DB).QueryContext(ctx, `SELECT id, user_id, type, subject, data, timestamp, period_id FROM events WHERE timestamp >= $1 AND timestamp < $2 ORDER BY id ASC`, from, to)
+	if err != nil {
+		return nil, db.Error(err)
+	}
+	defer db.Close(rows, "events")
+
+	return scanEvents(rows)
+}
+
+// ReadEventsByPeriod retrieves all events that happened during the given period.
+// Events are ordered by their sequence number ascending (first element in slice is first event).
+func (r Repository) ReadEventsByPeriod(ctx context.Context, periodID int) ([]event.Event, error) {
+	rows, err := db.Conn(ctx, r.DB).QueryContext(ctx, `SELECT id, user_id, type, subject, data, timestamp, period_id FROM events WHERE period_id = $1 ORDER BY id ASC`, periodID)
 	if err != nil {
 		return nil, db.Error(err)
 	}
 	defer db.Close(rows, "events")
 
+	return scanEvents(rows)
+}
+
+// ReadEventsAfter retrieves up to limit events of the given types with a sequence number greater than afterID.
+// Events are ordered by their sequence number ascending, so the last event is where to continue reading.
+func (r Repository) ReadEventsAfter(ctx context.Context, afterID int, types []string, limit int) ([]event.Event, error) {
+	rows, err := db.Conn(ctx, r.DB).QueryContext(ctx,
+		`SELECT id, user_id, type, subject, data, timestamp, period_id FROM events
+		 WHERE id > $1 AND type = ANY($2)
+		 ORDER BY id ASC
+		 LIMIT $3`,
+		afterID,
+		types,
+		limit,
+	)
+	if err != nil {
+		return nil, db.Error(err)
+	}
+	defer db.Close(rows, "events")
+
+	return scanEvents(rows)
+}
+
+// ReadEvents retrieves the events selected by the given filter.
+// Events are ordered by their sequence number descending (first element in slice is the latest event).
+func (r Repository) ReadEvents(ctx context.Context, f event.Filter) ([]event.Event, error) {
+	rows, err := db.Conn(ctx, r.DB).QueryContext(ctx,
+		`SELECT id, user_id, type, subject, data, timestamp, period_id FROM events
+		 WHERE (COALESCE(cardinality($1::text[]), 0) = 0 OR type = ANY($1))
+		   AND ($2 = 0 OR user_id = $2)
+		   AND ($3 = '' OR subject = $3)
+		   AND ($4::timestamptz IS NULL OR timestamp >= $4)
+		   AND ($5::timestamptz IS NULL OR timestamp < $5)
+		 ORDER BY id DESC
+		 LIMIT NULLIF($6, 0)`,
+		f.Types,
+		f.UserID,
+		f.Subject,
+		sql.NullTime{Time: f.From, Valid: !f.From.IsZero()},
+		sql.NullTime{Time: f.To, Valid: !f.To.IsZero()},
+		f.Limit,
+	)
+	if err != nil {
+		return nil, db.Error(err)
+	}
+	defer db.Close(rows, "events")
+
+	return scanEvents(rows)
+}
+
+func scanEvents(rows *sql.Rows) ([]event.Event, error) {
 	events := []event.Event{}
 	for rows.Next() {
-		var event event.Event
-		if err := rows.Scan(&event.ID, &event.UserID, &event.Type, &event.Subject, &event.Data, &event.Time); err != nil {
+		var e dbevent
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Type, &e.Subject, &e.Data, &e.Time, &e.PeriodID); err != nil {
 			return nil, db.Error(err)
 		}
-		events = append(events, event)
+		events = append(events, e.toDomain())
 	}
 
 	if err := rows.Err(); err != nil {
@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	dbpkg "github.com/nicograef/jotti/backend/db"
@@ -24,7 +25,7 @@ func createUser(db *sql.DB) (int, error) {
 }
 
 func setup(t *testing.T) (int, Repository, func(t *testing.T)) {
-	db := dbpkg.OpenTestDatabase()
+	db := dbpkg.OpenTestDatabase(t)
 
 	_, err := db.Exec("DELETE FROM events")
 	if err != nil {
@@ -73,6 +74,74 @@ func TestWriteEvent(t *testing.T) {
 	}
 }
 
+func TestWriteEvent_Anonymous(t *testing.T) {
+	_, repo, teardown := setup(t)
+	defer teardown(t)
+
+	e, err := event.NewAnonymous("login.failed:v1", "login:unknown", map[string]any{"reason": "unknown_user"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	eventID, err := repo.WriteEvent(context.Background(), e)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	readEvent, err := repo.ReadEvent(context.Background(), eventID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if readEvent.UserID != 0 {
+		t.Fatalf("Expected no user ID, got %d", readEvent.UserID)
+	}
+}
+
+func TestWriteEvent_SessionOpenedTwice(t *testing.T) {
+	userID, repo, teardown := setup(t)
+	defer teardown(t)
+
+	write := func(sessionNumber, opening int) error {
+		e, err := event.New(userID, "table.opened:v1", "table:42", map[string]any{"sessionNumber": sessionNumber, "opening": opening})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		_, err = repo.WriteEvent(context.Background(), e)
+		return err
+	}
+
+	if err := write(1, 1); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// reopening the session
+	if err := write(1, 2); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	err := write(1, 2)
+	if !errors.Is(err, dbpkg.ErrAlreadyExists) || dbpkg.Constraint(err) != "events_table_opened_idx" {
+		t.Fatalf("Expected unique violation of events_table_opened_idx, got %v", err)
+	}
+}
+
+func TestWriteEvent_AmountPaymentTwice(t *testing.T) {
+	userID, repo, teardown := setup(t)
+	defer teardown(t)
+
+	e, err := event.New(userID, "table.amount-payment-registered:v1", "table:42", map[string]any{"paymentId": "4f5a6b7c-8d9e-4f0a-9b1c-2d3e4f5a6b7c", "amountCents": 500})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := repo.WriteEvent(context.Background(), e); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = repo.WriteEvent(context.Background(), e)
+	if !errors.Is(err, dbpkg.ErrAlreadyExists) || dbpkg.Constraint(err) != "events_amount_payment_id_idx" {
+		t.Fatalf("Expected unique violation of events_amount_payment_id_idx, got %v", err)
+	}
+}
+
 func TestReadEvent(t *testing.T) {
 	userID, repo, teardown := setup(t)
 	defer teardown(t)
@@ -155,3 +224,151 @@ func TestReadEventsBySubject(t *testing.T) {
 		t.Fatalf("Expected subject table:42, got %s", events[0].Subject)
 	}
 }
+
+func TestReadEventsAfter(t *testing.T) {
+	userID, repo, teardown := setup(t)
+	defer teardown(t)
+
+	ids := []int{}
+	for _, eventType := range []string{"table.opened:v1", "user.created:v1", "table.closed:v1", "table.opened:v1"} {
+		e, err := event.New(userID, eventType, "table:1", map[string]any{"k": "v"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		id, err := repo.WriteEvent(context.Background(), e)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	events, err := repo.ReadEventsAfter(context.Background(), ids[0], []string{"table.opened:v1", "table.closed:v1"}, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(events) != 1 || events[0].ID != ids[2] {
+		t.Fatalf("Expected table closed event %d, got %+v", ids[2], events)
+	}
+}
+
+func TestReadEventsInTimeRange(t *testing.T) {
+	userID, repo, teardown := setup(t)
+	defer teardown(t)
+
+	event1, err := event.New(userID, "table.order-placed:v1", "table:1", map[string]any{"k": "v"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	event1.Time = time.Date(2025, 6, 13, 23, 0, 0, 0, time.UTC)
+	event2, err := event.New(userID, "table.order-placed:v1", "table:1", map[string]any{"k": "v"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	event2.Time = time.Date(2025, 6, 14, 12, 0, 0, 0, time.UTC)
+	_, _ = repo.WriteEvent(context.Background(), event1)
+	_, _ = repo.WriteEvent(context.Background(), event2)
+
+	from := time.Date(2025, 6, 14, 0, 0, 0, 0, time.UTC)
+	events, err := repo.ReadEventsInTimeRange(context.Background(), from, from.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if !events[0].Time.Equal(event2.Time) {
+		t.Fatalf("Expected event at %v, got %v", event2.Time, events[0].Time)
+	}
+}
+
+func TestWriteEvent_TaggedWithOpenPeriod(t *testing.T) {
+	userID, repo, teardown := setup(t)
+	defer teardown(t)
+
+	var periodID int
+	err := repo.DB.QueryRow("INSERT INTO periods (name, status, opened_at) VALUES ('Sommerfest', 'open', now()) RETURNING id").Scan(&periodID)
+	if err != nil {
+		t.Fatalf("Failed to insert period: %v", err)
+	}
+	defer func() {
+		_, _ = repo.DB.Exec("DELETE FROM events")
+		_, _ = repo.DB.Exec("DELETE FROM periods")
+	}()
+
+	e, err := event.New(userID, "table.order-placed:v1", "table:1", map[string]any{"k": "v"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_, err = repo.WriteEvent(context.Background(), e)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	events, err := repo.ReadEventsByPeriod(context.Background(), periodID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(events) != 1 || events[0].PeriodID == nil || *events[0].PeriodID != periodID {
+		t.Fatalf("Expected 1 event of period %d, got %+v", periodID, events)
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	userID, repo, teardown := setup(t)
+	defer teardown(t)
+
+	event1, err := event.New(userID, "product.created:v1", "audit:product:1", map[string]any{"k": "v"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	event1.Time = time.Date(2025, 6, 13, 23, 0, 0, 0, time.UTC)
+	event2, err := event.New(userID, "product.updated:v1", "audit:product:1", map[string]any{"k": "v"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	event2.Time = time.Date(2025, 6, 14, 12, 0, 0, 0, time.UTC)
+	event3, err := event.New(userID, "table.order-placed:v1", "table:1", map[string]any{"k": "v"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	event3.Time = time.Date(2025, 6, 14, 13, 0, 0, 0, time.UTC)
+	_, _ = repo.WriteEvent(context.Background(), event1)
+	_, _ = repo.WriteEvent(context.Background(), event2)
+	_, _ = repo.WriteEvent(context.Background(), event3)
+
+	events, err := repo.ReadEvents(context.Background(), event.Filter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events without filter, got %d", len(events))
+	}
+	if events[0].Type != "table.order-placed:v1" {
+		t.Fatalf("Expected latest event first, got %s", events[0].Type)
+	}
+
+	events, err = repo.ReadEvents(context.Background(), event.Filter{
+		Types:   []string{"product.created:v1", "product.updated:v1"},
+		UserID:  userID,
+		Subject: "audit:product:1",
+		From:    time.Date(2025, 6, 14, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if events[0].Type != "product.updated:v1" {
+		t.Fatalf("Expected product.updated:v1, got %s", events[0].Type)
+	}
+
+	events, err = repo.ReadEvents(context.Background(), event.Filter{Limit: 2})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events with limit, got %d", len(events))
+	}
+}
@@ -0,0 +1,41 @@
+package event_repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/nicograef/jotti/backend/domain/event"
+)
+
+type Repository struct {
+	DB *sql.DB
+}
+
+type dbevent struct {
+	ID       int             `db:"id"`
+	UserID   sql.NullInt64   `db:"user_id"`
+	Type     string          `db:"type"`
+	Subject  string          `db:"subject"`
+	Data     json.RawMessage `db:"data"`
+	Time     time.Time       `db:"timestamp"`
+	PeriodID sql.NullInt64   `db:"period_id"`
+}
+
+func (de *dbevent) toDomain() event.Event {
+	e := event.Event{
+		ID:      de.ID,
+		UserID:  int(de.UserID.Int64),
+		Type:    de.Type,
+		Subject: de.Subject,
+		Data:    de.Data,
+		Time:    de.Time,
+	}
+
+	if de.PeriodID.Valid {
+		periodID := int(de.PeriodID.Int64)
+		e.PeriodID = &periodID
+	}
+
+	return e
+}
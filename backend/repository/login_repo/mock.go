@@ -0,0 +1,80 @@
+package login_repo
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/login"
+)
+
+// NewMock creates a new mock repository with the given attempts and error.
+func NewMock(attempts []login.Attempts, err error) *mockRepo {
+	attemptsMap := make(map[string]login.Attempts)
+	for _, a := range attempts {
+		attemptsMap[a.Key()] = a
+	}
+
+	return &mockRepo{
+		attempts: attemptsMap,
+		err:      err,
+	}
+}
+
+type mockRepo struct {
+	attempts map[string]login.Attempts
+	err      error
+}
+
+func (m mockRepo) GetAttempts(ctx context.Context, kind login.Kind, value string) (login.Attempts, error) {
+	a, ok := m.attempts[login.Attempts{Kind: kind, Value: value}.Key()]
+	if !ok {
+		return login.Attempts{}, db.ErrNotFound
+	}
+	return a, m.err
+}
+
+func (m mockRepo) GetRecentAttempts(ctx context.Context, since time.Time) ([]login.Attempts, error) {
+	result := []login.Attempts{}
+	for _, a := range m.attempts {
+		if a.LastFailedAt.After(since) {
+			result = append(result, a)
+		}
+	}
+	slices.SortFunc(result, func(a, b login.Attempts) int { return b.LastFailedAt.Compare(a.LastFailedAt) })
+	return result, m.err
+}
+
+func (m mockRepo) CountFailedAttempt(ctx context.Context, kind login.Kind, value string, now time.Time) (login.Attempts, error) {
+	key := login.Attempts{Kind: kind, Value: value}.Key()
+	a, ok := m.attempts[key]
+	if !ok {
+		a = login.Attempts{Kind: kind, Value: value, BlockedUntil: now}
+	}
+	if now.Sub(a.LastFailedAt) > login.ResetWindow {
+		a.FailedAttempts = 0
+	}
+	a.FailedAttempts++
+	a.LastFailedAt = now
+	m.attempts[key] = a
+	return a, m.err
+}
+
+func (m mockRepo) BlockAttempts(ctx context.Context, kind login.Kind, value string, until time.Time) error {
+	key := login.Attempts{Kind: kind, Value: value}.Key()
+	if a, ok := m.attempts[key]; ok && until.After(a.BlockedUntil) {
+		a.BlockedUntil = until
+		m.attempts[key] = a
+	}
+	return m.err
+}
+
+func (m mockRepo) DeleteAttempts(ctx context.Context, kind login.Kind, value string) error {
+	key := login.Attempts{Kind: kind, Value: value}.Key()
+	if _, ok := m.attempts[key]; !ok {
+		return db.ErrNotFound
+	}
+	delete(m.attempts, key)
+	return m.err
+}
@@ -0,0 +1,86 @@
+package login_repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/login"
+)
+
+// GetAttempts returns the failed attempts for a username or client IP or db.ErrNotFound if there are none.
+func (r Repository) GetAttempts(ctx context.Context, kind login.Kind, value string) (login.Attempts, error) {
+	var a dbattempts
+	err := db.Conn(ctx, r.DB).QueryRowContext(ctx, "SELECT kind, value, failed_attempts, last_failed_at, blocked_until FROM login_attempts WHERE kind = $1 AND value = $2", kind, value).
+		Scan(&a.Kind, &a.Value, &a.FailedAttempts, &a.LastFailedAt, &a.BlockedUntil)
+	if err != nil {
+		return login.Attempts{}, db.Error(err)
+	}
+
+	return a.toDomain(), nil
+}
+
+// GetRecentAttempts returns all counters with a failed attempt after since, most recent first.
+func (r Repository) GetRecentAttempts(ctx context.Context, since time.Time) ([]login.Attempts, error) {
+	rows, err := db.Conn(ctx, r.DB).QueryContext(ctx, "SELECT kind, value, failed_attempts, last_failed_at, blocked_until FROM login_attempts WHERE last_failed_at > $1 ORDER BY last_failed_at DESC", since)
+	if err != nil {
+		return nil, db.Error(err)
+	}
+	defer db.Close(rows, "login_attempts")
+
+	attempts := []login.Attempts{}
+	for rows.Next() {
+		var a dbattempts
+		if err := rows.Scan(&a.Kind, &a.Value, &a.FailedAttempts, &a.LastFailedAt, &a.BlockedUntil); err != nil {
+			return nil, db.Error(err)
+		}
+		attempts = append(attempts, a.toDomain())
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, db.Error(err)
+	}
+
+	return attempts, nil
+}
+
+// CountFailedAttempt counts a failed attempt for a username or client IP in one statement and returns the counter,
+// so parallel attempts can't overwrite each other's count. The counter starts over after login.ResetWindow without
+// failed attempts. A new counter is not blocked.
+func (r Repository) CountFailedAttempt(ctx context.Context, kind login.Kind, value string, now time.Time) (login.Attempts, error) {
+	var a dbattempts
+	err := db.Conn(ctx, r.DB).QueryRowContext(ctx,
+		`INSERT INTO login_attempts (kind, value, failed_attempts, last_failed_at, blocked_until) VALUES ($1, $2, 1, $3, $3)
+		 ON CONFLICT (kind, value) DO UPDATE SET
+		 failed_attempts = CASE WHEN login_attempts.last_failed_at < $4 THEN 1 ELSE login_attempts.failed_attempts + 1 END,
+		 last_failed_at = EXCLUDED.last_failed_at
+		 RETURNING kind, value, failed_attempts, last_failed_at, blocked_until`,
+		kind, value, now, now.Add(-login.ResetWindow)).
+		Scan(&a.Kind, &a.Value, &a.FailedAttempts, &a.LastFailedAt, &a.BlockedUntil)
+	if err != nil {
+		return login.Attempts{}, db.Error(err)
+	}
+
+	return a.toDomain(), nil
+}
+
+// BlockAttempts blocks attempts for a username or client IP until the given time. A block is only ever extended, so a
+// parallel attempt with a lower count can't shorten it. It is not an error if the counter was removed meanwhile.
+func (r Repository) BlockAttempts(ctx context.Context, kind login.Kind, value string, until time.Time) error {
+	_, err := db.Conn(ctx, r.DB).ExecContext(ctx, "UPDATE login_attempts SET blocked_until = GREATEST(blocked_until, $1) WHERE kind = $2 AND value = $3", until, kind, value)
+	if err != nil {
+		return db.Error(err)
+	}
+
+	return nil
+}
+
+// DeleteAttempts removes the counter for a username or client IP. Returns db.ErrNotFound if there is none.
+func (r Repository) DeleteAttempts(ctx context.Context, kind login.Kind, value string) error {
+	result, err := db.Conn(ctx, r.DB).ExecContext(ctx, "DELETE FROM login_attempts WHERE kind = $1 AND value = $2", kind, value)
+	if err != nil {
+		return db.Error(err)
+	}
+
+	return db.ResultError(result)
+}
@@ -0,0 +1,124 @@
+//go:build integration
+
+package login_repo
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	dbpkg "github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/login"
+)
+
+func setup(t *testing.T) (Repository, func(t *testing.T)) {
+	db := dbpkg.OpenTestDatabase(t)
+
+	clean := func(t *testing.T) {
+		if _, err := db.Exec("DELETE FROM login_attempts"); err != nil {
+			t.Fatalf("Failed to clean login attempts: %v", err)
+		}
+	}
+	clean(t)
+
+	return Repository{DB: db}, func(t *testing.T) {
+		clean(t)
+		db.Close()
+	}
+}
+
+func TestCountFailedAttempt(t *testing.T) {
+	repo, teardown := setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	for i := 1; i <= 2; i++ {
+		a, err := repo.CountFailedAttempt(ctx, login.UsernameKind, "nico", now)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if a.FailedAttempts != i {
+			t.Fatalf("expected %d failed attempts, got %d", i, a.FailedAttempts)
+		}
+	}
+
+	blockedUntil := now.Add(time.Minute)
+	if err := repo.BlockAttempts(ctx, login.UsernameKind, "nico", blockedUntil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := repo.BlockAttempts(ctx, login.UsernameKind, "nico", now); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got, err := repo.GetAttempts(ctx, login.UsernameKind, "nico")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got.FailedAttempts != 2 || !got.BlockedUntil.Equal(blockedUntil.Truncate(time.Microsecond)) {
+		t.Fatalf("expected 2 failed attempts blocked until %s, got %+v", blockedUntil, got)
+	}
+
+	recent, err := repo.GetRecentAttempts(ctx, now.Add(-login.ResetWindow))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(recent) != 1 {
+		t.Fatalf("expected 1 recent counter, got %d", len(recent))
+	}
+
+	a, err := repo.CountFailedAttempt(ctx, login.UsernameKind, "nico", now.Add(login.ResetWindow+time.Minute))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if a.FailedAttempts != 1 {
+		t.Fatalf("expected counter to start over, got %d failed attempts", a.FailedAttempts)
+	}
+}
+
+func TestCountFailedAttempt_Parallel(t *testing.T) {
+	repo, teardown := setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	var wg sync.WaitGroup
+	for range login.LockoutThreshold {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.CountFailedAttempt(ctx, login.IPKind, "192.0.2.1", now); err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := repo.GetAttempts(ctx, login.IPKind, "192.0.2.1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got.FailedAttempts != login.LockoutThreshold {
+		t.Fatalf("expected %d failed attempts, got %d", login.LockoutThreshold, got.FailedAttempts)
+	}
+}
+
+func TestDeleteAttempts(t *testing.T) {
+	repo, teardown := setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+	_, _ = repo.CountFailedAttempt(ctx, login.IPKind, "192.0.2.1", time.Now().UTC())
+
+	if err := repo.DeleteAttempts(ctx, login.IPKind, "192.0.2.1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := repo.GetAttempts(ctx, login.IPKind, "192.0.2.1"); err != dbpkg.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := repo.DeleteAttempts(ctx, login.IPKind, "192.0.2.1"); err != dbpkg.ErrNotFound {
+		t.Fatalf("expected ErrNotFound for missing counter, got %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package login_repo
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/nicograef/jotti/backend/domain/login"
+)
+
+// Repository implements login attempt persistence layer using a SQL database.
+type Repository struct {
+	DB *sql.DB
+}
+
+type dbattempts struct {
+	Kind           string    `db:"kind"`
+	Value          string    `db:"value"`
+	FailedAttempts int       `db:"failed_attempts"`
+	LastFailedAt   time.Time `db:"last_failed_at"`
+	BlockedUntil   time.Time `db:"blocked_until"`
+}
+
+func (da *dbattempts) toDomain() login.Attempts {
+	return login.Attempts{
+		Kind:           login.Kind(da.Kind),
+		Value:          da.Value,
+		FailedAttempts: da.FailedAttempts,
+		LastFailedAt:   da.LastFailedAt,
+		BlockedUntil:   da.BlockedUntil,
+	}
+}
@@ -0,0 +1,76 @@
+package period_repo
+
+import (
+	"context"
+	"slices"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/period"
+)
+
+// NewMock creates a new mock repository with the given periods and error.
+func NewMock(periods []period.Period, err error) *mockRepo {
+	periodMap := make(map[int]period.Period)
+	for _, p := range periods {
+		periodMap[p.ID] = p
+	}
+
+	return &mockRepo{
+		periods: periodMap,
+		err:     err,
+	}
+}
+
+type mockRepo struct {
+	periods map[int]period.Period
+	err     error
+}
+
+func (m mockRepo) GetPeriod(ctx context.Context, id int) (period.Period, error) {
+	p, ok := m.periods[id]
+	if !ok {
+		return period.Period{}, db.ErrNotFound
+	}
+	return p, m.err
+}
+
+func (m mockRepo) GetOpenPeriod(ctx context.Context) (period.Period, error) {
+	for _, p := range m.periods {
+		if p.Status == period.OpenStatus {
+			return p, m.err
+		}
+	}
+	return period.Period{}, db.ErrNotFound
+}
+
+func (m mockRepo) GetOpenPeriodID(ctx context.Context) (int, error) {
+	p, err := m.GetOpenPeriod(ctx)
+	if err != nil {
+		return 0, nil
+	}
+	return p.ID, m.err
+}
+
+func (m mockRepo) GetAllPeriods(ctx context.Context) ([]period.Period, error) {
+	result := []period.Period{}
+	for _, p := range m.periods {
+		result = append(result, p)
+	}
+	slices.SortFunc(result, func(a, b period.Period) int { return b.ID - a.ID })
+	return result, m.err
+}
+
+func (m mockRepo) CreatePeriod(ctx context.Context, p period.Period) (int, error) {
+	if _, err := m.GetOpenPeriod(ctx); err == nil {
+		return 0, db.ErrAlreadyExists
+	}
+	newID := len(m.periods) + 1
+	p.ID = newID
+	m.periods[newID] = p
+	return newID, m.err
+}
+
+func (m mockRepo) UpdatePeriod(ctx context.Context, p period.Period) error {
+	m.periods[p.ID] = p
+	return m.err
+}
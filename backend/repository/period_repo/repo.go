@@ -0,0 +1,87 @@
+package period_repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/period"
+)
+
+func (r Repository) GetPeriod(ctx context.Context, id int) (period.Period, error) {
+	var p dbperiod
+	err := db.Conn(ctx, r.DB).QueryRowContext(ctx, "SELECT id, name, status, opened_at, closed_at FROM periods WHERE id = $1", id).
+		Scan(&p.ID, &p.Name, &p.Status, &p.OpenedAt, &p.ClosedAt)
+	if err != nil {
+		return period.Period{}, db.Error(err)
+	}
+
+	return p.toDomain(), nil
+}
+
+// GetOpenPeriod returns the currently open period or db.ErrNotFound if no period is open.
+func (r Repository) GetOpenPeriod(ctx context.Context) (period.Period, error) {
+	var p dbperiod
+	err := db.Conn(ctx, r.DB).QueryRowContext(ctx, "SELECT id, name, status, opened_at, closed_at FROM periods WHERE status = 'open'").
+		Scan(&p.ID, &p.Name, &p.Status, &p.OpenedAt, &p.ClosedAt)
+	if err != nil {
+		return period.Period{}, db.Error(err)
+	}
+
+	return p.toDomain(), nil
+}
+
+// GetOpenPeriodID returns the ID of the currently open period or 0 if no period is open.
+func (r Repository) GetOpenPeriodID(ctx context.Context) (int, error) {
+	p, err := r.GetOpenPeriod(ctx)
+	if errors.Is(err, db.ErrNotFound) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return p.ID, nil
+}
+
+func (r Repository) GetAllPeriods(ctx context.Context) ([]period.Period, error) {
+	rows, err := db.Conn(ctx, r.DB).QueryContext(ctx, "SELECT id, name, status, opened_at, closed_at FROM periods ORDER BY id DESC")
+	if err != nil {
+		return nil, db.Error(err)
+	}
+	defer db.Close(rows, "periods")
+
+	periods := []period.Period{}
+	for rows.Next() {
+		var p dbperiod
+		if err := rows.Scan(&p.ID, &p.Name, &p.Status, &p.OpenedAt, &p.ClosedAt); err != nil {
+			return nil, db.Error(err)
+		}
+		periods = append(periods, p.toDomain())
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, db.Error(err)
+	}
+
+	return periods, nil
+}
+
+// CreatePeriod stores a new period. Returns db.ErrAlreadyExists if another period is still open.
+func (r Repository) CreatePeriod(ctx context.Context, p period.Period) (int, error) {
+	var id int
+	err := db.Conn(ctx, r.DB).QueryRowContext(ctx, "INSERT INTO periods (name, status, opened_at, closed_at) VALUES ($1, $2, $3, $4) RETURNING id", p.Name, p.Status, p.OpenedAt, p.ClosedAt).Scan(&id)
+	if err != nil {
+		return 0, db.Error(err)
+	}
+
+	return id, nil
+}
+
+func (r Repository) UpdatePeriod(ctx context.Context, p period.Period) error {
+	result, err := db.Conn(ctx, r.DB).ExecContext(ctx, "UPDATE periods SET name = $1, status = $2, closed_at = $3 WHERE id = $4", p.Name, p.Status, p.ClosedAt, p.ID)
+	if err != nil {
+		return db.Error(err)
+	}
+
+	return db.ResultError(result)
+}
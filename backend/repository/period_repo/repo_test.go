@@ -0,0 +1,83 @@
+//go:build integration
+
+package period_repo
+
+import (
+	"context"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	dbpkg "github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/period"
+)
+
+func setup(t *testing.T) (Repository, func(t *testing.T)) {
+	db := dbpkg.OpenTestDatabase(t)
+
+	clean := func(t *testing.T) {
+		for _, stmt := range []string{"UPDATE products SET period_id = NULL", "UPDATE tables SET period_id = NULL", "DELETE FROM periods"} {
+			if _, err := db.Exec(stmt); err != nil {
+				t.Fatalf("Failed to clean periods: %v", err)
+			}
+		}
+	}
+	clean(t)
+
+	return Repository{DB: db}, func(t *testing.T) {
+		clean(t)
+		db.Close()
+	}
+}
+
+func TestCreatePeriod_SingleOpenPeriod(t *testing.T) {
+	repo, teardown := setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+	p, _ := period.NewPeriod("Sommerfest 2025")
+	id, err := repo.CreatePeriod(ctx, p)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	_, err = repo.CreatePeriod(ctx, p)
+	if err != dbpkg.ErrAlreadyExists {
+		t.Fatalf("expected ErrAlreadyExists for second open period, got %v", err)
+	}
+
+	open, err := repo.GetOpenPeriod(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if open.ID != id {
+		t.Fatalf("expected open period %d, got %d", id, open.ID)
+	}
+}
+
+func TestUpdatePeriod_Close(t *testing.T) {
+	repo, teardown := setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+	p, _ := period.NewPeriod("Weihnachtsmarkt")
+	id, _ := repo.CreatePeriod(ctx, p)
+
+	p.ID = id
+	_ = p.Close()
+	if err := repo.UpdatePeriod(ctx, p); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	_, err := repo.GetOpenPeriod(ctx)
+	if err != dbpkg.ErrNotFound {
+		t.Fatalf("expected ErrNotFound without open period, got %v", err)
+	}
+
+	periods, err := repo.GetAllPeriods(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(periods) != 1 || periods[0].ClosedAt == nil {
+		t.Fatalf("expected 1 closed period, got %+v", periods)
+	}
+}
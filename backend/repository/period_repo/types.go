@@ -0,0 +1,36 @@
+package period_repo
+
+import (
+	"database/sql"
+
+	"github.com/nicograef/jotti/backend/domain/period"
+)
+
+// Repository implements period persistence layer using a SQL database.
+type Repository struct {
+	DB *sql.DB
+}
+
+type dbperiod struct {
+	ID       int          `db:"id"`
+	Name     string       `db:"name"`
+	Status   string       `db:"status"`
+	OpenedAt sql.NullTime `db:"opened_at"`
+	ClosedAt sql.NullTime `db:"closed_at"`
+}
+
+func (dp *dbperiod) toDomain() period.Period {
+	p := period.Period{
+		ID:       dp.ID,
+		Name:     dp.Name,
+		Status:   period.Status(dp.Status),
+		OpenedAt: dp.OpenedAt.Time,
+	}
+
+	if dp.ClosedAt.Valid {
+		closedAt := dp.ClosedAt.Time
+		p.ClosedAt = &closedAt
+	}
+
+	return p
+}
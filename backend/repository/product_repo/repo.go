@@ -8,13 +8,13 @@ import (
 )
 
 func (r Repository) GetProduct(ctx context.Context, id int) (product.Product, error) {
-	row := r.DB.QueryRowContext(ctx,
-		"SELECT id, name, description, net_price_cents, status, category, created_at FROM products WHERE id = $1 AND status != 'deleted'",
+	row := db.Conn(ctx, r.DB).QueryRowContext(ctx,
+		"SELECT id, name, description, net_price_cents, status, category, created_at, period_id FROM products WHERE id = $1 AND status != 'deleted'",
 		id,
 	)
 
 	var p dbproduct
-	err := row.Scan(&p.ID, &p.Name, &p.Description, &p.NetPriceCents, &p.Status, &p.Category, &p.CreatedAt)
+	err := row.Scan(&p.ID, &p.Name, &p.Description, &p.NetPriceCents, &p.Status, &p.Category, &p.CreatedAt, &p.PeriodID)
 
 	if err != nil {
 		return product.Product{}, db.Error(err)
@@ -24,7 +24,7 @@ func (r Repository) GetProduct(ctx context.Context, id int) (product.Product, er
 }
 
 func (r Repository) GetAllProducts(ctx context.Context) ([]product.Product, error) {
-	rows, err := r.DB.QueryContext(ctx, "SELECT id, name, description, net_price_cents, status, category, created_at FROM products WHERE status != 'deleted' ORDER BY id ASC")
+	rows, err := db.Conn(ctx, r.DB).QueryContext(ctx, "SELECT id, name, description, net_price_cents, status, category, created_at, period_id FROM products WHERE status != 'deleted' ORDER BY id ASC")
 	if err != nil {
 		return nil, db.Error(err)
 	}
@@ -33,7 +33,7 @@ func (r Repository) GetAllProducts(ctx context.Context) ([]product.Product, erro
 	products := []product.Product{}
 	for rows.Next() {
 		var p dbproduct
-		err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.NetPriceCents, &p.Status, &p.Category, &p.CreatedAt)
+		err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.NetPriceCents, &p.Status, &p.Category, &p.CreatedAt, &p.PeriodID)
 		if err != nil {
 			return nil, db.Error(err)
 		}
@@ -47,8 +47,10 @@ func (r Repository) GetAllProducts(ctx context.Context) ([]product.Product, erro
 	return products, nil
 }
 
-func (r Repository) GetActiveProducts(ctx context.Context) ([]product.Product, error) {
-	rows, err := r.DB.QueryContext(ctx, "SELECT id, name, description, net_price_cents, status, category, created_at FROM products WHERE status = 'active' ORDER BY id ASC")
+// GetActiveProducts returns all active products of the given period and all products without a period.
+// If periodID is 0, active products of all periods are returned.
+func (r Repository) GetActiveProducts(ctx context.Context, periodID int) ([]product.Product, error) {
+	rows, err := db.Conn(ctx, r.DB).QueryContext(ctx, "SELECT id, name, description, net_price_cents, status, category, created_at, period_id FROM products WHERE status = 'active' AND ($1 = 0 OR period_id IS NULL OR period_id = $1) ORDER BY id ASC", periodID)
 	if err != nil {
 		return nil, db.Error(err)
 	}
@@ -57,7 +59,7 @@ func (r Repository) GetActiveProducts(ctx context.Context) ([]product.Product, e
 	products := []product.Product{}
 	for rows.Next() {
 		var p dbproduct
-		err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.NetPriceCents, &p.Status, &p.Category, &p.CreatedAt)
+		err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.NetPriceCents, &p.Status, &p.Category, &p.CreatedAt, &p.PeriodID)
 		if err != nil {
 			return nil, db.Error(err)
 		}
@@ -73,9 +75,9 @@ func (r Repository) GetActiveProducts(ctx context.Context) ([]product.Product, e
 
 func (r Repository) CreateProduct(ctx context.Context, p product.Product) (int, error) {
 	var id int
-	err := r.DB.QueryRowContext(ctx,
-		"INSERT INTO products (name, description, net_price_cents, category, status, created_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
-		p.Name, p.Description, p.NetPriceCents, string(p.Category), string(p.Status), p.CreatedAt,
+	err := db.Conn(ctx, r.DB).QueryRowContext(ctx,
+		"INSERT INTO products (name, description, net_price_cents, category, status, created_at, period_id) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		p.Name, p.Description, p.NetPriceCents, string(p.Category), string(p.Status), p.CreatedAt, p.PeriodID,
 	).Scan(&id)
 
 	if err != nil {
@@ -86,7 +88,7 @@ func (r Repository) CreateProduct(ctx context.Context, p product.Product) (int,
 }
 
 func (r Repository) UpdateProduct(ctx context.Context, p product.Product) error {
-	result, err := r.DB.ExecContext(ctx,
+	result, err := db.Conn(ctx, r.DB).ExecContext(ctx,
 		"UPDATE products SET name = $1, description = $2, net_price_cents = $3, category = $4, status = $5 WHERE id = $6",
 		p.Name, p.Description, p.NetPriceCents, string(p.Category), string(p.Status), p.ID,
 	)
@@ -13,7 +13,7 @@ import (
 )
 
 func setup(t *testing.T) (Repository, func(t *testing.T)) {
-	db := dbpkg.OpenTestDatabase()
+	db := dbpkg.OpenTestDatabase(t)
 
 	_, err := db.Exec("DELETE FROM products")
 	if err != nil {
@@ -67,7 +67,7 @@ func TestGetActiveProducts(t *testing.T) {
 	_, _ = repo.CreateProduct(ctx, NewProduct("Product 1", product.ActiveStatus))
 	_, _ = repo.CreateProduct(ctx, NewProduct("Product 2", product.InactiveStatus))
 
-	products, err := repo.GetActiveProducts(ctx)
+	products, err := repo.GetActiveProducts(ctx, 0)
 
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
@@ -11,13 +11,14 @@ type Repository struct {
 }
 
 type dbproduct struct {
-	ID            int          `db:"id"`
-	Name          string       `db:"name"`
-	Description   string       `db:"description"`
-	NetPriceCents int          `db:"net_price_cents"`
-	Status        string       `db:"status"`
-	Category      string       `db:"category"`
-	CreatedAt     sql.NullTime `db:"created_at"`
+	ID            int           `db:"id"`
+	Name          string        `db:"name"`
+	Description   string        `db:"description"`
+	NetPriceCents int           `db:"net_price_cents"`
+	Status        string        `db:"status"`
+	Category      string        `db:"category"`
+	CreatedAt     sql.NullTime  `db:"created_at"`
+	PeriodID      sql.NullInt64 `db:"period_id"`
 }
 
 func (dp *dbproduct) toDomain() product.Product {
@@ -29,5 +30,14 @@ func (dp *dbproduct) toDomain() product.Product {
 		Status:        product.Status(dp.Status),
 		Category:      product.Category(dp.Category),
 		CreatedAt:     dp.CreatedAt.Time,
+		PeriodID:      nullIntToPtr(dp.PeriodID),
 	}
 }
+
+func nullIntToPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
@@ -0,0 +1,76 @@
+package role_repo
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/role"
+)
+
+// NewMock creates a new mock repository with the given roles and error.
+// Roles listed in usedRoles are reported as assigned to users.
+func NewMock(roles []role.Role, usedRoles []string, err error) *mockRepo {
+	roleMap := make(map[string]role.Role)
+	for _, r := range roles {
+		roleMap[r.Name] = r
+	}
+
+	return &mockRepo{
+		roles:     roleMap,
+		usedRoles: usedRoles,
+		err:       err,
+	}
+}
+
+type mockRepo struct {
+	roles     map[string]role.Role
+	usedRoles []string
+	err       error
+}
+
+func (m mockRepo) GetRole(ctx context.Context, name string) (role.Role, error) {
+	r, ok := m.roles[name]
+	if !ok {
+		return role.Role{}, db.ErrNotFound
+	}
+	return r, m.err
+}
+
+func (m mockRepo) GetAllRoles(ctx context.Context) ([]role.Role, error) {
+	result := []role.Role{}
+	for _, r := range m.roles {
+		result = append(result, r)
+	}
+	slices.SortFunc(result, func(a, b role.Role) int { return strings.Compare(a.Name, b.Name) })
+	return result, m.err
+}
+
+func (m mockRepo) CreateRole(ctx context.Context, r role.Role) error {
+	if _, ok := m.roles[r.Name]; ok {
+		return db.ErrAlreadyExists
+	}
+	m.roles[r.Name] = r
+	return m.err
+}
+
+func (m mockRepo) UpdateRole(ctx context.Context, r role.Role) error {
+	if _, ok := m.roles[r.Name]; !ok {
+		return db.ErrNotFound
+	}
+	m.roles[r.Name] = r
+	return m.err
+}
+
+func (m mockRepo) DeleteRole(ctx context.Context, name string) error {
+	if _, ok := m.roles[name]; !ok {
+		return db.ErrNotFound
+	}
+	delete(m.roles, name)
+	return m.err
+}
+
+func (m mockRepo) IsRoleInUse(ctx context.Context, name string) (bool, error) {
+	return slices.Contains(m.usedRoles, name), m.err
+}
@@ -0,0 +1,100 @@
+package role_repo
+
+import (
+	"context"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/role"
+)
+
+func (r Repository) GetRole(ctx context.Context, name string) (role.Role, error) {
+	var dr dbrole
+	err := db.Conn(ctx, r.DB).QueryRowContext(ctx, "SELECT name, permissions, created_at FROM roles WHERE name = $1", name).
+		Scan(&dr.Name, &dr.Permissions, &dr.CreatedAt)
+	if err != nil {
+		return role.Role{}, db.Error(err)
+	}
+
+	rl, err := dr.toDomain()
+	if err != nil {
+		return role.Role{}, db.ErrDatabase
+	}
+
+	return rl, nil
+}
+
+func (r Repository) GetAllRoles(ctx context.Context) ([]role.Role, error) {
+	rows, err := db.Conn(ctx, r.DB).QueryContext(ctx, "SELECT name, permissions, created_at FROM roles ORDER BY name ASC")
+	if err != nil {
+		return nil, db.Error(err)
+	}
+	defer db.Close(rows, "roles")
+
+	roles := []role.Role{}
+	for rows.Next() {
+		var dr dbrole
+		if err := rows.Scan(&dr.Name, &dr.Permissions, &dr.CreatedAt); err != nil {
+			return nil, db.Error(err)
+		}
+
+		rl, err := dr.toDomain()
+		if err != nil {
+			return nil, db.ErrDatabase
+		}
+		roles = append(roles, rl)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, db.Error(err)
+	}
+
+	return roles, nil
+}
+
+func (r Repository) CreateRole(ctx context.Context, rl role.Role) error {
+	permissions, err := permissionsToDB(rl.Permissions)
+	if err != nil {
+		return db.ErrDatabase
+	}
+
+	_, err = db.Conn(ctx, r.DB).ExecContext(ctx, "INSERT INTO roles (name, permissions, created_at) VALUES ($1, $2, $3)", rl.Name, permissions, rl.CreatedAt)
+	if err != nil {
+		return db.Error(err)
+	}
+
+	return nil
+}
+
+func (r Repository) UpdateRole(ctx context.Context, rl role.Role) error {
+	permissions, err := permissionsToDB(rl.Permissions)
+	if err != nil {
+		return db.ErrDatabase
+	}
+
+	result, err := db.Conn(ctx, r.DB).ExecContext(ctx, "UPDATE roles SET permissions = $1 WHERE name = $2", permissions, rl.Name)
+	if err != nil {
+		return db.Error(err)
+	}
+
+	return db.ResultError(result)
+}
+
+func (r Repository) DeleteRole(ctx context.Context, name string) error {
+	result, err := db.Conn(ctx, r.DB).ExecContext(ctx, "DELETE FROM roles WHERE name = $1", name)
+	if err != nil {
+		return db.Error(err)
+	}
+
+	return db.ResultError(result)
+}
+
+// IsRoleInUse reports whether any user has the role.
+func (r Repository) IsRoleInUse(ctx context.Context, name string) (bool, error) {
+	var inUse bool
+	err := db.Conn(ctx, r.DB).QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE role = $1)", name).Scan(&inUse)
+	if err != nil {
+		return false, db.Error(err)
+	}
+
+	return inUse, nil
+}
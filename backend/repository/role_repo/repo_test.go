@@ -0,0 +1,90 @@
+//go:build integration
+
+package role_repo
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	dbpkg "github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/role"
+)
+
+func setup(t *testing.T) (Repository, func(t *testing.T)) {
+	db := dbpkg.OpenTestDatabase(t)
+
+	clean := func(t *testing.T) {
+		for _, stmt := range []string{"DELETE FROM users WHERE role NOT IN ('admin', 'service')", "DELETE FROM roles WHERE name NOT IN ('admin', 'service')"} {
+			if _, err := db.Exec(stmt); err != nil {
+				t.Fatalf("Failed to clean roles: %v", err)
+			}
+		}
+	}
+	clean(t)
+
+	return Repository{DB: db}, func(t *testing.T) {
+		clean(t)
+		db.Close()
+	}
+}
+
+func TestCreateRole_Update_Delete(t *testing.T) {
+	repo, teardown := setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+	r, _ := role.NewRole("schichtleitung", []role.Permission{role.ViewTables, role.WriteOffItems})
+	if err := repo.CreateRole(ctx, r); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := repo.CreateRole(ctx, r); err != dbpkg.ErrAlreadyExists {
+		t.Fatalf("expected ErrAlreadyExists, got %v", err)
+	}
+
+	_ = r.SetPermissions([]role.Permission{role.ViewTables})
+	if err := repo.UpdateRole(ctx, r); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got, err := repo.GetRole(ctx, "schichtleitung")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !slices.Equal(got.Permissions, []role.Permission{role.ViewTables}) {
+		t.Fatalf("expected updated permissions, got %v", got.Permissions)
+	}
+
+	if err := repo.DeleteRole(ctx, "schichtleitung"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := repo.GetRole(ctx, "schichtleitung"); err != dbpkg.ErrNotFound {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestIsRoleInUse(t *testing.T) {
+	repo, teardown := setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+	r, _ := role.NewRole("kueche", []role.Permission{role.ViewTables})
+	if err := repo.CreateRole(ctx, r); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	inUse, err := repo.IsRoleInUse(ctx, "kueche")
+	if err != nil || inUse {
+		t.Fatalf("expected unused role, got %v (%v)", inUse, err)
+	}
+	_, err = repo.DB.Exec("INSERT INTO users (name, username, role, status, created_at) VALUES ('Koch', 'koch', 'kueche', 'active', now())")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	inUse, err = repo.IsRoleInUse(ctx, "kueche")
+	if err != nil || !inUse {
+		t.Fatalf("expected role in use, got %v (%v)", inUse, err)
+	}
+}
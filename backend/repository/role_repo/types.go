@@ -0,0 +1,39 @@
+package role_repo
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/nicograef/jotti/backend/domain/role"
+)
+
+// Repository implements role persistence layer using a SQL database.
+type Repository struct {
+	DB *sql.DB
+}
+
+type dbrole struct {
+	Name        string       `db:"name"`
+	Permissions []byte       `db:"permissions"`
+	CreatedAt   sql.NullTime `db:"created_at"`
+}
+
+func (dr *dbrole) toDomain() (role.Role, error) {
+	permissions := []role.Permission{}
+	if err := json.Unmarshal(dr.Permissions, &permissions); err != nil {
+		return role.Role{}, err
+	}
+
+	return role.Role{
+		Name:        dr.Name,
+		Permissions: permissions,
+		CreatedAt:   dr.CreatedAt.Time,
+	}, nil
+}
+
+func permissionsToDB(permissions []role.Permission) ([]byte, error) {
+	if permissions == nil {
+		permissions = []role.Permission{}
+	}
+	return json.Marshal(permissions)
+}
@@ -0,0 +1,109 @@
+package session_repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/session"
+)
+
+// NewMock creates a new mock repository with the given sessions and error.
+func NewMock(sessions []session.Session, err error) *mockRepo {
+	sessionMap := make(map[string]session.Session)
+	for _, s := range sessions {
+		sessionMap[s.ID] = s
+	}
+
+	return &mockRepo{
+		sessions: sessionMap,
+		used:     make(map[string]string),
+		err:      err,
+	}
+}
+
+type mockRepo struct {
+	sessions map[string]session.Session
+	used     map[string]string // used refresh token hash to session ID
+	err      error
+}
+
+func (m mockRepo) CreateSession(ctx context.Context, s session.Session) error {
+	m.sessions[s.ID] = s
+	return m.err
+}
+
+func (m mockRepo) GetSession(ctx context.Context, id string) (session.Session, error) {
+	s, ok := m.sessions[id]
+	if !ok {
+		return session.Session{}, db.ErrNotFound
+	}
+	return s, m.err
+}
+
+func (m mockRepo) GetSessionByRefreshTokenHash(ctx context.Context, hash string) (session.Session, error) {
+	for _, s := range m.sessions {
+		if s.RefreshTokenHash == hash {
+			return s, m.err
+		}
+	}
+	return session.Session{}, db.ErrNotFound
+}
+
+func (m mockRepo) UpdateSession(ctx context.Context, s session.Session, refreshTokenHash string) error {
+	stored, ok := m.sessions[s.ID]
+	if !ok || stored.RefreshTokenHash != refreshTokenHash || stored.RevokedAt != nil {
+		return db.ErrNotFound
+	}
+	m.sessions[s.ID] = s
+	m.used[refreshTokenHash] = s.ID
+	return m.err
+}
+
+func (m mockRepo) GetSessionByUsedRefreshTokenHash(ctx context.Context, hash string) (session.Session, error) {
+	id, ok := m.used[hash]
+	if !ok {
+		return session.Session{}, db.ErrNotFound
+	}
+	return m.sessions[id], m.err
+}
+
+func (m mockRepo) RevokeSession(ctx context.Context, id string) error {
+	if s, ok := m.sessions[id]; ok {
+		s.Revoke()
+		m.sessions[id] = s
+	}
+	return m.err
+}
+
+func (m mockRepo) RevokeUserSessions(ctx context.Context, userID int) error {
+	now := time.Now().UTC()
+	for id, s := range m.sessions {
+		if s.UserID == userID && s.RevokedAt == nil {
+			s.RevokedAt = &now
+			m.sessions[id] = s
+		}
+	}
+	return m.err
+}
+
+func (m mockRepo) TouchSession(ctx context.Context, id string, at time.Time) error {
+	s, ok := m.sessions[id]
+	if !ok {
+		return db.ErrNotFound
+	}
+	s.LastActiveAt = at
+	m.sessions[id] = s
+	return m.err
+}
+
+func (m mockRepo) RevokeDeviceSessions(ctx context.Context, deviceID int) error {
+	now := time.Now().UTC()
+	for id, s := range m.sessions {
+		if s.DeviceID != nil && *s.DeviceID == deviceID && s.RevokedAt == nil {
+			s.RevokedAt = &now
+			m.sessions[id] = s
+		}
+	}
+	return m.err
+}
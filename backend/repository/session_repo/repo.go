@@ -0,0 +1,108 @@
+package session_repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/session"
+)
+
+func (r Repository) CreateSession(ctx context.Context, s session.Session) error {
+	_, err := db.Conn(ctx, r.DB).ExecContext(ctx, "INSERT INTO sessions (id, user_id, device_id, refresh_token_hash, created_at, expires_at, last_active_at, revoked_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)", s.ID, s.UserID, s.DeviceID, s.RefreshTokenHash, s.CreatedAt, s.ExpiresAt, s.LastActiveAt, s.RevokedAt)
+	if err != nil {
+		return db.Error(err)
+	}
+
+	return nil
+}
+
+func (r Repository) GetSession(ctx context.Context, id string) (session.Session, error) {
+	var s dbsession
+	err := db.Conn(ctx, r.DB).QueryRowContext(ctx, "SELECT id, user_id, device_id, refresh_token_hash, created_at, expires_at, last_active_at, revoked_at FROM sessions WHERE id = $1", id).
+		Scan(&s.ID, &s.UserID, &s.DeviceID, &s.RefreshTokenHash, &s.CreatedAt, &s.ExpiresAt, &s.LastActiveAt, &s.RevokedAt)
+	if err != nil {
+		return session.Session{}, db.Error(err)
+	}
+
+	return s.toDomain(), nil
+}
+
+// GetSessionByRefreshTokenHash returns the session whose current refresh token has the given hash.
+func (r Repository) GetSessionByRefreshTokenHash(ctx context.Context, hash string) (session.Session, error) {
+	var s dbsession
+	err := db.Conn(ctx, r.DB).QueryRowContext(ctx, "SELECT id, user_id, device_id, refresh_token_hash, created_at, expires_at, last_active_at, revoked_at FROM sessions WHERE refresh_token_hash = $1", hash).
+		Scan(&s.ID, &s.UserID, &s.DeviceID, &s.RefreshTokenHash, &s.CreatedAt, &s.ExpiresAt, &s.LastActiveAt, &s.RevokedAt)
+	if err != nil {
+		return session.Session{}, db.Error(err)
+	}
+
+	return s.toDomain(), nil
+}
+
+// UpdateSession rotates the refresh token of the session only if the session is not revoked and its refresh token
+// still has the given hash, so of two parallel refreshes with the same token only one succeeds and a revocation in
+// between is kept. The replaced hash is remembered as used. Otherwise it returns db.ErrNotFound.
+func (r Repository) UpdateSession(ctx context.Context, s session.Session, refreshTokenHash string) error {
+	result, err := db.Conn(ctx, r.DB).ExecContext(ctx, `WITH rotated AS (
+		UPDATE sessions SET refresh_token_hash = $1, expires_at = $2 WHERE id = $3 AND refresh_token_hash = $4 AND revoked_at IS NULL RETURNING id
+	) INSERT INTO used_refresh_tokens (refresh_token_hash, session_id, used_at) SELECT $4, id, $5 FROM rotated`,
+		s.RefreshTokenHash, s.ExpiresAt, s.ID, refreshTokenHash, time.Now().UTC())
+	if err != nil {
+		return db.Error(err)
+	}
+
+	return db.ResultError(result)
+}
+
+// GetSessionByUsedRefreshTokenHash returns the session whose refresh token with the given hash was already replaced.
+func (r Repository) GetSessionByUsedRefreshTokenHash(ctx context.Context, hash string) (session.Session, error) {
+	var s dbsession
+	err := db.Conn(ctx, r.DB).QueryRowContext(ctx, "SELECT s.id, s.user_id, s.device_id, s.refresh_token_hash, s.created_at, s.expires_at, s.last_active_at, s.revoked_at FROM used_refresh_tokens u JOIN sessions s ON s.id = u.session_id WHERE u.refresh_token_hash = $1", hash).
+		Scan(&s.ID, &s.UserID, &s.DeviceID, &s.RefreshTokenHash, &s.CreatedAt, &s.ExpiresAt, &s.LastActiveAt, &s.RevokedAt)
+	if err != nil {
+		return session.Session{}, db.Error(err)
+	}
+
+	return s.toDomain(), nil
+}
+
+// RevokeSession revokes a session. Revoking an already revoked session keeps the original revocation time.
+func (r Repository) RevokeSession(ctx context.Context, id string) error {
+	_, err := db.Conn(ctx, r.DB).ExecContext(ctx, "UPDATE sessions SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL", time.Now().UTC(), id)
+	if err != nil {
+		return db.Error(err)
+	}
+
+	return nil
+}
+
+// RevokeUserSessions revokes all active sessions of a user. It is not an error if the user has no active sessions.
+func (r Repository) RevokeUserSessions(ctx context.Context, userID int) error {
+	_, err := db.Conn(ctx, r.DB).ExecContext(ctx, "UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL", time.Now().UTC(), userID)
+	if err != nil {
+		return db.Error(err)
+	}
+
+	return nil
+}
+
+// TouchSession records activity of a session at the given time.
+func (r Repository) TouchSession(ctx context.Context, id string, at time.Time) error {
+	result, err := db.Conn(ctx, r.DB).ExecContext(ctx, "UPDATE sessions SET last_active_at = $1 WHERE id = $2", at, id)
+	if err != nil {
+		return db.Error(err)
+	}
+
+	return db.ResultError(result)
+}
+
+// RevokeDeviceSessions revokes all active sessions on a device. It is not an error if the device has no active sessions.
+func (r Repository) RevokeDeviceSessions(ctx context.Context, deviceID int) error {
+	_, err := db.Conn(ctx, r.DB).ExecContext(ctx, "UPDATE sessions SET revoked_at = $1 WHERE device_id = $2 AND revoked_at IS NULL", time.Now().UTC(), deviceID)
+	if err != nil {
+		return db.Error(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+//go:build integration
+
+package session_repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	dbpkg "github.com/nicograef/jotti/backend/db"
+	"github.com/nicograef/jotti/backend/domain/session"
+)
+
+func setup(t *testing.T) (int, Repository, func(t *testing.T)) {
+	db := dbpkg.OpenTestDatabase(t)
+
+	clean := func(t *testing.T) {
+		for _, stmt := range []string{"DELETE FROM sessions", "DELETE FROM users WHERE username = 'sessionuser'"} {
+			if _, err := db.Exec(stmt); err != nil {
+				t.Fatalf("Failed to clean sessions: %v", err)
+			}
+		}
+	}
+	clean(t)
+
+	var userID int
+	err := db.QueryRow("INSERT INTO users (name, username, role, status, created_at) VALUES ('Session User', 'sessionuser', 'service', 'active', now()) RETURNING id").Scan(&userID)
+	if err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+
+	return userID, Repository{DB: db}, func(t *testing.T) {
+		clean(t)
+		db.Close()
+	}
+}
+
+func TestCreateSession_GetByRefreshTokenHash(t *testing.T) {
+	userID, repo, teardown := setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+	s, refreshToken, _ := session.NewSession(userID)
+	if err := repo.CreateSession(ctx, s); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got, err := repo.GetSessionByRefreshTokenHash(ctx, session.HashRefreshToken(refreshToken))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got.ID != s.ID || got.UserID != userID {
+		t.Fatalf("expected session %s of user %d, got %+v", s.ID, userID, got)
+	}
+
+	_, err = repo.GetSessionByRefreshTokenHash(ctx, session.HashRefreshToken("unknown"))
+	if err != dbpkg.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestUpdateSession_Rotate(t *testing.T) {
+	userID, repo, teardown := setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+	s, oldToken, _ := session.NewSession(userID)
+	_ = repo.CreateSession(ctx, s)
+
+	oldHash := s.RefreshTokenHash
+	newToken, _ := s.Rotate()
+	if err := repo.UpdateSession(ctx, s, oldHash); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err := repo.GetSessionByRefreshTokenHash(ctx, session.HashRefreshToken(oldToken)); err != dbpkg.ErrNotFound {
+		t.Fatalf("expected old refresh token to be gone, got %v", err)
+	}
+	if _, err := repo.GetSessionByRefreshTokenHash(ctx, session.HashRefreshToken(newToken)); err != nil {
+		t.Fatalf("expected new refresh token to be found, got %v", err)
+	}
+}
+
+func TestUpdateSession_RefreshTokenReused(t *testing.T) {
+	userID, repo, teardown := setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+	s, _, _ := session.NewSession(userID)
+	_ = repo.CreateSession(ctx, s)
+	oldHash := s.RefreshTokenHash
+
+	first, second := s, s
+	_, _ = first.Rotate()
+	_, _ = second.Rotate()
+	if err := repo.UpdateSession(ctx, first, oldHash); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := repo.UpdateSession(ctx, second, oldHash); err != dbpkg.ErrNotFound {
+		t.Fatalf("expected second rotation with the same token to fail, got %v", err)
+	}
+
+	if err := repo.RevokeSession(ctx, s.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	got, err := repo.GetSession(ctx, s.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got.RevokedAt == nil || got.RefreshTokenHash != first.RefreshTokenHash {
+		t.Fatalf("expected revoked session with the first rotated token, got %+v", got)
+	}
+}
+
+func TestUpdateSession_UsedRefreshToken(t *testing.T) {
+	userID, repo, teardown := setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+	s, oldToken, _ := session.NewSession(userID)
+	_ = repo.CreateSession(ctx, s)
+	oldHash := s.RefreshTokenHash
+	_, _ = s.Rotate()
+	if err := repo.UpdateSession(ctx, s, oldHash); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got, err := repo.GetSessionByUsedRefreshTokenHash(ctx, session.HashRefreshToken(oldToken))
+	if err != nil || got.ID != s.ID {
+		t.Fatalf("expected session %s of the used refresh token, got %+v (%v)", s.ID, got, err)
+	}
+	if _, err := repo.GetSessionByUsedRefreshTokenHash(ctx, s.RefreshTokenHash); err != dbpkg.ErrNotFound {
+		t.Fatalf("expected current refresh token not to be used, got %v", err)
+	}
+}
+
+func TestUpdateSession_KeepsRevocation(t *testing.T) {
+	userID, repo, teardown := setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+	s, _, _ := session.NewSession(userID)
+	_ = repo.CreateSession(ctx, s)
+	oldHash := s.RefreshTokenHash
+
+	// the session is revoked between reading it and rotating its refresh token
+	if err := repo.RevokeUserSessions(ctx, userID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_, _ = s.Rotate()
+	if err := repo.UpdateSession(ctx, s, oldHash); err != dbpkg.ErrNotFound {
+		t.Fatalf("expected rotation of revoked session to fail, got %v", err)
+	}
+
+	got, err := repo.GetSession(ctx, s.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got.RevokedAt == nil || got.RefreshTokenHash != oldHash {
+		t.Fatalf("expected revoked session with the old refresh token, got %+v", got)
+	}
+}
+
+func TestRevokeUserSessions(t *testing.T) {
+	userID, repo, teardown := setup(t)
+	defer teardown(t)
+
+	ctx := context.Background()
+	s1, _, _ := session.NewSession(userID)
+	s2, _, _ := session.NewSession(userID)
+	_ = repo.CreateSession(ctx, s1)
+	_ = repo.CreateSession(ctx, s2)
+
+	if err := repo.RevokeUserSessions(ctx, userID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for _, id := range []string{s1.ID, s2.ID} {
+		got, err := repo.GetSession(ctx, id)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got.IsActive(time.Now()) {
+			t.Errorf("expected session %s to be revoked", id)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package session_repo
+
+import (
+	"database/sql"
+
+	"github.com/nicograef/jotti/backend/domain/session"
+)
+
+// Repository implements session persistence layer using a SQL database.
+type Repository struct {
+	DB *sql.DB
+}
+
+type dbsession struct {
+	ID               string        `db:"id"`
+	UserID           int           `db:"user_id"`
+	DeviceID         sql.NullInt64 `db:"device_id"`
+	RefreshTokenHash string        `db:"refresh_token_hash"`
+	CreatedAt        sql.NullTime  `db:"created_at"`
+	ExpiresAt        sql.NullTime  `db:"expires_at"`
+	LastActiveAt     sql.NullTime  `db:"last_active_at"`
+	RevokedAt        sql.NullTime  `db:"revoked_at"`
+}
+
+func (ds *dbsession) toDomain() session.Session {
+	s := session.Session{
+		ID:               ds.ID,
+		UserID:           ds.UserID,
+		RefreshTokenHash: ds.RefreshTokenHash,
+		CreatedAt:        ds.CreatedAt.Time,
+		ExpiresAt:        ds.ExpiresAt.Time,
+		LastActiveAt:     ds.LastActiveAt.Time,
+	}
+
+	if ds.DeviceID.Valid {
+		deviceID := int(ds.DeviceID.Int64)
+		s.DeviceID = &deviceID
+	}
+
+	if ds.RevokedAt.Valid {
+		revokedAt := ds.RevokedAt.Time
+		s.RevokedAt = &revokedAt
+	}
+
+	return s
+}
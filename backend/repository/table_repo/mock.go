@@ -40,10 +40,10 @@ func (m mockRepo) GetAllTables(ctx context.Context) ([]table.Table, error) {
 	return result, m.err
 }
 
-func (m mockRepo) GetActiveTables(ctx context.Context) ([]table.Table, error) {
+func (m mockRepo) GetActiveTables(ctx context.Context, periodID int) ([]table.Table, error) {
 	var result []table.Table
 	for _, t := range m.tables {
-		if t.Status == table.ActiveStatus {
+		if t.Status == table.ActiveStatus && (periodID == 0 || t.PeriodID == nil || *t.PeriodID == periodID) {
 			result = append(result, t)
 		}
 	}
@@ -9,8 +9,8 @@ import (
 
 func (r Repository) GetTable(ctx context.Context, id int) (table.Table, error) {
 	var dbTable dbtable
-	err := r.DB.QueryRowContext(ctx, "SELECT id, name, status, created_at FROM tables WHERE id = $1 AND status != 'deleted'", id).
-		Scan(&dbTable.ID, &dbTable.Name, &dbTable.Status, &dbTable.CreatedAt)
+	err := db.Conn(ctx, r.DB).QueryRowContext(ctx, "SELECT id, name, status, created_at, period_id FROM tables WHERE id = $1 AND status != 'deleted'", id).
+		Scan(&dbTable.ID, &dbTable.Name, &dbTable.Status, &dbTable.CreatedAt, &dbTable.PeriodID)
 	if err != nil {
 		return table.Table{}, db.Error(err)
 	}
@@ -19,7 +19,7 @@ func (r Repository) GetTable(ctx context.Context, id int) (table.Table, error) {
 }
 
 func (r Repository) GetAllTables(ctx context.Context) ([]table.Table, error) {
-	rows, err := r.DB.QueryContext(ctx, "SELECT id, name, status, created_at FROM tables WHERE status != 'deleted' ORDER BY id ASC")
+	rows, err := db.Conn(ctx, r.DB).QueryContext(ctx, "SELECT id, name, status, created_at, period_id FROM tables WHERE status != 'deleted' ORDER BY id ASC")
 	if err != nil {
 		return nil, db.Error(err)
 	}
@@ -28,7 +28,7 @@ func (r Repository) GetAllTables(ctx context.Context) ([]table.Table, error) {
 	tables := []table.Table{}
 	for rows.Next() {
 		var dbTable dbtable
-		if err := rows.Scan(&dbTable.ID, &dbTable.Name, &dbTable.Status, &dbTable.CreatedAt); err != nil {
+		if err := rows.Scan(&dbTable.ID, &dbTable.Name, &dbTable.Status, &dbTable.CreatedAt, &dbTable.PeriodID); err != nil {
 			return nil, db.Error(err)
 		}
 
@@ -42,8 +42,10 @@ func (r Repository) GetAllTables(ctx context.Context) ([]table.Table, error) {
 	return tables, nil
 }
 
-func (r Repository) GetActiveTables(ctx context.Context) ([]table.Table, error) {
-	rows, err := r.DB.QueryContext(ctx, "SELECT id, name, status, created_at FROM tables WHERE status = 'active' ORDER BY id ASC")
+// GetActiveTables returns all active tables of the given period and all tables without a period.
+// If periodID is 0, active tables of all periods are returned.
+func (r Repository) GetActiveTables(ctx context.Context, periodID int) ([]table.Table, error) {
+	rows, err := db.Conn(ctx, r.DB).QueryContext(ctx, "SELECT id, name, status, created_at, period_id FROM tables WHERE status = 'active' AND ($1 = 0 OR period_id IS NULL OR period_id = $1) ORDER BY id ASC", periodID)
 	if err != nil {
 		return nil, db.Error(err)
 	}
@@ -52,7 +54,7 @@ func (r Repository) GetActiveTables(ctx context.Context) ([]table.Table, error)
 	tables := []table.Table{}
 	for rows.Next() {
 		var dbTable dbtable
-		if err := rows.Scan(&dbTable.ID, &dbTable.Name, &dbTable.Status, &dbTable.CreatedAt); err != nil {
+		if err := rows.Scan(&dbTable.ID, &dbTable.Name, &dbTable.Status, &dbTable.CreatedAt, &dbTable.PeriodID); err != nil {
 			return nil, db.Error(err)
 		}
 
@@ -68,7 +70,7 @@ func (r Repository) GetActiveTables(ctx context.Context) ([]table.Table, error)
 
 func (r Repository) CreateTable(ctx context.Context, t table.Table) (int, error) {
 	var id int
-	err := r.DB.QueryRowContext(ctx, "INSERT INTO tables (name, status, created_at) VALUES ($1, $2, $3) RETURNING id", t.Name, t.Status, t.CreatedAt).Scan(&id)
+	err := db.Conn(ctx, r.DB).QueryRowContext(ctx, "INSERT INTO tables (name, status, created_at, period_id) VALUES ($1, $2, $3, $4) RETURNING id", t.Name, t.Status, t.CreatedAt, t.PeriodID).Scan(&id)
 	if err != nil {
 		return 0, db.Error(err)
 	}
@@ -77,7 +79,7 @@ func (r Repository) CreateTable(ctx context.Context, t table.Table) (int, error)
 }
 
 func (r Repository) UpdateTable(ctx context.Context, t table.Table) error {
-	result, err := r.DB.ExecContext(ctx, "UPDATE tables SET name = $1, status = $2 WHERE id = $3", t.Name, t.Status, t.ID)
+	result, err := db.Conn(ctx, r.DB).ExecContext(ctx, "UPDATE tables SET name = $1, status = $2 WHERE id = $3", t.Name, t.Status, t.ID)
 	if err != nil {
 		return db.Error(err)
 	}
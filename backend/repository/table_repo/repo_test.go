@@ -13,7 +13,7 @@ import (
 )
 
 func setup(t *testing.T) (Repository, func(t *testing.T)) {
-	db := dbpkg.OpenTestDatabase()
+	db := dbpkg.OpenTestDatabase(t)
 
 	_, err := db.Exec("DELETE FROM tables")
 	if err != nil {
@@ -55,7 +55,7 @@ func TestGetActiveTablesDB(t *testing.T) {
 	_, _ = repo.CreateTable(ctx, table.Table{Name: "GetAll Test 1", Status: table.ActiveStatus, CreatedAt: time.Now()})
 	_, _ = repo.CreateTable(ctx, table.Table{Name: "GetAll Test 2", Status: table.InactiveStatus, CreatedAt: time.Now()})
 
-	tables, err := repo.GetActiveTables(ctx)
+	tables, err := repo.GetActiveTables(ctx, 0)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
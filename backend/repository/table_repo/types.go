@@ -12,10 +12,11 @@ type Repository struct {
 }
 
 type dbtable struct {
-	ID        int          `db:"id"`
-	Name      string       `db:"name"`
-	Status    string       `db:"status"`
-	CreatedAt sql.NullTime `db:"created_at"`
+	ID        int           `db:"id"`
+	Name      string        `db:"name"`
+	Status    string        `db:"status"`
+	CreatedAt sql.NullTime  `db:"created_at"`
+	PeriodID  sql.NullInt64 `db:"period_id"`
 }
 
 func (dt *dbtable) toDomain() table.Table {
@@ -24,5 +25,14 @@ func (dt *dbtable) toDomain() table.Table {
 		Name:      dt.Name,
 		Status:    table.Status(dt.Status),
 		CreatedAt: dt.CreatedAt.Time,
+		PeriodID:  nullIntToPtr(dt.PeriodID),
 	}
 }
+
+func nullIntToPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
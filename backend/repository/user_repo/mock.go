@@ -2,6 +2,8 @@ package user_repo
 
 import (
 	"context"
+	"slices"
+	"strings"
 
 	"github.com/nicograef/jotti/backend/domain/user"
 )
@@ -48,6 +50,17 @@ func (m mockRepo) GetAllUsers(ctx context.Context) ([]user.User, error) {
 	return users, m.err
 }
 
+func (m mockRepo) GetUsersWithPin(ctx context.Context) ([]user.User, error) {
+	users := []user.User{}
+	for _, u := range m.user {
+		if u.Status == user.ActiveStatus && u.PinHash != "" {
+			users = append(users, u)
+		}
+	}
+	slices.SortFunc(users, func(a, b user.User) int { return strings.Compare(a.Name, b.Name) })
+	return users, m.err
+}
+
 func (m mockRepo) CreateUser(ctx context.Context, t user.User) (int, error) {
 	newID := len(m.user) + 1
 	t.ID = newID
@@ -59,3 +72,10 @@ func (m mockRepo) UpdateUser(ctx context.Context, t user.User) error {
 	m.user[t.ID] = t
 	return m.err
 }
+
+func (m mockRepo) CountOnetimePasswordAttempt(ctx context.Context, id int) (int, error) {
+	u := m.user[id]
+	u.OnetimePasswordFailedAttempts++
+	m.user[id] = u
+	return u.OnetimePasswordFailedAttempts, m.err
+}
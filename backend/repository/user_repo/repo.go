@@ -8,10 +8,10 @@ import (
 )
 
 func (r Repository) GetUser(ctx context.Context, id int) (user.User, error) {
-	row := r.DB.QueryRowContext(ctx, "SELECT id, name, username, role, status, password_hash, onetime_password_hash, created_at FROM users WHERE id = $1 AND status != 'deleted'", id)
+	row := db.Conn(ctx, r.DB).QueryRowContext(ctx, "SELECT id, name, username, role, status, password_hash, onetime_password_hash, onetime_password_expires_at, onetime_password_failed_attempts, pin_hash, password_change_required, created_at FROM users WHERE id = $1 AND status != 'deleted'", id)
 
 	var u dbuser
-	err := row.Scan(&u.ID, &u.Name, &u.Username, &u.Role, &u.Status, &u.PasswordHash, &u.OnetimePasswordHash, &u.CreatedAt)
+	err := row.Scan(&u.ID, &u.Name, &u.Username, &u.Role, &u.Status, &u.PasswordHash, &u.OnetimePasswordHash, &u.OnetimePasswordExpiresAt, &u.OnetimePasswordFailedAttempts, &u.PinHash, &u.PasswordChangeRequired, &u.CreatedAt)
 
 	if err != nil {
 		return user.User{}, db.Error(err)
@@ -21,10 +21,10 @@ func (r Repository) GetUser(ctx context.Context, id int) (user.User, error) {
 }
 
 func (r Repository) GetUserByUsername(ctx context.Context, username string) (user.User, error) {
-	row := r.DB.QueryRowContext(ctx, "SELECT id, name, username, role, status, password_hash, onetime_password_hash, created_at FROM users WHERE username = $1 AND status != 'deleted'", username)
+	row := db.Conn(ctx, r.DB).QueryRowContext(ctx, "SELECT id, name, username, role, status, password_hash, onetime_password_hash, onetime_password_expires_at, onetime_password_failed_attempts, pin_hash, password_change_required, created_at FROM users WHERE username = $1 AND status != 'deleted'", username)
 
 	var u dbuser
-	err := row.Scan(&u.ID, &u.Name, &u.Username, &u.Role, &u.Status, &u.PasswordHash, &u.OnetimePasswordHash, &u.CreatedAt)
+	err := row.Scan(&u.ID, &u.Name, &u.Username, &u.Role, &u.Status, &u.PasswordHash, &u.OnetimePasswordHash, &u.OnetimePasswordExpiresAt, &u.OnetimePasswordFailedAttempts, &u.PinHash, &u.PasswordChangeRequired, &u.CreatedAt)
 
 	if err != nil {
 		return user.User{}, db.Error(err)
@@ -34,7 +34,32 @@ func (r Repository) GetUserByUsername(ctx context.Context, username string) (use
 }
 
 func (r Repository) GetAllUsers(ctx context.Context) ([]user.User, error) {
-	rows, err := r.DB.QueryContext(ctx, "SELECT id, name, username, role, status, created_at FROM users WHERE status != 'deleted' ORDER BY id ASC")
+	rows, err := db.Conn(ctx, r.DB).QueryContext(ctx, "SELECT id, name, username, role, status, onetime_password_hash, onetime_password_expires_at, onetime_password_failed_attempts, password_change_required, created_at FROM users WHERE status != 'deleted' ORDER BY id ASC")
+	if err != nil {
+		return nil, db.Error(err)
+	}
+	defer db.Close(rows, "users")
+
+	users := []user.User{}
+	for rows.Next() {
+		var u dbuser
+		err := rows.Scan(&u.ID, &u.Name, &u.Username, &u.Role, &u.Status, &u.OnetimePasswordHash, &u.OnetimePasswordExpiresAt, &u.OnetimePasswordFailedAttempts, &u.PasswordChangeRequired, &u.CreatedAt)
+		if err != nil {
+			return nil, db.Error(err)
+		}
+		users = append(users, u.toDomain())
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, db.Error(err)
+	}
+
+	return users, nil
+}
+
+// GetUsersWithPin returns all active users that have a PIN set, ordered by name.
+func (r Repository) GetUsersWithPin(ctx context.Context) ([]user.User, error) {
+	rows, err := db.Conn(ctx, r.DB).QueryContext(ctx, "SELECT id, name, username, role, status, created_at FROM users WHERE status = 'active' AND pin_hash IS NOT NULL ORDER BY name ASC")
 	if err != nil {
 		return nil, db.Error(err)
 	}
@@ -59,9 +84,9 @@ func (r Repository) GetAllUsers(ctx context.Context) ([]user.User, error) {
 
 func (r Repository) CreateUser(ctx context.Context, u user.User) (int, error) {
 	var userID int
-	err := r.DB.QueryRowContext(ctx,
-		"INSERT INTO users (name, username, role, status, password_hash, onetime_password_hash, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
-		u.Name, u.Username, string(u.Role), string(u.Status), u.PasswordHash, u.OnetimePasswordHash, u.CreatedAt,
+	err := db.Conn(ctx, r.DB).QueryRowContext(ctx,
+		"INSERT INTO users (name, username, role, status, password_hash, onetime_password_hash, onetime_password_expires_at, onetime_password_failed_attempts, pin_hash, password_change_required, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10, $11) RETURNING id",
+		u.Name, u.Username, string(u.Role), string(u.Status), u.PasswordHash, u.OnetimePasswordHash, u.OnetimePasswordExpiresAt, u.OnetimePasswordFailedAttempts, u.PinHash, u.PasswordChangeRequired, u.CreatedAt,
 	).Scan(&userID)
 
 	if err != nil {
@@ -72,9 +97,9 @@ func (r Repository) CreateUser(ctx context.Context, u user.User) (int, error) {
 }
 
 func (r Repository) UpdateUser(ctx context.Context, u user.User) error {
-	result, err := r.DB.ExecContext(ctx,
-		"UPDATE users SET name = $1, username = $2, role = $3, status = $4, password_hash = $5, onetime_password_hash = $6 WHERE id = $7",
-		u.Name, u.Username, string(u.Role), string(u.Status), u.PasswordHash, u.OnetimePasswordHash, u.ID,
+	result, err := db.Conn(ctx, r.DB).ExecContext(ctx,
+		"UPDATE users SET name = $1, username = $2, role = $3, status = $4, password_hash = $5, onetime_password_hash = $6, onetime_password_expires_at = $7, onetime_password_failed_attempts = $8, pin_hash = NULLIF($9, ''), password_change_required = $10 WHERE id = $11",
+		u.Name, u.Username, string(u.Role), string(u.Status), u.PasswordHash, u.OnetimePasswordHash, u.OnetimePasswordExpiresAt, u.OnetimePasswordFailedAttempts, u.PinHash, u.PasswordChangeRequired, u.ID,
 	)
 	if err != nil {
 		return db.Error(err)
@@ -82,3 +107,19 @@ func (r Repository) UpdateUser(ctx context.Context, u user.User) error {
 
 	return db.ResultError(result)
 }
+
+// CountOnetimePasswordAttempt atomically increments the one-time password attempts of a user and returns the new
+// count, so parallel attempts each get their own count.
+func (r Repository) CountOnetimePasswordAttempt(ctx context.Context, id int) (int, error) {
+	row := db.Conn(ctx, r.DB).QueryRowContext(ctx,
+		"UPDATE users SET onetime_password_failed_attempts = onetime_password_failed_attempts + 1 WHERE id = $1 AND status != 'deleted' RETURNING onetime_password_failed_attempts",
+		id,
+	)
+
+	var attempts int
+	if err := row.Scan(&attempts); err != nil {
+		return 0, db.Error(err)
+	}
+
+	return attempts, nil
+}
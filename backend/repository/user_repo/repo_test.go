@@ -5,6 +5,7 @@ package user_repo
 import (
 	"context"
 	"testing"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	dbpkg "github.com/nicograef/jotti/backend/db"
@@ -28,7 +29,7 @@ func createTestUser(t *testing.T, repo Repository) (user.User, error) {
 }
 
 func setup(t *testing.T) (user.User, Repository, func(t *testing.T)) {
-	db := dbpkg.OpenTestDatabase()
+	db := dbpkg.OpenTestDatabase(t)
 
 	_, err := db.Exec("DELETE FROM users")
 	if err != nil {
@@ -73,6 +74,9 @@ func TestGetUser(t *testing.T) {
 	if retrievedUser.Role != user.Role {
 		t.Fatalf("expected user role %s, got %s", user.Role, retrievedUser.Role)
 	}
+	if retrievedUser.OnetimePasswordExpiresAt == nil || !retrievedUser.OnetimePasswordExpiresAt.Equal(user.OnetimePasswordExpiresAt.Truncate(time.Microsecond)) {
+		t.Fatalf("expected one-time password expiry %v, got %v", user.OnetimePasswordExpiresAt, retrievedUser.OnetimePasswordExpiresAt)
+	}
 }
 
 func TestGetUser_Error(t *testing.T) {
@@ -177,3 +181,49 @@ func TestUpdateUserInDB_Error(t *testing.T) {
 		t.Fatalf("expected user not found error, got %v", err)
 	}
 }
+
+func TestCountOnetimePasswordAttempt(t *testing.T) {
+	u, repo, teardown := setup(t)
+	defer teardown(t)
+
+	for i := 1; i <= 2; i++ {
+		attempts, err := repo.CountOnetimePasswordAttempt(context.Background(), u.ID)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if attempts != i {
+			t.Fatalf("expected %d attempts, got %d", i, attempts)
+		}
+	}
+
+	if _, err := repo.CountOnetimePasswordAttempt(context.Background(), 99999); err != dbpkg.ErrNotFound {
+		t.Fatalf("expected user not found error, got %v", err)
+	}
+}
+
+func TestGetUsersWithPin(t *testing.T) {
+	u, repo, teardown := setup(t)
+	defer teardown(t)
+
+	users, err := repo.GetUsersWithPin(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(users) != 0 {
+		t.Fatalf("expected no users with pin, got %d", len(users))
+	}
+
+	u.Activate()
+	u.PinHash = "pinhash"
+	if err := repo.UpdateUser(context.Background(), u); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	users, err = repo.GetUsersWithPin(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(users) != 1 || users[0].ID != u.ID {
+		t.Fatalf("expected user %d with pin, got %+v", u.ID, users)
+	}
+}
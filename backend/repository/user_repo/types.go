@@ -11,25 +11,39 @@ type Repository struct {
 }
 
 type dbuser struct {
-	ID                  int            `db:"id"`
-	Name                string         `db:"name"`
-	Username            string         `db:"username"`
-	Role                string         `db:"role"`
-	Status              string         `db:"status"`
-	PasswordHash        sql.NullString `db:"password_hash"`
-	OnetimePasswordHash sql.NullString `db:"onetime_password_hash"`
-	CreatedAt           sql.NullTime   `db:"created_at"`
+	ID                            int            `db:"id"`
+	Name                          string         `db:"name"`
+	Username                      string         `db:"username"`
+	Role                          string         `db:"role"`
+	Status                        string         `db:"status"`
+	PasswordHash                  sql.NullString `db:"password_hash"`
+	OnetimePasswordHash           sql.NullString `db:"onetime_password_hash"`
+	OnetimePasswordExpiresAt      sql.NullTime   `db:"onetime_password_expires_at"`
+	OnetimePasswordFailedAttempts int            `db:"onetime_password_failed_attempts"`
+	PinHash                       sql.NullString `db:"pin_hash"`
+	PasswordChangeRequired        bool           `db:"password_change_required"`
+	CreatedAt                     sql.NullTime   `db:"created_at"`
 }
 
 func (dp *dbuser) toDomain() user.User {
-	return user.User{
-		ID:                  dp.ID,
-		Name:                dp.Name,
-		Username:            dp.Username,
-		Role:                user.Role(dp.Role),
-		Status:              user.Status(dp.Status),
-		PasswordHash:        dp.PasswordHash.String,
-		OnetimePasswordHash: dp.OnetimePasswordHash.String,
-		CreatedAt:           dp.CreatedAt.Time,
+	u := user.User{
+		ID:                            dp.ID,
+		Name:                          dp.Name,
+		Username:                      dp.Username,
+		Role:                          user.Role(dp.Role),
+		Status:                        user.Status(dp.Status),
+		PasswordHash:                  dp.PasswordHash.String,
+		OnetimePasswordHash:           dp.OnetimePasswordHash.String,
+		OnetimePasswordFailedAttempts: dp.OnetimePasswordFailedAttempts,
+		PinHash:                       dp.PinHash.String,
+		PasswordChangeRequired:        dp.PasswordChangeRequired,
+		CreatedAt:                     dp.CreatedAt.Time,
 	}
+
+	if dp.OnetimePasswordExpiresAt.Valid {
+		expiresAt := dp.OnetimePasswordExpiresAt.Time
+		u.OnetimePasswordExpiresAt = &expiresAt
+	}
+
+	return u
 }
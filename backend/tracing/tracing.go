@@ -0,0 +1,72 @@
+// Package tracing sets up OpenTelemetry tracing and starts the spans of the backend.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters of OTEL_TRACES_EXPORTER.
+const (
+	// OTLPExporter sends spans via OTLP/HTTP to OTEL_EXPORTER_OTLP_ENDPOINT (default http://localhost:4318).
+	OTLPExporter = "otlp"
+	// ConsoleExporter writes spans as JSON to stdout.
+	ConsoleExporter = "console"
+	// NoExporter disables tracing. Spans are still started, but not recorded.
+	NoExporter = "none"
+)
+
+const instrumentationName = "github.com/nicograef/jotti/backend"
+
+// Setup installs the tracer provider for the exporter and the W3C trace context propagator.
+// The returned function flushes and stops the exporter.
+func Setup(ctx context.Context, exporter, serviceName string) (func(ctx context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case OTLPExporter:
+		spanExporter, err = otlptracehttp.New(ctx)
+	case ConsoleExporter:
+		spanExporter, err = stdouttrace.New()
+	case NoExporter, "":
+		return func(ctx context.Context) error { return nil }, nil
+	default:
+		return nil, fmt.Errorf("unknown traces exporter %q, use otlp, console or none", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s exporter: %w", exporter, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span that is a child of the span in ctx (if any).
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// TraceID returns the ID of the trace of the span in ctx, "" if there is no sampled span.
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
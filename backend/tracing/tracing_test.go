@@ -0,0 +1,42 @@
+//go:build unit
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSetup(t *testing.T) {
+	for _, exporter := range []string{NoExporter, ConsoleExporter} {
+		shutdown, err := Setup(context.Background(), exporter, "jotti-test")
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", exporter, err)
+		}
+		if err := shutdown(context.Background()); err != nil {
+			t.Errorf("Expected no error shutting down %s, got %v", exporter, err)
+		}
+	}
+
+	if _, err := Setup(context.Background(), "zipkin", "jotti-test"); err == nil {
+		t.Error("Expected error for unknown exporter")
+	}
+}
+
+func TestTraceID(t *testing.T) {
+	if id := TraceID(context.Background()); id != "" {
+		t.Errorf("Expected no trace ID without span, got %s", id)
+	}
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled,
+	}))
+
+	if id := TraceID(ctx); id != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected trace ID of span, got %s", id)
+	}
+}
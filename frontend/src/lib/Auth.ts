@@ -6,7 +6,8 @@ const JottiTokenSchema = z.object({
   exp: z.int().min(0),
   iat: z.int().min(0),
   sub: z.number().int().min(1),
-  role: z.enum(['admin', 'service']),
+  role: z.string().min(1),
+  perms: z.array(z.string()),
 })
 type JottiToken = z.infer<typeof JottiTokenSchema>
 
@@ -45,8 +46,13 @@ class Auth {
     return this.token?.role === 'admin'
   }
 
+  /** Every role other than admin uses the service app; the backend checks the permissions per endpoint. */
   public get isService(): boolean {
-    return this.token?.role === 'service'
+    return !!this.token && this.token.role !== 'admin'
+  }
+
+  public hasPermission(permission: string): boolean {
+    return this.token?.perms.includes(permission) ?? false
   }
 
   public logout(): void {
@@ -2,21 +2,35 @@ import { z } from 'zod'
 
 import { AuthSingleton } from './Auth'
 
+const FieldErrorSchema = z.object({
+  field: z.string(),
+  message: z.string(),
+})
+
+/** An invalid field of a request, e.g. `{ field: 'products[0].quantity', message: 'Quantity must be at least 1' }`. */
+export type FieldError = z.infer<typeof FieldErrorSchema>
+
 const ErrorResponseSchema = z.object({
   code: z.string(),
-  details: z.string().optional(),
+  details: z.union([z.string(), z.array(FieldErrorSchema)]).optional(),
 })
 
 export class BackendError extends Error {
   public readonly status: number
   public readonly code: string
+  /** The invalid fields of a validation error (`invalid_*_data`), empty for other errors. */
+  public readonly fieldErrors: FieldError[]
 
-  constructor(status: number, code: string, details?: string) {
+  constructor(status: number, code: string, details?: string | FieldError[]) {
+    const message = Array.isArray(details)
+      ? details.map((e) => `${e.field}: ${e.message}`).join(', ')
+      : details
     super(
-      details ? `BackendError: ${code} - ${details}` : `BackendError: ${code}`,
+      message ? `BackendError: ${code} - ${message}` : `BackendError: ${code}`,
     )
     this.status = status
     this.code = code
+    this.fieldErrors = Array.isArray(details) ? details : []
     Object.setPrototypeOf(this, BackendError.prototype)
   }
 }
@@ -28,6 +42,18 @@ export class ResponseBodyError extends Error {
   }
 }
 
+/**
+ * Creates a W3C trace context header, so the spans of the backend belong to a trace started by the client.
+ * https://www.w3.org/TR/trace-context/#traceparent-header
+ */
+function newTraceparent(): string {
+  const hex = (length: number) =>
+    Array.from(crypto.getRandomValues(new Uint8Array(length)), (b) =>
+      b.toString(16).padStart(2, '0'),
+    ).join('')
+  return `00-${hex(16)}-${hex(8)}-01`
+}
+
 interface TokenGetter {
   getToken(): string | null
 }
@@ -51,6 +77,7 @@ class Backend {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
+        traceparent: newTraceparent(),
         ...(token ? { Authorization: `Bearer ${token}` } : {}),
       },
       body: JSON.stringify(body),
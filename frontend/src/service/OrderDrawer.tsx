@@ -29,6 +29,8 @@ interface OrderDrawerProps {
 export function OrderDrawer(props: OrderDrawerProps) {
   const [open, setOpen] = useState(false)
   const [loading, setLoading] = useState(false)
+  // stays the same while the drawer is open, so a resubmit after a failed request is not booked twice
+  const [orderId, setOrderId] = useState(() => crypto.randomUUID())
   const orderedProducts = orderProducts(props.products, props.quantities)
   const totalPrice = calculateTotalPrice(orderedProducts)
   const noProductsSelected = orderedProducts.length === 0
@@ -39,6 +41,7 @@ export function OrderDrawer(props: OrderDrawerProps) {
     try {
       await props.backend.placeTableOrder({
         tableId: props.table.id,
+        orderId,
         products: orderedProducts,
       })
       props.orderPlaced()
@@ -54,6 +57,7 @@ export function OrderDrawer(props: OrderDrawerProps) {
     if (noProductsSelected) {
       setOpen(false)
     } else {
+      if (isOpen && !open) setOrderId(crypto.randomUUID())
       setOpen(isOpen)
     }
   }
@@ -29,6 +29,8 @@ interface PaymentDrawerProps {
 export function PaymentDrawer(props: PaymentDrawerProps) {
   const [open, setOpen] = useState(false)
   const [loading, setLoading] = useState(false)
+  // stays the same while the drawer is open, so a resubmit after a failed request is not booked twice
+  const [paymentId, setPaymentId] = useState(() => crypto.randomUUID())
   const productsToPay = buildPaymentProducts(
     props.unpaidProducts,
     props.quantities,
@@ -42,6 +44,7 @@ export function PaymentDrawer(props: PaymentDrawerProps) {
     try {
       await props.backend.registerTablePayment({
         tableId: props.table.id,
+        paymentId,
         products: productsToPay,
       })
       props.paymentRegistered()
@@ -57,6 +60,7 @@ export function PaymentDrawer(props: PaymentDrawerProps) {
     if (noProductsSelected) {
       setOpen(false)
     } else {
+      if (isOpen && !open) setPaymentId(crypto.randomUUID())
       setOpen(isOpen)
     }
   }
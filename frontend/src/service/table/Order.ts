@@ -10,6 +10,7 @@ export type OrderProduct = z.infer<typeof OrderProductSchema>
 
 export const PlaceOrderSchema = z.object({
   tableId: z.number().int().min(1),
+  orderId: z.uuid(),
   products: OrderProductSchema.array().min(1),
 })
 export type PlaceOrder = z.infer<typeof PlaceOrderSchema>
@@ -12,16 +12,23 @@ export const PaymentSchema = z.object({
   id: z.uuid(),
   userId: z.number().int().min(1),
   tableId: z.number().int().min(1),
-  products: PaymentProductSchema.array().min(1),
+  products: PaymentProductSchema.array(),
   totalPaymentCents: z.number().int().min(0),
   registeredAt: z.string().refine((date) => !isNaN(Date.parse(date)), {
     message: 'Invalid date format',
   }),
+  reversedAt: z
+    .string()
+    .refine((date) => !isNaN(Date.parse(date)), {
+      message: 'Invalid date format',
+    })
+    .optional(),
 })
 export type Payment = z.infer<typeof PaymentSchema>
 
 export const RegisterPaymentSchema = z.object({
   tableId: z.number().int().min(1),
+  paymentId: z.uuid(),
   products: PaymentProductSchema.array().min(1),
 })
 export type RegisterPayment = z.infer<typeof RegisterPaymentSchema>